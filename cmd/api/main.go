@@ -6,6 +6,8 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	// Profile timezones are validated without relying on the image's zoneinfo
+	_ "time/tzdata"
 
 	"github.com/alxand/nalo-workspace/internal/container"
 	"github.com/alxand/nalo-workspace/internal/pkg/logger"
@@ -25,12 +27,28 @@ func main() {
 	app := server.NewApp(container.Config, container.Logger)
 	app.SetupRoutes(
 		container.AuthHandler,
+		container.PasswordHandler,
+		container.VerificationHandler,
+		container.MFAHandler,
+		container.AccessTokenHandler,
+		container.OIDCHandler,
+		container.JWKSHandler,
+		container.InvitationHandler,
+		container.PermissionHandler,
+		container.ImpersonationHandler,
+		container.ProfileHandler,
+		container.SessionHandler,
+		container.AuditHandler,
 		container.DailyTaskHandler,
 		container.UserHandler,
 		container.ContinentHandler,
 		container.CountryHandler,
 		container.CompanyHandler,
 		container.AuthService,
+		container.AccessTokenService,
+		container.PermissionService,
+		container.Health,
+		container.RateLimitStore,
 	)
 
 	// Start server in a goroutine
@@ -47,11 +65,15 @@ func main() {
 
 	container.Logger.Info("Shutting down server...")
 
+	// Fail readiness first so load balancers stop sending new traffic
+	container.Health.SetShuttingDown()
+	time.Sleep(container.Config.Server.ShutdownDelay)
+
 	// Graceful shutdown with timeout
-	_, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := app.Shutdown(); err != nil {
+	if err := app.Shutdown(ctx); err != nil {
 		container.Logger.Error("Error during server shutdown", zap.Error(err))
 	}
 
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/migrations"
+)
+
+const usage = `Usage: migrate [-dir path] <command> [args]
+
+Commands:
+  up            Apply all pending migrations
+  down [n]      Roll back the last n migrations (default 1)
+  status        Show applied and pending migrations
+  redo          Roll back and re-apply the last migration
+  create <name> Create empty up/down files for every driver
+`
+
+func main() {
+	dir := flag.String("dir", "internal/migrations/sql", "migration source directory used by create")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	command := flag.Arg(0)
+
+	// create only touches the source tree, so it must work without a database
+	if command == "create" {
+		if flag.NArg() < 2 {
+			log.Fatal("create requires a migration name")
+		}
+		files, err := migrations.Create(*dir, flag.Arg(1))
+		if err != nil {
+			log.Fatal("Failed to create migration: ", err)
+		}
+		for _, file := range files {
+			fmt.Println("Created", file)
+		}
+		return
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load config: ", err)
+	}
+
+	connector, err := config.NewConnector(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to initialize database: ", err)
+	}
+	db, err := connector.Connect()
+	if err != nil {
+		log.Fatal("Failed to connect to database: ", err)
+	}
+
+	migrator, err := migrations.New(db, cfg.Database.Driver)
+	if err != nil {
+		log.Fatal("Failed to load migrations: ", err)
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Println("Applied", migration)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Schema is up to date")
+		}
+
+	case "down":
+		steps := 1
+		if flag.NArg() > 1 {
+			steps, err = strconv.Atoi(flag.Arg(1))
+			if err != nil || steps < 1 {
+				log.Fatal("down expects a positive number of steps")
+			}
+		}
+		rolledBack, err := migrator.Down(steps)
+		for _, migration := range rolledBack {
+			fmt.Println("Rolled back", migration)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+	case "redo":
+		migration, err := migrator.Redo()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Redone", migration)
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-40s %s\n", status.Migration, state)
+		}
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -1,18 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 
 	"github.com/alxand/nalo-workspace/internal/config"
 	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/migrations"
 	"github.com/alxand/nalo-workspace/internal/pkg/logger"
-	postgresRepo "github.com/alxand/nalo-workspace/internal/repository/postgres"
+	"github.com/alxand/nalo-workspace/internal/repository"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 func main() {
+	ctx := context.Background()
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -32,7 +36,11 @@ func main() {
 	}
 
 	// Run migrations
-	if err := config.RunMigrations(db); err != nil {
+	migrator, err := migrations.New(db, cfg.Database.Driver)
+	if err != nil {
+		log.Fatal("Failed to load migrations", zap.Error(err))
+	}
+	if _, err := migrator.Up(); err != nil {
 		log.Fatal("Failed to run migrations", zap.Error(err))
 	}
 
@@ -54,11 +62,15 @@ func main() {
 		log.Fatal("Failed to seed companies", zap.Error(err))
 	}
 
-	// Create user repository
-	userRepo := postgresRepo.NewUserRepository(db)
+	// Create user repository for the configured driver
+	repoFactory, err := repository.NewFactory(cfg.Database.Driver)
+	if err != nil {
+		log.Fatal("Failed to create repository factory", zap.Error(err))
+	}
+	userRepo := repoFactory.New(db).Users
 
 	// Check if admin user already exists
-	exists, err := userRepo.ExistsByEmail("admin@nalo-workspace.com")
+	exists, err := userRepo.ExistsByEmail(ctx, "admin@nalo-workspace.com")
 	if err != nil {
 		log.Fatal("Failed to check if admin exists", zap.Error(err))
 	}
@@ -79,7 +91,7 @@ func main() {
 		IsActive:  true,
 	}
 
-	if err := userRepo.Create(adminUser); err != nil {
+	if err := userRepo.Create(ctx, adminUser); err != nil {
 		log.Fatal("Failed to create admin user", zap.Error(err))
 	}
 
@@ -98,15 +110,9 @@ func main() {
 
 // initDatabase initializes the database connection
 func initDatabase(dbConfig config.DatabaseConfig) (*gorm.DB, error) {
-	var connector config.DBConnector
-
-	switch dbConfig.Driver {
-	case "postgres":
-		connector = config.NewPostgresConfig(dbConfig.DSN)
-	case "sqlite":
-		connector = config.NewSQLiteConfig(dbConfig.DSN)
-	default:
-		return nil, config.ErrUnsupportedDatabase
+	connector, err := config.NewConnector(dbConfig)
+	if err != nil {
+		return nil, err
 	}
 
 	return connector.Connect()
@@ -0,0 +1,191 @@
+// Package audit keeps an append-only record of who changed what, and of
+// authentication events, for admins to look back on
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"go.uber.org/zap"
+)
+
+// Source describes where a request came from and who it acts as
+type Source struct {
+	// ActorID is the authenticated user, 0 before login
+	ActorID int64
+	// ImpersonatorID is the admin acting as ActorID, 0 when not
+	// impersonating
+	ImpersonatorID int64
+	IP             string
+	UserAgent      string
+	RequestID      string
+}
+
+type ctxKey struct{}
+
+// NewContext returns ctx carrying s
+func NewContext(ctx context.Context, s Source) context.Context {
+	return context.WithValue(ctx, ctxKey{}, s)
+}
+
+// FromContext returns the source of ctx, which is empty for background
+// jobs
+func FromContext(ctx context.Context) Source {
+	s, _ := ctx.Value(ctxKey{}).(Source)
+	return s
+}
+
+// WithActor returns ctx with the authenticated user, and the admin acting
+// as them if any, added to its source
+func WithActor(ctx context.Context, actorID, impersonatorID int64) context.Context {
+	s := FromContext(ctx)
+	s.ActorID, s.ImpersonatorID = actorID, impersonatorID
+	return NewContext(ctx, s)
+}
+
+// ID formats a numeric entity ID
+func ID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// Event is a change or authentication event to record
+type Event struct {
+	Action     string
+	EntityType string
+	EntityID   string
+	// Before and After are the entity around the change, marshalled as
+	// JSON; either is nil when there is no such state
+	Before interface{}
+	After  interface{}
+	// ActorID names the actor where the request has none yet, such as a
+	// login
+	ActorID int64
+}
+
+// Service records events and lets admins read them back
+type Service struct {
+	cfg    config.AuditConfig
+	repo   interfaces.AuditLogInterface
+	logger *zap.Logger
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewService creates an audit service storing entries in repo
+func NewService(cfg config.AuditConfig, repo interfaces.AuditLogInterface, logger *zap.Logger) *Service {
+	return &Service{
+		cfg:    cfg,
+		repo:   repo,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Record appends event, attributed to the source of ctx. The change it
+// describes has already happened, so a failure is logged instead of
+// returned, and the entry is written even if the request was cancelled.
+func (s *Service) Record(ctx context.Context, event Event) {
+	source := FromContext(ctx)
+	entry := &models.AuditLog{
+		ActorID:        optionalID(source.ActorID),
+		ImpersonatorID: optionalID(source.ImpersonatorID),
+		Action:         event.Action,
+		EntityType:     event.EntityType,
+		EntityID:       event.EntityID,
+		Before:         s.marshal(event.Before),
+		After:          s.marshal(event.After),
+		IP:             source.IP,
+		UserAgent:      source.UserAgent,
+		RequestID:      source.RequestID,
+		CreatedAt:      s.now(),
+	}
+	if event.ActorID != 0 {
+		entry.ActorID = &event.ActorID
+	}
+
+	if err := s.repo.Create(context.WithoutCancel(ctx), entry); err != nil {
+		s.logger.Error("Failed to record audit log",
+			zap.String("action", entry.Action),
+			zap.String("entity_type", entry.EntityType),
+			zap.String("entity_id", entry.EntityID),
+			zap.String("request_id", entry.RequestID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (s *Service) marshal(v interface{}) models.AuditData {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.logger.Error("Failed to marshal audit data", zap.Error(err))
+		return ""
+	}
+	if string(data) == "null" {
+		return ""
+	}
+	return models.AuditData(data)
+}
+
+func optionalID(id int64) *int64 {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// List returns the entries matching filter, newest first
+func (s *Service) List(ctx context.Context, filter models.AuditLogFilter) ([]models.AuditLog, error) {
+	return s.repo.List(ctx, filter)
+}
+
+// Prune deletes the entries older than the retention period
+func (s *Service) Prune(ctx context.Context) (int64, error) {
+	if s.cfg.Retention <= 0 {
+		return 0, nil
+	}
+	return s.repo.DeleteBefore(ctx, s.now().Add(-s.cfg.Retention))
+}
+
+// Start prunes expired entries every interval until the returned stop
+// function is called. Nothing runs when entries are kept forever.
+func (s *Service) Start(interval time.Duration) (stop func()) {
+	if s.cfg.Retention <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				deleted, err := s.Prune(ctx)
+				if err != nil {
+					s.logger.Error("Failed to prune audit logs", zap.Error(err))
+				} else if deleted > 0 {
+					s.logger.Info("Pruned audit logs", zap.Int64("deleted", deleted))
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memoryAuditLogs keeps entries in memory, oldest first; List only
+// filters by actor
+type memoryAuditLogs struct {
+	entries []models.AuditLog
+}
+
+func (m *memoryAuditLogs) Create(ctx context.Context, entry *models.AuditLog) error {
+	entry.ID = int64(len(m.entries) + 1)
+	m.entries = append(m.entries, *entry)
+	return nil
+}
+
+func (m *memoryAuditLogs) List(ctx context.Context, filter models.AuditLogFilter) ([]models.AuditLog, error) {
+	var entries []models.AuditLog
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		entry := m.entries[i]
+		if filter.ActorID != 0 && (entry.ActorID == nil || *entry.ActorID != filter.ActorID) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (m *memoryAuditLogs) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	kept := m.entries[:0]
+	for _, entry := range m.entries {
+		if !entry.CreatedAt.Before(before) {
+			kept = append(kept, entry)
+		}
+	}
+	deleted := int64(len(m.entries) - len(kept))
+	m.entries = kept
+	return deleted, nil
+}
+
+func newTestService(cfg config.AuditConfig) *Service {
+	return NewService(cfg, &memoryAuditLogs{}, zap.NewNop())
+}
+
+func TestRecord_TakesSourceFromContext(t *testing.T) {
+	svc := newTestService(config.AuditConfig{})
+
+	ctx := NewContext(context.Background(), Source{IP: "203.0.113.7", UserAgent: "curl/8.0", RequestID: "req-1"})
+	ctx = WithActor(ctx, 3, 1)
+	svc.Record(ctx, Event{
+		Action:     models.AuditUpdate,
+		EntityType: models.AuditEntityCompany,
+		EntityID:   ID(42),
+		Before:     &models.Company{ID: 42, Name: "Acme"},
+		After:      &models.Company{ID: 42, Name: "Acme Corp"},
+	})
+
+	entries, err := svc.List(context.Background(), models.AuditLogFilter{Limit: 10})
+	if !assert.NoError(t, err) || !assert.Len(t, entries, 1) {
+		t.FailNow()
+	}
+	entry := entries[0]
+	if assert.NotNil(t, entry.ActorID) && assert.NotNil(t, entry.ImpersonatorID) {
+		assert.Equal(t, int64(3), *entry.ActorID)
+		assert.Equal(t, int64(1), *entry.ImpersonatorID)
+	}
+	assert.Equal(t, "42", entry.EntityID)
+	assert.Equal(t, "203.0.113.7", entry.IP)
+	assert.Equal(t, "curl/8.0", entry.UserAgent)
+	assert.Equal(t, "req-1", entry.RequestID)
+	assert.Contains(t, string(entry.Before), `"name":"Acme"`)
+	assert.Contains(t, string(entry.After), `"name":"Acme Corp"`)
+}
+
+func TestRecord_EventActorAndMissingState(t *testing.T) {
+	svc := newTestService(config.AuditConfig{})
+
+	// A login has no authenticated actor in the request yet
+	var user *models.User
+	svc.Record(context.Background(), Event{
+		Action:     models.AuditLogin,
+		EntityType: models.AuditEntityUser,
+		EntityID:   ID(5),
+		Before:     user,
+		ActorID:    5,
+	})
+
+	entries, err := svc.List(context.Background(), models.AuditLogFilter{ActorID: 5, Limit: 10})
+	if !assert.NoError(t, err) || !assert.Len(t, entries, 1) {
+		t.FailNow()
+	}
+	assert.Nil(t, entries[0].ImpersonatorID)
+	assert.Empty(t, entries[0].Before)
+	assert.Empty(t, entries[0].After)
+}
+
+func TestPrune_DeletesEntriesPastRetention(t *testing.T) {
+	svc := newTestService(config.AuditConfig{Retention: 24 * time.Hour})
+	now := time.Now()
+
+	svc.now = func() time.Time { return now.Add(-48 * time.Hour) }
+	svc.Record(context.Background(), Event{Action: models.AuditDelete, EntityType: models.AuditEntityCompany, EntityID: "1"})
+	svc.now = func() time.Time { return now }
+	svc.Record(context.Background(), Event{Action: models.AuditDelete, EntityType: models.AuditEntityCompany, EntityID: "2"})
+
+	deleted, err := svc.Prune(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	entries, err := svc.List(context.Background(), models.AuditLogFilter{Limit: 10})
+	if assert.NoError(t, err) && assert.Len(t, entries, 1) {
+		assert.Equal(t, "2", entries[0].EntityID)
+	}
+}
+
+func TestPrune_KeepsEverythingWithoutRetention(t *testing.T) {
+	svc := newTestService(config.AuditConfig{})
+
+	svc.now = func() time.Time { return time.Now().Add(-10 * 365 * 24 * time.Hour) }
+	svc.Record(context.Background(), Event{Action: models.AuditCreate, EntityType: models.AuditEntityCompany, EntityID: "1"})
+
+	deleted, err := svc.Prune(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, deleted)
+}
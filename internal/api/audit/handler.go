@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+type AuditHandler struct {
+	auditService *Service
+	logger       *zap.Logger
+}
+
+func NewAuditHandler(auditService *Service, logger *zap.Logger) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+		logger:       logger,
+	}
+}
+
+// ListAuditLogs godoc
+// @Summary List audit log entries (admin only)
+// @Description Changes and authentication events, newest first. Every filter is optional.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param actor_id query int false "User who acted"
+// @Param impersonator_id query int false "Admin who acted as the user"
+// @Param action query string false "Action, such as create, update, delete or login_failed"
+// @Param entity_type query string false "Entity type, such as company or user"
+// @Param entity_id query string false "Entity ID"
+// @Param from query string false "Earliest time, RFC 3339"
+// @Param to query string false "Time before which entries were recorded, RFC 3339"
+// @Param limit query int false "Limit number of results (default 50, at most 500)"
+// @Param offset query int false "Offset for pagination"
+// @Success 200 {array} models.AuditLog
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/audit-logs [get]
+func (h *AuditHandler) ListAuditLogs(c *fiber.Ctx) error {
+	filter := models.AuditLogFilter{
+		Action:     c.Query("action"),
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+		Limit:      defaultLimit,
+	}
+
+	var err error
+	if filter.ActorID, err = queryID(c, "actor_id"); err != nil {
+		return errors.BadRequest("Invalid actor ID", err).WithCode(errors.CodeInvalidID)
+	}
+	if filter.ImpersonatorID, err = queryID(c, "impersonator_id"); err != nil {
+		return errors.BadRequest("Invalid impersonator ID", err).WithCode(errors.CodeInvalidID)
+	}
+	if filter.From, err = queryTime(c, "from"); err != nil {
+		return errors.BadRequest("Invalid from time, expected RFC 3339", err).WithCode(errors.CodeInvalidRequest)
+	}
+	if filter.To, err = queryTime(c, "to"); err != nil {
+		return errors.BadRequest("Invalid to time, expected RFC 3339", err).WithCode(errors.CodeInvalidRequest)
+	}
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		filter.Limit = min(l, maxLimit)
+	}
+	if o, err := strconv.Atoi(c.Query("offset")); err == nil && o >= 0 {
+		filter.Offset = o
+	}
+
+	entries, err := h.auditService.List(c.UserContext(), filter)
+	if err != nil {
+		logger.FromCtx(c, h.logger).Error("Failed to list audit logs", zap.Error(err))
+		return errors.DatabaseError("Failed to list audit logs", err)
+	}
+
+	return c.JSON(entries)
+}
+
+// queryID parses an optional numeric query parameter, 0 when absent
+func queryID(c *fiber.Ctx, key string) (int64, error) {
+	param := c.Query(key)
+	if param == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(param, 10, 64)
+}
+
+// queryTime parses an optional RFC 3339 query parameter, zero when absent
+func queryTime(c *fiber.Ctx, key string) (time.Time, error) {
+	param := c.Query(key)
+	if param == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, param)
+}
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"go.uber.org/zap"
+)
+
+// Personal access token errors
+var (
+	ErrInvalidAccessToken         = errors.New("invalid, expired or revoked personal access token")
+	ErrAccessTokenNotFound        = errors.New("personal access token not found")
+	ErrAccessTokenLifetimeTooLong = errors.New("personal access token lifetime exceeds the maximum")
+)
+
+const (
+	// AccessTokenPrefix starts every personal access token, which tells
+	// them apart from JWTs and makes leaked tokens easy to scan for
+	AccessTokenPrefix = "nalo_pat_"
+	// accessTokenDisplayLength is how much of a token is kept in the clear
+	accessTokenDisplayLength = len(AccessTokenPrefix) + 4
+	// lastUsedInterval limits how often last_used_at is written per token
+	lastUsedInterval = time.Minute
+)
+
+// CreateAccessTokenRequest creates a personal access token. ExpiresInDays
+// defaults to the configured lifetime.
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=tasks:read tasks:write admin"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1"`
+}
+
+// CreatedAccessToken carries the plaintext token, which is shown only once
+type CreatedAccessToken struct {
+	Token string `json:"token"`
+	*models.PersonalAccessToken
+}
+
+// AccessTokenService manages personal access tokens and authenticates
+// requests made with them
+type AccessTokenService struct {
+	config   config.AccessTokenConfig
+	userRepo interfaces.UserInterface
+	tokens   interfaces.PersonalAccessTokenInterface
+	logger   *zap.Logger
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewAccessTokenService creates a personal access token service
+func NewAccessTokenService(cfg config.AccessTokenConfig, userRepo interfaces.UserInterface, tokens interfaces.PersonalAccessTokenInterface, logger *zap.Logger) *AccessTokenService {
+	return &AccessTokenService{
+		config:   cfg,
+		userRepo: userRepo,
+		tokens:   tokens,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// IsAccessToken reports whether a bearer token is a personal access token
+// rather than a JWT
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, AccessTokenPrefix)
+}
+
+// Create issues a personal access token for the user
+func (s *AccessTokenService) Create(ctx context.Context, userID int64, req *CreateAccessTokenRequest) (*CreatedAccessToken, error) {
+	lifetime := s.config.DefaultLifetime
+	if req.ExpiresInDays > 0 {
+		lifetime = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	}
+	if s.config.MaxLifetime > 0 && lifetime > s.config.MaxLifetime {
+		return nil, ErrAccessTokenLifetimeTooLong
+	}
+
+	secret, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	plain := AccessTokenPrefix + secret
+
+	token := &models.PersonalAccessToken{
+		UserID:      userID,
+		Name:        req.Name,
+		TokenPrefix: plain[:accessTokenDisplayLength],
+		TokenHash:   hashOpaqueToken(plain),
+		Scopes:      dedupeScopes(req.Scopes),
+		ExpiresAt:   s.now().Add(lifetime),
+	}
+	if err := s.tokens.Create(ctx, token); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Personal access token created",
+		zap.Int64("user_id", userID),
+		zap.Int64("token_id", token.ID),
+		zap.Strings("scopes", token.Scopes),
+	)
+	return &CreatedAccessToken{Token: plain, PersonalAccessToken: token}, nil
+}
+
+// List returns the tokens of the user that were not revoked
+func (s *AccessTokenService) List(ctx context.Context, userID int64) ([]models.PersonalAccessToken, error) {
+	return s.tokens.ListForUser(ctx, userID)
+}
+
+// Revoke revokes a token of the user
+func (s *AccessTokenService) Revoke(ctx context.Context, userID, tokenID int64) error {
+	revoked, err := s.tokens.Revoke(ctx, tokenID, userID, s.now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAccessTokenNotFound
+	}
+
+	s.logger.Info("Personal access token revoked", zap.Int64("user_id", userID), zap.Int64("token_id", tokenID))
+	return nil
+}
+
+// ListForUser returns the tokens of any user that were not revoked. Users
+// outside the tenant of ctx are not found.
+func (s *AccessTokenService) ListForUser(ctx context.Context, userID int64) ([]models.PersonalAccessToken, error) {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.List(ctx, userID)
+}
+
+// RevokeForUser revokes a token of any user. Users outside the tenant of ctx
+// are not found.
+func (s *AccessTokenService) RevokeForUser(ctx context.Context, userID, tokenID int64) error {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return err
+	}
+	return s.Revoke(ctx, userID, tokenID)
+}
+
+// Authenticate returns the owner of an active token and the token itself,
+// whose scopes limit what the request may do
+func (s *AccessTokenService) Authenticate(ctx context.Context, plain string) (*models.User, *models.PersonalAccessToken, error) {
+	token, err := s.tokens.GetByHash(ctx, hashOpaqueToken(plain))
+	if err != nil {
+		return nil, nil, ErrInvalidAccessToken
+	}
+	now := s.now()
+	if !token.IsActive(now) {
+		return nil, nil, ErrInvalidAccessToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !user.IsActive {
+		return nil, nil, ErrAccountDeactivated
+	}
+
+	// Tracking is best effort and must not fail the request
+	if err := s.tokens.TouchLastUsed(ctx, token.ID, now, now.Add(-lastUsedInterval)); err != nil {
+		s.logger.Error("Failed to record personal access token use", zap.Int64("token_id", token.ID), zap.Error(err))
+	}
+
+	return user, token, nil
+}
+
+// dedupeScopes drops repeated scopes, keeping the order they were given in
+func dedupeScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result
+}
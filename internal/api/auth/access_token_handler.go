@@ -0,0 +1,199 @@
+package auth
+
+import (
+	stderrors "errors"
+	"strconv"
+
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/alxand/nalo-workspace/internal/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type AccessTokenHandler struct {
+	accessTokenService *AccessTokenService
+	auditService       *audit.Service
+	logger             *zap.Logger
+}
+
+func NewAccessTokenHandler(accessTokenService *AccessTokenService, auditService *audit.Service, logger *zap.Logger) *AccessTokenHandler {
+	return &AccessTokenHandler{
+		accessTokenService: accessTokenService,
+		auditService:       auditService,
+		logger:             logger,
+	}
+}
+
+// ListTokens godoc
+// @Summary List personal access tokens
+// @Description Lists the tokens of the current user that were not revoked. The tokens themselves are never returned again.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.PersonalAccessToken
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/tokens [get]
+func (h *AccessTokenHandler) ListTokens(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	tokens, err := h.accessTokenService.List(c.UserContext(), user.ID)
+	if err != nil {
+		logger.FromCtx(c, h.logger).Error("Failed to list personal access tokens", zap.Error(err))
+		return errors.DatabaseError("Failed to list personal access tokens", err)
+	}
+	return c.JSON(tokens)
+}
+
+// CreateToken godoc
+// @Summary Create a personal access token
+// @Description Returns the token once; only its hash is stored
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body CreateAccessTokenRequest true "Token name, scopes and lifetime"
+// @Success 201 {object} CreatedAccessToken
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/tokens [post]
+func (h *AccessTokenHandler) CreateToken(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	var req CreateAccessTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+
+	created, err := h.accessTokenService.Create(c.UserContext(), user.ID, &req)
+	if err != nil {
+		if stderrors.Is(err, ErrAccessTokenLifetimeTooLong) {
+			return errors.BadRequest(err.Error(), nil).WithCode(errors.CodeInvalidRequest)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to create personal access token", zap.Error(err))
+		return errors.DatabaseError("Failed to create personal access token", err)
+	}
+
+	// Without the token itself, which is only shown once
+	h.auditService.Record(c.UserContext(), audit.Event{
+		Action: models.AuditCreate, EntityType: models.AuditEntityAccessToken, EntityID: audit.ID(created.ID), After: created.PersonalAccessToken,
+	})
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// RevokeToken godoc
+// @Summary Revoke a personal access token
+// @Tags auth
+// @Security BearerAuth
+// @Param id path int true "Token ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/tokens/{id} [delete]
+func (h *AccessTokenHandler) RevokeToken(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return errors.BadRequest("Invalid token ID", err).WithCode(errors.CodeInvalidID)
+	}
+
+	if err := h.accessTokenService.Revoke(c.UserContext(), user.ID, id); err != nil {
+		if stderrors.Is(err, ErrAccessTokenNotFound) {
+			return errors.NotFound(err.Error(), nil).WithCode(errors.CodeTokenNotFound)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to revoke personal access token", zap.Int64("token_id", id), zap.Error(err))
+		return errors.DatabaseError("Failed to revoke personal access token", err)
+	}
+
+	h.auditService.Record(c.UserContext(), audit.Event{Action: models.AuditDelete, EntityType: models.AuditEntityAccessToken, EntityID: audit.ID(id)})
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListUserTokens godoc
+// @Summary List a user's personal access tokens (admin only)
+// @Description Lists the tokens of the user that were not revoked. The tokens themselves are never returned again.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {array} models.PersonalAccessToken
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/tokens [get]
+func (h *AccessTokenHandler) ListUserTokens(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return errors.BadRequest("Invalid user ID", err).WithCode(errors.CodeInvalidID)
+	}
+
+	tokens, err := h.accessTokenService.ListForUser(c.UserContext(), id)
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NotFound("User not found", err).WithCode(errors.CodeUserNotFound)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to list user personal access tokens", zap.Int64("user_id", id), zap.Error(err))
+		return errors.DatabaseError("Failed to list personal access tokens", err)
+	}
+	return c.JSON(tokens)
+}
+
+// RevokeUserToken godoc
+// @Summary Revoke a user's personal access token (admin only)
+// @Tags users
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param token_id path int true "Token ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/tokens/{token_id} [delete]
+func (h *AccessTokenHandler) RevokeUserToken(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return errors.BadRequest("Invalid user ID", err).WithCode(errors.CodeInvalidID)
+	}
+	tokenID, err := strconv.ParseInt(c.Params("token_id"), 10, 64)
+	if err != nil {
+		return errors.BadRequest("Invalid token ID", err).WithCode(errors.CodeInvalidID)
+	}
+
+	if err := h.accessTokenService.RevokeForUser(c.UserContext(), id, tokenID); err != nil {
+		switch {
+		case stderrors.Is(err, gorm.ErrRecordNotFound):
+			return errors.NotFound("User not found", err).WithCode(errors.CodeUserNotFound)
+		case stderrors.Is(err, ErrAccessTokenNotFound):
+			return errors.NotFound(err.Error(), nil).WithCode(errors.CodeTokenNotFound)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to revoke user personal access token", zap.Int64("user_id", id), zap.Int64("token_id", tokenID), zap.Error(err))
+		return errors.DatabaseError("Failed to revoke personal access token", err)
+	}
+
+	h.auditService.Record(c.UserContext(), audit.Event{Action: models.AuditDelete, EntityType: models.AuditEntityAccessToken, EntityID: audit.ID(tokenID)})
+	return c.SendStatus(fiber.StatusNoContent)
+}
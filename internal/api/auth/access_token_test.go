@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// memoryAccessTokens is an in-memory PersonalAccessTokenInterface
+type memoryAccessTokens struct {
+	tokens []*models.PersonalAccessToken
+}
+
+func (m *memoryAccessTokens) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	token.ID = int64(len(m.tokens) + 1)
+	m.tokens = append(m.tokens, token)
+	return nil
+}
+
+func (m *memoryAccessTokens) GetByHash(ctx context.Context, hash string) (*models.PersonalAccessToken, error) {
+	for _, token := range m.tokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryAccessTokens) ListForUser(ctx context.Context, userID int64) ([]models.PersonalAccessToken, error) {
+	var result []models.PersonalAccessToken
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			result = append(result, *token)
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryAccessTokens) Revoke(ctx context.Context, id, userID int64, at time.Time) (bool, error) {
+	for _, token := range m.tokens {
+		if token.ID == id && token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryAccessTokens) RevokeAllForUser(ctx context.Context, userID int64, at time.Time) error {
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (m *memoryAccessTokens) TouchLastUsed(ctx context.Context, id int64, at, notBefore time.Time) error {
+	for _, token := range m.tokens {
+		if token.ID == id && (token.LastUsedAt == nil || token.LastUsedAt.Before(notBefore)) {
+			token.LastUsedAt = &at
+		}
+	}
+	return nil
+}
+
+func newAccessTokenTestService(t *testing.T) (*AccessTokenService, *memoryAccessTokens, *models.User) {
+	user := &models.User{ID: 1, Email: "test@example.com", Role: models.RoleUser, IsActive: true}
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("GetByID", int64(1)).Return(user, nil)
+	mockRepo.On("GetByID", int64(99)).Return((*models.User)(nil), gorm.ErrRecordNotFound)
+
+	tokens := &memoryAccessTokens{}
+	cfg := config.AccessTokenConfig{DefaultLifetime: 24 * time.Hour, MaxLifetime: 7 * 24 * time.Hour}
+	return NewAccessTokenService(cfg, mockRepo, tokens, zap.NewNop()), tokens, user
+}
+
+func TestAccessToken_CreateAndAuthenticate(t *testing.T) {
+	service, tokens, _ := newAccessTokenTestService(t)
+
+	created, err := service.Create(context.Background(), 1, &CreateAccessTokenRequest{
+		Name:   "ci",
+		Scopes: []string{models.ScopeTasksRead, models.ScopeTasksRead},
+	})
+	assert.NoError(t, err)
+	assert.True(t, IsAccessToken(created.Token))
+	assert.True(t, strings.HasPrefix(created.Token, created.TokenPrefix))
+	assert.Equal(t, []string{models.ScopeTasksRead}, created.Scopes)
+	assert.NotContains(t, tokens.tokens[0].TokenHash, created.Token[len(AccessTokenPrefix):], "only the hash is stored")
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), created.ExpiresAt, time.Minute)
+
+	user, token, err := service.Authenticate(context.Background(), created.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), user.ID)
+	assert.True(t, token.HasScope(models.ScopeTasksRead))
+	assert.False(t, token.HasScope(models.ScopeTasksWrite))
+	assert.NotNil(t, tokens.tokens[0].LastUsedAt)
+
+	_, _, err = service.Authenticate(context.Background(), created.Token+"x")
+	assert.ErrorIs(t, err, ErrInvalidAccessToken)
+
+	_, err = service.Create(context.Background(), 1, &CreateAccessTokenRequest{Name: "long", Scopes: []string{models.ScopeAdmin}, ExpiresInDays: 8})
+	assert.ErrorIs(t, err, ErrAccessTokenLifetimeTooLong)
+}
+
+func TestAccessToken_RevokedExpiredAndDeactivated(t *testing.T) {
+	service, _, user := newAccessTokenTestService(t)
+	ctx := context.Background()
+
+	revoked, err := service.Create(ctx, 1, &CreateAccessTokenRequest{Name: "revoked", Scopes: []string{models.ScopeAdmin}})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, service.Revoke(ctx, 2, revoked.ID), ErrAccessTokenNotFound, "only the owner can revoke")
+	assert.NoError(t, service.Revoke(ctx, 1, revoked.ID))
+	assert.ErrorIs(t, service.Revoke(ctx, 1, revoked.ID), ErrAccessTokenNotFound)
+	_, _, err = service.Authenticate(ctx, revoked.Token)
+	assert.ErrorIs(t, err, ErrInvalidAccessToken)
+
+	listed, err := service.List(ctx, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, listed)
+
+	active, err := service.Create(ctx, 1, &CreateAccessTokenRequest{Name: "active", Scopes: []string{models.ScopeAdmin}, ExpiresInDays: 1})
+	assert.NoError(t, err)
+
+	user.IsActive = false
+	_, _, err = service.Authenticate(ctx, active.Token)
+	assert.ErrorIs(t, err, ErrAccountDeactivated)
+	user.IsActive = true
+
+	later := time.Now().Add(25 * time.Hour)
+	service.now = func() time.Time { return later }
+	_, _, err = service.Authenticate(ctx, active.Token)
+	assert.ErrorIs(t, err, ErrInvalidAccessToken)
+}
+
+func TestAccessToken_AdminListAndRevokeCheckUser(t *testing.T) {
+	service, _, _ := newAccessTokenTestService(t)
+	ctx := context.Background()
+
+	created, err := service.Create(ctx, 1, &CreateAccessTokenRequest{Name: "ci", Scopes: []string{models.ScopeTasksRead}})
+	assert.NoError(t, err)
+
+	_, err = service.ListForUser(ctx, 99)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, service.RevokeForUser(ctx, 99, created.ID), gorm.ErrRecordNotFound)
+
+	listed, err := service.ListForUser(ctx, 1)
+	if assert.NoError(t, err) && assert.Len(t, listed, 1) {
+		assert.Equal(t, created.ID, listed[0].ID)
+	}
+	assert.NoError(t, service.RevokeForUser(ctx, 1, created.ID))
+	assert.ErrorIs(t, service.RevokeForUser(ctx, 1, created.ID), ErrAccessTokenNotFound)
+	_, _, err = service.Authenticate(ctx, created.Token)
+	assert.ErrorIs(t, err, ErrInvalidAccessToken)
+}
+
+func TestAccessToken_RevokeAllTokensRevokesAccessTokens(t *testing.T) {
+	service, tokens, _ := newAccessTokenTestService(t)
+	authService := newTestService(t, new(MockUserRepository), withAccessTokens(tokens))
+	ctx := context.Background()
+
+	created, err := service.Create(ctx, 1, &CreateAccessTokenRequest{Name: "ci", Scopes: []string{models.ScopeAdmin}})
+	assert.NoError(t, err)
+	other, err := service.Create(ctx, 2, &CreateAccessTokenRequest{Name: "other", Scopes: []string{models.ScopeAdmin}})
+	assert.NoError(t, err)
+
+	assert.NoError(t, authService.RevokeAllTokens(ctx, 1))
+	_, _, err = service.Authenticate(ctx, created.Token)
+	assert.ErrorIs(t, err, ErrInvalidAccessToken)
+	assert.Nil(t, tokens.tokens[other.ID-1].RevokedAt, "tokens of other users are kept")
+}
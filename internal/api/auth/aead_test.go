@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAEAD_SharedKeyIsSplitByPurpose(t *testing.T) {
+	mfa, err := newAEAD("shared-key", "jwt-secret", "mfa-secret")
+	assert.NoError(t, err)
+	keys, err := newAEAD("shared-key", "jwt-secret", "signing-keys")
+	assert.NoError(t, err)
+
+	sealed, err := seal(mfa, []byte("totp-secret"))
+	assert.NoError(t, err)
+	plain, err := open(mfa, sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "totp-secret", string(plain))
+
+	_, err = open(keys, sealed)
+	assert.Error(t, err, "data sealed for one purpose does not open for another")
+}
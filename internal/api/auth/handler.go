@@ -1,35 +1,46 @@
 package auth
 
 import (
+	stderrors "errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/api/audit"
 	"github.com/alxand/nalo-workspace/internal/domain/models"
 	"github.com/alxand/nalo-workspace/internal/pkg/errors"
-	"github.com/go-playground/validator/v10"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/alxand/nalo-workspace/internal/pkg/validation"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 )
 
 type AuthHandler struct {
-	authService *Service
-	logger      *zap.Logger
-	validate    *validator.Validate
+	authService         *Service
+	verificationService *VerificationService
+	auditService        *audit.Service
+	logger              *zap.Logger
 }
 
-func NewAuthHandler(authService *Service, logger *zap.Logger) *AuthHandler {
+func NewAuthHandler(authService *Service, verificationService *VerificationService, auditService *audit.Service, logger *zap.Logger) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		logger:      logger,
-		validate:    validator.New(),
+		authService:         authService,
+		verificationService: verificationService,
+		auditService:        auditService,
+		logger:              logger,
 	}
 }
 
 // Register godoc
 // @Summary Register a new user
+// @Description Self-registered users get the user role. Answers 403 when registration is disabled.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param user body RegisterRequest true "User registration data"
 // @Success 201 {object} models.User
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /auth/register [post]
@@ -38,32 +49,50 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 
 	// Parse request body
 	if err := c.BodyParser(&req); err != nil {
-		h.logger.Error("Failed to parse register request", zap.Error(err))
-		return errors.BadRequest("Invalid request body", err)
+		logger.FromCtx(c, h.logger).Error("Failed to parse register request", zap.Error(err))
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
 	}
 
 	// Validate request
-	if err := h.validate.Struct(req); err != nil {
-		h.logger.Error("Register validation failed", zap.Error(err))
+	if err := validation.ValidateStruct(req); err != nil {
+		logger.FromCtx(c, h.logger).Error("Register validation failed", zap.Error(err))
 		return errors.ValidationError("Validation failed", err)
 	}
 
 	// Register user
-	user, err := h.authService.Register(&req)
+	user, err := h.authService.Register(c.UserContext(), &req)
 	if err != nil {
-		h.logger.Error("Failed to register user", zap.String("email", req.Email), zap.Error(err))
-		if err.Error() == "email already exists" || err.Error() == "username already exists" {
-			return errors.BadRequest(err.Error(), err)
+		logger.FromCtx(c, h.logger).Error("Failed to register user", zap.String("email", req.Email), zap.Error(err))
+		switch {
+		case stderrors.Is(err, ErrEmailTaken):
+			return errors.Conflict(err.Error(), nil).WithCode(errors.CodeEmailTaken)
+		case stderrors.Is(err, ErrUsernameTaken):
+			return errors.Conflict(err.Error(), nil).WithCode(errors.CodeUsernameTaken)
 		}
 		return errors.InternalServerError("Failed to register user", err)
 	}
 
-	h.logger.Info("User registered successfully", zap.String("email", req.Email), zap.Int64("user_id", user.ID))
+	logger.FromCtx(c, h.logger).Info("User registered successfully", zap.String("email", req.Email), zap.Int64("user_id", user.ID))
+	h.auditService.Record(c.UserContext(), audit.Event{
+		Action: models.AuditCreate, EntityType: models.AuditEntityUser, EntityID: audit.ID(user.ID), After: user, ActorID: user.ID,
+	})
+
+	// The account exists either way; a lost email can be resent
+	if err := h.verificationService.SendVerification(c.UserContext(), user); err != nil {
+		logger.FromCtx(c, h.logger).Error("Failed to send verification email", zap.Int64("user_id", user.ID), zap.Error(err))
+	}
 	return c.Status(fiber.StatusCreated).JSON(user)
 }
 
+// RegistrationDisabled answers public registration attempts when accounts
+// are only created by invitation
+func (h *AuthHandler) RegistrationDisabled(c *fiber.Ctx) error {
+	return errors.Forbidden(ErrRegistrationDisabled.Error(), nil).WithCode(errors.CodeRegistrationDisabled)
+}
+
 // Login godoc
 // @Summary Login user
+// @Description Answers with an MFAChallenge instead of tokens when two-factor authentication is enabled
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -71,6 +100,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 // @Success 200 {object} LoginResponse
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
@@ -78,30 +108,82 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 
 	// Parse request body
 	if err := c.BodyParser(&req); err != nil {
-		h.logger.Error("Failed to parse login request", zap.Error(err))
-		return errors.BadRequest("Invalid request body", err)
+		logger.FromCtx(c, h.logger).Error("Failed to parse login request", zap.Error(err))
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
 	}
 
 	// Validate request
-	if err := h.validate.Struct(req); err != nil {
-		h.logger.Error("Login validation failed", zap.Error(err))
+	if err := validation.ValidateStruct(req); err != nil {
+		logger.FromCtx(c, h.logger).Error("Login validation failed", zap.Error(err))
 		return errors.ValidationError("Validation failed", err)
 	}
 
 	// Login user
-	response, err := h.authService.Login(&req)
+	response, err := h.authService.Login(c.UserContext(), &req)
 	if err != nil {
-		h.logger.Error("Failed to login user", zap.String("email", req.Email), zap.Error(err))
-		if err.Error() == "invalid credentials" || err.Error() == "account is deactivated" {
-			return errors.Unauthorized(err.Error(), err)
+		// The password was right; the client continues at /auth/login/mfa
+		var challenge *MFAChallenge
+		if stderrors.As(err, &challenge) {
+			return c.JSON(challenge)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to login user", zap.String("email", req.Email), zap.Error(err))
+		appErr := loginError(c, err)
+		if appErr == nil {
+			return errors.InternalServerError("Failed to login user", err)
 		}
-		return errors.InternalServerError("Failed to login user", err)
+		recordLoginFailure(c, h.auditService, req.Email, appErr)
+		return appErr
 	}
 
-	h.logger.Info("User logged in successfully", zap.String("email", req.Email), zap.Int64("user_id", response.User.ID))
+	logger.FromCtx(c, h.logger).Info("User logged in successfully", zap.String("email", req.Email), zap.Int64("user_id", response.User.ID))
+	recordLogin(c, h.auditService, response.User)
 	return c.JSON(response)
 }
 
+// loginError maps login errors to client errors, shared by password and
+// two-factor logins
+func loginError(c *fiber.Ctx, err error) *errors.AppError {
+	var lockedErr *AccountLockedError
+	if stderrors.As(err, &lockedErr) {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(time.Until(lockedErr.Until).Seconds()))))
+		return errors.TooManyRequests(err.Error(), nil).WithCode(errors.CodeAccountLocked)
+	}
+	switch {
+	case stderrors.Is(err, ErrInvalidCredentials):
+		return errors.Unauthorized(err.Error(), nil).WithCode(errors.CodeInvalidCredentials)
+	case stderrors.Is(err, ErrAccountDeactivated):
+		return errors.Unauthorized(err.Error(), nil).WithCode(errors.CodeAccountDeactivated)
+	case stderrors.Is(err, ErrEmailNotVerified):
+		return errors.Forbidden(err.Error(), nil).WithCode(errors.CodeEmailNotVerified)
+	case stderrors.Is(err, ErrInvalidMFAChallenge):
+		return errors.Unauthorized(err.Error(), nil).WithCode(errors.CodeMFAChallenge)
+	case stderrors.Is(err, ErrInvalidMFACode):
+		return errors.Unauthorized(err.Error(), nil).WithCode(errors.CodeMFACodeInvalid)
+	}
+	return nil
+}
+
+// recordLogin records a finished login of user, however it was made
+func recordLogin(c *fiber.Ctx, auditService *audit.Service, user *models.User) {
+	auditService.Record(c.UserContext(), audit.Event{
+		Action: models.AuditLogin, EntityType: models.AuditEntityUser, EntityID: audit.ID(user.ID), ActorID: user.ID,
+	})
+}
+
+// recordLoginFailure records a login refused with appErr. Password logins
+// name the email tried, which may not belong to an account.
+func recordLoginFailure(c *fiber.Ctx, auditService *audit.Service, email string, appErr *errors.AppError) {
+	details := fiber.Map{"reason": appErr.ErrorCode}
+	if email != "" {
+		details["email"] = email
+	}
+	auditService.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditLoginFailed,
+		EntityType: models.AuditEntityUser,
+		After:      details,
+	})
+}
+
 // Profile godoc
 // @Summary Get current user profile
 // @Tags auth
@@ -122,35 +204,107 @@ func (h *AuthHandler) Profile(c *fiber.Ctx) error {
 }
 
 // RefreshToken godoc
-// @Summary Refresh JWT token
+// @Summary Exchange a refresh token for new tokens
+// @Description Rotates the refresh token. Reusing an already rotated token revokes the whole session.
 // @Tags auth
+// @Accept json
 // @Produce json
-// @Security BearerAuth
+// @Param body body RefreshRequest true "Refresh token"
 // @Success 200 {object} LoginResponse
+// @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /auth/refresh [post]
 func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
-	// Get user from context (set by JWT middleware)
-	user := c.Locals("user").(*models.User)
-	if user == nil {
-		return errors.Unauthorized("User not found in context", nil)
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
 	}
 
-	// Generate new token
-	token, expiresAt, err := h.authService.GenerateJWT(user)
+	response, err := h.authService.Refresh(c.UserContext(), req.RefreshToken)
 	if err != nil {
-		h.logger.Error("Failed to generate refresh token", zap.Int64("user_id", user.ID), zap.Error(err))
+		switch {
+		case stderrors.Is(err, ErrRefreshTokenReused):
+			return errors.Unauthorized("Refresh token has already been used", nil).WithCode(errors.CodeRefreshTokenReused)
+		case stderrors.Is(err, ErrInvalidRefreshToken):
+			return errors.Unauthorized("Invalid or expired refresh token", nil).WithCode(errors.CodeRefreshTokenInvalid)
+		case stderrors.Is(err, ErrAccountDeactivated):
+			return errors.Unauthorized(err.Error(), nil).WithCode(errors.CodeAccountDeactivated)
+		case stderrors.Is(err, ErrEmailNotVerified):
+			return errors.Forbidden(err.Error(), nil).WithCode(errors.CodeEmailNotVerified)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to refresh token", zap.Error(err))
 		return errors.InternalServerError("Failed to refresh token", err)
 	}
 
-	response := &LoginResponse{
-		Token:     token,
-		Type:      "Bearer",
-		User:      user,
-		ExpiresAt: expiresAt,
+	logger.FromCtx(c, h.logger).Info("Token refreshed successfully", zap.Int64("user_id", response.User.ID))
+	h.auditService.Record(c.UserContext(), audit.Event{
+		Action: models.AuditTokenRefresh, EntityType: models.AuditEntityUser, EntityID: audit.ID(response.User.ID), ActorID: response.User.ID,
+	})
+	return c.JSON(response)
+}
+
+// Logout godoc
+// @Summary Log out of the current session
+// @Description Revokes the access token of the request, the given refresh token and every token rotated from it
+// @Tags auth
+// @Accept json
+// @Security BearerAuth
+// @Param body body RefreshRequest true "Refresh token"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
 	}
 
-	h.logger.Info("Token refreshed successfully", zap.Int64("user_id", user.ID))
-	return c.JSON(response)
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+
+	accessToken, _ := c.Locals("token").(string)
+	if err := h.authService.Logout(c.UserContext(), user.ID, accessToken, req.RefreshToken); err != nil {
+		logger.FromCtx(c, h.logger).Error("Failed to log out", zap.Error(err))
+		return errors.DatabaseError("Failed to log out", err)
+	}
+
+	logger.FromCtx(c, h.logger).Info("User logged out")
+	h.auditService.Record(c.UserContext(), audit.Event{Action: models.AuditLogout, EntityType: models.AuditEntityUser, EntityID: audit.ID(user.ID)})
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// LogoutAll godoc
+// @Summary Log out of every session
+// @Description Revokes all access, refresh and personal access tokens of the current user
+// @Tags auth
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	if err := h.authService.LogoutAll(c.UserContext(), user.ID); err != nil {
+		logger.FromCtx(c, h.logger).Error("Failed to log out everywhere", zap.Error(err))
+		return errors.DatabaseError("Failed to log out", err)
+	}
+
+	logger.FromCtx(c, h.logger).Info("User logged out of all sessions")
+	h.auditService.Record(c.UserContext(), audit.Event{Action: models.AuditLogoutAll, EntityType: models.AuditEntityUser, EntityID: audit.ID(user.ID)})
+	return c.SendStatus(fiber.StatusNoContent)
 }
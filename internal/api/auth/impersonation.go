@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"go.uber.org/zap"
+)
+
+// Impersonation errors
+var (
+	ErrImpersonationNotAllowed = errors.New("this user cannot be impersonated")
+	ErrNotImpersonating        = errors.New("the request is not made while impersonating")
+)
+
+// ImpersonationResponse carries a token acting as another user. There is
+// no refresh token; impersonation ends when the token expires at the latest.
+type ImpersonationResponse struct {
+	Token          string       `json:"token"`
+	Type           string       `json:"type"`
+	User           *models.User `json:"user"`
+	ImpersonatorID int64        `json:"impersonator_id"`
+	ExpiresAt      time.Time    `json:"expires_at"`
+}
+
+// ImpersonationService lets admins act as another user to see what they
+// see when supporting them
+type ImpersonationService struct {
+	authService *Service
+	userRepo    interfaces.UserInterface
+	permissions *PermissionService
+	logger      *zap.Logger
+}
+
+// NewImpersonationService creates an impersonation service
+func NewImpersonationService(authService *Service, userRepo interfaces.UserInterface, permissions *PermissionService, logger *zap.Logger) *ImpersonationService {
+	return &ImpersonationService{
+		authService: authService,
+		userRepo:    userRepo,
+		permissions: permissions,
+		logger:      logger,
+	}
+}
+
+// Start issues a token acting as the user with userID on behalf of actor.
+// Users who can manage other users or impersonate themselves cannot be
+// impersonated, so impersonation never gains privileges.
+func (s *ImpersonationService) Start(ctx context.Context, actor *models.User, userID int64) (*ImpersonationResponse, error) {
+	if userID == actor.ID {
+		return nil, ErrImpersonationNotAllowed
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrAccountDeactivated
+	}
+	if s.permissions.Has(user, models.PermissionUserImpersonate, models.PermissionUserAdmin, models.PermissionRoleAdmin) {
+		return nil, ErrImpersonationNotAllowed
+	}
+
+	token, expiresAt, err := s.authService.GenerateImpersonationJWT(user, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Warn("Impersonation started",
+		zap.Int64("impersonator_id", actor.ID),
+		zap.Int64("user_id", user.ID),
+		zap.Time("expires_at", expiresAt),
+	)
+	return &ImpersonationResponse{
+		Token:          token,
+		Type:           "Bearer",
+		User:           user,
+		ImpersonatorID: actor.ID,
+		ExpiresAt:      expiresAt,
+	}, nil
+}
+
+// End revokes the impersonation token actor used to act as user
+func (s *ImpersonationService) End(ctx context.Context, actor, user *models.User, token string) error {
+	if actor == nil {
+		return ErrNotImpersonating
+	}
+	if err := s.authService.RevokeAccessToken(ctx, token); err != nil {
+		return err
+	}
+
+	s.logger.Warn("Impersonation ended",
+		zap.Int64("impersonator_id", actor.ID),
+		zap.Int64("user_id", user.ID),
+	)
+	return nil
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	stderrors "errors"
+	"strconv"
+
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ImpersonationHandler struct {
+	impersonationService *ImpersonationService
+	auditService         *audit.Service
+	logger               *zap.Logger
+}
+
+func NewImpersonationHandler(impersonationService *ImpersonationService, auditService *audit.Service, logger *zap.Logger) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		impersonationService: impersonationService,
+		auditService:         auditService,
+		logger:               logger,
+	}
+}
+
+// Impersonate godoc
+// @Summary Act as another user (admin only)
+// @Description Returns a short-lived token acting as the user, with an act claim naming the admin. It cannot be refreshed, and changing the password, two-factor authentication or tokens and deleting accounts are refused with it. Users who can manage users cannot be impersonated.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} ImpersonationResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/impersonate [post]
+func (h *ImpersonationHandler) Impersonate(c *fiber.Ctx) error {
+	actor, ok := c.Locals("user").(*models.User)
+	if !ok || actor == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return errors.BadRequest("Invalid user ID", err).WithCode(errors.CodeInvalidID)
+	}
+
+	response, err := h.impersonationService.Start(c.UserContext(), actor, id)
+	if err != nil {
+		switch {
+		case stderrors.Is(err, gorm.ErrRecordNotFound):
+			return errors.NotFound("User not found", err).WithCode(errors.CodeUserNotFound)
+		case stderrors.Is(err, ErrImpersonationNotAllowed):
+			return errors.Forbidden(err.Error(), nil).WithCode(errors.CodePermissionDenied)
+		case stderrors.Is(err, ErrAccountDeactivated):
+			return errors.Forbidden(err.Error(), nil).WithCode(errors.CodeAccountDeactivated)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to impersonate user", zap.Int64("user_id", id), zap.Error(err))
+		return errors.InternalServerError("Failed to impersonate user", err)
+	}
+
+	h.auditService.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditImpersonationStart,
+		EntityType: models.AuditEntityUser,
+		EntityID:   audit.ID(id),
+		After:      fiber.Map{"expires_at": response.ExpiresAt},
+	})
+	return c.JSON(response)
+}
+
+// EndImpersonation godoc
+// @Summary End impersonation
+// @Description Revokes the impersonation token of the request. The admin continues with their own token.
+// @Tags auth
+// @Security BearerAuth
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/impersonation/end [post]
+func (h *ImpersonationHandler) EndImpersonation(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	actor, _ := c.Locals("impersonator").(*models.User)
+	token, _ := c.Locals("token").(string)
+	if err := h.impersonationService.End(c.UserContext(), actor, user, token); err != nil {
+		if stderrors.Is(err, ErrNotImpersonating) {
+			return errors.BadRequest("Not impersonating", nil).WithCode(errors.CodeNotImpersonating)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to end impersonation", zap.Error(err))
+		return errors.DatabaseError("Failed to end impersonation", err)
+	}
+
+	// Attributed to the user, with the admin as impersonator
+	h.auditService.Record(c.UserContext(), audit.Event{Action: models.AuditImpersonationEnd, EntityType: models.AuditEntityUser, EntityID: audit.ID(user.ID)})
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type impersonationTestEnv struct {
+	service     *ImpersonationService
+	authService *Service
+	users       *memoryUsers
+	admin       *models.User
+	manager     *models.User
+	user        *models.User
+}
+
+func newImpersonationTestEnv(t *testing.T) *impersonationTestEnv {
+	env := &impersonationTestEnv{
+		users:   &memoryUsers{},
+		admin:   &models.User{Email: "admin@example.com", Role: models.RoleAdmin, IsActive: true},
+		manager: &models.User{Email: "manager@example.com", Role: models.RoleManager, IsActive: true},
+		user:    &models.User{Email: "user@example.com", Role: models.RoleUser, IsActive: true},
+	}
+	for _, user := range []*models.User{env.admin, env.manager, env.user} {
+		assert.NoError(t, env.users.Create(context.Background(), user))
+	}
+
+	env.authService = newTestService(t, env.users, withJWT(config.JWTConfig{Expiration: 15 * time.Minute, ImpersonationExpiration: time.Hour}))
+	env.service = NewImpersonationService(env.authService, env.users, newTestPermissions(), zap.NewNop())
+	return env
+}
+
+func TestImpersonation_ActsAsTheUser(t *testing.T) {
+	env := newImpersonationTestEnv(t)
+	ctx := context.Background()
+
+	response, err := env.service.Start(ctx, env.admin, env.user.ID)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, env.user.ID, response.User.ID)
+	assert.Equal(t, env.admin.ID, response.ImpersonatorID)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), response.ExpiresAt, 5*time.Second, "capped at the access token lifetime")
+
+	claims, err := env.authService.ValidateJWT(response.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, env.admin.ID, env.authService.ExtractActorID(claims))
+
+	user, actor, err := env.authService.AuthenticateToken(ctx, response.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, env.user.ID, user.ID)
+	if assert.NotNil(t, actor) {
+		assert.Equal(t, env.admin.ID, actor.ID)
+	}
+
+	// Regular tokens have no actor
+	token, _, err := env.authService.GenerateJWT(env.user)
+	assert.NoError(t, err)
+	_, actor, err = env.authService.AuthenticateToken(ctx, token)
+	assert.NoError(t, err)
+	assert.Nil(t, actor)
+}
+
+func TestImpersonation_RefusesPrivilegedAndMissingUsers(t *testing.T) {
+	env := newImpersonationTestEnv(t)
+	ctx := context.Background()
+	other := &models.User{Email: "other-admin@example.com", Role: models.RoleAdmin, IsActive: true}
+	inactive := &models.User{Email: "gone@example.com", Role: models.RoleUser}
+	assert.NoError(t, env.users.Create(ctx, other))
+	assert.NoError(t, env.users.Create(ctx, inactive))
+
+	_, err := env.service.Start(ctx, env.admin, env.admin.ID)
+	assert.ErrorIs(t, err, ErrImpersonationNotAllowed)
+	_, err = env.service.Start(ctx, env.admin, other.ID)
+	assert.ErrorIs(t, err, ErrImpersonationNotAllowed)
+	_, err = env.service.Start(ctx, env.admin, inactive.ID)
+	assert.ErrorIs(t, err, ErrAccountDeactivated)
+	_, err = env.service.Start(ctx, env.admin, 99)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	_, err = env.service.Start(ctx, env.admin, env.manager.ID)
+	assert.NoError(t, err)
+}
+
+func TestImpersonation_Ends(t *testing.T) {
+	env := newImpersonationTestEnv(t)
+	ctx := context.Background()
+
+	response, err := env.service.Start(ctx, env.admin, env.user.ID)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.ErrorIs(t, env.service.End(ctx, nil, env.user, response.Token), ErrNotImpersonating)
+	assert.NoError(t, env.service.End(ctx, env.admin, env.user, response.Token))
+	_, _, err = env.authService.AuthenticateToken(ctx, response.Token)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	// Deactivating the admin ends it too
+	response, err = env.service.Start(ctx, env.admin, env.user.ID)
+	assert.NoError(t, err)
+	env.admin.IsActive = false
+	_, _, err = env.authService.AuthenticateToken(ctx, response.Token)
+	assert.ErrorIs(t, err, ErrAccountDeactivated)
+	env.admin.IsActive = true
+
+	// and so does logging them out everywhere
+	assert.NoError(t, env.authService.RevokeAllTokens(ctx, env.admin.ID))
+	_, _, err = env.authService.AuthenticateToken(ctx, response.Token)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+}
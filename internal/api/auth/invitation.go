@@ -0,0 +1,274 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/notify"
+	"go.uber.org/zap"
+)
+
+// Invitation errors
+var (
+	ErrRegistrationDisabled = errors.New("public registration is disabled; accounts are created by invitation")
+	ErrInvalidInvitation    = errors.New("invalid, expired or revoked invitation")
+	ErrInvitationNotFound   = errors.New("invitation not found")
+	ErrInvitationNotAllowed = errors.New("you cannot invite with this role or company")
+	ErrCompanyNotFound      = errors.New("company not found")
+	ErrCountryNotFound      = errors.New("country not found")
+)
+
+// CreateInvitationRequest invites someone to create an account. CountryID
+// defaults to the country of the company.
+type CreateInvitationRequest struct {
+	Email     string          `json:"email" validate:"required,email"`
+	Role      models.UserRole `json:"role" validate:"required,oneof=admin user manager"`
+	CompanyID *int64          `json:"company_id" validate:"omitempty,min=1"`
+	CountryID *int64          `json:"country_id" validate:"omitempty,min=1"`
+}
+
+// AcceptInvitationRequest creates the invited account. Email, role, company
+// and country come from the invitation.
+type AcceptInvitationRequest struct {
+	Token     string `json:"token" validate:"required"`
+	Username  string `json:"username" validate:"required,min=3,max=50"`
+	Password  string `json:"password" validate:"required,min=8"`
+	FirstName string `json:"first_name" validate:"required,min=2,max=50"`
+	LastName  string `json:"last_name" validate:"required,min=2,max=50"`
+}
+
+// InvitationResponse is an invitation with its current status
+type InvitationResponse struct {
+	*models.Invitation
+	Status string `json:"status"`
+}
+
+// InvitationService lets admins and managers invite people with a role,
+// company and country chosen for them, instead of people picking their own
+type InvitationService struct {
+	config      config.RegistrationConfig
+	publicURL   string
+	invitations interfaces.InvitationInterface
+	companies   interfaces.CompanyInterface
+	countries   interfaces.CountryInterface
+	txManager   interfaces.TransactionManager
+	permissions *PermissionService
+	notifier    notify.Notifier
+	logger      *zap.Logger
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewInvitationService creates an invitation service
+func NewInvitationService(cfg config.RegistrationConfig, publicURL string, invitations interfaces.InvitationInterface, companies interfaces.CompanyInterface, countries interfaces.CountryInterface, txManager interfaces.TransactionManager, permissions *PermissionService, notifier notify.Notifier, logger *zap.Logger) *InvitationService {
+	return &InvitationService{
+		config:      cfg,
+		publicURL:   publicURL,
+		invitations: invitations,
+		companies:   companies,
+		countries:   countries,
+		txManager:   txManager,
+		permissions: permissions,
+		notifier:    notifier,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// Create stores an invitation and mails its link. Users with user:admin
+// may invite with any role into any company. Others may not invite admins
+// and may only invite into their own company, or into none when they
+// belong to none. A new invitation replaces the pending ones to the same
+// email.
+func (s *InvitationService) Create(ctx context.Context, inviter *models.User, req *CreateInvitationRequest) (*InvitationResponse, error) {
+	invitation := &models.Invitation{
+		Email:       req.Email,
+		Role:        req.Role,
+		CompanyID:   req.CompanyID,
+		CountryID:   req.CountryID,
+		InvitedByID: inviter.ID,
+	}
+
+	if !s.permissions.Has(inviter, models.PermissionUserAdmin) {
+		if invitation.Role == models.RoleAdmin {
+			return nil, ErrInvitationNotAllowed
+		}
+		if invitation.CompanyID == nil {
+			invitation.CompanyID = inviter.CompanyID
+		} else if inviter.CompanyID == nil || *invitation.CompanyID != *inviter.CompanyID {
+			return nil, ErrInvitationNotAllowed
+		}
+	}
+
+	if invitation.CompanyID != nil {
+		company, err := s.companies.GetByID(ctx, *invitation.CompanyID)
+		if err != nil {
+			return nil, ErrCompanyNotFound
+		}
+		if invitation.CountryID == nil {
+			invitation.CountryID = &company.CountryID
+		}
+	}
+	if invitation.CountryID != nil {
+		if _, err := s.countries.GetByID(ctx, *invitation.CountryID); err != nil {
+			return nil, ErrCountryNotFound
+		}
+	}
+
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	invitation.TokenHash = hashOpaqueToken(token)
+	invitation.ExpiresAt = s.now().Add(s.config.InvitationTTL)
+
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context, repos *interfaces.Repositories) error {
+		exists, err := repos.Users.ExistsByEmail(ctx, req.Email)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrEmailTaken
+		}
+		if err := repos.Invitations.RevokeForEmail(ctx, req.Email, s.now()); err != nil {
+			return err
+		}
+		return repos.Invitations.Create(ctx, invitation)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Invitation created",
+		zap.Int64("invitation_id", invitation.ID),
+		zap.Int64("invited_by", inviter.ID),
+		zap.String("role", string(invitation.Role)),
+	)
+
+	link := fmt.Sprintf("%s/accept-invitation?token=%s", s.publicURL, url.QueryEscape(token))
+	message := notify.Message{
+		To:      req.Email,
+		Subject: "You are invited to Nalo Workspace",
+		Body: fmt.Sprintf(
+			"Hello,\n\n%s invited you to Nalo Workspace. Use the link below to create your account. It expires in %s and can be used once.\n\n%s\n\nIf you were not expecting this, you can ignore this email.",
+			inviter.FullName(), s.config.InvitationTTL, link,
+		),
+	}
+	// The invitation exists either way; a lost email is fixed by inviting again
+	if err := s.notifier.Send(ctx, message); err != nil {
+		s.logger.Error("Failed to send invitation", zap.Int64("invitation_id", invitation.ID), zap.Error(err))
+	}
+
+	return s.response(invitation), nil
+}
+
+// List returns every invitation to users with user:admin, and the
+// invitations they sent to others
+func (s *InvitationService) List(ctx context.Context, user *models.User) ([]InvitationResponse, error) {
+	invitations, err := s.invitations.List(ctx, s.senderScope(user))
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]InvitationResponse, len(invitations))
+	for i := range invitations {
+		responses[i] = *s.response(&invitations[i])
+	}
+	return responses, nil
+}
+
+// Revoke revokes a pending invitation. Only users with user:admin can
+// revoke invitations they did not send.
+func (s *InvitationService) Revoke(ctx context.Context, user *models.User, id int64) error {
+	revoked, err := s.invitations.Revoke(ctx, id, s.senderScope(user), s.now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrInvitationNotFound
+	}
+
+	s.logger.Info("Invitation revoked", zap.Int64("invitation_id", id), zap.Int64("revoked_by", user.ID))
+	return nil
+}
+
+// Accept consumes an invitation and creates the account it describes. The
+// email counts as verified, since the link was mailed to it.
+func (s *InvitationService) Accept(ctx context.Context, req *AcceptInvitationRequest) (*models.User, error) {
+	invitation, err := s.invitations.GetByHash(ctx, hashOpaqueToken(req.Token))
+	now := s.now()
+	if err != nil || invitation.Status(now) != models.InvitationPending {
+		return nil, ErrInvalidInvitation
+	}
+
+	user := &models.User{
+		Email:           invitation.Email,
+		Username:        req.Username,
+		Password:        req.Password, // Will be hashed by GORM hook
+		FirstName:       req.FirstName,
+		LastName:        req.LastName,
+		Role:            invitation.Role,
+		IsActive:        true,
+		CompanyID:       invitation.CompanyID,
+		CountryID:       invitation.CountryID,
+		EmailVerifiedAt: &now,
+	}
+
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context, repos *interfaces.Repositories) error {
+		exists, err := repos.Users.ExistsByEmail(ctx, user.Email)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrEmailTaken
+		}
+		exists, err = repos.Users.ExistsByUsername(ctx, user.Username)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrUsernameTaken
+		}
+
+		if err := repos.Users.Create(ctx, user); err != nil {
+			return err
+		}
+		accepted, err := repos.Invitations.Accept(ctx, invitation.ID, user.ID, now)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return ErrInvalidInvitation
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Invitation accepted",
+		zap.Int64("invitation_id", invitation.ID),
+		zap.Int64("user_id", user.ID),
+		zap.String("role", string(user.Role)),
+	)
+	return user, nil
+}
+
+// senderScope limits users without user:admin to the invitations they
+// sent; zero means all
+func (s *InvitationService) senderScope(user *models.User) int64 {
+	if s.permissions.Has(user, models.PermissionUserAdmin) {
+		return 0
+	}
+	return user.ID
+}
+
+func (s *InvitationService) response(invitation *models.Invitation) *InvitationResponse {
+	return &InvitationResponse{Invitation: invitation, Status: invitation.Status(s.now())}
+}
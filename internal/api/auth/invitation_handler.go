@@ -0,0 +1,184 @@
+package auth
+
+import (
+	stderrors "errors"
+	"strconv"
+
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/alxand/nalo-workspace/internal/pkg/tenant"
+	"github.com/alxand/nalo-workspace/internal/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type InvitationHandler struct {
+	invitationService *InvitationService
+	auditService      *audit.Service
+	logger            *zap.Logger
+}
+
+func NewInvitationHandler(invitationService *InvitationService, auditService *audit.Service, logger *zap.Logger) *InvitationHandler {
+	return &InvitationHandler{
+		invitationService: invitationService,
+		auditService:      auditService,
+		logger:            logger,
+	}
+}
+
+// CreateInvitation godoc
+// @Summary Invite someone to create an account
+// @Description Mails a single-use link. Managers cannot invite admins and only invite into their own company.
+// @Tags invitations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body CreateInvitationRequest true "Invitation"
+// @Success 201 {object} InvitationResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /invitations [post]
+func (h *InvitationHandler) CreateInvitation(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	var req CreateInvitationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+
+	invitation, err := h.invitationService.Create(c.UserContext(), user, &req)
+	if err != nil {
+		switch {
+		case stderrors.Is(err, ErrInvitationNotAllowed):
+			return errors.Forbidden(err.Error(), nil)
+		case stderrors.Is(err, tenant.ErrOutsideTenant):
+			return errors.Forbidden("Invitations can only be sent into your company", nil).WithCode(errors.CodeOutsideTenant)
+		case stderrors.Is(err, ErrCompanyNotFound):
+			return errors.NotFound(err.Error(), nil).WithCode(errors.CodeCompanyNotFound)
+		case stderrors.Is(err, ErrCountryNotFound):
+			return errors.NotFound(err.Error(), nil).WithCode(errors.CodeCountryNotFound)
+		case stderrors.Is(err, ErrEmailTaken):
+			return errors.Conflict(err.Error(), nil).WithCode(errors.CodeEmailTaken)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to create invitation", zap.Error(err))
+		return errors.DatabaseError("Failed to create invitation", err)
+	}
+
+	h.auditService.Record(c.UserContext(), audit.Event{
+		Action: models.AuditCreate, EntityType: models.AuditEntityInvitation, EntityID: audit.ID(invitation.ID), After: invitation,
+	})
+	return c.Status(fiber.StatusCreated).JSON(invitation)
+}
+
+// ListInvitations godoc
+// @Summary List invitations
+// @Description Admins see every invitation, managers the ones they sent
+// @Tags invitations
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} InvitationResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /invitations [get]
+func (h *InvitationHandler) ListInvitations(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	invitations, err := h.invitationService.List(c.UserContext(), user)
+	if err != nil {
+		logger.FromCtx(c, h.logger).Error("Failed to list invitations", zap.Error(err))
+		return errors.DatabaseError("Failed to list invitations", err)
+	}
+	return c.JSON(invitations)
+}
+
+// RevokeInvitation godoc
+// @Summary Revoke a pending invitation
+// @Tags invitations
+// @Security BearerAuth
+// @Param id path int true "Invitation ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /invitations/{id} [delete]
+func (h *InvitationHandler) RevokeInvitation(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return errors.BadRequest("Invalid invitation ID", err).WithCode(errors.CodeInvalidID)
+	}
+
+	if err := h.invitationService.Revoke(c.UserContext(), user, id); err != nil {
+		if stderrors.Is(err, ErrInvitationNotFound) {
+			return errors.NotFound(err.Error(), nil).WithCode(errors.CodeInvitationNotFound)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to revoke invitation", zap.Int64("invitation_id", id), zap.Error(err))
+		return errors.DatabaseError("Failed to revoke invitation", err)
+	}
+
+	h.auditService.Record(c.UserContext(), audit.Event{Action: models.AuditDelete, EntityType: models.AuditEntityInvitation, EntityID: audit.ID(id)})
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// AcceptInvitation godoc
+// @Summary Accept an invitation
+// @Description Creates the account with the email, role, company and country of the invitation. The email counts as verified.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body AcceptInvitationRequest true "Invitation token and profile"
+// @Success 201 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/invitations/accept [post]
+func (h *InvitationHandler) AcceptInvitation(c *fiber.Ctx) error {
+	var req AcceptInvitationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+
+	user, err := h.invitationService.Accept(c.UserContext(), &req)
+	if err != nil {
+		switch {
+		case stderrors.Is(err, ErrInvalidInvitation):
+			return errors.BadRequest(err.Error(), nil).WithCode(errors.CodeInvitationInvalid)
+		case stderrors.Is(err, ErrEmailTaken):
+			return errors.Conflict(err.Error(), nil).WithCode(errors.CodeEmailTaken)
+		case stderrors.Is(err, ErrUsernameTaken):
+			return errors.Conflict(err.Error(), nil).WithCode(errors.CodeUsernameTaken)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to accept invitation", zap.Error(err))
+		return errors.InternalServerError("Failed to accept invitation", err)
+	}
+
+	logger.FromCtx(c, h.logger).Info("User registered by invitation", zap.Int64("user_id", user.ID))
+	h.auditService.Record(c.UserContext(), audit.Event{
+		Action: models.AuditCreate, EntityType: models.AuditEntityUser, EntityID: audit.ID(user.ID), After: user, ActorID: user.ID,
+	})
+	return c.Status(fiber.StatusCreated).JSON(user)
+}
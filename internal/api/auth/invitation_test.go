@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// memoryInvitations is an in-memory InvitationInterface
+type memoryInvitations struct {
+	invitations []*models.Invitation
+}
+
+func (m *memoryInvitations) Create(ctx context.Context, invitation *models.Invitation) error {
+	invitation.ID = int64(len(m.invitations) + 1)
+	m.invitations = append(m.invitations, invitation)
+	return nil
+}
+
+func (m *memoryInvitations) GetByHash(ctx context.Context, hash string) (*models.Invitation, error) {
+	for _, invitation := range m.invitations {
+		if invitation.TokenHash == hash {
+			copied := *invitation
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryInvitations) List(ctx context.Context, invitedByID int64) ([]models.Invitation, error) {
+	var result []models.Invitation
+	for i := len(m.invitations) - 1; i >= 0; i-- {
+		if invitedByID == 0 || m.invitations[i].InvitedByID == invitedByID {
+			result = append(result, *m.invitations[i])
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryInvitations) Accept(ctx context.Context, id, userID int64, at time.Time) (bool, error) {
+	for _, invitation := range m.invitations {
+		if invitation.ID == id && invitation.Status(at) == models.InvitationPending {
+			invitation.AcceptedAt = &at
+			invitation.UserID = &userID
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryInvitations) Revoke(ctx context.Context, id, invitedByID int64, at time.Time) (bool, error) {
+	for _, invitation := range m.invitations {
+		if invitation.ID == id && (invitedByID == 0 || invitation.InvitedByID == invitedByID) &&
+			invitation.AcceptedAt == nil && invitation.RevokedAt == nil {
+			invitation.RevokedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryInvitations) RevokeForEmail(ctx context.Context, email string, at time.Time) error {
+	for _, invitation := range m.invitations {
+		if invitation.Email == email && invitation.AcceptedAt == nil && invitation.RevokedAt == nil {
+			invitation.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+// stubCountryRepository knows a fixed set of country IDs
+type stubCountryRepository struct {
+	interfaces.CountryInterface
+}
+
+func (s *stubCountryRepository) GetByID(ctx context.Context, id int64) (*models.Country, error) {
+	if id == 7 || id == 8 {
+		return &models.Country{ID: id}, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type invitationTestEnv struct {
+	service     *InvitationService
+	users       *memoryUsers
+	invitations *memoryInvitations
+	notifier    *recordingNotifier
+	now         time.Time
+	admin       *models.User
+	manager     *models.User
+}
+
+func newInvitationTestEnv(t *testing.T) *invitationTestEnv {
+	companyID := int64(1)
+	env := &invitationTestEnv{
+		users:       &memoryUsers{},
+		invitations: &memoryInvitations{},
+		notifier:    &recordingNotifier{},
+		now:         time.Now(),
+		admin:       &models.User{Email: "admin@example.com", Username: "admin", FirstName: "Ada", LastName: "Admin", Role: models.RoleAdmin, IsActive: true},
+		manager:     &models.User{Email: "manager@example.com", Username: "manager", FirstName: "Max", LastName: "Manager", Role: models.RoleManager, IsActive: true, CompanyID: &companyID},
+	}
+	assert.NoError(t, env.users.Create(context.Background(), env.admin))
+	assert.NoError(t, env.users.Create(context.Background(), env.manager))
+
+	companies := &stubCompanyRepository{}
+	assert.NoError(t, companies.Create(context.Background(), &models.Company{Name: "Acme", CountryID: 7}))
+	assert.NoError(t, companies.Create(context.Background(), &models.Company{Name: "Globex", CountryID: 8}))
+
+	txManager := &fakeTxManager{repos: &interfaces.Repositories{Users: env.users, Invitations: env.invitations}}
+	cfg := config.RegistrationConfig{Enabled: false, InvitationTTL: 24 * time.Hour}
+	env.service = NewInvitationService(cfg, "https://app.example.com", env.invitations, companies, &stubCountryRepository{}, txManager, newTestPermissions(), env.notifier, zap.NewNop())
+	env.service.now = func() time.Time { return env.now }
+	return env
+}
+
+// invitationToken extracts the token from the last invitation mailed
+func (env *invitationTestEnv) invitationToken(t *testing.T) string {
+	if !assert.NotEmpty(t, env.notifier.messages) {
+		t.FailNow()
+	}
+	msg := env.notifier.messages[len(env.notifier.messages)-1]
+	for _, field := range strings.Fields(msg.Body) {
+		if strings.HasPrefix(field, "https://app.example.com/accept-invitation?") {
+			link, err := url.Parse(field)
+			assert.NoError(t, err)
+			return link.Query().Get("token")
+		}
+	}
+	t.Fatalf("no invitation link in %q", msg.Body)
+	return ""
+}
+
+func acceptRequest(token, username string) *AcceptInvitationRequest {
+	return &AcceptInvitationRequest{Token: token, Username: username, Password: "password123", FirstName: "Nina", LastName: "Newhire"}
+}
+
+func TestInvitationService_AcceptCreatesTheInvitedAccount(t *testing.T) {
+	env := newInvitationTestEnv(t)
+	ctx := context.Background()
+	companyID := int64(1)
+
+	invitation, err := env.service.Create(ctx, env.admin, &CreateInvitationRequest{Email: "nina@example.com", Role: models.RoleManager, CompanyID: &companyID})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, models.InvitationPending, invitation.Status)
+	if assert.NotNil(t, invitation.CountryID) {
+		assert.Equal(t, int64(7), *invitation.CountryID, "the country defaults to the company's")
+	}
+	if assert.Len(t, env.notifier.messages, 1) {
+		assert.Equal(t, "nina@example.com", env.notifier.messages[0].To)
+	}
+
+	token := env.invitationToken(t)
+	assert.Equal(t, hashOpaqueToken(token), env.invitations.invitations[0].TokenHash, "only the hash is stored")
+
+	user, err := env.service.Accept(ctx, acceptRequest(token, "nina"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "nina@example.com", user.Email)
+	assert.Equal(t, models.RoleManager, user.Role)
+	assert.Equal(t, &companyID, user.CompanyID)
+	assert.Equal(t, int64(7), *user.CountryID)
+	assert.True(t, user.IsEmailVerified(), "the invitation link proves the mailbox")
+
+	listed, err := env.service.List(ctx, env.admin)
+	assert.NoError(t, err)
+	if assert.Len(t, listed, 1) {
+		assert.Equal(t, models.InvitationAccepted, listed[0].Status)
+		assert.Equal(t, &user.ID, listed[0].UserID)
+	}
+
+	// The link works once
+	_, err = env.service.Accept(ctx, acceptRequest(token, "nina2"))
+	assert.ErrorIs(t, err, ErrInvalidInvitation)
+}
+
+func TestInvitationService_RefusesUnusableInvitations(t *testing.T) {
+	env := newInvitationTestEnv(t)
+	ctx := context.Background()
+
+	_, err := env.service.Create(ctx, env.admin, &CreateInvitationRequest{Email: "manager@example.com", Role: models.RoleUser})
+	assert.ErrorIs(t, err, ErrEmailTaken)
+
+	// A new invitation replaces the pending one
+	_, err = env.service.Create(ctx, env.admin, &CreateInvitationRequest{Email: "nina@example.com", Role: models.RoleUser})
+	assert.NoError(t, err)
+	first := env.invitationToken(t)
+	_, err = env.service.Create(ctx, env.admin, &CreateInvitationRequest{Email: "nina@example.com", Role: models.RoleManager})
+	assert.NoError(t, err)
+	second := env.invitationToken(t)
+	_, err = env.service.Accept(ctx, acceptRequest(first, "nina"))
+	assert.ErrorIs(t, err, ErrInvalidInvitation)
+
+	// Revoked
+	assert.NoError(t, env.service.Revoke(ctx, env.admin, 2))
+	assert.ErrorIs(t, env.service.Revoke(ctx, env.admin, 2), ErrInvitationNotFound)
+	_, err = env.service.Accept(ctx, acceptRequest(second, "nina"))
+	assert.ErrorIs(t, err, ErrInvalidInvitation)
+
+	// Expired
+	_, err = env.service.Create(ctx, env.admin, &CreateInvitationRequest{Email: "nina@example.com", Role: models.RoleUser})
+	assert.NoError(t, err)
+	third := env.invitationToken(t)
+	env.now = env.now.Add(25 * time.Hour)
+	_, err = env.service.Accept(ctx, acceptRequest(third, "nina"))
+	assert.ErrorIs(t, err, ErrInvalidInvitation)
+
+	listed, err := env.service.List(ctx, env.admin)
+	assert.NoError(t, err)
+	if assert.Len(t, listed, 3) {
+		assert.Equal(t, models.InvitationExpired, listed[0].Status)
+		assert.Equal(t, models.InvitationRevoked, listed[1].Status)
+		assert.Equal(t, models.InvitationRevoked, listed[2].Status)
+	}
+	assert.Len(t, env.users.users, 2, "no account was created")
+}
+
+func TestInvitationService_LimitsManagers(t *testing.T) {
+	env := newInvitationTestEnv(t)
+	ctx := context.Background()
+	otherCompany := int64(2)
+	missingCountry := int64(99)
+
+	_, err := env.service.Create(ctx, env.manager, &CreateInvitationRequest{Email: "boss@example.com", Role: models.RoleAdmin})
+	assert.ErrorIs(t, err, ErrInvitationNotAllowed)
+	_, err = env.service.Create(ctx, env.manager, &CreateInvitationRequest{Email: "spy@example.com", Role: models.RoleUser, CompanyID: &otherCompany})
+	assert.ErrorIs(t, err, ErrInvitationNotAllowed)
+	_, err = env.service.Create(ctx, env.admin, &CreateInvitationRequest{Email: "x@example.com", Role: models.RoleUser, CountryID: &missingCountry})
+	assert.ErrorIs(t, err, ErrCountryNotFound)
+
+	// Managers invite into their own company
+	invitation, err := env.service.Create(ctx, env.manager, &CreateInvitationRequest{Email: "nina@example.com", Role: models.RoleUser})
+	if assert.NoError(t, err) && assert.NotNil(t, invitation.CompanyID) {
+		assert.Equal(t, *env.manager.CompanyID, *invitation.CompanyID)
+	}
+	_, err = env.service.Create(ctx, env.admin, &CreateInvitationRequest{Email: "omar@example.com", Role: models.RoleAdmin, CompanyID: &otherCompany})
+	assert.NoError(t, err)
+
+	// and only see and revoke the invitations they sent
+	listed, err := env.service.List(ctx, env.manager)
+	assert.NoError(t, err)
+	if assert.Len(t, listed, 1) {
+		assert.Equal(t, "nina@example.com", listed[0].Email)
+	}
+	assert.ErrorIs(t, env.service.Revoke(ctx, env.manager, 2), ErrInvitationNotFound)
+	assert.NoError(t, env.service.Revoke(ctx, env.manager, 1))
+
+	listed, err = env.service.List(ctx, env.admin)
+	assert.NoError(t, err)
+	assert.Len(t, listed, 2)
+}
+
+func TestInvitationService_ManagerWithoutCompanyCannotPickOne(t *testing.T) {
+	env := newInvitationTestEnv(t)
+	ctx := context.Background()
+	company := int64(1)
+	drifter := &models.User{Email: "drifter@example.com", Username: "drifter", Role: models.RoleManager, IsActive: true}
+	assert.NoError(t, env.users.Create(ctx, drifter))
+
+	_, err := env.service.Create(ctx, drifter, &CreateInvitationRequest{Email: "spy@example.com", Role: models.RoleUser, CompanyID: &company})
+	assert.ErrorIs(t, err, ErrInvitationNotAllowed)
+
+	invitation, err := env.service.Create(ctx, drifter, &CreateInvitationRequest{Email: "nina@example.com", Role: models.RoleUser})
+	if assert.NoError(t, err) {
+		assert.Nil(t, invitation.CompanyID)
+	}
+}
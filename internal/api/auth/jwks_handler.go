@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type JWKSHandler struct {
+	keys   *KeyRing
+	logger *zap.Logger
+}
+
+func NewJWKSHandler(keys *KeyRing, logger *zap.Logger) *JWKSHandler {
+	return &JWKSHandler{
+		keys:   keys,
+		logger: logger,
+	}
+}
+
+// JWKS godoc
+// @Summary Get the public keys that verify access tokens
+// @Description JSON Web Key Set of the RS256 or EdDSA keys, selected by the kid header of a token. Empty when tokens are signed with HS256.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} JWKSet
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) JWKS(c *fiber.Ctx) error {
+	// New keys are published two sync intervals before they sign, so
+	// caching for one interval never misses a key in use
+	maxAge := int(h.keys.config.KeySyncInterval.Seconds())
+	c.Set(fiber.HeaderCacheControl, "public, max-age="+strconv.Itoa(maxAge))
+	return c.JSON(h.keys.JWKS())
+}
@@ -0,0 +1,467 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/golang-jwt/jwt/v4"
+	"go.uber.org/zap"
+)
+
+// Signing key errors
+var (
+	ErrNoSigningKey      = errors.New("no active JWT signing key")
+	ErrUnknownSigningKey = errors.New("unknown JWT signing key")
+)
+
+// rsaKeyBits is the size of generated RSA keys
+const rsaKeyBits = 2048
+
+// JWK is a public key in the JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// signingKey is a loaded key pair. private is nil for keys that only
+// verify.
+type signingKey struct {
+	kid         string
+	method      jwt.SigningMethod
+	private     crypto.Signer
+	public      crypto.PublicKey
+	activatesAt time.Time
+}
+
+// KeyRing signs and verifies JWTs. With HS256 it uses the shared secret.
+// With RS256 or EdDSA it holds several key pairs told apart by the kid
+// header: key pairs are loaded from PEM files, or generated, stored
+// encrypted in the database and rotated on a schedule. A new key is
+// published a while before it signs, and a replaced key keeps verifying
+// until the tokens it signed have expired.
+type KeyRing struct {
+	config config.JWTConfig
+	repo   interfaces.SigningKeyInterface
+	aead   cipher.AEAD
+	logger *zap.Logger
+
+	mu     sync.RWMutex
+	keys   map[string]*signingKey
+	signer *signingKey
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewKeyRing creates an empty key ring; Sync loads the keys. repo stores
+// generated keys and is unused with HS256 or when keys come from files.
+func NewKeyRing(cfg config.JWTConfig, repo interfaces.SigningKeyInterface, logger *zap.Logger) (*KeyRing, error) {
+	aead, err := newAEAD(cfg.KeyEncryptionKey, cfg.Secret, "signing-keys")
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyRing{
+		config: cfg,
+		repo:   repo,
+		aead:   aead,
+		logger: logger,
+		keys:   make(map[string]*signingKey),
+		now:    time.Now,
+	}, nil
+}
+
+// Sign returns the signed token for claims
+func (r *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	if !r.config.Asymmetric() {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(r.config.Secret))
+	}
+
+	r.mu.RLock()
+	signer := r.signer
+	r.mu.RUnlock()
+	if signer == nil {
+		return "", ErrNoSigningKey
+	}
+
+	token := jwt.NewWithClaims(signer.method, claims)
+	token.Header["kid"] = signer.kid
+	return token.SignedString(signer.private)
+}
+
+// Keyfunc returns the key that verifies token. The algorithm must match
+// the key, so a public key is never mistaken for an HMAC secret.
+func (r *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if !r.config.Asymmetric() {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(r.config.Secret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	r.mu.RLock()
+	key, ok := r.keys[kid]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownSigningKey
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.public, nil
+}
+
+// JWKS returns the public keys, oldest first. It is empty with HS256.
+func (r *KeyRing) JWKS() JWKSet {
+	r.mu.RLock()
+	keys := make([]*signingKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].activatesAt.Equal(keys[j].activatesAt) {
+			return keys[i].activatesAt.Before(keys[j].activatesAt)
+		}
+		return keys[i].kid < keys[j].kid
+	})
+
+	set := JWKSet{Keys: make([]JWK, 0, len(keys))}
+	for _, key := range keys {
+		jwk := JWK{KeyID: key.kid, Use: "sig", Algorithm: key.method.Alg()}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// Sync reloads the keys. Generated keys are rotated and retired first.
+func (r *KeyRing) Sync(ctx context.Context) error {
+	if !r.config.Asymmetric() {
+		return nil
+	}
+	if r.config.KeysDir != "" {
+		return r.loadFiles()
+	}
+	return r.syncStored(ctx)
+}
+
+// Start syncs every interval until the returned stop func is called
+func (r *KeyRing) Start(interval time.Duration) (stop func()) {
+	if !r.config.Asymmetric() {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.Sync(ctx); err != nil {
+					r.logger.Error("Failed to sync JWT signing keys", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// syncStored rotates and retires the generated keys and loads the rest
+func (r *KeyRing) syncStored(ctx context.Context) error {
+	stored, err := r.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	now := r.now()
+
+	if stored, err = r.rotate(ctx, stored, now); err != nil {
+		return err
+	}
+	stored = r.retire(ctx, stored, now)
+
+	keys := make(map[string]*signingKey, len(stored))
+	var signer *signingKey
+	for _, row := range stored {
+		key, err := r.openStored(&row)
+		if err != nil {
+			// A key encrypted with another key encryption key is skipped
+			// rather than taking every other key down with it
+			r.logger.Error("Failed to load JWT signing key", zap.String("kid", row.KID), zap.Error(err))
+			continue
+		}
+		keys[key.kid] = key
+		// Stored keys are ordered by activation, so the newest active wins
+		if row.Algorithm == r.config.Algorithm && !row.ActivatesAt.After(now) {
+			signer = key
+		}
+	}
+	if signer == nil {
+		return ErrNoSigningKey
+	}
+
+	r.mu.Lock()
+	r.keys = keys
+	r.signer = signer
+	r.mu.Unlock()
+	return nil
+}
+
+// rotate generates a key when there is none for the algorithm, which is
+// used at once, or when the current one is due for replacement. The
+// replacement is published two sync intervals before it signs, so every
+// instance and JWKS cache knows it by then.
+func (r *KeyRing) rotate(ctx context.Context, stored []models.SigningKey, now time.Time) ([]models.SigningKey, error) {
+	var latest *models.SigningKey
+	for i := range stored {
+		if stored[i].Algorithm == r.config.Algorithm {
+			latest = &stored[i]
+		}
+	}
+
+	publishAhead := 2 * r.config.KeySyncInterval
+	activatesAt := now
+	if latest != nil {
+		if latest.ActivatesAt.After(now) || now.Before(latest.ActivatesAt.Add(r.config.KeyRotationInterval-publishAhead)) {
+			return stored, nil
+		}
+		activatesAt = now.Add(publishAhead)
+	}
+
+	key, err := r.generate(activatesAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.repo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	r.logger.Info("Generated JWT signing key",
+		zap.String("kid", key.KID),
+		zap.String("algorithm", key.Algorithm),
+		zap.Time("activates_at", key.ActivatesAt),
+	)
+	return append(stored, *key), nil
+}
+
+// retire deletes keys replaced for longer than the retention, since every
+// token they signed has expired
+func (r *KeyRing) retire(ctx context.Context, stored []models.SigningKey, now time.Time) []models.SigningKey {
+	cutoff := now.Add(-r.config.KeyRetention)
+	kept := make([]models.SigningKey, 0, len(stored))
+	for i, row := range stored {
+		if i+1 < len(stored) && !stored[i+1].ActivatesAt.After(cutoff) {
+			if err := r.repo.Delete(ctx, row.ID); err != nil {
+				r.logger.Error("Failed to delete retired JWT signing key", zap.String("kid", row.KID), zap.Error(err))
+				kept = append(kept, row)
+				continue
+			}
+			r.logger.Info("Retired JWT signing key", zap.String("kid", row.KID))
+			continue
+		}
+		kept = append(kept, row)
+	}
+	return kept
+}
+
+// generate creates a key pair for the configured algorithm
+func (r *KeyRing) generate(activatesAt time.Time) (*models.SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch r.config.Algorithm {
+	case config.JWTAlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case config.JWTAlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("cannot generate %s keys", r.config.Algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := seal(r.aead, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}))
+	if err != nil {
+		return nil, err
+	}
+
+	kid := make([]byte, 12)
+	if _, err := rand.Read(kid); err != nil {
+		return nil, err
+	}
+
+	return &models.SigningKey{
+		KID:         base64.RawURLEncoding.EncodeToString(kid),
+		Algorithm:   r.config.Algorithm,
+		PublicKey:   string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		PrivateKey:  base64.StdEncoding.EncodeToString(sealed),
+		ActivatesAt: activatesAt,
+	}, nil
+}
+
+// openStored decrypts a generated key
+func (r *KeyRing) openStored(row *models.SigningKey) (*signingKey, error) {
+	sealed, err := base64.StdEncoding.DecodeString(row.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := open(r.aead, sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := parsePEMKey(plain)
+	if err != nil {
+		return nil, err
+	}
+	if key.method.Alg() != row.Algorithm {
+		return nil, fmt.Errorf("key is not an %s key", row.Algorithm)
+	}
+	key.kid = row.KID
+	key.activatesAt = row.ActivatesAt
+	return key, nil
+}
+
+// loadFiles loads every .pem file in KeysDir, named after its kid. Files
+// holding only a public key verify but never sign. SigningKeyID picks the
+// signing key; otherwise the last private key by name signs.
+func (r *KeyRing) loadFiles() error {
+	entries, err := os.ReadDir(r.config.KeysDir)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*signingKey)
+	var signer *signingKey
+	// Entries are sorted by name
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".pem" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(r.config.KeysDir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		key, err := parsePEMKey(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		key.kid = strings.TrimSuffix(entry.Name(), ".pem")
+		keys[key.kid] = key
+
+		if key.private != nil && key.method.Alg() == r.config.Algorithm &&
+			(r.config.SigningKeyID == "" || r.config.SigningKeyID == key.kid) {
+			signer = key
+		}
+	}
+	if signer == nil {
+		return fmt.Errorf("%w: no private %s key in %s", ErrNoSigningKey, r.config.Algorithm, r.config.KeysDir)
+	}
+
+	r.mu.Lock()
+	r.keys = keys
+	r.signer = signer
+	r.mu.Unlock()
+	return nil
+}
+
+// parsePEMKey reads an RSA or Ed25519 private key in PKCS #8 or PKCS #1
+// form, or a public key in PKIX form
+func parsePEMKey(data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	key := &signingKey{}
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case ed25519.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PublicKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+	return key, nil
+}
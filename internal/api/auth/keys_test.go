@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// newTestKeys returns an HS256 key ring using the secret the tests share
+func newTestKeys() *KeyRing {
+	keys, err := NewKeyRing(config.JWTConfig{Algorithm: config.JWTAlgorithmHS256, Secret: "test-secret"}, nil, zap.NewNop())
+	if err != nil {
+		panic(err)
+	}
+	return keys
+}
+
+// memorySigningKeys is an in-memory SigningKeyInterface
+type memorySigningKeys struct {
+	keys   []models.SigningKey
+	nextID int64
+}
+
+func (m *memorySigningKeys) Create(ctx context.Context, key *models.SigningKey) error {
+	m.nextID++
+	key.ID = m.nextID
+	m.keys = append(m.keys, *key)
+	return nil
+}
+
+func (m *memorySigningKeys) List(ctx context.Context) ([]models.SigningKey, error) {
+	return append([]models.SigningKey(nil), m.keys...), nil
+}
+
+func (m *memorySigningKeys) Delete(ctx context.Context, id int64) error {
+	kept := m.keys[:0]
+	for _, key := range m.keys {
+		if key.ID != id {
+			kept = append(kept, key)
+		}
+	}
+	m.keys = kept
+	return nil
+}
+
+func newStoredKeyRing(t *testing.T, algorithm string, repo *memorySigningKeys, now *time.Time) *KeyRing {
+	cfg := config.JWTConfig{
+		Algorithm:           algorithm,
+		Secret:              "test-secret",
+		KeyRotationInterval: 24 * time.Hour,
+		KeyRetention:        time.Hour,
+		KeySyncInterval:     time.Minute,
+	}
+	keys, err := NewKeyRing(cfg, repo, zap.NewNop())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	keys.now = func() time.Time { return *now }
+	return keys
+}
+
+// signAt signs a token that expires well after now
+func signAt(t *testing.T, keys *KeyRing, now time.Time) string {
+	token, err := keys.Sign(jwt.MapClaims{"sub": "1", "exp": now.Add(48 * time.Hour).Unix()})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return token
+}
+
+func verify(keys *KeyRing, token string) error {
+	_, err := jwt.Parse(token, keys.Keyfunc)
+	return err
+}
+
+func TestKeyRing_SignsAndVerifiesWithGeneratedKeys(t *testing.T) {
+	for _, algorithm := range []string{config.JWTAlgorithmRS256, config.JWTAlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			repo := &memorySigningKeys{}
+			now := time.Now()
+			keys := newStoredKeyRing(t, algorithm, repo, &now)
+			assert.NoError(t, keys.Sync(context.Background()))
+			if !assert.Len(t, repo.keys, 1) {
+				t.FailNow()
+			}
+			assert.NotContains(t, repo.keys[0].PrivateKey, "PRIVATE KEY", "private keys are stored encrypted")
+
+			token := signAt(t, keys, now)
+			parsed, err := jwt.Parse(token, keys.Keyfunc)
+			assert.NoError(t, err)
+			assert.Equal(t, algorithm, parsed.Method.Alg())
+			assert.Equal(t, repo.keys[0].KID, parsed.Header["kid"])
+
+			jwks := keys.JWKS()
+			if assert.Len(t, jwks.Keys, 1) {
+				assert.Equal(t, repo.keys[0].KID, jwks.Keys[0].KeyID)
+				assert.Equal(t, algorithm, jwks.Keys[0].Algorithm)
+			}
+
+			// Another instance loads the same key from storage
+			other := newStoredKeyRing(t, algorithm, repo, &now)
+			assert.NoError(t, other.Sync(context.Background()))
+			assert.NoError(t, verify(other, token))
+			assert.Len(t, repo.keys, 1)
+		})
+	}
+}
+
+func TestKeyRing_RotatesAndRetiresKeys(t *testing.T) {
+	repo := &memorySigningKeys{}
+	now := time.Now()
+	keys := newStoredKeyRing(t, config.JWTAlgorithmEdDSA, repo, &now)
+	assert.NoError(t, keys.Sync(context.Background()))
+	first := repo.keys[0].KID
+	oldToken := signAt(t, keys, now)
+
+	// Shortly before the rotation is due the next key is published but
+	// does not sign yet
+	now = now.Add(24*time.Hour - time.Minute)
+	assert.NoError(t, keys.Sync(context.Background()))
+	if !assert.Len(t, repo.keys, 2) {
+		t.FailNow()
+	}
+	second := repo.keys[1].KID
+	assert.Len(t, keys.JWKS().Keys, 2)
+	parsed, err := jwt.Parse(signAt(t, keys, now), keys.Keyfunc)
+	assert.NoError(t, err)
+	assert.Equal(t, first, parsed.Header["kid"])
+
+	// Once active the new key signs and the old one still verifies
+	now = now.Add(2 * time.Minute)
+	assert.NoError(t, keys.Sync(context.Background()))
+	parsed, err = jwt.Parse(signAt(t, keys, now), keys.Keyfunc)
+	assert.NoError(t, err)
+	assert.Equal(t, second, parsed.Header["kid"])
+	assert.NoError(t, verify(keys, oldToken))
+
+	// After the retention the old key is gone
+	now = now.Add(time.Hour)
+	assert.NoError(t, keys.Sync(context.Background()))
+	assert.Len(t, repo.keys, 1)
+	assert.Equal(t, second, keys.JWKS().Keys[0].KeyID)
+	assert.ErrorIs(t, verify(keys, oldToken), ErrUnknownSigningKey)
+}
+
+func TestKeyRing_RejectsAlgorithmConfusion(t *testing.T) {
+	repo := &memorySigningKeys{}
+	now := time.Now()
+	keys := newStoredKeyRing(t, config.JWTAlgorithmRS256, repo, &now)
+	assert.NoError(t, keys.Sync(context.Background()))
+	kid := repo.keys[0].KID
+
+	// An HMAC token keyed with the public key must not verify
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "1"})
+	hmacToken.Header["kid"] = kid
+	signed, err := hmacToken.SignedString([]byte(repo.keys[0].PublicKey))
+	assert.NoError(t, err)
+	assert.Error(t, verify(keys, signed))
+
+	// Nor a token with the shared secret
+	assert.Error(t, verify(keys, signAt(t, newTestKeys(), now)))
+	// And an HS256 ring refuses RS256 tokens
+	assert.Error(t, verify(newTestKeys(), signAt(t, keys, now)))
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+}
+
+func TestKeyRing_LoadsKeysFromFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	oldPublic, err := x509.MarshalPKIXPublicKey(&oldKey.PublicKey)
+	assert.NoError(t, err)
+	writePEM(t, filepath.Join(dir, "2026-01.pem"), "PUBLIC KEY", oldPublic)
+
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	writePEM(t, filepath.Join(dir, "2026-02.pem"), "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(newKey))
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	assert.NoError(t, err)
+	writePEM(t, filepath.Join(dir, "ed.pem"), "PRIVATE KEY", edDER)
+
+	cfg := config.JWTConfig{Algorithm: config.JWTAlgorithmRS256, Secret: "test-secret", KeysDir: dir}
+	keys, err := NewKeyRing(cfg, nil, zap.NewNop())
+	assert.NoError(t, err)
+	if !assert.NoError(t, keys.Sync(context.Background())) {
+		t.FailNow()
+	}
+	assert.Len(t, keys.JWKS().Keys, 3)
+
+	// The last RS256 private key by name signs
+	parsed, err := jwt.Parse(signAt(t, keys, time.Now()), keys.Keyfunc)
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-02", parsed.Header["kid"])
+
+	// A public key file still verifies tokens it signed
+	oldToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "1"})
+	oldToken.Header["kid"] = "2026-01"
+	signed, err := oldToken.SignedString(oldKey)
+	assert.NoError(t, err)
+	assert.NoError(t, verify(keys, signed))
+
+	// A public key cannot be picked to sign
+	cfg.SigningKeyID = "2026-01"
+	keys, err = NewKeyRing(cfg, nil, zap.NewNop())
+	assert.NoError(t, err)
+	assert.ErrorIs(t, keys.Sync(context.Background()), ErrNoSigningKey)
+}
@@ -0,0 +1,430 @@
+package auth
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/metrics"
+	"github.com/alxand/nalo-workspace/internal/pkg/totp"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Two-factor authentication errors
+var (
+	ErrInvalidMFACode      = errors.New("invalid two-factor authentication code")
+	ErrInvalidMFAChallenge = errors.New("invalid or expired two-factor challenge")
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled      = errors.New("two-factor authentication is not set up")
+	ErrMFARequired         = errors.New("two-factor authentication is required for this account")
+)
+
+const (
+	// accessAudience and mfaAudience keep access and challenge tokens apart
+	accessAudience = "nalo-workspace-users"
+	mfaAudience    = "nalo-workspace-mfa"
+
+	// mfaChallengeTTL is how long a password-checked login waits for its code
+	mfaChallengeTTL = 5 * time.Minute
+	// mfaSkew accepts codes from one step before or after the current one
+	mfaSkew = 1
+	// recoveryCodeCount is how many recovery codes are issued at a time
+	recoveryCodeCount = 10
+)
+
+// MFALoginRequest completes a login that answered with an MFA challenge.
+// Code is a TOTP code or one of the recovery codes.
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// MFACodeRequest carries a TOTP code
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// DisableMFARequest turns two-factor authentication off. Code is a TOTP
+// code or a recovery code.
+type DisableMFARequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// MFAStatus describes the two-factor setup of a user
+type MFAStatus struct {
+	Enabled           bool  `json:"enabled"`
+	Required          bool  `json:"required"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+}
+
+// MFAEnrollment is the secret to add to an authenticator app. URI is the
+// otpauth:// form, usually shown as a QR code.
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// RecoveryCodes are shown once; only their hashes are stored
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAService handles TOTP enrollment and the second step of logins. TOTP
+// secrets are stored encrypted with AES-GCM.
+type MFAService struct {
+	config        config.MFAConfig
+	aead          cipher.AEAD
+	sessions      *Service
+	recoveryCodes interfaces.MFARecoveryCodeInterface
+	logger        *zap.Logger
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewMFAService creates an MFA service. Secrets are encrypted with a key
+// derived from cfg.EncryptionKey, or from jwtSecret when none is set.
+// sessions provides the user repository and starts sessions after the
+// second step.
+func NewMFAService(cfg config.MFAConfig, jwtSecret string, sessions *Service, recoveryCodes interfaces.MFARecoveryCodeInterface, logger *zap.Logger) (*MFAService, error) {
+	aead, err := newAEAD(cfg.EncryptionKey, jwtSecret, "mfa-secret")
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAService{
+		config:        cfg,
+		aead:          aead,
+		sessions:      sessions,
+		recoveryCodes: recoveryCodes,
+		logger:        logger,
+		now:           time.Now,
+	}, nil
+}
+
+// issueMFAChallenge returns a short-lived token proving the password of
+// user was checked
+func (s *Service) issueMFAChallenge(user *models.User) (string, time.Time, error) {
+	now := s.now()
+	expiresAt := now.Add(mfaChallengeTTL)
+
+	claims := jwt.MapClaims{
+		"jti":     uuid.NewString(),
+		"user_id": user.ID,
+		"exp":     expiresAt.Unix(),
+		"iat":     now.Unix(),
+		"iss":     "nalo-workspace",
+		"aud":     mfaAudience,
+	}
+
+	token, err := s.keys.Sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+func (s *MFAService) encryptSecret(secret string) (string, error) {
+	sealed, err := seal(s.aead, []byte(secret))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *MFAService) decryptSecret(encrypted string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	plain, err := open(s.aead, sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// Status reports whether two-factor authentication is on for the user
+func (s *MFAService) Status(ctx context.Context, userID int64) (*MFAStatus, error) {
+	user, err := s.sessions.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MFAStatus{Enabled: user.MFAEnabled, Required: s.config.Requires(string(user.Role))}
+	if user.MFAEnabled {
+		if status.RecoveryCodesLeft, err = s.recoveryCodes.CountUnused(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// Enroll creates a new TOTP secret for the user. It takes effect once
+// confirmed with a code; enrolling again replaces an unconfirmed secret.
+func (s *MFAService) Enroll(ctx context.Context, userID int64) (*MFAEnrollment, error) {
+	user, err := s.sessions.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.encryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessions.userRepo.UpdateMFA(ctx, userID, false, encrypted); err != nil {
+		return nil, err
+	}
+
+	return &MFAEnrollment{Secret: secret, URI: totp.URI(s.config.Issuer, user.Email, secret)}, nil
+}
+
+// Confirm turns two-factor authentication on once the user proves the
+// authenticator works, and returns the first set of recovery codes
+func (s *MFAService) Confirm(ctx context.Context, userID int64, code string) (*RecoveryCodes, error) {
+	user, err := s.sessions.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.MFASecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	secret, err := s.decryptSecret(user.MFASecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, code, s.now(), mfaSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = s.sessions.txManager.WithinTransaction(ctx, func(ctx context.Context, repos *interfaces.Repositories) error {
+		if err := repos.Users.UpdateMFA(ctx, userID, true, user.MFASecret); err != nil {
+			return err
+		}
+		// The confirming code cannot be used again to log in
+		if _, err := repos.Users.RecordMFAStep(ctx, userID, step); err != nil {
+			return err
+		}
+		return repos.MFARecoveryCodes.ReplaceForUser(ctx, userID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Two-factor authentication enabled", zap.Int64("user_id", userID))
+	return &RecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// Disable turns two-factor authentication off after checking the password
+// and a code. Users whose role requires it cannot turn it off.
+func (s *MFAService) Disable(ctx context.Context, userID int64, password, code string) error {
+	user, err := s.sessions.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if s.config.Requires(string(user.Role)) {
+		return ErrMFARequired
+	}
+	if !user.MFAEnabled {
+		return ErrMFANotEnrolled
+	}
+	if !user.CheckPassword(password) {
+		return ErrInvalidCurrentPassword
+	}
+	ok, err := s.verifyCode(ctx, user, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	err = s.sessions.txManager.WithinTransaction(ctx, func(ctx context.Context, repos *interfaces.Repositories) error {
+		if err := repos.Users.UpdateMFA(ctx, userID, false, ""); err != nil {
+			return err
+		}
+		return repos.MFARecoveryCodes.DeleteForUser(ctx, userID)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Two-factor authentication disabled", zap.Int64("user_id", userID))
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the user after
+// checking a TOTP code
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) (*RecoveryCodes, error) {
+	user, err := s.sessions.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled {
+		return nil, ErrMFANotEnrolled
+	}
+	ok, err := s.verifyTOTP(ctx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recoveryCodes.ReplaceForUser(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Recovery codes regenerated", zap.Int64("user_id", userID))
+	return &RecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// CompleteLogin exchanges an MFA challenge and a code for a session. Wrong
+// codes count towards the account lockout like wrong passwords.
+func (s *MFAService) CompleteLogin(ctx context.Context, mfaToken, code string) (*LoginResponse, error) {
+	claims, err := s.sessions.parseJWT(mfaToken, mfaAudience)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+	userID, err := s.sessions.ExtractUserID(claims)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+	jti, _ := claims["jti"].(string)
+	if jti == "" || s.sessions.revocations.IsRevoked(jti, userID, claimTime(claims, "iat")) {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	user, err := s.sessions.userRepo.GetByID(ctx, userID)
+	if err != nil || !user.MFAEnabled {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if user.IsLocked(s.now()) {
+		return nil, &AccountLockedError{Until: *user.LockedUntil}
+	}
+	if !user.IsActive {
+		return nil, ErrAccountDeactivated
+	}
+
+	ok, err := s.verifyCode(ctx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.logger.Warn("Invalid two-factor code", zap.Int64("user_id", userID))
+		s.sessions.recordFailedLogin(ctx, user)
+		metrics.LoginFailed()
+		return nil, ErrInvalidMFACode
+	}
+
+	// A challenge starts one session only
+	if err := s.sessions.revocations.RevokeToken(ctx, jti, userID, claimTime(claims, "exp")); err != nil {
+		return nil, err
+	}
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := s.sessions.userRepo.ResetFailedLogins(ctx, userID); err != nil {
+			s.logger.Error("Failed to reset failed logins", zap.Int64("user_id", userID), zap.Error(err))
+		}
+	}
+
+	response, err := s.sessions.newSession(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.LoginSucceeded()
+	return response, nil
+}
+
+// verifyCode accepts a TOTP code or, failing that, an unused recovery code
+func (s *MFAService) verifyCode(ctx context.Context, user *models.User, code string) (bool, error) {
+	ok, err := s.verifyTOTP(ctx, user, code)
+	if err != nil || ok {
+		return ok, err
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return false, nil
+	}
+	used, err := s.recoveryCodes.Consume(ctx, user.ID, hashOpaqueToken(normalized), s.now())
+	if used {
+		left, _ := s.recoveryCodes.CountUnused(ctx, user.ID)
+		s.logger.Info("Recovery code used", zap.Int64("user_id", user.ID), zap.Int64("recovery_codes_left", left))
+	}
+	return used, err
+}
+
+// verifyTOTP checks a TOTP code and records its step, so each code is
+// accepted once
+func (s *MFAService) verifyTOTP(ctx context.Context, user *models.User, code string) (bool, error) {
+	secret, err := s.decryptSecret(user.MFASecret)
+	if err != nil {
+		return false, err
+	}
+	step, ok := totp.Validate(secret, code, s.now(), mfaSkew)
+	if !ok {
+		return false, nil
+	}
+	return s.sessions.userRepo.RecordMFAStep(ctx, user.ID, step)
+}
+
+// recoveryEncoding spells recovery codes in lower case base32
+var recoveryEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// generateRecoveryCodes returns codes of the form "xxxxx-xxxxx" and their
+// hashes
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		// 10 base32 characters hold 50 bits
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		plain := recoveryEncoding.EncodeToString(raw)[:10]
+		codes = append(codes, plain[:5]+"-"+plain[5:])
+		hashes = append(hashes, hashOpaqueToken(plain))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	if len(code) != 10 {
+		return ""
+	}
+	return code
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	stderrors "errors"
+
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/alxand/nalo-workspace/internal/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type MFAHandler struct {
+	mfaService   *MFAService
+	auditService *audit.Service
+	logger       *zap.Logger
+}
+
+func NewMFAHandler(mfaService *MFAService, auditService *audit.Service, logger *zap.Logger) *MFAHandler {
+	return &MFAHandler{
+		mfaService:   mfaService,
+		auditService: auditService,
+		logger:       logger,
+	}
+}
+
+// mfaError maps MFA service errors to client errors
+func mfaError(err error) *errors.AppError {
+	switch {
+	case stderrors.Is(err, ErrInvalidMFACode):
+		return errors.BadRequest(err.Error(), nil).WithCode(errors.CodeMFACodeInvalid)
+	case stderrors.Is(err, ErrMFAAlreadyEnabled):
+		return errors.Conflict(err.Error(), nil).WithCode(errors.CodeMFAAlreadyEnabled)
+	case stderrors.Is(err, ErrMFANotEnrolled):
+		return errors.BadRequest(err.Error(), nil).WithCode(errors.CodeMFANotEnrolled)
+	case stderrors.Is(err, ErrMFARequired):
+		return errors.Forbidden(err.Error(), nil).WithCode(errors.CodeMFARequired)
+	case stderrors.Is(err, ErrInvalidCurrentPassword):
+		return errors.BadRequest(err.Error(), nil).WithCode(errors.CodeInvalidPassword)
+	}
+	return nil
+}
+
+// LoginMFA godoc
+// @Summary Complete a login with a two-factor code
+// @Description Exchanges the mfa_token from /auth/login and a TOTP or recovery code for tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body MFALoginRequest true "Challenge token and code"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/login/mfa [post]
+func (h *MFAHandler) LoginMFA(c *fiber.Ctx) error {
+	var req MFALoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+
+	response, err := h.mfaService.CompleteLogin(c.UserContext(), req.MFAToken, req.Code)
+	if err != nil {
+		if appErr := loginError(c, err); appErr != nil {
+			recordLoginFailure(c, h.auditService, "", appErr)
+			return appErr
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to complete two-factor login", zap.Error(err))
+		return errors.InternalServerError("Failed to login user", err)
+	}
+
+	logger.FromCtx(c, h.logger).Info("User logged in successfully", zap.Int64("user_id", response.User.ID), zap.Bool("mfa", true))
+	recordLogin(c, h.auditService, response.User)
+	return c.JSON(response)
+}
+
+// Status godoc
+// @Summary Get the two-factor setup of the current user
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} MFAStatus
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/mfa [get]
+func (h *MFAHandler) Status(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	status, err := h.mfaService.Status(c.UserContext(), user.ID)
+	if err != nil {
+		logger.FromCtx(c, h.logger).Error("Failed to get two-factor status", zap.Error(err))
+		return errors.DatabaseError("Failed to get two-factor status", err)
+	}
+	return c.JSON(status)
+}
+
+// Enroll godoc
+// @Summary Start two-factor enrollment
+// @Description Returns a new TOTP secret and otpauth URI; confirm it with a code to turn two-factor authentication on
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} MFAEnrollment
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/mfa/enroll [post]
+func (h *MFAHandler) Enroll(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	enrollment, err := h.mfaService.Enroll(c.UserContext(), user.ID)
+	if err != nil {
+		if appErr := mfaError(err); appErr != nil {
+			return appErr
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to start two-factor enrollment", zap.Error(err))
+		return errors.InternalServerError("Failed to start two-factor enrollment", err)
+	}
+	return c.JSON(enrollment)
+}
+
+// Confirm godoc
+// @Summary Turn two-factor authentication on
+// @Description Checks a code from the enrolled authenticator and returns recovery codes, which are shown only once
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body MFACodeRequest true "TOTP code"
+// @Success 200 {object} RecoveryCodes
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/mfa/confirm [post]
+func (h *MFAHandler) Confirm(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	var req MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+
+	codes, err := h.mfaService.Confirm(c.UserContext(), user.ID, req.Code)
+	if err != nil {
+		if appErr := mfaError(err); appErr != nil {
+			return appErr
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to enable two-factor authentication", zap.Error(err))
+		return errors.InternalServerError("Failed to enable two-factor authentication", err)
+	}
+
+	logger.FromCtx(c, h.logger).Info("Two-factor authentication enabled")
+	h.auditService.Record(c.UserContext(), audit.Event{Action: models.AuditMFAEnabled, EntityType: models.AuditEntityUser, EntityID: audit.ID(user.ID)})
+	return c.JSON(codes)
+}
+
+// Disable godoc
+// @Summary Turn two-factor authentication off
+// @Description Requires the password and a TOTP or recovery code. Not allowed for roles that require two-factor authentication.
+// @Tags auth
+// @Accept json
+// @Security BearerAuth
+// @Param body body DisableMFARequest true "Password and code"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/mfa/disable [post]
+func (h *MFAHandler) Disable(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	var req DisableMFARequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+
+	if err := h.mfaService.Disable(c.UserContext(), user.ID, req.Password, req.Code); err != nil {
+		if appErr := mfaError(err); appErr != nil {
+			return appErr
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to disable two-factor authentication", zap.Error(err))
+		return errors.InternalServerError("Failed to disable two-factor authentication", err)
+	}
+
+	logger.FromCtx(c, h.logger).Info("Two-factor authentication disabled")
+	h.auditService.Record(c.UserContext(), audit.Event{Action: models.AuditMFADisabled, EntityType: models.AuditEntityUser, EntityID: audit.ID(user.ID)})
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Replace the recovery codes
+// @Description Checks a TOTP code and returns new recovery codes; the old ones stop working
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body MFACodeRequest true "TOTP code"
+// @Success 200 {object} RecoveryCodes
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	var req MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(c.UserContext(), user.ID, req.Code)
+	if err != nil {
+		if appErr := mfaError(err); appErr != nil {
+			return appErr
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to regenerate recovery codes", zap.Error(err))
+		return errors.InternalServerError("Failed to regenerate recovery codes", err)
+	}
+
+	logger.FromCtx(c, h.logger).Info("Recovery codes regenerated")
+	h.auditService.Record(c.UserContext(), audit.Event{Action: models.AuditRecoveryCodes, EntityType: models.AuditEntityUser, EntityID: audit.ID(user.ID)})
+	return c.JSON(codes)
+}
@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/totp"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// mfaUserRepository keeps the MFA columns of a single user in memory
+type mfaUserRepository struct {
+	*MockUserRepository
+	user *models.User
+}
+
+func (r *mfaUserRepository) UpdateMFA(ctx context.Context, id int64, enabled bool, secret string) error {
+	r.user.MFAEnabled, r.user.MFASecret, r.user.MFALastStep = enabled, secret, 0
+	return nil
+}
+
+func (r *mfaUserRepository) RecordMFAStep(ctx context.Context, id int64, step int64) (bool, error) {
+	if step <= r.user.MFALastStep {
+		return false, nil
+	}
+	r.user.MFALastStep = step
+	return true, nil
+}
+
+// RecordFailedLogin and ResetFailedLogins keep the count on the user as
+// the database does
+func (r *mfaUserRepository) RecordFailedLogin(ctx context.Context, id int64, now time.Time) (int, error) {
+	if _, err := r.MockUserRepository.RecordFailedLogin(ctx, id, now); err != nil {
+		return 0, err
+	}
+	r.user.FailedLoginAttempts++
+	return r.user.FailedLoginAttempts, nil
+}
+
+func (r *mfaUserRepository) ResetFailedLogins(ctx context.Context, id int64) error {
+	if err := r.MockUserRepository.ResetFailedLogins(ctx, id); err != nil {
+		return err
+	}
+	r.user.FailedLoginAttempts, r.user.LockedUntil = 0, nil
+	return nil
+}
+
+// memoryRecoveryCodes is an in-memory MFARecoveryCodeInterface
+type memoryRecoveryCodes struct {
+	codes []*models.MFARecoveryCode
+}
+
+func (m *memoryRecoveryCodes) ReplaceForUser(ctx context.Context, userID int64, hashes []string) error {
+	_ = m.DeleteForUser(ctx, userID)
+	for _, hash := range hashes {
+		m.codes = append(m.codes, &models.MFARecoveryCode{UserID: userID, CodeHash: hash})
+	}
+	return nil
+}
+
+func (m *memoryRecoveryCodes) Consume(ctx context.Context, userID int64, hash string, at time.Time) (bool, error) {
+	for _, code := range m.codes {
+		if code.UserID == userID && code.CodeHash == hash && code.UsedAt == nil {
+			code.UsedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryRecoveryCodes) CountUnused(ctx context.Context, userID int64) (int64, error) {
+	var count int64
+	for _, code := range m.codes {
+		if code.UserID == userID && code.UsedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *memoryRecoveryCodes) DeleteForUser(ctx context.Context, userID int64) error {
+	kept := m.codes[:0]
+	for _, code := range m.codes {
+		if code.UserID != userID {
+			kept = append(kept, code)
+		}
+	}
+	m.codes = kept
+	return nil
+}
+
+type mfaTestEnv struct {
+	service       *MFAService
+	sessions      *Service
+	user          *models.User
+	mockRepo      *MockUserRepository
+	recoveryCodes *memoryRecoveryCodes
+	now           time.Time
+}
+
+func newMFATestEnv(t *testing.T, cfg config.MFAConfig) *mfaTestEnv {
+	hash, err := models.HashPassword("password123")
+	assert.NoError(t, err)
+	user := &models.User{ID: 1, Email: "test@example.com", Password: hash, Role: models.RoleUser, IsActive: true}
+
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("GetByEmail", "test@example.com").Return(user, nil)
+	mockRepo.On("GetByID", int64(1)).Return(user, nil)
+	mockRepo.On("UpdateLastLogin", int64(1)).Return(nil)
+	mockRepo.On("RecordFailedLogin", int64(1)).Return(1, nil)
+	mockRepo.On("ResetFailedLogins", int64(1)).Return(nil)
+	users := &mfaUserRepository{MockUserRepository: mockRepo, user: user}
+
+	recoveryCodes := &memoryRecoveryCodes{}
+	sessions := newTestService(t, users, withTxRepos(interfaces.Repositories{MFARecoveryCodes: recoveryCodes}))
+
+	cfg.Issuer = "Nalo Workspace"
+	service, err := NewMFAService(cfg, sessions.jwtConfig.Secret, sessions, recoveryCodes, zap.NewNop())
+	assert.NoError(t, err)
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	return &mfaTestEnv{service: service, sessions: sessions, user: user, mockRepo: mockRepo, recoveryCodes: recoveryCodes, now: now}
+}
+
+// code returns the TOTP code offset steps from now
+func (e *mfaTestEnv) code(t *testing.T, secret string, offset int64) string {
+	code, err := totp.Code(secret, totp.Step(e.now)+offset)
+	assert.NoError(t, err)
+	return code
+}
+
+// enable enrolls and confirms the user and returns the secret and
+// recovery codes
+func (e *mfaTestEnv) enable(t *testing.T) (string, []string) {
+	enrollment, err := e.service.Enroll(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.NotEqual(t, enrollment.Secret, e.user.MFASecret, "secret is stored encrypted")
+	assert.False(t, e.user.MFAEnabled)
+
+	codes, err := e.service.Confirm(context.Background(), 1, e.code(t, enrollment.Secret, 0))
+	assert.NoError(t, err)
+	assert.True(t, e.user.MFAEnabled)
+	assert.Len(t, codes.RecoveryCodes, recoveryCodeCount)
+	return enrollment.Secret, codes.RecoveryCodes
+}
+
+// challenge logs in with the password and returns the MFA token
+func (e *mfaTestEnv) challenge(t *testing.T) string {
+	_, err := e.sessions.Login(context.Background(), &LoginRequest{Email: "test@example.com", Password: "password123"})
+	var challenge *MFAChallenge
+	if !assert.ErrorAs(t, err, &challenge) {
+		t.FailNow()
+	}
+	assert.True(t, challenge.MFARequired)
+	return challenge.MFAToken
+}
+
+func TestMFA_EnrollConfirmAndLogin(t *testing.T) {
+	env := newMFATestEnv(t, config.MFAConfig{})
+	secret, _ := env.enable(t)
+
+	_, err := env.service.Confirm(context.Background(), 1, env.code(t, secret, 0))
+	assert.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+
+	mfaToken := env.challenge(t)
+	_, err = env.sessions.ValidateJWT(mfaToken)
+	assert.Error(t, err, "a challenge is not an access token")
+
+	// The code used to confirm cannot be replayed
+	_, err = env.service.CompleteLogin(context.Background(), mfaToken, env.code(t, secret, 0))
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+	env.mockRepo.AssertCalled(t, "RecordFailedLogin", int64(1))
+
+	response, err := env.service.CompleteLogin(context.Background(), mfaToken, env.code(t, secret, 1))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.Token)
+	assert.NotEmpty(t, response.RefreshToken)
+
+	// A challenge starts one session only
+	_, err = env.service.CompleteLogin(context.Background(), mfaToken, env.code(t, secret, 1))
+	assert.ErrorIs(t, err, ErrInvalidMFAChallenge)
+
+	// An access token is not a challenge
+	_, err = env.service.CompleteLogin(context.Background(), response.Token, env.code(t, secret, 1))
+	assert.ErrorIs(t, err, ErrInvalidMFAChallenge)
+}
+
+func TestMFA_FailedCodesAddUpAcrossChallenges(t *testing.T) {
+	env := newMFATestEnv(t, config.MFAConfig{})
+	secret, _ := env.enable(t)
+
+	// Logging in with the password again must not reset the count, or
+	// codes could be guessed without end
+	for i := 1; i <= 3; i++ {
+		_, err := env.service.CompleteLogin(context.Background(), env.challenge(t), "000000")
+		assert.ErrorIs(t, err, ErrInvalidMFACode)
+		assert.Equal(t, i, env.user.FailedLoginAttempts)
+	}
+	env.mockRepo.AssertNotCalled(t, "ResetFailedLogins", int64(1))
+
+	_, err := env.service.CompleteLogin(context.Background(), env.challenge(t), env.code(t, secret, 1))
+	assert.NoError(t, err)
+	assert.Zero(t, env.user.FailedLoginAttempts)
+}
+
+func TestMFA_RecoveryCodes(t *testing.T) {
+	env := newMFATestEnv(t, config.MFAConfig{})
+	secret, codes := env.enable(t)
+
+	// Case, spaces and dashes are ignored
+	typed := strings.ToUpper(strings.ReplaceAll(codes[0], "-", " "))
+	_, err := env.service.CompleteLogin(context.Background(), env.challenge(t), typed)
+	assert.NoError(t, err)
+
+	_, err = env.service.CompleteLogin(context.Background(), env.challenge(t), codes[0])
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	status, err := env.service.Status(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(recoveryCodeCount-1), status.RecoveryCodesLeft)
+
+	regenerated, err := env.service.RegenerateRecoveryCodes(context.Background(), 1, env.code(t, secret, 1))
+	assert.NoError(t, err)
+	_, err = env.service.CompleteLogin(context.Background(), env.challenge(t), codes[1])
+	assert.ErrorIs(t, err, ErrInvalidMFACode, "old codes stop working")
+	_, err = env.service.CompleteLogin(context.Background(), env.challenge(t), regenerated.RecoveryCodes[1])
+	assert.NoError(t, err)
+}
+
+func TestMFA_Disable(t *testing.T) {
+	env := newMFATestEnv(t, config.MFAConfig{})
+	secret, _ := env.enable(t)
+
+	err := env.service.Disable(context.Background(), 1, "wrong-password", env.code(t, secret, 1))
+	assert.ErrorIs(t, err, ErrInvalidCurrentPassword)
+	err = env.service.Disable(context.Background(), 1, "password123", "000000")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	assert.NoError(t, env.service.Disable(context.Background(), 1, "password123", env.code(t, secret, 1)))
+	assert.False(t, env.user.MFAEnabled)
+	assert.Empty(t, env.user.MFASecret)
+	assert.Empty(t, env.recoveryCodes.codes)
+
+	// Without MFA the password alone logs in again
+	response, err := env.sessions.Login(context.Background(), &LoginRequest{Email: "test@example.com", Password: "password123"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.Token)
+}
+
+func TestMFA_RequiredRoleCannotDisable(t *testing.T) {
+	env := newMFATestEnv(t, config.MFAConfig{RequiredRoles: []string{"admin"}})
+	env.user.Role = models.RoleAdmin
+	secret, _ := env.enable(t)
+
+	status, err := env.service.Status(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.True(t, status.Required)
+
+	err = env.service.Disable(context.Background(), 1, "password123", env.code(t, secret, 1))
+	assert.ErrorIs(t, err, ErrMFARequired)
+	assert.True(t, env.user.MFAEnabled)
+}
@@ -0,0 +1,464 @@
+package auth
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+// Single sign-on errors
+var (
+	ErrInvalidSSOState = errors.New("invalid or expired single sign-on state")
+	ErrSSOFailed       = errors.New("single sign-on failed")
+	ErrSSONotLinked    = errors.New("no account is linked to this identity")
+)
+
+const (
+	// oidcStateTTL is how long a login may take at the identity provider
+	oidcStateTTL = 10 * time.Minute
+	// maxUsernameLength matches the validation of registered usernames
+	maxUsernameLength = 50
+)
+
+// roleRank orders roles so the most privileged mapped role wins
+var roleRank = map[models.UserRole]int{
+	models.RoleUser:    1,
+	models.RoleManager: 2,
+	models.RoleAdmin:   3,
+}
+
+// oidcState is kept in an encrypted cookie between the redirect to the
+// identity provider and the callback
+type oidcState struct {
+	State     string    `json:"state"`
+	Nonce     string    `json:"nonce"`
+	Verifier  string    `json:"verifier"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// oidcClaims are the ID token claims used to find or provision a user
+type oidcClaims struct {
+	Subject           string      `json:"sub"`
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"`
+	PreferredUsername string      `json:"preferred_username"`
+	Name              string      `json:"name"`
+	GivenName         string      `json:"given_name"`
+	FamilyName        string      `json:"family_name"`
+}
+
+// emailVerified accepts the boolean claim and the string form some
+// providers send
+func (c *oidcClaims) emailVerified() bool {
+	switch v := c.EmailVerified.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	}
+	return false
+}
+
+// OIDCService logs users in through an OpenID Connect provider with the
+// authorization code flow and PKCE, then starts a session of the app
+type OIDCService struct {
+	config     config.OIDCConfig
+	aead       cipher.AEAD
+	sessions   *Service
+	identities interfaces.ExternalIdentityInterface
+	logger     *zap.Logger
+
+	// The provider is discovered on first use so the API starts even when
+	// the identity provider is down
+	mu       sync.Mutex
+	provider *oidc.Provider
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewOIDCService creates a single sign-on service. The state cookie is
+// encrypted with a key derived from jwtSecret. sessions provides the
+// repositories and issues the tokens once the user is known.
+func NewOIDCService(cfg config.OIDCConfig, jwtSecret string, sessions *Service, identities interfaces.ExternalIdentityInterface, logger *zap.Logger) (*OIDCService, error) {
+	aead, err := newAEAD("", jwtSecret, "oidc-state")
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCService{
+		config:     cfg,
+		aead:       aead,
+		sessions:   sessions,
+		identities: identities,
+		logger:     logger,
+		now:        time.Now,
+	}, nil
+}
+
+// getProvider returns the discovered provider, fetching the discovery
+// document on first use
+func (s *OIDCService) getProvider(ctx context.Context) (*oidc.Provider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.provider != nil {
+		return s.provider, nil
+	}
+
+	// The provider keeps the context to refresh its signing keys later
+	provider, err := oidc.NewProvider(context.WithoutCancel(ctx), s.config.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	s.provider = provider
+	return provider, nil
+}
+
+func (s *OIDCService) oauth2Config(provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     s.config.ClientID,
+		ClientSecret: s.config.ClientSecret,
+		RedirectURL:  s.config.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       s.config.Scopes,
+	}
+}
+
+// AuthorizationURL starts a login. The returned state must be sent back to
+// Callback, usually in a cookie, together with the parameters the provider
+// redirects with.
+func (s *OIDCService) AuthorizationURL(ctx context.Context) (string, string, error) {
+	provider, err := s.getProvider(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := generateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := generateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	pending := oidcState{
+		State:     state,
+		Nonce:     nonce,
+		Verifier:  oauth2.GenerateVerifier(),
+		ExpiresAt: s.now().Add(oidcStateTTL),
+	}
+	sealed, err := s.sealState(&pending)
+	if err != nil {
+		return "", "", err
+	}
+
+	url := s.oauth2Config(provider).AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(pending.Verifier))
+	return url, sealed, nil
+}
+
+// Callback finishes a login: it checks the state, exchanges the code with
+// the PKCE verifier, validates the ID token against the provider keys and
+// starts a session for the linked, matched or provisioned user
+func (s *OIDCService) Callback(ctx context.Context, sealedState, state, code string) (*LoginResponse, error) {
+	pending, err := s.openState(sealedState)
+	if err != nil || s.now().After(pending.ExpiresAt) ||
+		subtle.ConstantTimeCompare([]byte(pending.State), []byte(state)) != 1 {
+		return nil, ErrInvalidSSOState
+	}
+
+	provider, err := s.getProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.oauth2Config(provider).Exchange(ctx, code, oauth2.VerifierOption(pending.Verifier))
+	if err != nil {
+		s.logger.Warn("OIDC code exchange failed", zap.Error(err))
+		return nil, ErrSSOFailed
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		s.logger.Warn("OIDC token response has no id_token")
+		return nil, ErrSSOFailed
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: s.config.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		s.logger.Warn("OIDC ID token rejected", zap.Error(err))
+		return nil, ErrSSOFailed
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(pending.Nonce)) != 1 {
+		s.logger.Warn("OIDC ID token nonce mismatch", zap.String("subject", idToken.Subject))
+		return nil, ErrSSOFailed
+	}
+
+	var claims oidcClaims
+	var rawClaims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+	if err := idToken.Claims(&rawClaims); err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolveUser(ctx, idToken.Issuer, &claims, s.mapRole(rawClaims))
+	if err != nil {
+		return nil, err
+	}
+	return s.sessions.StartSession(ctx, user)
+}
+
+// resolveUser returns the user linked to the identity. Otherwise it links
+// an existing user with the same verified email or provisions a new one,
+// as allowed by the configuration. role, when set, replaces the role of
+// the user.
+func (s *OIDCService) resolveUser(ctx context.Context, issuer string, claims *oidcClaims, role models.UserRole) (*models.User, error) {
+	now := s.now()
+
+	if identity, err := s.identities.GetBySubject(ctx, issuer, claims.Subject); err == nil {
+		user, err := s.sessions.userRepo.GetByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.identities.TouchLogin(ctx, identity.ID, now); err != nil {
+			s.logger.Error("Failed to record SSO login", zap.Int64("identity_id", identity.ID), zap.Error(err))
+		}
+		return s.syncRole(ctx, user, role)
+	}
+
+	identity := &models.ExternalIdentity{Issuer: issuer, Subject: claims.Subject, Email: claims.Email, LastLoginAt: &now}
+	verified := claims.Email != "" && claims.emailVerified()
+
+	if s.config.LinkByEmail && verified {
+		if user, err := s.sessions.userRepo.GetByEmail(ctx, claims.Email); err == nil {
+			// Linking hands the account to whoever controls the address at
+			// the provider, and syncRole then lets its claims replace the
+			// role, so accounts above the default role are never linked
+			if roleRank[user.Role] > roleRank[models.UserRole(s.config.DefaultRole)] {
+				s.logger.Warn("SSO identity not linked to a privileged account",
+					zap.Int64("user_id", user.ID),
+					zap.String("issuer", issuer),
+					zap.String("subject", claims.Subject),
+				)
+				return nil, ErrSSONotLinked
+			}
+			identity.UserID = user.ID
+			if err := s.identities.Create(ctx, identity); err != nil {
+				return nil, err
+			}
+			// The provider vouches for the address
+			if !user.IsEmailVerified() {
+				if err := s.sessions.userRepo.MarkEmailVerified(ctx, user.ID, now); err != nil {
+					return nil, err
+				}
+				user.EmailVerifiedAt = &now
+			}
+			s.logger.Info("SSO identity linked by email", zap.Int64("user_id", user.ID), zap.String("issuer", issuer))
+			return s.syncRole(ctx, user, role)
+		}
+	}
+
+	if !s.config.AllowSignup || claims.Email == "" {
+		s.logger.Warn("SSO login without a linked account", zap.String("issuer", issuer), zap.String("subject", claims.Subject))
+		return nil, ErrSSONotLinked
+	}
+	return s.provisionUser(ctx, identity, claims, verified, role)
+}
+
+// provisionUser creates the user and its identity on the first login
+func (s *OIDCService) provisionUser(ctx context.Context, identity *models.ExternalIdentity, claims *oidcClaims, verified bool, role models.UserRole) (*models.User, error) {
+	if role == "" {
+		role = models.UserRole(s.config.DefaultRole)
+	}
+	// The account has no usable password until the user resets it
+	password, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(strings.TrimSpace(claims.Name), " ")
+	}
+
+	user := &models.User{
+		Email:     claims.Email,
+		Password:  password,
+		FirstName: firstName,
+		LastName:  strings.TrimSpace(lastName),
+		Role:      role,
+		IsActive:  true,
+	}
+	if verified {
+		user.EmailVerifiedAt = identity.LastLoginAt
+	}
+
+	err = s.sessions.txManager.WithinTransaction(ctx, func(ctx context.Context, repos *interfaces.Repositories) error {
+		exists, err := repos.Users.ExistsByEmail(ctx, user.Email)
+		if err != nil {
+			return err
+		}
+		if exists {
+			// Taken by an account that may not be linked by email
+			return ErrSSONotLinked
+		}
+
+		if user.Username, err = s.availableUsername(ctx, repos.Users, claims); err != nil {
+			return err
+		}
+		if user.FirstName == "" {
+			user.FirstName = user.Username
+		}
+		if err := repos.Users.Create(ctx, user); err != nil {
+			return err
+		}
+
+		identity.UserID = user.ID
+		return repos.ExternalIdentities.Create(ctx, identity)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("User provisioned by SSO",
+		zap.Int64("user_id", user.ID),
+		zap.String("issuer", identity.Issuer),
+		zap.String("role", string(user.Role)),
+	)
+	return user, nil
+}
+
+// availableUsername derives a username from the claims, adding a random
+// suffix when it is taken
+func (s *OIDCService) availableUsername(ctx context.Context, users interfaces.UserInterface, claims *oidcClaims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	base = sanitizeUsername(base)
+
+	candidate := base
+	for attempt := 0; attempt < 5; attempt++ {
+		exists, err := users.ExistsByUsername(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+
+		suffix := make([]byte, 3)
+		if _, err := rand.Read(suffix); err != nil {
+			return "", err
+		}
+		candidate = base + "-" + hex.EncodeToString(suffix)
+	}
+	return "", ErrUsernameTaken
+}
+
+// sanitizeUsername keeps letters, digits, dots, dashes and underscores and
+// pads or cuts the result to a valid length
+func sanitizeUsername(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			b.WriteRune(r)
+		}
+	}
+	username := b.String()
+	for len(username) < 3 {
+		username += "_"
+	}
+	// Leave room for a suffix
+	if len(username) > maxUsernameLength-7 {
+		username = username[:maxUsernameLength-7]
+	}
+	return username
+}
+
+// syncRole applies the role mapped from the ID token, so role changes at
+// the identity provider take effect on the next login
+func (s *OIDCService) syncRole(ctx context.Context, user *models.User, role models.UserRole) (*models.User, error) {
+	if role == "" || role == user.Role {
+		return user, nil
+	}
+	if err := s.sessions.userRepo.UpdateRole(ctx, user.ID, role); err != nil {
+		return nil, err
+	}
+	s.logger.Info("User role updated from SSO claims",
+		zap.Int64("user_id", user.ID),
+		zap.String("from", string(user.Role)),
+		zap.String("to", string(role)),
+	)
+	user.Role = role
+	return user, nil
+}
+
+// mapRole maps the role claim, a string or a list of strings, onto the
+// most privileged configured role. It returns "" when nothing matches.
+func (s *OIDCService) mapRole(claims map[string]interface{}) models.UserRole {
+	var values []string
+	switch v := claims[s.config.RoleClaim].(type) {
+	case string:
+		values = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			if value, ok := item.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+
+	var role models.UserRole
+	for _, value := range values {
+		mapped := models.UserRole(s.config.RoleMapping[value])
+		if roleRank[mapped] > roleRank[role] {
+			role = mapped
+		}
+	}
+	return role
+}
+
+func (s *OIDCService) sealState(state *oidcState) (string, error) {
+	plain, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(s.aead, plain)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (s *OIDCService) openState(sealed string) (*oidcState, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := open(s.aead, raw)
+	if err != nil {
+		return nil, err
+	}
+	var state oidcState
+	if err := json.Unmarshal(plain, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	stderrors "errors"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// oidcStateCookie carries the encrypted login state through the redirect
+// to the identity provider
+const oidcStateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	oidcService  *OIDCService
+	auditService *audit.Service
+	logger       *zap.Logger
+}
+
+func NewOIDCHandler(oidcService *OIDCService, auditService *audit.Service, logger *zap.Logger) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService:  oidcService,
+		auditService: auditService,
+		logger:       logger,
+	}
+}
+
+// Login godoc
+// @Summary Start a single sign-on login
+// @Description Redirects to the identity provider using the authorization code flow with PKCE
+// @Tags auth
+// @Success 302
+// @Failure 503 {object} map[string]string
+// @Router /auth/oidc/login [get]
+func (h *OIDCHandler) Login(c *fiber.Ctx) error {
+	url, state, err := h.oidcService.AuthorizationURL(c.UserContext())
+	if err != nil {
+		logger.FromCtx(c, h.logger).Error("Failed to start single sign-on", zap.Error(err))
+		return errors.ServiceUnavailable("Identity provider is unavailable", err)
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   int(oidcStateTTL / time.Second),
+		Secure:   c.Protocol() == "https",
+		HTTPOnly: true,
+		// Lax lets the cookie through on the top-level redirect back
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return c.Redirect(url, fiber.StatusFound)
+}
+
+// Callback godoc
+// @Summary Finish a single sign-on login
+// @Description The identity provider redirects here. Returns tokens like /auth/login, or an MFA challenge when two-factor authentication is on.
+// @Tags auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State sent with the authorization request"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/oidc/callback [get]
+func (h *OIDCHandler) Callback(c *fiber.Ctx) error {
+	sealed := c.Cookies(oidcStateCookie)
+	// The state is single use
+	c.ClearCookie(oidcStateCookie)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		logger.FromCtx(c, h.logger).Warn("Identity provider returned an error",
+			zap.String("error", providerErr),
+			zap.String("description", c.Query("error_description")),
+		)
+		return errors.Unauthorized(ErrSSOFailed.Error(), nil).WithCode(errors.CodeSSOFailed)
+	}
+
+	response, err := h.oidcService.Callback(c.UserContext(), sealed, c.Query("state"), c.Query("code"))
+	if err != nil {
+		var challenge *MFAChallenge
+		if stderrors.As(err, &challenge) {
+			return c.JSON(challenge)
+		}
+		var appErr *errors.AppError
+		switch {
+		case stderrors.Is(err, ErrInvalidSSOState):
+			return errors.BadRequest(err.Error(), nil).WithCode(errors.CodeSSOStateInvalid)
+		case stderrors.Is(err, ErrSSOFailed):
+			appErr = errors.Unauthorized(err.Error(), nil).WithCode(errors.CodeSSOFailed)
+		case stderrors.Is(err, ErrSSONotLinked):
+			appErr = errors.Forbidden(err.Error(), nil).WithCode(errors.CodeSSONotLinked)
+		case stderrors.Is(err, ErrAccountDeactivated):
+			appErr = errors.Unauthorized(err.Error(), nil).WithCode(errors.CodeAccountDeactivated)
+		default:
+			logger.FromCtx(c, h.logger).Error("Failed to complete single sign-on", zap.Error(err))
+			return errors.InternalServerError("Failed to login user", err)
+		}
+		recordLoginFailure(c, h.auditService, "", appErr)
+		return appErr
+	}
+
+	logger.FromCtx(c, h.logger).Info("User logged in successfully", zap.Int64("user_id", response.User.ID), zap.Bool("sso", true))
+	recordLogin(c, h.auditService, response.User)
+	return c.JSON(response)
+}
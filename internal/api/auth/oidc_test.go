@@ -0,0 +1,375 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/oidctest"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// memoryUsers is an in-memory UserInterface covering what SSO logins use
+type memoryUsers struct {
+	interfaces.UserInterface
+	users []*models.User
+}
+
+func (m *memoryUsers) Create(ctx context.Context, user *models.User) error {
+	user.ID = int64(len(m.users) + 1)
+	m.users = append(m.users, user)
+	return nil
+}
+
+func (m *memoryUsers) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	for _, user := range m.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, user := range m.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryUsers) ExistsByEmail(ctx context.Context, email string) (bool, error) {
+	_, err := m.GetByEmail(ctx, email)
+	return err == nil, nil
+}
+
+func (m *memoryUsers) ExistsByUsername(ctx context.Context, username string) (bool, error) {
+	for _, user := range m.users {
+		if user.Username == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryUsers) UpdateLastLogin(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *memoryUsers) UpdateRole(ctx context.Context, id int64, role models.UserRole) error {
+	user, err := m.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	user.Role = role
+	return nil
+}
+
+func (m *memoryUsers) MarkEmailVerified(ctx context.Context, id int64, at time.Time) error {
+	user, err := m.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	user.EmailVerifiedAt = &at
+	return nil
+}
+
+// memoryIdentities is an in-memory ExternalIdentityInterface
+type memoryIdentities struct {
+	identities []*models.ExternalIdentity
+}
+
+func (m *memoryIdentities) Create(ctx context.Context, identity *models.ExternalIdentity) error {
+	identity.ID = int64(len(m.identities) + 1)
+	m.identities = append(m.identities, identity)
+	return nil
+}
+
+func (m *memoryIdentities) GetBySubject(ctx context.Context, issuer, subject string) (*models.ExternalIdentity, error) {
+	for _, identity := range m.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryIdentities) TouchLogin(ctx context.Context, id int64, at time.Time) error {
+	for _, identity := range m.identities {
+		if identity.ID == id {
+			identity.LastLoginAt = &at
+		}
+	}
+	return nil
+}
+
+type oidcTestEnv struct {
+	service    *OIDCService
+	sessions   *Service
+	provider   *oidctest.Provider
+	users      *memoryUsers
+	identities *memoryIdentities
+}
+
+func newOIDCTestEnv(t *testing.T, configure func(*config.OIDCConfig)) *oidcTestEnv {
+	provider := oidctest.NewProvider("nalo", "client-secret")
+	t.Cleanup(provider.Close)
+
+	cfg := config.OIDCConfig{
+		IssuerURL:    provider.Issuer,
+		ClientID:     "nalo",
+		ClientSecret: "client-secret",
+		RedirectURL:  "http://localhost:3000/api/v1/auth/oidc/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+		RoleClaim:    "groups",
+		RoleMapping:  map[string]string{"staff": "user", "leads": "manager", "it-admins": "admin"},
+		DefaultRole:  "user",
+		AllowSignup:  true,
+		LinkByEmail:  true,
+	}
+	if configure != nil {
+		configure(&cfg)
+	}
+
+	users := &memoryUsers{}
+	identities := &memoryIdentities{}
+	sessions := newTestService(t, users, withTxRepos(interfaces.Repositories{ExternalIdentities: identities}))
+
+	service, err := NewOIDCService(cfg, sessions.jwtConfig.Secret, sessions, identities, zap.NewNop())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return &oidcTestEnv{service: service, sessions: sessions, provider: provider, users: users, identities: identities}
+}
+
+// authorize starts a login and follows it through the provider, returning
+// the sealed state and the callback parameters
+func (e *oidcTestEnv) authorize(t *testing.T) (string, string, string) {
+	authURL, sealed, err := e.service.AuthorizationURL(context.Background())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer resp.Body.Close()
+	if !assert.Equal(t, http.StatusFound, resp.StatusCode) {
+		t.FailNow()
+	}
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return sealed, callback.Query().Get("state"), callback.Query().Get("code")
+}
+
+func (e *oidcTestEnv) login(t *testing.T) (*LoginResponse, error) {
+	sealed, state, code := e.authorize(t)
+	return e.service.Callback(context.Background(), sealed, state, code)
+}
+
+func TestOIDC_ProvisionsUserOnFirstLogin(t *testing.T) {
+	env := newOIDCTestEnv(t, nil)
+	env.provider.SetUser(map[string]interface{}{
+		"sub":                "ada-1",
+		"email":              "ada@example.com",
+		"email_verified":     true,
+		"preferred_username": "Ada Lovelace",
+		"given_name":         "Ada",
+		"family_name":        "Lovelace",
+		"groups":             []string{"staff", "leads"},
+	})
+
+	response, err := env.login(t)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NotEmpty(t, response.RefreshToken)
+
+	claims, err := env.sessions.ValidateJWT(response.Token)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, string(models.RoleManager), claims["role"], "the most privileged mapped role wins")
+
+	if !assert.Len(t, env.users.users, 1) {
+		t.FailNow()
+	}
+	user := env.users.users[0]
+	assert.Equal(t, "adalovelace", user.Username)
+	assert.Equal(t, "Ada", user.FirstName)
+	assert.Equal(t, "Lovelace", user.LastName)
+	assert.True(t, user.IsEmailVerified())
+
+	if !assert.Len(t, env.identities.identities, 1) {
+		t.FailNow()
+	}
+	assert.Equal(t, env.provider.Issuer, env.identities.identities[0].Issuer)
+	assert.Equal(t, user.ID, env.identities.identities[0].UserID)
+
+	// The next login finds the same user
+	_, err = env.login(t)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Len(t, env.users.users, 1)
+	assert.Len(t, env.identities.identities, 1)
+}
+
+func TestOIDC_SyncsMappedRoleOnLogin(t *testing.T) {
+	env := newOIDCTestEnv(t, nil)
+	claims := map[string]interface{}{"sub": "grace-1", "email": "grace@example.com", "email_verified": true, "groups": "it-admins"}
+	env.provider.SetUser(claims)
+
+	_, err := env.login(t)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, models.RoleAdmin, env.users.users[0].Role)
+
+	claims["groups"] = []string{"staff"}
+	_, err = env.login(t)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleUser, env.users.users[0].Role)
+
+	// Without a mapped group the role is left alone
+	claims["groups"] = []string{"unknown"}
+	env.users.users[0].Role = models.RoleManager
+	_, err = env.login(t)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleManager, env.users.users[0].Role)
+}
+
+func TestOIDC_LinksExistingUserByVerifiedEmail(t *testing.T) {
+	env := newOIDCTestEnv(t, nil)
+	existing := &models.User{Email: "linus@example.com", Username: "linus", Role: models.RoleUser, IsActive: true}
+	assert.NoError(t, env.users.Create(context.Background(), existing))
+
+	// An unverified email is not trusted to take over the account
+	env.provider.SetUser(map[string]interface{}{"sub": "linus-1", "email": "linus@example.com", "email_verified": false})
+	_, err := env.login(t)
+	assert.ErrorIs(t, err, ErrSSONotLinked)
+	assert.Empty(t, env.identities.identities)
+
+	env.provider.SetUser(map[string]interface{}{"sub": "linus-1", "email": "linus@example.com", "email_verified": "true"})
+	response, err := env.login(t)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, existing.ID, response.User.ID)
+	assert.Len(t, env.users.users, 1)
+	assert.True(t, existing.IsEmailVerified())
+}
+
+func TestOIDC_DoesNotLinkPrivilegedAccounts(t *testing.T) {
+	env := newOIDCTestEnv(t, nil)
+	admin := &models.User{Email: "root@example.com", Username: "root", Role: models.RoleAdmin, IsActive: true}
+	assert.NoError(t, env.users.Create(context.Background(), admin))
+
+	// Whoever holds the address at the provider must not get the admin account
+	env.provider.SetUser(map[string]interface{}{"sub": "root-1", "email": "root@example.com", "email_verified": true, "groups": "staff"})
+	_, err := env.login(t)
+	assert.ErrorIs(t, err, ErrSSONotLinked)
+	assert.Empty(t, env.identities.identities)
+	assert.Len(t, env.users.users, 1)
+	assert.Equal(t, models.RoleAdmin, admin.Role)
+}
+
+func TestOIDC_RespectsLinkingAndSignupSettings(t *testing.T) {
+	env := newOIDCTestEnv(t, func(cfg *config.OIDCConfig) {
+		cfg.AllowSignup = false
+		cfg.LinkByEmail = false
+	})
+	assert.NoError(t, env.users.Create(context.Background(), &models.User{Email: "known@example.com", Username: "known", IsActive: true}))
+
+	env.provider.SetUser(map[string]interface{}{"sub": "known-1", "email": "known@example.com", "email_verified": true})
+	_, err := env.login(t)
+	assert.ErrorIs(t, err, ErrSSONotLinked)
+
+	env.provider.SetUser(map[string]interface{}{"sub": "new-1", "email": "new@example.com", "email_verified": true})
+	_, err = env.login(t)
+	assert.ErrorIs(t, err, ErrSSONotLinked)
+	assert.Len(t, env.users.users, 1)
+}
+
+func TestOIDC_RejectsDeactivatedUser(t *testing.T) {
+	env := newOIDCTestEnv(t, nil)
+	env.provider.SetUser(map[string]interface{}{"sub": "gone-1", "email": "gone@example.com", "email_verified": true})
+	_, err := env.login(t)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	env.users.users[0].IsActive = false
+	_, err = env.login(t)
+	assert.ErrorIs(t, err, ErrAccountDeactivated)
+}
+
+func TestOIDC_RejectsTamperedState(t *testing.T) {
+	env := newOIDCTestEnv(t, nil)
+	env.provider.SetUser(map[string]interface{}{"sub": "eve-1", "email": "eve@example.com", "email_verified": true})
+
+	sealed, state, code := env.authorize(t)
+	_, err := env.service.Callback(context.Background(), sealed, "other-state", code)
+	assert.ErrorIs(t, err, ErrInvalidSSOState)
+	_, err = env.service.Callback(context.Background(), "", state, code)
+	assert.ErrorIs(t, err, ErrInvalidSSOState)
+	_, err = env.service.Callback(context.Background(), sealed[:len(sealed)-2]+"AA", state, code)
+	assert.ErrorIs(t, err, ErrInvalidSSOState)
+
+	// A state older than the TTL is refused
+	env.service.now = func() time.Time { return time.Now().Add(oidcStateTTL + time.Minute) }
+	_, err = env.service.Callback(context.Background(), sealed, state, code)
+	assert.ErrorIs(t, err, ErrInvalidSSOState)
+	assert.Empty(t, env.users.users)
+}
+
+func TestOIDC_RejectsWrongVerifierAndNonce(t *testing.T) {
+	env := newOIDCTestEnv(t, nil)
+	env.provider.SetUser(map[string]interface{}{"sub": "mallory-1", "email": "mallory@example.com", "email_verified": true})
+
+	// A code can only be redeemed with the verifier of its own login
+	sealed, state, code := env.authorize(t)
+	pending, err := env.service.openState(sealed)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	pending.Verifier = "a-verifier-that-does-not-match-the-challenge-sent"
+	forged, err := env.service.sealState(pending)
+	assert.NoError(t, err)
+	_, err = env.service.Callback(context.Background(), forged, state, code)
+	assert.ErrorIs(t, err, ErrSSOFailed)
+
+	// An ID token issued for another login is refused
+	sealed, state, code = env.authorize(t)
+	pending, err = env.service.openState(sealed)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	pending.Nonce = "another-nonce"
+	forged, err = env.service.sealState(pending)
+	assert.NoError(t, err)
+	_, err = env.service.Callback(context.Background(), forged, state, code)
+	assert.ErrorIs(t, err, ErrSSOFailed)
+	assert.Empty(t, env.users.users)
+}
+
+func TestSanitizeUsername(t *testing.T) {
+	assert.Equal(t, "ada.lovelace", sanitizeUsername("Ada.Lovelace"))
+	assert.Equal(t, "j__", sanitizeUsername("Jö"), "padded to the minimum length")
+	assert.Len(t, sanitizeUsername("a-very-long-preferred-username-from-the-identity-provider"), maxUsernameLength-7)
+}
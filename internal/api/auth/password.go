@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/notify"
+	"go.uber.org/zap"
+)
+
+// Password errors
+var (
+	ErrInvalidResetToken      = errors.New("invalid or expired password reset token")
+	ErrInvalidCurrentPassword = errors.New("current password is incorrect")
+)
+
+// ForgotPasswordRequest starts a password reset
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password with a mailed reset token
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+// ChangePasswordRequest sets a new password for the logged-in user
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8"`
+}
+
+// PasswordService handles forgotten and changed passwords. Every password
+// change ends all sessions of the user.
+type PasswordService struct {
+	config      config.PasswordConfig
+	publicURL   string
+	sessions    *Service
+	resetTokens interfaces.PasswordResetTokenInterface
+	notifier    notify.Notifier
+	logger      *zap.Logger
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewPasswordService creates a password service. sessions provides the
+// user repository and ends sessions after a change.
+func NewPasswordService(cfg config.PasswordConfig, publicURL string, sessions *Service, resetTokens interfaces.PasswordResetTokenInterface, notifier notify.Notifier, logger *zap.Logger) *PasswordService {
+	return &PasswordService{
+		config:      cfg,
+		publicURL:   publicURL,
+		sessions:    sessions,
+		resetTokens: resetTokens,
+		notifier:    notifier,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// ForgotPassword mails a reset link to the account with the given email.
+// It reports success for unknown or inactive accounts too, so callers
+// cannot probe which emails are registered.
+func (s *PasswordService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.sessions.userRepo.GetByEmail(ctx, email)
+	if err != nil || !user.IsActive {
+		s.logger.Info("Password reset requested for unknown or inactive account")
+		return nil
+	}
+
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	err = s.resetTokens.Create(ctx, &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashOpaqueToken(token),
+		ExpiresAt: s.now().Add(s.config.ResetTokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.publicURL, url.QueryEscape(token))
+	s.send(ctx, user, "Reset your password", fmt.Sprintf(
+		"Hello %s,\n\nUse the link below to choose a new password. It expires in %s and can be used once.\n\n%s\n\nIf you did not ask for this, you can ignore this email.",
+		user.FirstName, s.config.ResetTokenTTL, link,
+	))
+	return nil
+}
+
+// ResetPassword consumes a reset token, sets the new password and returns
+// the ID of the user the token belonged to
+func (s *PasswordService) ResetPassword(ctx context.Context, token, newPassword string) (int64, error) {
+	stored, err := s.resetTokens.GetByHash(ctx, hashOpaqueToken(token))
+	if err != nil || !stored.IsUsable(s.now()) {
+		return 0, ErrInvalidResetToken
+	}
+
+	hash, err := models.HashPassword(newPassword)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.sessions.txManager.WithinTransaction(ctx, func(ctx context.Context, repos *interfaces.Repositories) error {
+		used, err := repos.PasswordResetTokens.MarkUsed(ctx, stored.ID, s.now())
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidResetToken
+		}
+		if err := repos.Users.UpdatePassword(ctx, stored.UserID, hash); err != nil {
+			return err
+		}
+		// Proving control of the mailbox also lifts a brute-force lockout
+		if err := repos.Users.ResetFailedLogins(ctx, stored.UserID); err != nil {
+			return err
+		}
+		return repos.PasswordResetTokens.InvalidateForUser(ctx, stored.UserID, s.now())
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return stored.UserID, s.passwordChanged(ctx, stored.UserID)
+}
+
+// ChangePassword replaces the password of a logged-in user after checking
+// the current one
+func (s *PasswordService) ChangePassword(ctx context.Context, userID int64, currentPassword, newPassword string) error {
+	user, err := s.sessions.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.CheckPassword(currentPassword) {
+		return ErrInvalidCurrentPassword
+	}
+
+	hash, err := models.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	err = s.sessions.txManager.WithinTransaction(ctx, func(ctx context.Context, repos *interfaces.Repositories) error {
+		if err := repos.Users.UpdatePassword(ctx, userID, hash); err != nil {
+			return err
+		}
+		return repos.PasswordResetTokens.InvalidateForUser(ctx, userID, s.now())
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.passwordChanged(ctx, userID)
+}
+
+// passwordChanged ends every session of the user and tells them about it
+func (s *PasswordService) passwordChanged(ctx context.Context, userID int64) error {
+	if err := s.sessions.RevokeAllTokens(ctx, userID); err != nil {
+		return err
+	}
+	s.logger.Info("Password changed, sessions revoked", zap.Int64("user_id", userID))
+
+	user, err := s.sessions.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to load user for password change notice", zap.Int64("user_id", userID), zap.Error(err))
+		return nil
+	}
+	s.send(ctx, user, "Your password was changed", fmt.Sprintf(
+		"Hello %s,\n\nThe password of your account was just changed and all sessions were signed out.\n\nIf this was not you, reset your password immediately and contact support.",
+		user.FirstName,
+	))
+	return nil
+}
+
+// send delivers a message on a best-effort basis; a failed delivery is
+// logged but does not fail the request
+func (s *PasswordService) send(ctx context.Context, user *models.User, subject, body string) {
+	if err := s.notifier.Send(ctx, notify.Message{To: user.Email, Subject: subject, Body: body}); err != nil {
+		s.logger.Error("Failed to send notification", zap.Int64("user_id", user.ID), zap.String("subject", subject), zap.Error(err))
+	}
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	stderrors "errors"
+
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/alxand/nalo-workspace/internal/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type PasswordHandler struct {
+	passwordService *PasswordService
+	auditService    *audit.Service
+	logger          *zap.Logger
+}
+
+func NewPasswordHandler(passwordService *PasswordService, auditService *audit.Service, logger *zap.Logger) *PasswordHandler {
+	return &PasswordHandler{
+		passwordService: passwordService,
+		auditService:    auditService,
+		logger:          logger,
+	}
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset link
+// @Description Always answers 202 so the response does not reveal whether the email is registered
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body ForgotPasswordRequest true "Account email"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/password/forgot [post]
+func (h *PasswordHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+
+	if err := h.passwordService.ForgotPassword(c.UserContext(), req.Email); err != nil {
+		logger.FromCtx(c, h.logger).Error("Failed to start password reset", zap.Error(err))
+		return errors.InternalServerError("Failed to start password reset", err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "If the account exists, a password reset link has been sent",
+	})
+}
+
+// ResetPassword godoc
+// @Summary Set a new password with a reset token
+// @Description Consumes the token and signs the user out of every session
+// @Tags auth
+// @Accept json
+// @Param body body ResetPasswordRequest true "Reset token and new password"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/password/reset [post]
+func (h *PasswordHandler) ResetPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+
+	userID, err := h.passwordService.ResetPassword(c.UserContext(), req.Token, req.Password)
+	if err != nil {
+		if stderrors.Is(err, ErrInvalidResetToken) {
+			return errors.BadRequest(err.Error(), nil).WithCode(errors.CodeResetTokenInvalid)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to reset password", zap.Error(err))
+		return errors.InternalServerError("Failed to reset password", err)
+	}
+
+	logger.FromCtx(c, h.logger).Info("Password reset", zap.Int64("user_id", userID))
+	h.auditService.Record(c.UserContext(), audit.Event{
+		Action: models.AuditPasswordReset, EntityType: models.AuditEntityUser, EntityID: audit.ID(userID), ActorID: userID,
+	})
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ChangePassword godoc
+// @Summary Change the password of the current user
+// @Description Requires the current password and signs the user out of every session
+// @Tags auth
+// @Accept json
+// @Security BearerAuth
+// @Param body body ChangePasswordRequest true "Current and new password"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/password/change [post]
+func (h *PasswordHandler) ChangePassword(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	var req ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+
+	if err := h.passwordService.ChangePassword(c.UserContext(), user.ID, req.CurrentPassword, req.NewPassword); err != nil {
+		if stderrors.Is(err, ErrInvalidCurrentPassword) {
+			return errors.BadRequest(err.Error(), nil).WithCode(errors.CodeInvalidPassword)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to change password", zap.Error(err))
+		return errors.InternalServerError("Failed to change password", err)
+	}
+
+	logger.FromCtx(c, h.logger).Info("Password changed")
+	h.auditService.Record(c.UserContext(), audit.Event{Action: models.AuditPasswordChange, EntityType: models.AuditEntityUser, EntityID: audit.ID(user.ID)})
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// memoryResetTokens is an in-memory PasswordResetTokenInterface
+type memoryResetTokens struct {
+	tokens []*models.PasswordResetToken
+}
+
+func (m *memoryResetTokens) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	token.ID = int64(len(m.tokens) + 1)
+	m.tokens = append(m.tokens, token)
+	return nil
+}
+
+func (m *memoryResetTokens) GetByHash(ctx context.Context, hash string) (*models.PasswordResetToken, error) {
+	for _, token := range m.tokens {
+		if token.TokenHash == hash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryResetTokens) MarkUsed(ctx context.Context, id int64, at time.Time) (bool, error) {
+	token := m.tokens[id-1]
+	if token.UsedAt != nil {
+		return false, nil
+	}
+	token.UsedAt = &at
+	return true, nil
+}
+
+func (m *memoryResetTokens) InvalidateForUser(ctx context.Context, userID int64, at time.Time) error {
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.UsedAt == nil {
+			token.UsedAt = &at
+		}
+	}
+	return nil
+}
+
+// recordingNotifier keeps every message it is asked to send
+type recordingNotifier struct {
+	messages []notify.Message
+}
+
+func (n *recordingNotifier) Send(ctx context.Context, msg notify.Message) error {
+	n.messages = append(n.messages, msg)
+	return nil
+}
+
+// resetLinkToken extracts the token from the reset link in a message body
+func resetLinkToken(t *testing.T, msg notify.Message) string {
+	for _, field := range strings.Fields(msg.Body) {
+		if strings.HasPrefix(field, "https://app.example.com/reset-password?") {
+			link, err := url.Parse(field)
+			assert.NoError(t, err)
+			return link.Query().Get("token")
+		}
+	}
+	t.Fatalf("no reset link in %q", msg.Body)
+	return ""
+}
+
+type passwordTestEnv struct {
+	service       *PasswordService
+	sessions      *Service
+	user          *models.User
+	resetTokens   *memoryResetTokens
+	refreshTokens *memoryRefreshTokens
+	notifier      *recordingNotifier
+}
+
+func newPasswordTestEnv(t *testing.T) *passwordTestEnv {
+	hash, err := models.HashPassword("old-password")
+	assert.NoError(t, err)
+	user := &models.User{ID: 1, Email: "test@example.com", FirstName: "Test", Password: hash, Role: models.RoleUser, IsActive: true}
+
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("GetByEmail", "test@example.com").Return(user, nil)
+	mockRepo.On("GetByEmail", mock.Anything).Return((*models.User)(nil), gorm.ErrRecordNotFound)
+	mockRepo.On("GetByID", int64(1)).Return(user, nil)
+	mockRepo.On("ResetFailedLogins", int64(1)).Return(nil)
+	mockRepo.On("UpdatePassword", int64(1), mock.Anything).Run(func(args mock.Arguments) {
+		user.Password = args.String(1)
+	}).Return(nil)
+
+	resetTokens := &memoryResetTokens{}
+	refreshTokens := newMemoryRefreshTokens()
+	sessions := newTestService(t, mockRepo, withRefreshTokens(refreshTokens), withTxRepos(interfaces.Repositories{PasswordResetTokens: resetTokens}))
+
+	notifier := &recordingNotifier{}
+	service := NewPasswordService(config.PasswordConfig{ResetTokenTTL: time.Hour}, "https://app.example.com", sessions, resetTokens, notifier, zap.NewNop())
+	return &passwordTestEnv{
+		service:       service,
+		sessions:      sessions,
+		user:          user,
+		resetTokens:   resetTokens,
+		refreshTokens: refreshTokens,
+		notifier:      notifier,
+	}
+}
+
+func TestForgotPassword_MailsSingleUseToken(t *testing.T) {
+	env := newPasswordTestEnv(t)
+	session := startSession(t, env.sessions)
+
+	assert.NoError(t, env.service.ForgotPassword(context.Background(), "test@example.com"))
+	if !assert.Len(t, env.notifier.messages, 1) {
+		return
+	}
+	assert.Equal(t, "test@example.com", env.notifier.messages[0].To)
+	token := resetLinkToken(t, env.notifier.messages[0])
+
+	// Only the hash is stored
+	assert.NotEqual(t, token, env.resetTokens.tokens[0].TokenHash)
+
+	userID, err := env.service.ResetPassword(context.Background(), token, "new-password")
+	assert.NoError(t, err)
+	assert.Equal(t, env.user.ID, userID)
+	assert.True(t, env.user.CheckPassword("new-password"))
+	assert.False(t, env.user.CheckPassword("old-password"))
+
+	// Existing sessions end with the reset
+	_, err = env.sessions.GetUserFromToken(context.Background(), session.Token)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	_, err = env.sessions.Refresh(context.Background(), session.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// The token works once
+	_, err = env.service.ResetPassword(context.Background(), token, "another-password")
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+}
+
+func TestForgotPassword_UnknownEmailIsSilent(t *testing.T) {
+	env := newPasswordTestEnv(t)
+
+	assert.NoError(t, env.service.ForgotPassword(context.Background(), "nobody@example.com"))
+	assert.Empty(t, env.notifier.messages)
+	assert.Empty(t, env.resetTokens.tokens)
+}
+
+func TestResetPassword_RejectsExpiredAndUnknownTokens(t *testing.T) {
+	env := newPasswordTestEnv(t)
+	assert.NoError(t, env.service.ForgotPassword(context.Background(), "test@example.com"))
+	token := resetLinkToken(t, env.notifier.messages[0])
+
+	_, err := env.service.ResetPassword(context.Background(), "not-a-token", "new-password")
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+
+	later := time.Now().Add(2 * time.Hour)
+	env.service.now = func() time.Time { return later }
+	_, err = env.service.ResetPassword(context.Background(), token, "new-password")
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+	assert.True(t, env.user.CheckPassword("old-password"))
+}
+
+func TestChangePassword(t *testing.T) {
+	env := newPasswordTestEnv(t)
+	session := startSession(t, env.sessions)
+	assert.NoError(t, env.service.ForgotPassword(context.Background(), "test@example.com"))
+	pending := resetLinkToken(t, env.notifier.messages[0])
+
+	err := env.service.ChangePassword(context.Background(), 1, "wrong-password", "new-password")
+	assert.ErrorIs(t, err, ErrInvalidCurrentPassword)
+	assert.True(t, env.user.CheckPassword("old-password"))
+
+	assert.NoError(t, env.service.ChangePassword(context.Background(), 1, "old-password", "new-password"))
+	assert.True(t, env.user.CheckPassword("new-password"))
+
+	_, err = env.sessions.GetUserFromToken(context.Background(), session.Token)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	// Reset links sent before the change no longer work
+	_, err = env.service.ResetPassword(context.Background(), pending, "other-password")
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"go.uber.org/zap"
+)
+
+// Permission errors
+var (
+	ErrUnknownRole       = errors.New("unknown role")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrPermissionLockout = errors.New("the admin role must keep the role:admin permission")
+)
+
+// UpdateRolePermissionsRequest replaces the permissions of a role
+type UpdateRolePermissionsRequest struct {
+	Permissions []string `json:"permissions" validate:"required"`
+}
+
+// RolePermissions lists the permissions of a role
+type RolePermissions struct {
+	Role        models.UserRole `json:"role"`
+	Permissions []string        `json:"permissions"`
+}
+
+// PermissionService answers which permissions a role has without a
+// database round trip per request. Changes made here apply at once; those
+// made by other instances are picked up by the next Sync.
+type PermissionService struct {
+	repo   interfaces.RolePermissionInterface
+	logger *zap.Logger
+
+	mu    sync.RWMutex
+	roles map[models.UserRole]map[string]bool
+}
+
+// NewPermissionService creates a service with no permissions until the
+// first Sync
+func NewPermissionService(repo interfaces.RolePermissionInterface, logger *zap.Logger) *PermissionService {
+	return &PermissionService{
+		repo:   repo,
+		logger: logger,
+		roles:  make(map[models.UserRole]map[string]bool),
+	}
+}
+
+// Has reports whether the role of user grants any of permissions
+func (s *PermissionService) Has(user *models.User, permissions ...string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	granted := s.roles[user.Role]
+	for _, permission := range permissions {
+		if granted[permission] {
+			return true
+		}
+	}
+	return false
+}
+
+// For returns the permissions of role, sorted
+func (s *PermissionService) For(role models.UserRole) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	permissions := make([]string, 0, len(s.roles[role]))
+	for permission := range s.roles[role] {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+// Roles returns the permissions of every role
+func (s *PermissionService) Roles() []RolePermissions {
+	roles := make([]RolePermissions, len(models.Roles))
+	for i, role := range models.Roles {
+		roles[i] = RolePermissions{Role: role, Permissions: s.For(role)}
+	}
+	return roles
+}
+
+// Update replaces the permissions of role. The admin role cannot give up
+// role:admin, so permissions can always be edited.
+func (s *PermissionService) Update(ctx context.Context, role models.UserRole, permissions []string) error {
+	// Keys of the cache use the declared role, since role may be backed by
+	// a request buffer that is reused
+	known := false
+	for _, r := range models.Roles {
+		if r == role {
+			role, known = r, true
+		}
+	}
+	if !known {
+		return ErrUnknownRole
+	}
+
+	granted := make(map[string]bool, len(permissions))
+	unique := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		if !models.IsPermission(permission) {
+			return fmt.Errorf("%w %q", ErrUnknownPermission, permission)
+		}
+		if !granted[permission] {
+			granted[permission] = true
+			unique = append(unique, permission)
+		}
+	}
+	if role == models.RoleAdmin && !granted[models.PermissionRoleAdmin] {
+		return ErrPermissionLockout
+	}
+
+	if err := s.repo.ReplaceForRole(ctx, role, unique); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.roles[role] = granted
+	s.mu.Unlock()
+
+	s.logger.Info("Role permissions changed", zap.String("role", string(role)), zap.Strings("permissions", unique))
+	return nil
+}
+
+// Sync replaces the cached permissions with the ones in the database
+func (s *PermissionService) Sync(ctx context.Context) error {
+	grants, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	roles := make(map[models.UserRole]map[string]bool)
+	for _, grant := range grants {
+		if roles[grant.Role] == nil {
+			roles[grant.Role] = make(map[string]bool)
+		}
+		roles[grant.Role][grant.Permission] = true
+	}
+
+	s.mu.Lock()
+	s.roles = roles
+	s.mu.Unlock()
+	return nil
+}
+
+// Start syncs every interval until the returned stop func is called
+func (s *PermissionService) Start(interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Sync(ctx); err != nil {
+					s.logger.Error("Failed to sync role permissions", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	stderrors "errors"
+
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/alxand/nalo-workspace/internal/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type PermissionHandler struct {
+	permissionService *PermissionService
+	auditService      *audit.Service
+	logger            *zap.Logger
+}
+
+func NewPermissionHandler(permissionService *PermissionService, auditService *audit.Service, logger *zap.Logger) *PermissionHandler {
+	return &PermissionHandler{
+		permissionService: permissionService,
+		auditService:      auditService,
+		logger:            logger,
+	}
+}
+
+// EffectivePermissions godoc
+// @Summary Get the permissions of the current user
+// @Description The permissions granted to the user's role. Personal access tokens are further limited by their scopes.
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} RolePermissions
+// @Failure 401 {object} map[string]string
+// @Router /auth/permissions [get]
+func (h *PermissionHandler) EffectivePermissions(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+	return c.JSON(RolePermissions{Role: user.Role, Permissions: h.permissionService.For(user.Role)})
+}
+
+// ListPermissions godoc
+// @Summary List every permission that can be granted
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.PermissionInfo
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/permissions [get]
+func (h *PermissionHandler) ListPermissions(c *fiber.Ctx) error {
+	return c.JSON(models.PermissionCatalog)
+}
+
+// ListRoles godoc
+// @Summary List the permissions of every role
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} RolePermissions
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/roles [get]
+func (h *PermissionHandler) ListRoles(c *fiber.Ctx) error {
+	return c.JSON(h.permissionService.Roles())
+}
+
+// UpdateRolePermissions godoc
+// @Summary Replace the permissions of a role
+// @Description Applies to every user with the role. The admin role must keep role:admin.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param role path string true "Role"
+// @Param body body UpdateRolePermissionsRequest true "Permissions"
+// @Success 200 {object} RolePermissions
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/roles/{role}/permissions [put]
+func (h *PermissionHandler) UpdateRolePermissions(c *fiber.Ctx) error {
+	var req UpdateRolePermissionsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+
+	role := models.UserRole(c.Params("role"))
+	before := RolePermissions{Role: role, Permissions: h.permissionService.For(role)}
+	if err := h.permissionService.Update(c.UserContext(), role, req.Permissions); err != nil {
+		switch {
+		case stderrors.Is(err, ErrUnknownRole):
+			return errors.NotFound(err.Error(), nil)
+		case stderrors.Is(err, ErrUnknownPermission), stderrors.Is(err, ErrPermissionLockout):
+			return errors.BadRequest(err.Error(), nil).WithCode(errors.CodeInvalidRequest)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to update role permissions", zap.String("role", string(role)), zap.Error(err))
+		return errors.DatabaseError("Failed to update role permissions", err)
+	}
+
+	after := RolePermissions{Role: role, Permissions: h.permissionService.For(role)}
+	h.auditService.Record(c.UserContext(), audit.Event{
+		Action: models.AuditUpdate, EntityType: models.AuditEntityRole, EntityID: string(role), Before: before, After: after,
+	})
+	return c.JSON(after)
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"unsafe"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memoryRolePermissions is an in-memory RolePermissionInterface holding
+// the default mapping
+type memoryRolePermissions struct {
+	grants map[models.UserRole][]string
+}
+
+func newMemoryRolePermissions() *memoryRolePermissions {
+	grants := make(map[models.UserRole][]string)
+	for role, permissions := range models.DefaultRolePermissions {
+		grants[role] = append([]string(nil), permissions...)
+	}
+	return &memoryRolePermissions{grants: grants}
+}
+
+func (m *memoryRolePermissions) List(ctx context.Context) ([]models.RolePermission, error) {
+	var result []models.RolePermission
+	for role, permissions := range m.grants {
+		for _, permission := range permissions {
+			result = append(result, models.RolePermission{Role: role, Permission: permission})
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryRolePermissions) ReplaceForRole(ctx context.Context, role models.UserRole, permissions []string) error {
+	m.grants[role] = append([]string(nil), permissions...)
+	return nil
+}
+
+// newTestPermissions returns a permission service with the default mapping
+func newTestPermissions() *PermissionService {
+	permissions := NewPermissionService(newMemoryRolePermissions(), zap.NewNop())
+	if err := permissions.Sync(context.Background()); err != nil {
+		panic(err)
+	}
+	return permissions
+}
+
+func TestPermissionService_DefaultMapping(t *testing.T) {
+	permissions := newTestPermissions()
+	admin := &models.User{Role: models.RoleAdmin}
+	manager := &models.User{Role: models.RoleManager}
+	user := &models.User{Role: models.RoleUser}
+
+	assert.True(t, permissions.Has(admin, models.PermissionCompanyWrite))
+	assert.True(t, permissions.Has(manager, models.PermissionTaskReadTeam))
+	assert.False(t, permissions.Has(manager, models.PermissionCompanyWrite))
+	assert.False(t, permissions.Has(user, models.PermissionCompanyWrite, models.PermissionInvitationWrite))
+	assert.True(t, permissions.Has(user, models.PermissionUserAdmin, models.PermissionTaskWrite), "any of the permissions is enough")
+	assert.False(t, permissions.Has(&models.User{Role: "guest"}, models.PermissionTaskRead))
+
+	assert.Equal(t, len(models.PermissionCatalog), len(permissions.For(models.RoleAdmin)), "admins have every permission")
+	assert.Equal(t, []string{"company:read", "continent:read", "country:read", "task:read", "task:write"}, permissions.For(models.RoleUser))
+	for _, permission := range permissions.For(models.RoleAdmin) {
+		assert.True(t, models.IsPermission(permission), permission)
+	}
+}
+
+func TestPermissionService_Update(t *testing.T) {
+	repo := newMemoryRolePermissions()
+	permissions := NewPermissionService(repo, zap.NewNop())
+	assert.NoError(t, permissions.Sync(context.Background()))
+	ctx := context.Background()
+	user := &models.User{Role: models.RoleUser}
+
+	assert.ErrorIs(t, permissions.Update(ctx, "guest", nil), ErrUnknownRole)
+	assert.ErrorIs(t, permissions.Update(ctx, models.RoleUser, []string{"task:fly"}), ErrUnknownPermission)
+	assert.ErrorIs(t, permissions.Update(ctx, models.RoleAdmin, []string{models.PermissionUserAdmin}), ErrPermissionLockout)
+
+	err := permissions.Update(ctx, models.RoleUser, []string{models.PermissionTaskRead, models.PermissionCompanyWrite, models.PermissionTaskRead})
+	assert.NoError(t, err)
+	assert.True(t, permissions.Has(user, models.PermissionCompanyWrite), "applies at once")
+	assert.False(t, permissions.Has(user, models.PermissionTaskWrite))
+	assert.Equal(t, []string{models.PermissionTaskRead, models.PermissionCompanyWrite}, repo.grants[models.RoleUser], "stored without duplicates")
+
+	// Another instance picks the change up on its next sync
+	other := NewPermissionService(repo, zap.NewNop())
+	assert.NoError(t, other.Sync(ctx))
+	assert.Equal(t, permissions.For(models.RoleUser), other.For(models.RoleUser))
+
+	// A role can lose every permission
+	assert.NoError(t, permissions.Update(ctx, models.RoleUser, []string{}))
+	assert.Empty(t, permissions.For(models.RoleUser))
+
+	// The role may come from a request buffer that is reused afterwards
+	buf := []byte("manager")
+	assert.NoError(t, permissions.Update(ctx, models.UserRole(unsafe.String(&buf[0], len(buf))), []string{models.PermissionTaskRead}))
+	copy(buf, "zzzzzzz")
+	assert.Equal(t, []string{models.PermissionTaskRead}, permissions.For(models.RoleManager))
+}
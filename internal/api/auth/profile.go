@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// UpdateProfileRequest replaces the profile of the current user. Role,
+// active state and company are not part of it; only admins change those.
+// Changing the email or username needs the current password.
+type UpdateProfileRequest struct {
+	Email           string                         `json:"email" validate:"required,email"`
+	Username        string                         `json:"username" validate:"required,min=3,max=50"`
+	FirstName       string                         `json:"first_name" validate:"required,min=2,max=50"`
+	LastName        string                         `json:"last_name" validate:"required,min=2,max=50"`
+	CountryID       *int64                         `json:"country_id"`
+	Timezone        string                         `json:"timezone" validate:"omitempty,timezone"`
+	Locale          string                         `json:"locale" validate:"omitempty,bcp47_language_tag"`
+	AvatarURL       string                         `json:"avatar_url" validate:"omitempty,http_url,max=500"`
+	Notifications   models.NotificationPreferences `json:"notifications"`
+	CurrentPassword string                         `json:"current_password"`
+}
+
+// PatchProfileRequest changes the fields of the profile that are given
+type PatchProfileRequest struct {
+	Email           *string                       `json:"email" validate:"omitempty,email"`
+	Username        *string                       `json:"username" validate:"omitempty,min=3,max=50"`
+	FirstName       *string                       `json:"first_name" validate:"omitempty,min=2,max=50"`
+	LastName        *string                       `json:"last_name" validate:"omitempty,min=2,max=50"`
+	CountryID       *int64                        `json:"country_id"`
+	Timezone        *string                       `json:"timezone" validate:"omitempty,timezone"`
+	Locale          *string                       `json:"locale" validate:"omitempty,bcp47_language_tag"`
+	AvatarURL       *string                       `json:"avatar_url" validate:"omitempty,http_url,max=500"`
+	Notifications   *NotificationPreferencesPatch `json:"notifications"`
+	CurrentPassword string                        `json:"current_password"`
+
+	// replace clears the country when CountryID is nil, as PUT does
+	replace bool
+}
+
+// NotificationPreferencesPatch changes the notification preferences given
+type NotificationPreferencesPatch struct {
+	TaskReminders *bool `json:"task_reminders"`
+	WeeklySummary *bool `json:"weekly_summary"`
+}
+
+// Patch returns the change that replaces every field of the profile
+func (r *UpdateProfileRequest) Patch() *PatchProfileRequest {
+	return &PatchProfileRequest{
+		Email:     &r.Email,
+		Username:  &r.Username,
+		FirstName: &r.FirstName,
+		LastName:  &r.LastName,
+		CountryID: r.CountryID,
+		Timezone:  &r.Timezone,
+		Locale:    &r.Locale,
+		AvatarURL: &r.AvatarURL,
+		Notifications: &NotificationPreferencesPatch{
+			TaskReminders: &r.Notifications.TaskReminders,
+			WeeklySummary: &r.Notifications.WeeklySummary,
+		},
+		CurrentPassword: r.CurrentPassword,
+		replace:         true,
+	}
+}
+
+// ChangesEmail reports whether the request changes the email of user
+func (r *PatchProfileRequest) ChangesEmail(user *models.User) bool {
+	return r.Email != nil && !strings.EqualFold(*r.Email, user.Email)
+}
+
+// ChangesUsername reports whether the request changes the username of user
+func (r *PatchProfileRequest) ChangesUsername(user *models.User) bool {
+	return r.Username != nil && *r.Username != user.Username
+}
+
+// ProfileService lets users edit their own profile
+type ProfileService struct {
+	userRepo     interfaces.UserInterface
+	countryRepo  interfaces.CountryInterface
+	verification *VerificationService
+	logger       *zap.Logger
+}
+
+// NewProfileService creates a profile service
+func NewProfileService(userRepo interfaces.UserInterface, countryRepo interfaces.CountryInterface, verification *VerificationService, logger *zap.Logger) *ProfileService {
+	return &ProfileService{
+		userRepo:     userRepo,
+		countryRepo:  countryRepo,
+		verification: verification,
+		logger:       logger,
+	}
+}
+
+// Update applies req to the profile of user and returns the result. A new
+// username applies at once; a new email is only stored as pending and
+// replaces the current one when confirmed from the link mailed to it.
+func (s *ProfileService) Update(ctx context.Context, user *models.User, req *PatchProfileRequest) (*models.User, error) {
+	changesEmail, changesUsername := req.ChangesEmail(user), req.ChangesUsername(user)
+	if (changesEmail || changesUsername) && !user.CheckPassword(req.CurrentPassword) {
+		return nil, ErrInvalidCurrentPassword
+	}
+
+	updated := *user
+	if changesUsername {
+		taken, err := s.userRepo.ExistsByUsername(ctx, *req.Username)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, ErrUsernameTaken
+		}
+		updated.Username = *req.Username
+	}
+	if changesEmail {
+		taken, err := s.userRepo.ExistsByEmail(ctx, *req.Email)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, ErrEmailTaken
+		}
+	}
+	var country *models.Country
+	if req.CountryID != nil {
+		var err error
+		if country, err = s.countryRepo.GetByID(ctx, *req.CountryID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrCountryNotFound
+			}
+			return nil, err
+		}
+	}
+
+	if req.FirstName != nil {
+		updated.FirstName = *req.FirstName
+	}
+	if req.LastName != nil {
+		updated.LastName = *req.LastName
+	}
+	if req.CountryID != nil || req.replace {
+		// The preloaded country would be shown next to the new ID
+		updated.CountryID, updated.Country = req.CountryID, country
+	}
+	if req.Timezone != nil {
+		updated.Timezone = *req.Timezone
+	}
+	if req.Locale != nil {
+		updated.Locale = *req.Locale
+	}
+	if req.AvatarURL != nil {
+		updated.AvatarURL = *req.AvatarURL
+	}
+	if n := req.Notifications; n != nil {
+		if n.TaskReminders != nil {
+			updated.Notifications.TaskReminders = *n.TaskReminders
+		}
+		if n.WeeklySummary != nil {
+			updated.Notifications.WeeklySummary = *n.WeeklySummary
+		}
+	}
+
+	// Mailed first, so a throttled change stores nothing
+	if changesEmail {
+		if err := s.verification.SendEmailChange(ctx, &updated, *req.Email); err != nil {
+			return nil, err
+		}
+		if err := s.userRepo.RequestEmailChange(ctx, user.ID, *req.Email); err != nil {
+			return nil, err
+		}
+		updated.PendingEmail = req.Email
+	}
+	if err := s.userRepo.UpdateProfile(ctx, &updated); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Profile updated",
+		zap.Int64("user_id", user.ID),
+		zap.Bool("email_change_requested", changesEmail),
+		zap.Bool("username_changed", changesUsername),
+	)
+	return &updated, nil
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	stderrors "errors"
+
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/alxand/nalo-workspace/internal/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type ProfileHandler struct {
+	profileService *ProfileService
+	auditService   *audit.Service
+	logger         *zap.Logger
+}
+
+func NewProfileHandler(profileService *ProfileService, auditService *audit.Service, logger *zap.Logger) *ProfileHandler {
+	return &ProfileHandler{
+		profileService: profileService,
+		auditService:   auditService,
+		logger:         logger,
+	}
+}
+
+// UpdateProfile godoc
+// @Summary Replace the current user's profile
+// @Description Role, active state and company cannot be changed here. Changing the email or username needs current_password; a new email is stored as pending_email until confirmed from the link mailed to it.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body UpdateProfileRequest true "Profile"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/profile [put]
+func (h *ProfileHandler) UpdateProfile(c *fiber.Ctx) error {
+	var req UpdateProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+	return h.update(c, req.Patch())
+}
+
+// PatchProfile godoc
+// @Summary Change fields of the current user's profile
+// @Description Changes only the fields given, with the same rules as PUT /auth/profile
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body PatchProfileRequest true "Profile fields"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/profile [patch]
+func (h *ProfileHandler) PatchProfile(c *fiber.Ctx) error {
+	var req PatchProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+	return h.update(c, &req)
+}
+
+func (h *ProfileHandler) update(c *fiber.Ctx, req *PatchProfileRequest) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	// Admins acting as the user may fix their profile, not take over how
+	// they sign in
+	if _, impersonating := c.Locals("impersonator").(*models.User); impersonating && (req.ChangesEmail(user) || req.ChangesUsername(user)) {
+		return errors.Forbidden("Email and username cannot be changed while impersonating", nil).WithCode(errors.CodeImpersonating)
+	}
+
+	updated, err := h.profileService.Update(c.UserContext(), user, req)
+	if err != nil {
+		switch {
+		case stderrors.Is(err, ErrInvalidCurrentPassword):
+			return errors.BadRequest("The current password is required to change the email or username", nil).WithCode(errors.CodeInvalidPassword)
+		case stderrors.Is(err, ErrEmailTaken):
+			return errors.Conflict(err.Error(), nil).WithCode(errors.CodeEmailTaken)
+		case stderrors.Is(err, ErrUsernameTaken):
+			return errors.Conflict(err.Error(), nil).WithCode(errors.CodeUsernameTaken)
+		case stderrors.Is(err, ErrCountryNotFound):
+			return errors.NotFound(err.Error(), nil).WithCode(errors.CodeCountryNotFound)
+		case stderrors.Is(err, ErrVerificationThrottled):
+			return errors.TooManyRequests("An email change was requested recently; try again later", nil).WithCode(errors.CodeRateLimited)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to update profile", zap.Error(err))
+		return errors.DatabaseError("Failed to update profile", err)
+	}
+
+	h.auditService.Record(c.UserContext(), audit.Event{
+		Action: models.AuditUpdate, EntityType: models.AuditEntityUser, EntityID: audit.ID(user.ID), Before: user, After: updated,
+	})
+	return c.JSON(updated)
+}
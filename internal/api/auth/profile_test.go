@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func (m *memoryUsers) MarkVerificationSent(ctx context.Context, id int64, at, notBefore time.Time) (bool, error) {
+	user, err := m.GetByID(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if user.VerificationSentAt != nil && user.VerificationSentAt.After(notBefore) {
+		return false, nil
+	}
+	user.VerificationSentAt = &at
+	return true, nil
+}
+
+func (m *memoryUsers) UpdateProfile(ctx context.Context, updated *models.User) error {
+	user, err := m.GetByID(ctx, updated.ID)
+	if err != nil {
+		return err
+	}
+	user.Username, user.FirstName, user.LastName = updated.Username, updated.FirstName, updated.LastName
+	user.CountryID, user.Timezone, user.Locale, user.AvatarURL = updated.CountryID, updated.Timezone, updated.Locale, updated.AvatarURL
+	user.Notifications = updated.Notifications
+	return nil
+}
+
+func (m *memoryUsers) RequestEmailChange(ctx context.Context, id int64, email string) error {
+	user, err := m.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	user.PendingEmail = &email
+	return nil
+}
+
+func (m *memoryUsers) ConfirmEmailChange(ctx context.Context, id int64, email string, at time.Time) (bool, error) {
+	user, err := m.GetByID(ctx, id)
+	if err != nil || user.PendingEmail == nil || *user.PendingEmail != email {
+		return false, err
+	}
+	user.Email, user.PendingEmail, user.EmailVerifiedAt = email, nil, &at
+	return true, nil
+}
+
+type profileTestEnv struct {
+	service      *ProfileService
+	verification *VerificationService
+	users        *memoryUsers
+	notifier     *recordingNotifier
+	user         *models.User
+	now          time.Time
+}
+
+func newProfileTestEnv(t *testing.T) *profileTestEnv {
+	country := int64(7)
+	env := &profileTestEnv{
+		users:    &memoryUsers{},
+		notifier: &recordingNotifier{},
+		now:      time.Now(),
+	}
+	env.user = &models.User{
+		Email: "nina@example.com", Username: "nina", FirstName: "Nina", LastName: "Newhire",
+		Role: models.RoleUser, IsActive: true, CountryID: &country, EmailVerifiedAt: &env.now,
+	}
+	assert.NoError(t, env.user.BeforeCreate(nil))
+	assert.NoError(t, env.users.Create(context.Background(), env.user))
+	assert.NoError(t, env.users.Create(context.Background(), &models.User{Email: "omar@example.com", Username: "omar"}))
+	env.user.Password = mustHash(t, "password123")
+
+	cfg := config.VerificationConfig{Mode: config.VerificationOptional, TokenTTL: time.Hour, ResendInterval: time.Minute}
+	env.verification = NewVerificationService(cfg, "test-secret", "https://app.example.com", env.users, env.notifier, zap.NewNop())
+	env.verification.now = func() time.Time { return env.now }
+	env.service = NewProfileService(env.users, &stubCountryRepository{}, env.verification, zap.NewNop())
+	return env
+}
+
+func mustHash(t *testing.T, password string) string {
+	hash, err := models.HashPassword(password)
+	assert.NoError(t, err)
+	return hash
+}
+
+func TestProfile_PatchChangesOnlyGivenFields(t *testing.T) {
+	env := newProfileTestEnv(t)
+	ctx := context.Background()
+	name, timezone, reminders := "Nadia", "Europe/Berlin", true
+
+	updated, err := env.service.Update(ctx, env.user, &PatchProfileRequest{
+		FirstName:     &name,
+		Timezone:      &timezone,
+		Notifications: &NotificationPreferencesPatch{TaskReminders: &reminders},
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "Nadia", updated.FirstName)
+	assert.Equal(t, "Newhire", updated.LastName)
+	assert.Equal(t, "Europe/Berlin", env.user.Timezone)
+	assert.True(t, env.user.Notifications.TaskReminders)
+	assert.False(t, env.user.Notifications.WeeklySummary)
+	if assert.NotNil(t, env.user.CountryID) {
+		assert.Equal(t, int64(7), *env.user.CountryID)
+	}
+	assert.Empty(t, env.notifier.messages)
+
+	// PUT replaces the profile, clearing what is left out
+	_, err = env.service.Update(ctx, env.user, (&UpdateProfileRequest{
+		Email: "nina@example.com", Username: "nina", FirstName: "Nina", LastName: "Newhire",
+	}).Patch())
+	assert.NoError(t, err)
+	assert.Nil(t, env.user.CountryID)
+	assert.Empty(t, env.user.Timezone)
+	assert.False(t, env.user.Notifications.TaskReminders)
+
+	missing := int64(99)
+	_, err = env.service.Update(ctx, env.user, &PatchProfileRequest{CountryID: &missing})
+	assert.ErrorIs(t, err, ErrCountryNotFound)
+}
+
+func TestProfile_ResponseShowsTheNewCountry(t *testing.T) {
+	env := newProfileTestEnv(t)
+	ctx := context.Background()
+	env.user.Country = &models.Country{ID: 7, Name: "Germany"}
+
+	next := int64(8)
+	updated, err := env.service.Update(ctx, env.user, &PatchProfileRequest{CountryID: &next})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	body := profileResponseBody(t, updated)
+	assert.Equal(t, float64(8), body["country_id"])
+	if country, ok := body["country"].(map[string]interface{}); assert.True(t, ok) {
+		assert.Equal(t, float64(8), country["id"])
+	}
+
+	// Clearing the country drops it from the response as well
+	updated, err = env.service.Update(ctx, updated, (&UpdateProfileRequest{
+		Email: "nina@example.com", Username: "nina", FirstName: "Nina", LastName: "Newhire",
+	}).Patch())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	body = profileResponseBody(t, updated)
+	assert.Nil(t, body["country_id"])
+	assert.NotContains(t, body, "country")
+}
+
+// profileResponseBody renders a user the way the profile endpoints return it
+func profileResponseBody(t *testing.T, user *models.User) map[string]interface{} {
+	raw, err := json.Marshal(user)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(raw, &body))
+	return body
+}
+
+func TestProfile_UsernameNeedsThePassword(t *testing.T) {
+	env := newProfileTestEnv(t)
+	ctx := context.Background()
+	taken, free := "omar", "nina2"
+
+	_, err := env.service.Update(ctx, env.user, &PatchProfileRequest{Username: &free})
+	assert.ErrorIs(t, err, ErrInvalidCurrentPassword)
+	_, err = env.service.Update(ctx, env.user, &PatchProfileRequest{Username: &taken, CurrentPassword: "password123"})
+	assert.ErrorIs(t, err, ErrUsernameTaken)
+	assert.Equal(t, "nina", env.user.Username)
+
+	_, err = env.service.Update(ctx, env.user, &PatchProfileRequest{Username: &free, CurrentPassword: "password123"})
+	assert.NoError(t, err)
+	assert.Equal(t, "nina2", env.user.Username)
+}
+
+func TestProfile_EmailChangesOnceVerified(t *testing.T) {
+	env := newProfileTestEnv(t)
+	ctx := context.Background()
+	taken, next := "omar@example.com", "nina@work.example.com"
+
+	_, err := env.service.Update(ctx, env.user, &PatchProfileRequest{Email: &next, CurrentPassword: "wrong"})
+	assert.ErrorIs(t, err, ErrInvalidCurrentPassword)
+	_, err = env.service.Update(ctx, env.user, &PatchProfileRequest{Email: &taken, CurrentPassword: "password123"})
+	assert.ErrorIs(t, err, ErrEmailTaken)
+
+	updated, err := env.service.Update(ctx, env.user, &PatchProfileRequest{Email: &next, CurrentPassword: "password123"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "nina@example.com", updated.Email, "the email only changes once verified")
+	assert.Equal(t, &next, updated.PendingEmail)
+	if !assert.Len(t, env.notifier.messages, 2) {
+		t.FailNow()
+	}
+	assert.Equal(t, next, env.notifier.messages[0].To)
+	assert.Equal(t, "nina@example.com", env.notifier.messages[1].To, "the current address is told")
+
+	// Another change right away is throttled
+	other := "nina@home.example.com"
+	_, err = env.service.Update(ctx, env.user, &PatchProfileRequest{Email: &other, CurrentPassword: "password123"})
+	assert.ErrorIs(t, err, ErrVerificationThrottled)
+
+	verified, err := env.verification.Verify(ctx, verificationLinkToken(t, env.notifier.messages[0]))
+	if assert.NoError(t, err) {
+		assert.Equal(t, next, verified.Email)
+		assert.Nil(t, verified.PendingEmail)
+		assert.True(t, verified.IsEmailVerified())
+	}
+
+	// Used again, the link only verifies the email it already set
+	_, err = env.verification.Verify(ctx, verificationLinkToken(t, env.notifier.messages[0]))
+	assert.NoError(t, err)
+	assert.Equal(t, next, env.user.Email)
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Refresh token errors
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// opaqueTokenBytes is the entropy of refresh and password reset tokens
+const opaqueTokenBytes = 32
+
+// RefreshRequest carries the refresh token to exchange or revoke
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// generateOpaqueToken returns a random URL-safe token
+func generateOpaqueToken() (string, error) {
+	raw := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// hashOpaqueToken returns the form an opaque token is stored and looked up in
+func hashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueRefreshToken creates a refresh token in the given family and returns
+// the plaintext, which is never stored
+func (s *Service) issueRefreshToken(ctx context.Context, repo interfaces.RefreshTokenInterface, userID int64, familyID string) (string, time.Time, error) {
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := s.now().Add(s.jwtConfig.RefreshExpiration)
+	err = repo.Create(ctx, &models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashOpaqueToken(token),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// newSession records a login from the device of ctx and issues its first
+// access and refresh tokens. The session ID is the refresh token family.
+func (s *Service) newSession(ctx context.Context, user *models.User) (*LoginResponse, error) {
+	now := s.now()
+	source := audit.FromContext(ctx)
+	session := &models.Session{
+		ID:         uuid.NewString(),
+		UserID:     user.ID,
+		UserAgent:  source.UserAgent,
+		IP:         source.IP,
+		ExpiresAt:  now.Add(s.jwtConfig.RefreshExpiration),
+		LastSeenAt: now,
+	}
+	if err := s.sessions.Create(ctx, session); err != nil {
+		return nil, err
+	}
+	return s.continueSession(ctx, s.refreshTokens, user, session.ID)
+}
+
+// continueSession issues an access token for the session familyID and a
+// refresh token in its family
+func (s *Service) continueSession(ctx context.Context, repo interfaces.RefreshTokenInterface, user *models.User, familyID string) (*LoginResponse, error) {
+	token, expiresAt, err := s.signAccessToken(user, s.jwtConfig.Expiration, jwt.MapClaims{"sid": familyID})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshExpiresAt, err := s.issueRefreshToken(ctx, repo, user.ID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		Token:            token,
+		Type:             "Bearer",
+		User:             user,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a new
+// refresh token. Presenting a token that was already rotated means it was
+// copied, so the whole family is revoked and the user has to log in again.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*LoginResponse, error) {
+	stored, err := s.refreshTokens.GetByHash(ctx, hashOpaqueToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if stored.RotatedAt != nil {
+		s.revokeReusedFamily(ctx, stored)
+		return nil, ErrRefreshTokenReused
+	}
+	if !stored.IsActive(s.now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if !user.IsActive {
+		return nil, ErrAccountDeactivated
+	}
+	if s.verification.Mode == config.VerificationLogin && !user.IsEmailVerified() {
+		return nil, ErrEmailNotVerified
+	}
+
+	var response *LoginResponse
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context, repos *interfaces.Repositories) error {
+		rotated, err := repos.RefreshTokens.MarkRotated(ctx, stored.ID, s.now())
+		if err != nil {
+			return err
+		}
+		// Lost a race with another refresh using the same token
+		if !rotated {
+			return ErrRefreshTokenReused
+		}
+
+		response, err = s.continueSession(ctx, repos.RefreshTokens, user, stored.FamilyID)
+		return err
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		s.revokeReusedFamily(ctx, stored)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The session lives on with its new refresh token
+	if err := s.sessions.Extend(ctx, stored.FamilyID, response.RefreshExpiresAt); err != nil {
+		s.logger.Error("Failed to extend session", zap.String("session_id", stored.FamilyID), zap.Error(err))
+	}
+	s.touchSession(ctx, stored.FamilyID)
+	return response, nil
+}
+
+// revokeReusedFamily ends the session the reused token belongs to,
+// including the access tokens issued to it
+func (s *Service) revokeReusedFamily(ctx context.Context, token *models.RefreshToken) {
+	s.logger.Warn("Refresh token reuse detected, revoking token family",
+		zap.Int64("user_id", token.UserID),
+		zap.String("family_id", token.FamilyID),
+	)
+	if err := s.endSession(ctx, token.UserID, token.FamilyID); err != nil {
+		s.logger.Error("Failed to revoke refresh token family", zap.String("family_id", token.FamilyID), zap.Error(err))
+	}
+}
+
+// Logout revokes the access token used for the request and the session
+// the refresh token belongs to. Unknown refresh tokens and those of other
+// users are ignored so logout is idempotent.
+func (s *Service) Logout(ctx context.Context, userID int64, accessToken, refreshToken string) error {
+	if err := s.RevokeAccessToken(ctx, accessToken); err != nil {
+		return err
+	}
+
+	stored, err := s.refreshTokens.GetByHash(ctx, hashOpaqueToken(refreshToken))
+	if err != nil || stored.UserID != userID {
+		return nil
+	}
+	return s.endSession(ctx, userID, stored.FamilyID)
+}
+
+// LogoutAll revokes every access, refresh and personal access token of the
+// user
+func (s *Service) LogoutAll(ctx context.Context, userID int64) error {
+	return s.RevokeAllTokens(ctx, userID)
+}
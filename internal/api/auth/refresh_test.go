@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// memoryRefreshTokens is an in-memory RefreshTokenInterface
+type memoryRefreshTokens struct {
+	tokens []*models.RefreshToken
+}
+
+func newMemoryRefreshTokens() *memoryRefreshTokens {
+	return &memoryRefreshTokens{}
+}
+
+func (m *memoryRefreshTokens) Create(ctx context.Context, token *models.RefreshToken) error {
+	token.ID = int64(len(m.tokens) + 1)
+	m.tokens = append(m.tokens, token)
+	return nil
+}
+
+func (m *memoryRefreshTokens) GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	for _, token := range m.tokens {
+		if token.TokenHash == hash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryRefreshTokens) MarkRotated(ctx context.Context, id int64, at time.Time) (bool, error) {
+	token := m.tokens[id-1]
+	if token.RotatedAt != nil || token.RevokedAt != nil {
+		return false, nil
+	}
+	token.RotatedAt = &at
+	return true, nil
+}
+
+func (m *memoryRefreshTokens) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	for _, token := range m.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (m *memoryRefreshTokens) RevokeAllForUser(ctx context.Context, userID int64, at time.Time) error {
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+// memorySessions is an in-memory SessionInterface
+type memorySessions struct {
+	sessions []*models.Session
+}
+
+func newMemorySessions() *memorySessions {
+	return &memorySessions{}
+}
+
+func (m *memorySessions) find(id string) *models.Session {
+	for _, session := range m.sessions {
+		if session.ID == id {
+			return session
+		}
+	}
+	return nil
+}
+
+func (m *memorySessions) Create(ctx context.Context, session *models.Session) error {
+	m.sessions = append(m.sessions, session)
+	return nil
+}
+
+func (m *memorySessions) GetByID(ctx context.Context, id string) (*models.Session, error) {
+	session := m.find(id)
+	if session == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (m *memorySessions) ListActive(ctx context.Context, userID int64, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	for i := len(m.sessions) - 1; i >= 0; i-- {
+		if session := m.sessions[i]; session.UserID == userID && session.IsActive(now) {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *memorySessions) Touch(ctx context.Context, id string, at time.Time, ip string) error {
+	if session := m.find(id); session != nil && session.RevokedAt == nil {
+		session.LastSeenAt = at
+		if ip != "" {
+			session.IP = ip
+		}
+	}
+	return nil
+}
+
+func (m *memorySessions) Extend(ctx context.Context, id string, expiresAt time.Time) error {
+	if session := m.find(id); session != nil && session.RevokedAt == nil {
+		session.ExpiresAt = expiresAt
+	}
+	return nil
+}
+
+func (m *memorySessions) Revoke(ctx context.Context, id string, at time.Time) error {
+	if session := m.find(id); session != nil && session.RevokedAt == nil {
+		session.RevokedAt = &at
+	}
+	return nil
+}
+
+func (m *memorySessions) RevokeAllForUser(ctx context.Context, userID int64, at time.Time) error {
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func newRefreshTestService(t *testing.T) (*Service, *MockUserRepository, *memoryRefreshTokens) {
+	mockRepo := new(MockUserRepository)
+	refreshTokens := newMemoryRefreshTokens()
+	service := newTestService(t, mockRepo, withRefreshTokens(refreshTokens))
+
+	user := &models.User{ID: 1, Email: "test@example.com", Role: models.RoleUser, IsActive: true}
+	mockRepo.On("GetByID", int64(1)).Return(user, nil)
+	return service, mockRepo, refreshTokens
+}
+
+func startSession(t *testing.T, service *Service) *LoginResponse {
+	response, err := service.newSession(context.Background(), &models.User{ID: 1, Role: models.RoleUser, IsActive: true})
+	assert.NoError(t, err)
+	return response
+}
+
+func TestRefresh_RotatesToken(t *testing.T) {
+	service, _, refreshTokens := newRefreshTestService(t)
+	session := startSession(t, service)
+
+	// Only the hash is stored
+	assert.NotEqual(t, session.RefreshToken, refreshTokens.tokens[0].TokenHash)
+
+	response, err := service.Refresh(context.Background(), session.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.Token)
+	assert.NotEqual(t, session.RefreshToken, response.RefreshToken)
+
+	if assert.Len(t, refreshTokens.tokens, 2) {
+		assert.NotNil(t, refreshTokens.tokens[0].RotatedAt)
+		assert.Equal(t, refreshTokens.tokens[0].FamilyID, refreshTokens.tokens[1].FamilyID)
+	}
+}
+
+func TestRefresh_ReuseRevokesFamily(t *testing.T) {
+	service, _, refreshTokens := newRefreshTestService(t)
+	session := startSession(t, service)
+	other := startSession(t, service)
+
+	rotated, err := service.Refresh(context.Background(), session.RefreshToken)
+	assert.NoError(t, err)
+
+	// Replaying the old token is treated as theft
+	_, err = service.Refresh(context.Background(), session.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	// The legitimate successor is revoked with it
+	_, err = service.Refresh(context.Background(), rotated.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// Sessions from other logins are untouched
+	_, err = service.Refresh(context.Background(), other.RefreshToken)
+	assert.NoError(t, err)
+	assert.Len(t, refreshTokens.tokens, 4)
+}
+
+func TestRefresh_RejectsUnknownAndExpired(t *testing.T) {
+	service, _, _ := newRefreshTestService(t)
+	session := startSession(t, service)
+
+	_, err := service.Refresh(context.Background(), "not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	later := time.Now().Add(2 * time.Hour)
+	service.now = func() time.Time { return later }
+	_, err = service.Refresh(context.Background(), session.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefresh_RejectsDeactivatedUser(t *testing.T) {
+	service, mockRepo, _ := newRefreshTestService(t)
+	session := startSession(t, service)
+
+	mockRepo.ExpectedCalls = nil
+	mockRepo.On("GetByID", int64(1)).Return(&models.User{ID: 1, IsActive: false}, nil)
+
+	_, err := service.Refresh(context.Background(), session.RefreshToken)
+	assert.ErrorIs(t, err, ErrAccountDeactivated)
+}
+
+func TestLogout_RevokesOnlyThatSession(t *testing.T) {
+	service, _, _ := newRefreshTestService(t)
+	session := startSession(t, service)
+	other := startSession(t, service)
+
+	// Another user's logout cannot touch this session
+	intruder, _, err := service.GenerateJWT(&models.User{ID: 2, Role: models.RoleUser})
+	assert.NoError(t, err)
+	assert.NoError(t, service.Logout(context.Background(), 2, intruder, session.RefreshToken))
+	_, err = service.Refresh(context.Background(), session.RefreshToken)
+	assert.NoError(t, err)
+
+	session = startSession(t, service)
+	assert.NoError(t, service.Logout(context.Background(), 1, session.Token, session.RefreshToken))
+
+	// The access token used to log out stops working at once
+	_, err = service.GetUserFromToken(context.Background(), session.Token)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	_, err = service.Refresh(context.Background(), session.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, err = service.Refresh(context.Background(), other.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestLogoutAll_RevokesEverySession(t *testing.T) {
+	service, _, _ := newRefreshTestService(t)
+	first := startSession(t, service)
+	second := startSession(t, service)
+
+	assert.NoError(t, service.LogoutAll(context.Background(), 1))
+
+	for _, session := range []*LoginResponse{first, second} {
+		_, err := service.Refresh(context.Background(), session.RefreshToken)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	}
+}
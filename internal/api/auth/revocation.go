@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"go.uber.org/zap"
+)
+
+// RevocationList answers whether an access token was revoked without a
+// database round trip per request. Revocations made here apply at once;
+// those made by other instances are picked up by the next Sync.
+type RevocationList struct {
+	repo   interfaces.RevokedTokenInterface
+	logger *zap.Logger
+
+	mu       sync.RWMutex
+	tokens   map[string]time.Time // jti -> expiry of the revocation
+	sessions map[string]time.Time // session ID -> expiry of the revocation
+	users    map[int64]userRevocation
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// userRevocation invalidates every token of a user issued up to before
+type userRevocation struct {
+	before    time.Time
+	expiresAt time.Time
+}
+
+// NewRevocationList creates an empty list backed by repo
+func NewRevocationList(repo interfaces.RevokedTokenInterface, logger *zap.Logger) *RevocationList {
+	return &RevocationList{
+		repo:     repo,
+		logger:   logger,
+		tokens:   make(map[string]time.Time),
+		sessions: make(map[string]time.Time),
+		users:    make(map[int64]userRevocation),
+		now:      time.Now,
+	}
+}
+
+// RevokeToken revokes a single access token until it would have expired
+func (l *RevocationList) RevokeToken(ctx context.Context, jti string, userID int64, expiresAt time.Time) error {
+	err := l.repo.Create(ctx, &models.RevokedToken{JTI: &jti, UserID: userID, ExpiresAt: expiresAt})
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.tokens[jti] = expiresAt
+	l.mu.Unlock()
+	return nil
+}
+
+// RevokeSession revokes every access token issued to a session. ttl is the
+// access token lifetime, after which the revocation has nothing left to
+// cover.
+func (l *RevocationList) RevokeSession(ctx context.Context, sessionID string, userID int64, ttl time.Duration) error {
+	expiresAt := l.now().Add(ttl)
+	err := l.repo.Create(ctx, &models.RevokedToken{SessionID: &sessionID, UserID: userID, ExpiresAt: expiresAt})
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.sessions[sessionID] = expiresAt
+	l.mu.Unlock()
+	return nil
+}
+
+// RevokeUser revokes every access token of the user issued so far. ttl is
+// the access token lifetime, after which the revocation has nothing left
+// to cover.
+func (l *RevocationList) RevokeUser(ctx context.Context, userID int64, ttl time.Duration) error {
+	now := l.now()
+	revocation := userRevocation{before: now, expiresAt: now.Add(ttl)}
+	err := l.repo.Create(ctx, &models.RevokedToken{UserID: userID, RevokedBefore: &revocation.before, ExpiresAt: revocation.expiresAt})
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.addUser(userID, revocation)
+	l.mu.Unlock()
+	return nil
+}
+
+// addUser keeps the latest cutoff per user; callers hold mu
+func (l *RevocationList) addUser(userID int64, revocation userRevocation) {
+	if current, ok := l.users[userID]; ok && current.before.After(revocation.before) {
+		return
+	}
+	l.users[userID] = revocation
+}
+
+// IsRevoked reports whether the token identified by jti, issued to userID at
+// issuedAt, has been revoked. Token timestamps have second precision, so a
+// token issued in the same second as a user revocation counts as revoked.
+func (l *RevocationList) IsRevoked(jti string, userID int64, issuedAt time.Time) bool {
+	now := l.now()
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if expiresAt, ok := l.tokens[jti]; ok && now.Before(expiresAt) {
+		return true
+	}
+	if revocation, ok := l.users[userID]; ok && now.Before(revocation.expiresAt) {
+		return issuedAt.Unix() <= revocation.before.Unix()
+	}
+	return false
+}
+
+// IsSessionRevoked reports whether the access tokens of the session have
+// been revoked
+func (l *RevocationList) IsSessionRevoked(sessionID string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	expiresAt, ok := l.sessions[sessionID]
+	return ok && l.now().Before(expiresAt)
+}
+
+// Sync merges the active revocations from the database into the cached
+// ones so revocations made by other instances take effect. Cached entries
+// are only dropped once expired, so a revocation made here while the
+// database is read is not lost.
+func (l *RevocationList) Sync(ctx context.Context) error {
+	now := l.now()
+	rows, err := l.repo.ListActive(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for jti, expiresAt := range l.tokens {
+		if !now.Before(expiresAt) {
+			delete(l.tokens, jti)
+		}
+	}
+	for sessionID, expiresAt := range l.sessions {
+		if !now.Before(expiresAt) {
+			delete(l.sessions, sessionID)
+		}
+	}
+	for userID, revocation := range l.users {
+		if !now.Before(revocation.expiresAt) {
+			delete(l.users, userID)
+		}
+	}
+
+	for _, row := range rows {
+		switch {
+		case row.JTI != nil:
+			l.tokens[*row.JTI] = laterOf(l.tokens[*row.JTI], row.ExpiresAt)
+		case row.SessionID != nil:
+			l.sessions[*row.SessionID] = laterOf(l.sessions[*row.SessionID], row.ExpiresAt)
+		case row.RevokedBefore != nil:
+			l.addUser(row.UserID, userRevocation{before: *row.RevokedBefore, expiresAt: row.ExpiresAt})
+		}
+	}
+	return nil
+}
+
+// laterOf returns the later of two times
+func laterOf(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// Cleanup deletes revocations whose tokens have expired anyway
+func (l *RevocationList) Cleanup(ctx context.Context) error {
+	deleted, err := l.repo.DeleteExpired(ctx, l.now())
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		l.logger.Debug("Deleted expired token revocations", zap.Int64("count", deleted))
+	}
+	return nil
+}
+
+// Start cleans up and syncs every interval until the returned stop func is
+// called
+func (l *RevocationList) Start(interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := l.Cleanup(ctx); err != nil {
+					l.logger.Error("Failed to clean up token revocations", zap.Error(err))
+				}
+				if err := l.Sync(ctx); err != nil {
+					l.logger.Error("Failed to sync token revocations", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memoryRevokedTokens is an in-memory RevokedTokenInterface shared by the
+// lists in a test, standing in for the database
+type memoryRevokedTokens struct {
+	rows []models.RevokedToken
+}
+
+func (m *memoryRevokedTokens) Create(ctx context.Context, token *models.RevokedToken) error {
+	token.ID = int64(len(m.rows) + 1)
+	m.rows = append(m.rows, *token)
+	return nil
+}
+
+func (m *memoryRevokedTokens) ListActive(ctx context.Context, now time.Time) ([]models.RevokedToken, error) {
+	var active []models.RevokedToken
+	for _, row := range m.rows {
+		if row.ExpiresAt.After(now) {
+			active = append(active, row)
+		}
+	}
+	return active, nil
+}
+
+func (m *memoryRevokedTokens) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	rows, err := m.ListActive(ctx, now)
+	deleted := int64(len(m.rows) - len(rows))
+	m.rows = rows
+	return deleted, err
+}
+
+func newTestRevocations() *RevocationList {
+	return NewRevocationList(&memoryRevokedTokens{}, zap.NewNop())
+}
+
+func TestRevocationList_RevokeToken(t *testing.T) {
+	list := newTestRevocations()
+	issuedAt := time.Now().Add(-time.Minute)
+
+	assert.NoError(t, list.RevokeToken(context.Background(), "jti-1", 1, time.Now().Add(time.Minute)))
+
+	assert.True(t, list.IsRevoked("jti-1", 1, issuedAt))
+	assert.False(t, list.IsRevoked("jti-2", 1, issuedAt))
+}
+
+func TestRevocationList_RevokeUserOnlyCoversEarlierTokens(t *testing.T) {
+	list := newTestRevocations()
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	list.now = func() time.Time { return now }
+
+	assert.NoError(t, list.RevokeUser(context.Background(), 1, 15*time.Minute))
+
+	assert.True(t, list.IsRevoked("a", 1, now.Add(-time.Minute)))
+	assert.True(t, list.IsRevoked("b", 1, now))
+	assert.False(t, list.IsRevoked("c", 1, now.Add(time.Second)))
+	assert.False(t, list.IsRevoked("d", 2, now.Add(-time.Minute)))
+
+	// Once every covered token has expired the revocation lapses
+	now = now.Add(16 * time.Minute)
+	assert.False(t, list.IsRevoked("a", 1, now.Add(-20*time.Minute)))
+}
+
+func TestRevocationList_SyncSharesRevocationsAcrossInstances(t *testing.T) {
+	db := &memoryRevokedTokens{}
+	first := NewRevocationList(db, zap.NewNop())
+	second := NewRevocationList(db, zap.NewNop())
+	issuedAt := time.Now().Add(-time.Minute)
+
+	assert.NoError(t, first.RevokeToken(context.Background(), "jti-1", 1, time.Now().Add(time.Minute)))
+	assert.NoError(t, first.RevokeUser(context.Background(), 2, time.Minute))
+	assert.False(t, second.IsRevoked("jti-1", 1, issuedAt))
+
+	assert.NoError(t, second.Sync(context.Background()))
+	assert.True(t, second.IsRevoked("jti-1", 1, issuedAt))
+	assert.True(t, second.IsRevoked("other", 2, issuedAt))
+}
+
+// revokeDuringList runs revoke after ListActive has read the rows, the way
+// a request on another goroutine may revoke while a sync is in flight
+type revokeDuringList struct {
+	*memoryRevokedTokens
+	revoke func()
+}
+
+func (r *revokeDuringList) ListActive(ctx context.Context, now time.Time) ([]models.RevokedToken, error) {
+	rows, err := r.memoryRevokedTokens.ListActive(ctx, now)
+	if r.revoke != nil {
+		revoke := r.revoke
+		r.revoke = nil
+		revoke()
+	}
+	return rows, err
+}
+
+func TestRevocationList_SyncKeepsConcurrentRevocations(t *testing.T) {
+	db := &revokeDuringList{memoryRevokedTokens: &memoryRevokedTokens{}}
+	list := NewRevocationList(db, zap.NewNop())
+	ctx := context.Background()
+	issuedAt := time.Now().Add(-time.Minute)
+
+	assert.NoError(t, list.RevokeToken(ctx, "expired", 1, time.Now().Add(-time.Second)))
+	db.revoke = func() {
+		assert.NoError(t, list.RevokeToken(ctx, "jti-1", 1, time.Now().Add(time.Minute)))
+		assert.NoError(t, list.RevokeSession(ctx, "session-1", 1, time.Minute))
+		assert.NoError(t, list.RevokeUser(ctx, 2, time.Minute))
+	}
+
+	assert.NoError(t, list.Sync(ctx))
+	assert.True(t, list.IsRevoked("jti-1", 1, issuedAt))
+	assert.True(t, list.IsSessionRevoked("session-1"))
+	assert.True(t, list.IsRevoked("other", 2, issuedAt))
+	// Expired revocations are pruned
+	assert.NotContains(t, list.tokens, "expired")
+}
+
+func TestRevocationList_CleanupDeletesExpired(t *testing.T) {
+	db := &memoryRevokedTokens{}
+	list := NewRevocationList(db, zap.NewNop())
+
+	assert.NoError(t, list.RevokeToken(context.Background(), "old", 1, time.Now().Add(-time.Second)))
+	assert.NoError(t, list.RevokeToken(context.Background(), "new", 1, time.Now().Add(time.Minute)))
+
+	assert.NoError(t, list.Cleanup(context.Background()))
+	if assert.Len(t, db.rows, 1) {
+		assert.Equal(t, "new", *db.rows[0].JTI)
+	}
+}
+
+func TestAuthService_RevokedTokensAreRejected(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newTestService(t, mockRepo, withJWT(config.JWTConfig{Secret: "test-secret", Expiration: time.Hour}))
+	user := &models.User{ID: 1, Role: models.RoleUser, IsActive: true}
+	mockRepo.On("GetByID", int64(1)).Return(user, nil)
+
+	first, _, err := service.GenerateJWT(user)
+	assert.NoError(t, err)
+	second, _, err := service.GenerateJWT(user)
+	assert.NoError(t, err)
+
+	// Every token carries its own jti
+	firstClaims, err := service.ValidateJWT(first)
+	assert.NoError(t, err)
+	secondClaims, err := service.ValidateJWT(second)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, firstClaims["jti"])
+	assert.NotEqual(t, firstClaims["jti"], secondClaims["jti"])
+
+	assert.NoError(t, service.RevokeAccessToken(context.Background(), first))
+	_, err = service.GetUserFromToken(context.Background(), first)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	_, err = service.GetUserFromToken(context.Background(), second)
+	assert.NoError(t, err)
+
+	assert.NoError(t, service.RevokeAllTokens(context.Background(), 1))
+	_, err = service.GetUserFromToken(context.Background(), second)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+}
+
+func TestRevocationList_RevokeSessionSyncs(t *testing.T) {
+	db := &memoryRevokedTokens{}
+	first := NewRevocationList(db, zap.NewNop())
+	second := NewRevocationList(db, zap.NewNop())
+
+	assert.NoError(t, first.RevokeSession(context.Background(), "session-1", 1, time.Minute))
+	assert.True(t, first.IsSessionRevoked("session-1"))
+	assert.False(t, first.IsSessionRevoked("session-2"))
+	// Other tokens of the user are unaffected
+	assert.False(t, first.IsRevoked("jti-1", 1, time.Now().Add(-time.Minute)))
+
+	assert.False(t, second.IsSessionRevoked("session-1"))
+	assert.NoError(t, second.Sync(context.Background()))
+	assert.True(t, second.IsSessionRevoked("session-1"))
+}
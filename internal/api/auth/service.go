@@ -1,75 +1,177 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"time"
 
 	"github.com/alxand/nalo-workspace/internal/config"
 	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
 	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/metrics"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
 // Service handles authentication operations
 type Service struct {
-	jwtConfig config.JWTConfig
-	userRepo  interfaces.UserInterface
-	logger    *zap.Logger
+	jwtConfig     config.JWTConfig
+	loginConfig   config.LoginConfig
+	verification  config.VerificationConfig
+	userRepo      interfaces.UserInterface
+	refreshTokens interfaces.RefreshTokenInterface
+	sessions      interfaces.SessionInterface
+	accessTokens  interfaces.PersonalAccessTokenInterface
+	revocations   *RevocationList
+	keys          *KeyRing
+	txManager     interfaces.TransactionManager
+	logger        *zap.Logger
+	seen          *sessionSeen
+
+	// now and sleep are replaced in tests
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration)
 }
 
 // NewService creates a new auth service
-func NewService(jwtConfig config.JWTConfig, userRepo interfaces.UserInterface, logger *zap.Logger) *Service {
+func NewService(jwtConfig config.JWTConfig, loginConfig config.LoginConfig, verification config.VerificationConfig, userRepo interfaces.UserInterface, refreshTokens interfaces.RefreshTokenInterface, sessions interfaces.SessionInterface, accessTokens interfaces.PersonalAccessTokenInterface, revocations *RevocationList, keys *KeyRing, txManager interfaces.TransactionManager, logger *zap.Logger) *Service {
 	return &Service{
-		jwtConfig: jwtConfig,
-		userRepo:  userRepo,
-		logger:    logger,
+		jwtConfig:     jwtConfig,
+		loginConfig:   loginConfig,
+		verification:  verification,
+		userRepo:      userRepo,
+		refreshTokens: refreshTokens,
+		sessions:      sessions,
+		accessTokens:  accessTokens,
+		revocations:   revocations,
+		keys:          keys,
+		txManager:     txManager,
+		logger:        logger,
+		seen:          newSessionSeen(),
+		now:           time.Now,
+		sleep:         sleepContext,
 	}
 }
 
+// Errors returned by the auth service that callers map to client responses
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountDeactivated = errors.New("account is deactivated")
+	ErrEmailTaken         = errors.New("email already exists")
+	ErrUsernameTaken      = errors.New("username already exists")
+	ErrTokenExpired       = errors.New("token has expired")
+	ErrTokenRevoked       = errors.New("token has been revoked")
+)
+
+// AccountLockedError is returned while an account is locked after too many
+// failed logins
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string {
+	return "account is temporarily locked"
+}
+
 // LoginRequest represents login credentials
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
 }
 
-// RegisterRequest represents user registration data
+// RegisterRequest represents user registration data. Self-registered users
+// always get the user role; other roles are given by invitation.
 type RegisterRequest struct {
-	Email     string          `json:"email" validate:"required,email"`
-	Username  string          `json:"username" validate:"required,min=3,max=50"`
-	Password  string          `json:"password" validate:"required,min=8"`
-	FirstName string          `json:"first_name" validate:"required,min=2,max=50"`
-	LastName  string          `json:"last_name" validate:"required,min=2,max=50"`
-	Role      models.UserRole `json:"role" validate:"required,oneof=admin user manager"`
+	Email     string `json:"email" validate:"required,email"`
+	Username  string `json:"username" validate:"required,min=3,max=50"`
+	Password  string `json:"password" validate:"required,min=8"`
+	FirstName string `json:"first_name" validate:"required,min=2,max=50"`
+	LastName  string `json:"last_name" validate:"required,min=2,max=50"`
+
+	// Company is optional and created together with the user
+	Company *RegisterCompanyRequest `json:"company,omitempty"`
+}
+
+// RegisterCompanyRequest represents a company created during registration
+type RegisterCompanyRequest struct {
+	Name      string `json:"name" validate:"required"`
+	Code      string `json:"code"`
+	CountryID int64  `json:"country_id" validate:"required"`
+	Industry  string `json:"industry"`
+	Size      string `json:"size" validate:"omitempty,oneof=small medium large enterprise"`
+	Website   string `json:"website"`
+}
+
+// MFAChallenge is returned by Login instead of a session when the user has
+// two-factor authentication enabled. The token is exchanged for a session
+// together with a TOTP or recovery code.
+type MFAChallenge struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func (e *MFAChallenge) Error() string {
+	return "two-factor authentication required"
 }
 
 // LoginResponse represents the response after successful login
 type LoginResponse struct {
-	Token     string       `json:"token"`
-	Type      string       `json:"type"`
-	User      *models.User `json:"user"`
-	ExpiresAt time.Time    `json:"expires_at"`
+	Token            string       `json:"token"`
+	Type             string       `json:"type"`
+	User             *models.User `json:"user"`
+	ExpiresAt        time.Time    `json:"expires_at"`
+	RefreshToken     string       `json:"refresh_token"`
+	RefreshExpiresAt time.Time    `json:"refresh_expires_at"`
 }
 
+// timingPasswordHash is checked against the password of logins with an
+// unknown email so they cost a bcrypt comparison like any other
+const timingPasswordHash = "$2a$10$RIYRvhuXTugTQ/8RRLvHqOBJZVCsnBr0LYLVdh4bHCWmYSwBYch9O"
+
 // Authenticate validates user credentials and returns user if valid
-func (s *Service) Authenticate(email, password string) (*models.User, error) {
-	user, err := s.userRepo.GetByEmail(email)
+func (s *Service) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
 		s.logger.Error("Failed to get user by email", zap.String("email", email), zap.Error(err))
-		return nil, errors.New("invalid credentials")
+		// Take as long as a first wrong password, so response times do not
+		// tell which emails have accounts
+		(&models.User{Password: timingPasswordHash}).CheckPassword(password)
+		s.sleep(ctx, loginDelay(s.loginConfig, 1))
+		return nil, ErrInvalidCredentials
+	}
+
+	// Checked before the password so a locked account gives nothing away
+	if user.IsLocked(s.now()) {
+		return nil, &AccountLockedError{Until: *user.LockedUntil}
 	}
 
 	if !user.IsActive {
-		return nil, errors.New("account is deactivated")
+		return nil, ErrAccountDeactivated
 	}
 
 	if !user.CheckPassword(password) {
 		s.logger.Error("Invalid password for user", zap.String("email", email))
-		return nil, errors.New("invalid credentials")
+		s.recordFailedLogin(ctx, user)
+		return nil, ErrInvalidCredentials
+	}
+
+	// With two-factor authentication the count is only reset once a code
+	// is accepted, so new challenges do not restart it
+	if !user.MFAEnabled && (user.FailedLoginAttempts > 0 || user.LockedUntil != nil) {
+		if err := s.userRepo.ResetFailedLogins(ctx, user.ID); err != nil {
+			s.logger.Error("Failed to reset failed logins", zap.Int64("user_id", user.ID), zap.Error(err))
+		}
+	}
+
+	// Checked after the password so it does not reveal unverified accounts
+	if s.verification.Mode == config.VerificationLogin && !user.IsEmailVerified() {
+		return nil, ErrEmailNotVerified
 	}
 
 	// Update last login
-	if err := s.userRepo.UpdateLastLogin(user.ID); err != nil {
+	if err := s.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
 		s.logger.Error("Failed to update last login", zap.Int64("user_id", user.ID), zap.Error(err))
 		// Don't return error, just log it
 	}
@@ -77,39 +179,116 @@ func (s *Service) Authenticate(email, password string) (*models.User, error) {
 	return user, nil
 }
 
-// Register creates a new user account
-func (s *Service) Register(req *RegisterRequest) (*models.User, error) {
-	// Check if email already exists
-	exists, err := s.userRepo.ExistsByEmail(req.Email)
+// recordFailedLogin counts a wrong password, locks the account once
+// MaxAttempts is reached and slows the response down progressively
+func (s *Service) recordFailedLogin(ctx context.Context, user *models.User) {
+	attempts, err := s.userRepo.RecordFailedLogin(ctx, user.ID, s.now())
 	if err != nil {
-		return nil, err
+		s.logger.Error("Failed to record failed login", zap.Int64("user_id", user.ID), zap.Error(err))
+		return
 	}
-	if exists {
-		return nil, errors.New("email already exists")
+
+	if s.loginConfig.MaxAttempts > 0 && attempts >= s.loginConfig.MaxAttempts {
+		until := s.now().Add(s.loginConfig.LockoutDuration)
+		if err := s.userRepo.LockUntil(ctx, user.ID, until); err != nil {
+			s.logger.Error("Failed to lock account", zap.Int64("user_id", user.ID), zap.Error(err))
+		} else {
+			s.logger.Warn("Account locked after repeated failed logins",
+				zap.Int64("user_id", user.ID),
+				zap.Int("attempts", attempts),
+				zap.Time("locked_until", until),
+			)
+		}
 	}
 
-	// Check if username already exists
-	exists, err = s.userRepo.ExistsByUsername(req.Username)
-	if err != nil {
-		return nil, err
+	s.sleep(ctx, loginDelay(s.loginConfig, attempts))
+}
+
+// loginDelay doubles DelayBase for every failure after the first, capped
+// at DelayMax
+func loginDelay(cfg config.LoginConfig, attempts int) time.Duration {
+	if cfg.DelayBase <= 0 || attempts < 1 {
+		return 0
+	}
+	delay := cfg.DelayBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if cfg.DelayMax > 0 && delay >= cfg.DelayMax {
+			return cfg.DelayMax
+		}
+	}
+	return delay
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
 	}
-	if exists {
-		return nil, errors.New("username already exists")
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
 	}
+}
 
-	// Create new user
+// Register creates a new user account, and its company when one is given,
+// in a single transaction
+func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*models.User, error) {
 	user := &models.User{
 		Email:     req.Email,
 		Username:  req.Username,
 		Password:  req.Password, // Will be hashed by GORM hook
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
-		Role:      req.Role,
+		Role:      models.RoleUser,
 		IsActive:  true,
 	}
 
-	if err := s.userRepo.Create(user); err != nil {
-		s.logger.Error("Failed to create user", zap.String("email", req.Email), zap.Error(err))
+	err := s.txManager.WithinTransaction(ctx, func(ctx context.Context, repos *interfaces.Repositories) error {
+		// Check if email already exists
+		exists, err := repos.Users.ExistsByEmail(ctx, req.Email)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrEmailTaken
+		}
+
+		// Check if username already exists
+		exists, err = repos.Users.ExistsByUsername(ctx, req.Username)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrUsernameTaken
+		}
+
+		if req.Company != nil {
+			company := &models.Company{
+				Name:      req.Company.Name,
+				Code:      req.Company.Code,
+				CountryID: req.Company.CountryID,
+				Industry:  req.Company.Industry,
+				Size:      req.Company.Size,
+				Website:   req.Company.Website,
+			}
+			if err := repos.Companies.Create(ctx, company); err != nil {
+				s.logger.Error("Failed to create company for user", zap.String("email", req.Email), zap.Error(err))
+				return err
+			}
+			user.CompanyID = &company.ID
+			user.CountryID = &company.CountryID
+		}
+
+		if err := repos.Users.Create(ctx, user); err != nil {
+			s.logger.Error("Failed to create user", zap.String("email", req.Email), zap.Error(err))
+			return err
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -117,31 +296,86 @@ func (s *Service) Register(req *RegisterRequest) (*models.User, error) {
 	return user, nil
 }
 
-// Login authenticates user and returns JWT token
-func (s *Service) Login(req *LoginRequest) (*LoginResponse, error) {
-	user, err := s.Authenticate(req.Email, req.Password)
+// Login authenticates user and returns an access token with a refresh
+// token starting a new session
+func (s *Service) Login(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
+	user, err := s.Authenticate(ctx, req.Email, req.Password)
+	if err != nil {
+		metrics.LoginFailed()
+		return nil, err
+	}
+
+	if user.MFAEnabled {
+		token, expiresAt, err := s.issueMFAChallenge(user)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &MFAChallenge{MFARequired: true, MFAToken: token, ExpiresAt: expiresAt}
+	}
+
+	response, err := s.newSession(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 
-	token, expiresAt, err := s.GenerateJWT(user)
+	metrics.LoginSucceeded()
+	return response, nil
+}
+
+// StartSession logs in a user that was authenticated elsewhere, such as by
+// an identity provider. Two-factor authentication still applies.
+func (s *Service) StartSession(ctx context.Context, user *models.User) (*LoginResponse, error) {
+	if !user.IsActive {
+		metrics.LoginFailed()
+		return nil, ErrAccountDeactivated
+	}
+
+	if err := s.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
+		s.logger.Error("Failed to update last login", zap.Int64("user_id", user.ID), zap.Error(err))
+	}
+
+	if user.MFAEnabled {
+		token, expiresAt, err := s.issueMFAChallenge(user)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &MFAChallenge{MFARequired: true, MFAToken: token, ExpiresAt: expiresAt}
+	}
+
+	response, err := s.newSession(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 
-	return &LoginResponse{
-		Token:     token,
-		Type:      "Bearer",
-		User:      user,
-		ExpiresAt: expiresAt,
-	}, nil
+	metrics.LoginSucceeded()
+	return response, nil
 }
 
 // GenerateJWT generates a new JWT token for a user
 func (s *Service) GenerateJWT(user *models.User) (string, time.Time, error) {
-	expiresAt := time.Now().Add(s.jwtConfig.Expiration)
+	return s.signAccessToken(user, s.jwtConfig.Expiration, nil)
+}
+
+// GenerateImpersonationJWT generates a token acting as user on behalf of
+// actor. The act claim names the actor; the token cannot be refreshed and
+// never outlives a regular access token.
+func (s *Service) GenerateImpersonationJWT(user, actor *models.User) (string, time.Time, error) {
+	ttl := s.jwtConfig.ImpersonationExpiration
+	if ttl <= 0 || ttl > s.jwtConfig.Expiration {
+		ttl = s.jwtConfig.Expiration
+	}
+	return s.signAccessToken(user, ttl, jwt.MapClaims{
+		"act": map[string]interface{}{"user_id": actor.ID, "email": actor.Email},
+	})
+}
+
+// signAccessToken signs an access token for user valid for ttl, with extra
+// claims added
+func (s *Service) signAccessToken(user *models.User, ttl time.Duration, extra jwt.MapClaims) (string, time.Time, error) {
+	expiresAt := time.Now().Add(ttl)
 
 	claims := jwt.MapClaims{
+		"jti":      uuid.NewString(),
 		"user_id":  user.ID,
 		"email":    user.Email,
 		"username": user.Username,
@@ -149,11 +383,13 @@ func (s *Service) GenerateJWT(user *models.User) (string, time.Time, error) {
 		"exp":      expiresAt.Unix(),
 		"iat":      time.Now().Unix(),
 		"iss":      "nalo-workspace",
-		"aud":      "nalo-workspace-users",
+		"aud":      accessAudience,
+	}
+	for key, value := range extra {
+		claims[key] = value
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.jwtConfig.Secret))
+	tokenString, err := s.keys.Sign(claims)
 	if err != nil {
 		return "", time.Time{}, err
 	}
@@ -161,21 +397,25 @@ func (s *Service) GenerateJWT(user *models.User) (string, time.Time, error) {
 	return tokenString, expiresAt, nil
 }
 
-// ValidateJWT validates a JWT token and returns the claims
+// ValidateJWT validates an access token and returns the claims
 func (s *Service) ValidateJWT(tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(s.jwtConfig.Secret), nil
-	})
+	return s.parseJWT(tokenString, accessAudience)
+}
+
+// parseJWT validates a token signed by this service for the given audience,
+// so MFA challenge tokens are never accepted as access tokens
+func (s *Service) parseJWT(tokenString, audience string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, s.keys.Keyfunc)
 
 	if err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorExpired != 0 {
+			return nil, ErrTokenExpired
+		}
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid && claims.VerifyAudience(audience, true) {
 		return claims, nil
 	}
 
@@ -198,26 +438,121 @@ func (s *Service) ExtractUserRole(claims jwt.MapClaims) (models.UserRole, error)
 	return "", errors.New("role not found in token claims")
 }
 
+// ExtractActorID extracts the ID of the user acting through an
+// impersonation token from its act claim, or 0 for regular tokens
+func (s *Service) ExtractActorID(claims jwt.MapClaims) int64 {
+	act, ok := claims["act"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	userID, _ := act["user_id"].(float64)
+	return int64(userID)
+}
+
+// ExtractSessionID extracts the session an access token was issued to from
+// its sid claim, or "" for impersonation tokens
+func (s *Service) ExtractSessionID(claims jwt.MapClaims) string {
+	sessionID, _ := claims["sid"].(string)
+	return sessionID
+}
+
+// claimTime reads a NumericDate claim such as iat or exp
+func claimTime(claims jwt.MapClaims, key string) time.Time {
+	if value, ok := claims[key].(float64); ok {
+		return time.Unix(int64(value), 0)
+	}
+	return time.Time{}
+}
+
 // GetUserFromToken validates token and returns the user
-func (s *Service) GetUserFromToken(tokenString string) (*models.User, error) {
+func (s *Service) GetUserFromToken(ctx context.Context, tokenString string) (*models.User, error) {
+	user, _, err := s.AuthenticateToken(ctx, tokenString)
+	return user, err
+}
+
+// AuthenticateToken validates token and returns the user, and for an
+// impersonation token the actor acting as them. Revoking the tokens of
+// either user, or deactivating either, ends the impersonation.
+func (s *Service) AuthenticateToken(ctx context.Context, tokenString string) (user, actor *models.User, err error) {
 	claims, err := s.ValidateJWT(tokenString)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	userID, err := s.ExtractUserID(claims)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	user, err := s.userRepo.GetByID(userID)
+	jti, _ := claims["jti"].(string)
+	issuedAt := claimTime(claims, "iat")
+	if s.revocations.IsRevoked(jti, userID, issuedAt) {
+		return nil, nil, ErrTokenRevoked
+	}
+	sessionID := s.ExtractSessionID(claims)
+	if sessionID != "" && s.revocations.IsSessionRevoked(sessionID) {
+		return nil, nil, ErrTokenRevoked
+	}
+
+	user, err = s.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if !user.IsActive {
-		return nil, errors.New("user account is deactivated")
+		return nil, nil, ErrAccountDeactivated
 	}
 
-	return user, nil
+	if actorID := s.ExtractActorID(claims); actorID != 0 {
+		if s.revocations.IsRevoked("", actorID, issuedAt) {
+			return nil, nil, ErrTokenRevoked
+		}
+		actor, err = s.userRepo.GetByID(ctx, actorID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !actor.IsActive {
+			return nil, nil, ErrAccountDeactivated
+		}
+	}
+
+	if sessionID != "" {
+		s.touchSession(ctx, sessionID)
+	}
+	return user, actor, nil
+}
+
+// RevokeAccessToken revokes a single access token for the rest of its
+// lifetime
+func (s *Service) RevokeAccessToken(ctx context.Context, tokenString string) error {
+	claims, err := s.ValidateJWT(tokenString)
+	if err != nil {
+		return err
+	}
+
+	// Tokens issued before jti was introduced cannot be revoked one by one
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return nil
+	}
+	userID, err := s.ExtractUserID(claims)
+	if err != nil {
+		return err
+	}
+	return s.revocations.RevokeToken(ctx, jti, userID, claimTime(claims, "exp"))
+}
+
+// RevokeAllTokens ends every session of the user: all access tokens issued
+// so far, all refresh tokens and all personal access tokens
+func (s *Service) RevokeAllTokens(ctx context.Context, userID int64) error {
+	if err := s.revocations.RevokeUser(ctx, userID, s.jwtConfig.Expiration); err != nil {
+		return err
+	}
+	if err := s.refreshTokens.RevokeAllForUser(ctx, userID, s.now()); err != nil {
+		return err
+	}
+	if err := s.accessTokens.RevokeAllForUser(ctx, userID, s.now()); err != nil {
+		return err
+	}
+	return s.sessions.RevokeAllForUser(ctx, userID, s.now())
 }
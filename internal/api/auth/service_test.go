@@ -1,14 +1,17 @@
 package auth
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
 	"github.com/alxand/nalo-workspace/internal/domain/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // MockUserRepository is a mock implementation of UserInterface
@@ -16,75 +19,224 @@ type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) Create(user *models.User) error {
+func (m *MockUserRepository) Create(ctx context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) GetByID(id int64) (*models.User, error) {
+func (m *MockUserRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
 	args := m.Called(id)
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetByEmail(email string) (*models.User, error) {
+func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	args := m.Called(email)
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetByUsername(username string) (*models.User, error) {
+func (m *MockUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	args := m.Called(username)
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetByCountry(countryID int64) ([]models.User, error) {
+func (m *MockUserRepository) GetByCountry(ctx context.Context, countryID int64) ([]models.User, error) {
 	args := m.Called(countryID)
 	return args.Get(0).([]models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetByCompany(companyID int64) ([]models.User, error) {
+func (m *MockUserRepository) GetByCompany(ctx context.Context, companyID int64) ([]models.User, error) {
 	args := m.Called(companyID)
 	return args.Get(0).([]models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) Update(user *models.User) error {
+func (m *MockUserRepository) Update(ctx context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) Delete(id int64) error {
+func (m *MockUserRepository) Delete(ctx context.Context, id int64) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) List(limit, offset int) ([]models.User, error) {
+func (m *MockUserRepository) List(ctx context.Context, limit, offset int) ([]models.User, error) {
 	args := m.Called(limit, offset)
 	return args.Get(0).([]models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) UpdateLastLogin(id int64) error {
+func (m *MockUserRepository) UpdateLastLogin(ctx context.Context, id int64) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) ExistsByEmail(email string) (bool, error) {
+func (m *MockUserRepository) RecordFailedLogin(ctx context.Context, id int64, now time.Time) (int, error) {
+	args := m.Called(id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) LockUntil(ctx context.Context, id int64, until time.Time) error {
+	args := m.Called(id, until)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ResetFailedLogins(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, id int64, hash string) error {
+	args := m.Called(id, hash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, id int64, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) MarkVerificationSent(ctx context.Context, id int64, at, notBefore time.Time) (bool, error) {
+	args := m.Called(id, at, notBefore)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) UpdateMFA(ctx context.Context, id int64, enabled bool, secret string) error {
+	args := m.Called(id, enabled, secret)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) RecordMFAStep(ctx context.Context, id int64, step int64) (bool, error) {
+	args := m.Called(id, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) UpdateRole(ctx context.Context, id int64, role models.UserRole) error {
+	args := m.Called(id, role)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateProfile(ctx context.Context, user *models.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) RequestEmailChange(ctx context.Context, id int64, email string) error {
+	args := m.Called(id, email)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ConfirmEmailChange(ctx context.Context, id int64, email string, at time.Time) (bool, error) {
+	args := m.Called(id, email, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	args := m.Called(email)
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockUserRepository) ExistsByUsername(username string) (bool, error) {
+func (m *MockUserRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
 	args := m.Called(username)
 	return args.Bool(0), args.Error(1)
 }
 
+// fakeTxManager runs the unit of work directly against the given repositories
+type fakeTxManager struct {
+	repos *interfaces.Repositories
+}
+
+func (f *fakeTxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context, repos *interfaces.Repositories) error) error {
+	return fn(ctx, f.repos)
+}
+
+// testServiceDeps are what newTestService builds a Service from
+type testServiceDeps struct {
+	jwt           config.JWTConfig
+	login         config.LoginConfig
+	verification  config.VerificationConfig
+	refreshTokens interfaces.RefreshTokenInterface
+	sessions      interfaces.SessionInterface
+	accessTokens  interfaces.PersonalAccessTokenInterface
+	repos         interfaces.Repositories
+}
+
+type testServiceOption func(*testServiceDeps)
+
+func withJWT(cfg config.JWTConfig) testServiceOption {
+	return func(d *testServiceDeps) { d.jwt = cfg }
+}
+
+func withLoginConfig(cfg config.LoginConfig) testServiceOption {
+	return func(d *testServiceDeps) { d.login = cfg }
+}
+
+func withVerification(cfg config.VerificationConfig) testServiceOption {
+	return func(d *testServiceDeps) { d.verification = cfg }
+}
+
+func withRefreshTokens(refreshTokens interfaces.RefreshTokenInterface) testServiceOption {
+	return func(d *testServiceDeps) { d.refreshTokens = refreshTokens }
+}
+
+func withSessions(sessions interfaces.SessionInterface) testServiceOption {
+	return func(d *testServiceDeps) { d.sessions = sessions }
+}
+
+func withAccessTokens(accessTokens interfaces.PersonalAccessTokenInterface) testServiceOption {
+	return func(d *testServiceDeps) { d.accessTokens = accessTokens }
+}
+
+// withTxRepos sets the repositories reached through transactions; users
+// and refresh tokens are filled in from the service
+func withTxRepos(repos interfaces.Repositories) testServiceOption {
+	return func(d *testServiceDeps) { d.repos = repos }
+}
+
+// newTestService builds a Service on users with in-memory stores for
+// everything the options leave out
+func newTestService(t *testing.T, users interfaces.UserInterface, opts ...testServiceOption) *Service {
+	t.Helper()
+	deps := &testServiceDeps{
+		jwt:           config.JWTConfig{Secret: "test-secret", Expiration: 15 * time.Minute, RefreshExpiration: time.Hour},
+		refreshTokens: newMemoryRefreshTokens(),
+		sessions:      newMemorySessions(),
+		accessTokens:  &memoryAccessTokens{},
+	}
+	for _, opt := range opts {
+		opt(deps)
+	}
+	repos := deps.repos
+	repos.Users = users
+	if repos.RefreshTokens == nil {
+		repos.RefreshTokens = deps.refreshTokens
+	}
+	return NewService(deps.jwt, deps.login, deps.verification, users, deps.refreshTokens, deps.sessions, deps.accessTokens, newTestRevocations(), newTestKeys(), &fakeTxManager{repos: &repos}, zap.NewNop())
+}
+
+// stubCompanyRepository records created companies and assigns them IDs
+type stubCompanyRepository struct {
+	interfaces.CompanyInterface
+	created []*models.Company
+}
+
+func (s *stubCompanyRepository) Create(ctx context.Context, company *models.Company) error {
+	company.ID = int64(len(s.created) + 1)
+	s.created = append(s.created, company)
+	return nil
+}
+
+func (s *stubCompanyRepository) GetByID(ctx context.Context, id int64) (*models.Company, error) {
+	for _, company := range s.created {
+		if company.ID == id {
+			return company, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
 func TestAuthService_Register(t *testing.T) {
 	// Setup
-	logger, _ := zap.NewDevelopment()
-	jwtConfig := config.JWTConfig{
-		Secret:     "test-secret",
-		Expiration: 24 * time.Hour,
-	}
 	mockRepo := new(MockUserRepository)
-	service := NewService(jwtConfig, mockRepo, logger)
+	service := newTestService(t, mockRepo, withJWT(config.JWTConfig{Secret: "test-secret", Expiration: 24 * time.Hour}))
 
 	req := &RegisterRequest{
 		Email:     "test@example.com",
@@ -92,7 +244,6 @@ func TestAuthService_Register(t *testing.T) {
 		Password:  "password123",
 		FirstName: "John",
 		LastName:  "Doe",
-		Role:      models.RoleUser,
 	}
 
 	// Mock expectations
@@ -101,7 +252,7 @@ func TestAuthService_Register(t *testing.T) {
 	mockRepo.On("Create", mock.AnythingOfType("*models.User")).Return(nil)
 
 	// Test
-	user, err := service.Register(req)
+	user, err := service.Register(context.Background(), req)
 
 	// Assertions
 	assert.NoError(t, err)
@@ -116,15 +267,73 @@ func TestAuthService_Register(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
-func TestAuthService_Login(t *testing.T) {
+func TestAuthService_RegisterWithCompany(t *testing.T) {
+	// Setup
+	mockRepo := new(MockUserRepository)
+	companies := &stubCompanyRepository{}
+	service := newTestService(t, mockRepo, withJWT(config.JWTConfig{Secret: "test-secret", Expiration: 24 * time.Hour}), withTxRepos(interfaces.Repositories{Companies: companies}))
+
+	req := &RegisterRequest{
+		Email:     "founder@example.com",
+		Username:  "founder",
+		Password:  "password123",
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Company: &RegisterCompanyRequest{
+			Name:      "Acme",
+			Code:      "ACME",
+			CountryID: 7,
+		},
+	}
+
+	// Mock expectations
+	mockRepo.On("ExistsByEmail", "founder@example.com").Return(false, nil)
+	mockRepo.On("ExistsByUsername", "founder").Return(false, nil)
+	mockRepo.On("Create", mock.AnythingOfType("*models.User")).Return(nil)
+
+	// Test
+	user, err := service.Register(context.Background(), req)
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Len(t, companies.created, 1)
+	assert.Equal(t, "Acme", companies.created[0].Name)
+	if assert.NotNil(t, user.CompanyID) && assert.NotNil(t, user.CountryID) {
+		assert.Equal(t, companies.created[0].ID, *user.CompanyID)
+		assert.Equal(t, int64(7), *user.CountryID)
+	}
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthService_RegisterDuplicateEmailCreatesNothing(t *testing.T) {
 	// Setup
-	logger, _ := zap.NewDevelopment()
-	jwtConfig := config.JWTConfig{
-		Secret:     "test-secret",
-		Expiration: 24 * time.Hour,
+	mockRepo := new(MockUserRepository)
+	companies := &stubCompanyRepository{}
+	service := newTestService(t, mockRepo, withJWT(config.JWTConfig{Secret: "test-secret", Expiration: 24 * time.Hour}), withTxRepos(interfaces.Repositories{Companies: companies}))
+
+	req := &RegisterRequest{
+		Email:    "taken@example.com",
+		Username: "taken",
+		Company:  &RegisterCompanyRequest{Name: "Acme", CountryID: 7},
 	}
+
+	mockRepo.On("ExistsByEmail", "taken@example.com").Return(true, nil)
+
+	// Test
+	user, err := service.Register(context.Background(), req)
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrEmailTaken)
+	assert.Nil(t, user)
+	assert.Empty(t, companies.created)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestAuthService_Login(t *testing.T) {
+	// Setup
 	mockRepo := new(MockUserRepository)
-	service := NewService(jwtConfig, mockRepo, logger)
+	service := newTestService(t, mockRepo, withJWT(config.JWTConfig{Secret: "test-secret", Expiration: 24 * time.Hour}))
 
 	// Create a test user with hashed password
 	testUser := &models.User{
@@ -134,7 +343,6 @@ func TestAuthService_Login(t *testing.T) {
 		Password:  "$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi", // "password"
 		FirstName: "John",
 		LastName:  "Doe",
-		Role:      models.RoleUser,
 		IsActive:  true,
 	}
 
@@ -148,7 +356,7 @@ func TestAuthService_Login(t *testing.T) {
 	mockRepo.On("UpdateLastLogin", int64(1)).Return(nil)
 
 	// Test
-	response, err := service.Login(req)
+	response, err := service.Login(context.Background(), req)
 
 	// Assertions
 	assert.NoError(t, err)
@@ -157,19 +365,111 @@ func TestAuthService_Login(t *testing.T) {
 	assert.Equal(t, "Bearer", response.Type)
 	assert.Equal(t, testUser, response.User)
 	assert.True(t, response.ExpiresAt.After(time.Now()))
+	assert.NotEmpty(t, response.RefreshToken)
 
 	mockRepo.AssertExpectations(t)
 }
 
+func newLockoutTestService(t *testing.T, mockRepo *MockUserRepository) (*Service, *[]time.Duration) {
+	loginConfig := config.LoginConfig{
+		MaxAttempts:     3,
+		LockoutDuration: 15 * time.Minute,
+		DelayBase:       100 * time.Millisecond,
+		DelayMax:        300 * time.Millisecond,
+	}
+	service := newTestService(t, mockRepo, withLoginConfig(loginConfig))
+
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	delays := []time.Duration{}
+	service.sleep = func(ctx context.Context, d time.Duration) { delays = append(delays, d) }
+	return service, &delays
+}
+
+func lockoutTestUser() *models.User {
+	return &models.User{
+		ID:       1,
+		Email:    "test@example.com",
+		Password: "$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi", // "password"
+		Role:     models.RoleUser,
+		IsActive: true,
+	}
+}
+
+func TestAuthService_Login_LocksAfterRepeatedFailures(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service, delays := newLockoutTestService(t, mockRepo)
+	lockedUntil := service.now().Add(15 * time.Minute)
+
+	mockRepo.On("GetByEmail", "test@example.com").Return(lockoutTestUser(), nil)
+	mockRepo.On("RecordFailedLogin", int64(1)).Return(1, nil).Once()
+	mockRepo.On("RecordFailedLogin", int64(1)).Return(2, nil).Once()
+	mockRepo.On("RecordFailedLogin", int64(1)).Return(3, nil).Once()
+	mockRepo.On("LockUntil", int64(1), lockedUntil).Return(nil).Once()
+
+	for i := 0; i < 3; i++ {
+		_, err := service.Login(context.Background(), &LoginRequest{Email: "test@example.com", Password: "wrong"})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	// The delay doubles per failure and is capped
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}, *delays)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthService_Login_UnknownEmailIsDelayedLikeAFailure(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service, delays := newLockoutTestService(t, mockRepo)
+	mockRepo.On("GetByEmail", "nobody@example.com").Return((*models.User)(nil), gorm.ErrRecordNotFound)
+
+	_, err := service.Login(context.Background(), &LoginRequest{Email: "nobody@example.com", Password: "wrong"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	assert.Equal(t, []time.Duration{100 * time.Millisecond}, *delays)
+}
+
+func TestAuthService_Login_LockedAccountRejectsCorrectPassword(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service, _ := newLockoutTestService(t, mockRepo)
+
+	user := lockoutTestUser()
+	lockedUntil := service.now().Add(5 * time.Minute)
+	user.LockedUntil = &lockedUntil
+	mockRepo.On("GetByEmail", "test@example.com").Return(user, nil)
+
+	_, err := service.Login(context.Background(), &LoginRequest{Email: "test@example.com", Password: "password"})
+
+	var lockedErr *AccountLockedError
+	if assert.ErrorAs(t, err, &lockedErr) {
+		assert.Equal(t, lockedUntil, lockedErr.Until)
+	}
+	mockRepo.AssertNotCalled(t, "RecordFailedLogin", mock.Anything)
+	mockRepo.AssertNotCalled(t, "UpdateLastLogin", mock.Anything)
+}
+
+func TestAuthService_Login_SuccessResetsFailures(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service, _ := newLockoutTestService(t, mockRepo)
+
+	// The lock has expired
+	user := lockoutTestUser()
+	user.FailedLoginAttempts = 3
+	expired := service.now().Add(-time.Minute)
+	user.LockedUntil = &expired
+
+	mockRepo.On("GetByEmail", "test@example.com").Return(user, nil)
+	mockRepo.On("ResetFailedLogins", int64(1)).Return(nil)
+	mockRepo.On("UpdateLastLogin", int64(1)).Return(nil)
+
+	_, err := service.Login(context.Background(), &LoginRequest{Email: "test@example.com", Password: "password"})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestAuthService_GenerateJWT(t *testing.T) {
 	// Setup
-	logger, _ := zap.NewDevelopment()
-	jwtConfig := config.JWTConfig{
-		Secret:     "test-secret",
-		Expiration: 24 * time.Hour,
-	}
 	mockRepo := new(MockUserRepository)
-	service := NewService(jwtConfig, mockRepo, logger)
+	service := newTestService(t, mockRepo, withJWT(config.JWTConfig{Secret: "test-secret", Expiration: 24 * time.Hour}))
 
 	user := &models.User{
 		ID:       1,
@@ -203,13 +503,8 @@ func TestAuthService_GenerateJWT(t *testing.T) {
 
 func TestAuthService_ValidateJWT(t *testing.T) {
 	// Setup
-	logger, _ := zap.NewDevelopment()
-	jwtConfig := config.JWTConfig{
-		Secret:     "test-secret",
-		Expiration: 24 * time.Hour,
-	}
 	mockRepo := new(MockUserRepository)
-	service := NewService(jwtConfig, mockRepo, logger)
+	service := newTestService(t, mockRepo, withJWT(config.JWTConfig{Secret: "test-secret", Expiration: 24 * time.Hour}))
 
 	user := &models.User{
 		ID:       1,
@@ -231,3 +526,14 @@ func TestAuthService_ValidateJWT(t *testing.T) {
 	_, err = service.ValidateJWT("invalid-token")
 	assert.Error(t, err)
 }
+
+func TestAuthService_ValidateJWT_Expired(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newTestService(t, mockRepo, withJWT(config.JWTConfig{Secret: "test-secret", Expiration: -time.Minute}))
+
+	token, _, err := service.GenerateJWT(&models.User{ID: 1, Role: models.RoleUser})
+	assert.NoError(t, err)
+
+	_, err = service.ValidateJWT(token)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+}
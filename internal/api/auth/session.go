@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"go.uber.org/zap"
+)
+
+// ErrSessionNotFound is returned for sessions that do not exist, have
+// ended or belong to another user
+var ErrSessionNotFound = errors.New("session not found")
+
+// sessionTouchInterval limits how often each instance records the use of a
+// session, so authenticated requests do not each write to the database
+const sessionTouchInterval = time.Minute
+
+// sessionSeen remembers when sessions were last recorded as used
+type sessionSeen struct {
+	mu    sync.Mutex
+	last  map[string]time.Time
+	swept time.Time
+}
+
+func newSessionSeen() *sessionSeen {
+	return &sessionSeen{last: make(map[string]time.Time)}
+}
+
+// due reports whether the use of the session at now should be recorded,
+// and if so remembers it
+func (s *sessionSeen) due(sessionID string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if last, ok := s.last[sessionID]; ok && now.Sub(last) < sessionTouchInterval {
+		return false
+	}
+	// Forget sessions that have gone quiet so the map does not grow
+	if now.Sub(s.swept) >= sessionTouchInterval {
+		for id, last := range s.last {
+			if now.Sub(last) >= sessionTouchInterval {
+				delete(s.last, id)
+			}
+		}
+		s.swept = now
+	}
+	s.last[sessionID] = now
+	return true
+}
+
+// touchSession records that the session was used by the request of ctx. A
+// failure only makes the last seen time less accurate, so it is logged.
+func (s *Service) touchSession(ctx context.Context, sessionID string) {
+	now := s.now()
+	if !s.seen.due(sessionID, now) {
+		return
+	}
+	if err := s.sessions.Touch(ctx, sessionID, now, audit.FromContext(ctx).IP); err != nil {
+		s.logger.Error("Failed to record session use", zap.String("session_id", sessionID), zap.Error(err))
+	}
+}
+
+// endSession revokes the refresh tokens of the session and the access
+// tokens issued to it
+func (s *Service) endSession(ctx context.Context, userID int64, sessionID string) error {
+	now := s.now()
+	if err := s.refreshTokens.RevokeFamily(ctx, sessionID, now); err != nil {
+		return err
+	}
+	if err := s.revocations.RevokeSession(ctx, sessionID, userID, s.jwtConfig.Expiration); err != nil {
+		return err
+	}
+	return s.sessions.Revoke(ctx, sessionID, now)
+}
+
+// SessionService lets users see where they are logged in and end sessions,
+// and admins do the same for any user
+type SessionService struct {
+	authService *Service
+	sessions    interfaces.SessionInterface
+	userRepo    interfaces.UserInterface
+	logger      *zap.Logger
+}
+
+// NewSessionService creates a session service
+func NewSessionService(authService *Service, sessions interfaces.SessionInterface, userRepo interfaces.UserInterface, logger *zap.Logger) *SessionService {
+	return &SessionService{
+		authService: authService,
+		sessions:    sessions,
+		userRepo:    userRepo,
+		logger:      logger,
+	}
+}
+
+// List returns the active sessions of the user, most recently seen first,
+// with the session accessToken was issued to marked current
+func (s *SessionService) List(ctx context.Context, userID int64, accessToken string) ([]models.Session, error) {
+	sessions, err := s.sessions.ListActive(ctx, userID, s.authService.now())
+	if err != nil {
+		return nil, err
+	}
+
+	current := s.sessionOf(accessToken)
+	for i := range sessions {
+		sessions[i].Current = current != "" && sessions[i].ID == current
+	}
+	return sessions, nil
+}
+
+// Revoke ends a session of the user. Its refresh token stops working at
+// once and so do the access tokens issued to it.
+func (s *SessionService) Revoke(ctx context.Context, userID int64, sessionID string) error {
+	session, err := s.sessions.GetByID(ctx, sessionID)
+	if err != nil || session.UserID != userID || !session.IsActive(s.authService.now()) {
+		return ErrSessionNotFound
+	}
+	if err := s.authService.endSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Session revoked", zap.Int64("user_id", userID), zap.String("session_id", sessionID))
+	return nil
+}
+
+// RevokeOthers ends every session of the user except the one accessToken
+// was issued to, and returns the IDs of the sessions it ended
+func (s *SessionService) RevokeOthers(ctx context.Context, userID int64, accessToken string) ([]string, error) {
+	sessions, err := s.sessions.ListActive(ctx, userID, s.authService.now())
+	if err != nil {
+		return nil, err
+	}
+
+	current := s.sessionOf(accessToken)
+	var revoked []string
+	for _, session := range sessions {
+		if session.ID == current {
+			continue
+		}
+		if err := s.authService.endSession(ctx, userID, session.ID); err != nil {
+			return revoked, err
+		}
+		revoked = append(revoked, session.ID)
+	}
+
+	s.logger.Info("Other sessions revoked", zap.Int64("user_id", userID), zap.Int("count", len(revoked)))
+	return revoked, nil
+}
+
+// ListForUser returns the active sessions of any user. Users outside the
+// tenant of ctx are not found.
+func (s *SessionService) ListForUser(ctx context.Context, userID int64) ([]models.Session, error) {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.List(ctx, userID, "")
+}
+
+// RevokeForUser ends a session of any user. Users outside the tenant of ctx
+// are not found.
+func (s *SessionService) RevokeForUser(ctx context.Context, userID int64, sessionID string) error {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return err
+	}
+	return s.Revoke(ctx, userID, sessionID)
+}
+
+// sessionOf returns the session accessToken was issued to, or "" when it
+// names none
+func (s *SessionService) sessionOf(accessToken string) string {
+	if accessToken == "" {
+		return ""
+	}
+	claims, err := s.authService.ValidateJWT(accessToken)
+	if err != nil {
+		return ""
+	}
+	return s.authService.ExtractSessionID(claims)
+}
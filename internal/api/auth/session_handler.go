@@ -0,0 +1,193 @@
+package auth
+
+import (
+	stderrors "errors"
+	"strconv"
+
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type SessionHandler struct {
+	sessionService *SessionService
+	auditService   *audit.Service
+	logger         *zap.Logger
+}
+
+func NewSessionHandler(sessionService *SessionService, auditService *audit.Service, logger *zap.Logger) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+		auditService:   auditService,
+		logger:         logger,
+	}
+}
+
+// ListSessions godoc
+// @Summary List sessions
+// @Description Devices the current user is logged in on, most recently seen first. The session of the request is marked current.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Session
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/sessions [get]
+func (h *SessionHandler) ListSessions(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	token, _ := c.Locals("token").(string)
+	sessions, err := h.sessionService.List(c.UserContext(), user.ID, token)
+	if err != nil {
+		logger.FromCtx(c, h.logger).Error("Failed to list sessions", zap.Error(err))
+		return errors.DatabaseError("Failed to list sessions", err)
+	}
+
+	return c.JSON(sessions)
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Logs the device out. Its refresh token and the access tokens issued to it stop working at once.
+// @Tags auth
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	sessionID := c.Params("id")
+	if err := h.sessionService.Revoke(c.UserContext(), user.ID, sessionID); err != nil {
+		if stderrors.Is(err, ErrSessionNotFound) {
+			return errors.NotFound(err.Error(), nil).WithCode(errors.CodeSessionNotFound)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to revoke session", zap.String("session_id", sessionID), zap.Error(err))
+		return errors.DatabaseError("Failed to revoke session", err)
+	}
+
+	h.recordRevoked(c, user.ID, sessionID)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RevokeOtherSessions godoc
+// @Summary Revoke all other sessions
+// @Description Logs out every device except the one making the request
+// @Tags auth
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/sessions/revoke-others [post]
+func (h *SessionHandler) RevokeOtherSessions(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return errors.Unauthorized("User not found in context", nil)
+	}
+
+	token, _ := c.Locals("token").(string)
+	revoked, err := h.sessionService.RevokeOthers(c.UserContext(), user.ID, token)
+	// Sessions ended before a failure stay ended, so they are recorded too
+	for _, sessionID := range revoked {
+		h.recordRevoked(c, user.ID, sessionID)
+	}
+	if err != nil {
+		logger.FromCtx(c, h.logger).Error("Failed to revoke other sessions", zap.Error(err))
+		return errors.DatabaseError("Failed to revoke sessions", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListUserSessions godoc
+// @Summary List a user's sessions (admin only)
+// @Description Devices the user is logged in on, most recently seen first
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {array} models.Session
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/sessions [get]
+func (h *SessionHandler) ListUserSessions(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return errors.BadRequest("Invalid user ID", err).WithCode(errors.CodeInvalidID)
+	}
+
+	sessions, err := h.sessionService.ListForUser(c.UserContext(), id)
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NotFound("User not found", err).WithCode(errors.CodeUserNotFound)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to list user sessions", zap.Int64("user_id", id), zap.Error(err))
+		return errors.DatabaseError("Failed to list sessions", err)
+	}
+
+	return c.JSON(sessions)
+}
+
+// RevokeUserSession godoc
+// @Summary Revoke a user's session (admin only)
+// @Description Logs the user out of the device. Its refresh token and the access tokens issued to it stop working at once.
+// @Tags users
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param session_id path string true "Session ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/sessions/{session_id} [delete]
+func (h *SessionHandler) RevokeUserSession(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return errors.BadRequest("Invalid user ID", err).WithCode(errors.CodeInvalidID)
+	}
+
+	sessionID := c.Params("session_id")
+	if err := h.sessionService.RevokeForUser(c.UserContext(), id, sessionID); err != nil {
+		switch {
+		case stderrors.Is(err, gorm.ErrRecordNotFound):
+			return errors.NotFound("User not found", err).WithCode(errors.CodeUserNotFound)
+		case stderrors.Is(err, ErrSessionNotFound):
+			return errors.NotFound(err.Error(), nil).WithCode(errors.CodeSessionNotFound)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to revoke user session", zap.Int64("user_id", id), zap.String("session_id", sessionID), zap.Error(err))
+		return errors.DatabaseError("Failed to revoke session", err)
+	}
+
+	h.recordRevoked(c, id, sessionID)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// recordRevoked audits the end of a session on the user it belonged to
+func (h *SessionHandler) recordRevoked(c *fiber.Ctx, userID int64, sessionID string) {
+	h.auditService.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditSessionRevoked,
+		EntityType: models.AuditEntityUser,
+		EntityID:   audit.ID(userID),
+		After:      fiber.Map{"session_id": sessionID},
+	})
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type sessionTestEnv struct {
+	authService *Service
+	service     *SessionService
+	users       *MockUserRepository
+	sessions    *memorySessions
+}
+
+func newSessionTestEnv(t *testing.T) *sessionTestEnv {
+	env := &sessionTestEnv{users: new(MockUserRepository), sessions: newMemorySessions()}
+	env.authService = newTestService(t, env.users, withSessions(env.sessions))
+	env.service = NewSessionService(env.authService, env.sessions, env.users, zap.NewNop())
+
+	env.users.On("GetByID", int64(1)).Return(&models.User{ID: 1, Role: models.RoleUser, IsActive: true}, nil)
+	env.users.On("GetByID", int64(2)).Return(&models.User{ID: 2, Role: models.RoleUser, IsActive: true}, nil)
+	env.users.On("GetByID", int64(99)).Return((*models.User)(nil), gorm.ErrRecordNotFound)
+	return env
+}
+
+// login starts a session for the user from a device
+func (env *sessionTestEnv) login(t *testing.T, userID int64, userAgent string) *LoginResponse {
+	ctx := audit.NewContext(context.Background(), audit.Source{IP: "203.0.113.7", UserAgent: userAgent})
+	response, err := env.authService.newSession(ctx, &models.User{ID: userID, Role: models.RoleUser, IsActive: true})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return response
+}
+
+// sessionID returns the session an access token was issued to
+func (env *sessionTestEnv) sessionID(t *testing.T, token string) string {
+	claims, err := env.authService.ValidateJWT(token)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return env.authService.ExtractSessionID(claims)
+}
+
+func TestSessions_ListMarksCurrent(t *testing.T) {
+	env := newSessionTestEnv(t)
+	laptop := env.login(t, 1, "Firefox")
+	phone := env.login(t, 1, "Safari")
+	env.login(t, 2, "Chrome")
+
+	sessions, err := env.service.List(context.Background(), 1, laptop.Token)
+	if !assert.NoError(t, err) || !assert.Len(t, sessions, 2) {
+		t.FailNow()
+	}
+	for _, session := range sessions {
+		assert.Equal(t, "203.0.113.7", session.IP)
+		switch session.ID {
+		case env.sessionID(t, laptop.Token):
+			assert.True(t, session.Current)
+			assert.Equal(t, "Firefox", session.UserAgent)
+		case env.sessionID(t, phone.Token):
+			assert.False(t, session.Current)
+			assert.Equal(t, "Safari", session.UserAgent)
+		default:
+			t.Errorf("unexpected session %s", session.ID)
+		}
+	}
+}
+
+func TestSessions_RevokeEndsRefreshAndAccessTokens(t *testing.T) {
+	env := newSessionTestEnv(t)
+	laptop := env.login(t, 1, "Firefox")
+	phone := env.login(t, 1, "Safari")
+
+	assert.NoError(t, env.service.Revoke(context.Background(), 1, env.sessionID(t, phone.Token)))
+
+	_, err := env.authService.GetUserFromToken(context.Background(), phone.Token)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	_, err = env.authService.Refresh(context.Background(), phone.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// The other device stays logged in
+	_, err = env.authService.GetUserFromToken(context.Background(), laptop.Token)
+	assert.NoError(t, err)
+	sessions, err := env.service.List(context.Background(), 1, laptop.Token)
+	if assert.NoError(t, err) && assert.Len(t, sessions, 1) {
+		assert.True(t, sessions[0].Current)
+	}
+
+	// An ended session cannot be revoked again
+	err = env.service.Revoke(context.Background(), 1, env.sessionID(t, phone.Token))
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestSessions_RevokeRejectsOtherUsersSessions(t *testing.T) {
+	env := newSessionTestEnv(t)
+	victim := env.login(t, 2, "Chrome")
+
+	err := env.service.Revoke(context.Background(), 1, env.sessionID(t, victim.Token))
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	err = env.service.Revoke(context.Background(), 1, "missing")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	_, err = env.authService.GetUserFromToken(context.Background(), victim.Token)
+	assert.NoError(t, err)
+}
+
+func TestSessions_RevokeOthersKeepsCurrent(t *testing.T) {
+	env := newSessionTestEnv(t)
+	laptop := env.login(t, 1, "Firefox")
+	phone := env.login(t, 1, "Safari")
+	tablet := env.login(t, 1, "Chrome")
+	other := env.login(t, 2, "Chrome")
+
+	revoked, err := env.service.RevokeOthers(context.Background(), 1, laptop.Token)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{env.sessionID(t, phone.Token), env.sessionID(t, tablet.Token)}, revoked)
+
+	for _, response := range []*LoginResponse{phone, tablet} {
+		_, err = env.authService.Refresh(context.Background(), response.RefreshToken)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	}
+	_, err = env.authService.Refresh(context.Background(), laptop.RefreshToken)
+	assert.NoError(t, err)
+	_, err = env.authService.GetUserFromToken(context.Background(), other.Token)
+	assert.NoError(t, err)
+}
+
+func TestSessions_RefreshKeepsSessionAndExtendsIt(t *testing.T) {
+	env := newSessionTestEnv(t)
+	login := env.login(t, 1, "Firefox")
+	id := env.sessionID(t, login.Token)
+
+	later := time.Now().Add(30 * time.Minute)
+	env.authService.now = func() time.Time { return later }
+	refreshed, err := env.authService.Refresh(context.Background(), login.RefreshToken)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	assert.Equal(t, id, env.sessionID(t, refreshed.Token))
+	session, err := env.sessions.GetByID(context.Background(), id)
+	if assert.NoError(t, err) {
+		assert.Equal(t, refreshed.RefreshExpiresAt, session.ExpiresAt)
+		assert.Equal(t, later, session.LastSeenAt)
+	}
+}
+
+func TestSessions_LogoutAndReuseEndSession(t *testing.T) {
+	env := newSessionTestEnv(t)
+	loggedOut := env.login(t, 1, "Firefox")
+	stolen := env.login(t, 1, "Safari")
+
+	assert.NoError(t, env.authService.Logout(context.Background(), 1, loggedOut.Token, loggedOut.RefreshToken))
+
+	_, err := env.authService.Refresh(context.Background(), stolen.RefreshToken)
+	assert.NoError(t, err)
+	_, err = env.authService.Refresh(context.Background(), stolen.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	// Access tokens of a stolen session stop working along with its refresh tokens
+	_, err = env.authService.GetUserFromToken(context.Background(), stolen.Token)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	sessions, err := env.service.List(context.Background(), 1, "")
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestSessions_AdminRevokeChecksUser(t *testing.T) {
+	env := newSessionTestEnv(t)
+	login := env.login(t, 2, "Chrome")
+	id := env.sessionID(t, login.Token)
+
+	_, err := env.service.ListForUser(context.Background(), 99)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, env.service.RevokeForUser(context.Background(), 99, id), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, env.service.RevokeForUser(context.Background(), 1, id), ErrSessionNotFound)
+
+	sessions, err := env.service.ListForUser(context.Background(), 2)
+	if assert.NoError(t, err) && assert.Len(t, sessions, 1) {
+		assert.False(t, sessions[0].Current)
+	}
+	assert.NoError(t, env.service.RevokeForUser(context.Background(), 2, id))
+	_, err = env.authService.GetUserFromToken(context.Background(), login.Token)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+}
+
+func TestSessionSeen_ThrottlesWrites(t *testing.T) {
+	seen := newSessionSeen()
+	now := time.Now()
+
+	assert.True(t, seen.due("a", now))
+	assert.False(t, seen.due("a", now.Add(30*time.Second)))
+	assert.True(t, seen.due("b", now.Add(30*time.Second)))
+	assert.True(t, seen.due("a", now.Add(sessionTouchInterval)))
+
+	// Quiet sessions are forgotten
+	seen.due("c", now.Add(3*sessionTouchInterval))
+	assert.NotContains(t, seen.last, "b")
+}
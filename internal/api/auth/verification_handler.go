@@ -0,0 +1,101 @@
+package auth
+
+import (
+	stderrors "errors"
+
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/alxand/nalo-workspace/internal/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type VerificationHandler struct {
+	verificationService *VerificationService
+	auditService        *audit.Service
+	logger              *zap.Logger
+}
+
+func NewVerificationHandler(verificationService *VerificationService, auditService *audit.Service, logger *zap.Logger) *VerificationHandler {
+	return &VerificationHandler{
+		verificationService: verificationService,
+		auditService:        auditService,
+		logger:              logger,
+	}
+}
+
+// VerifyEmail godoc
+// @Summary Verify an email address
+// @Description Confirms the email address with the token from a verification link. Links sent after a profile change of the email make the new address the account's email.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body VerifyEmailRequest true "Verification token"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/verify-email [post]
+func (h *VerificationHandler) VerifyEmail(c *fiber.Ctx) error {
+	var req VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+
+	user, err := h.verificationService.Verify(c.UserContext(), req.Token)
+	if err != nil {
+		switch {
+		case stderrors.Is(err, ErrInvalidVerificationToken):
+			return errors.BadRequest(err.Error(), nil).WithCode(errors.CodeVerificationInvalid)
+		case stderrors.Is(err, ErrEmailTaken):
+			return errors.Conflict(err.Error(), nil).WithCode(errors.CodeEmailTaken)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to verify email", zap.Error(err))
+		return errors.DatabaseError("Failed to verify email", err)
+	}
+
+	// Names the address, which differs from before when it confirms a change
+	h.auditService.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditEmailVerified,
+		EntityType: models.AuditEntityUser,
+		EntityID:   audit.ID(user.ID),
+		After:      fiber.Map{"email": user.Email},
+		ActorID:    user.ID,
+	})
+	return c.JSON(user)
+}
+
+// ResendVerification godoc
+// @Summary Resend the email verification link
+// @Description Always answers 202; links to the same account are throttled
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body ResendVerificationRequest true "Account email"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/verify-email/resend [post]
+func (h *VerificationHandler) ResendVerification(c *fiber.Ctx) error {
+	var req ResendVerificationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+
+	if err := h.verificationService.ResendVerification(c.UserContext(), req.Email); err != nil {
+		logger.FromCtx(c, h.logger).Error("Failed to resend verification email", zap.Error(err))
+		return errors.InternalServerError("Failed to resend verification email", err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "If the account exists and is unverified, a verification link has been sent",
+	})
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func newVerificationTestService(t *testing.T) (*VerificationService, *MockUserRepository, *recordingNotifier, *models.User) {
+	user := &models.User{ID: 1, Email: "test@example.com", FirstName: "Test", IsActive: true}
+
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("GetByID", int64(1)).Return(user, nil)
+	mockRepo.On("GetByID", int64(2)).Return(&models.User{ID: 2, Email: "other@example.com", IsActive: true}, nil)
+	mockRepo.On("GetByEmail", "test@example.com").Return(user, nil)
+	mockRepo.On("MarkVerificationSent", int64(1), mock.Anything, mock.Anything).Return(true, nil)
+	mockRepo.On("MarkEmailVerified", int64(1), mock.Anything).Return(nil)
+
+	notifier := &recordingNotifier{}
+	cfg := config.VerificationConfig{Mode: config.VerificationOptional, TokenTTL: time.Hour, ResendInterval: time.Minute}
+	service := NewVerificationService(cfg, "test-secret", "https://app.example.com", mockRepo, notifier, zap.NewNop())
+	return service, mockRepo, notifier, user
+}
+
+// verificationLinkToken extracts the token from the link in a message body
+func verificationLinkToken(t *testing.T, msg notify.Message) string {
+	for _, field := range strings.Fields(msg.Body) {
+		if strings.HasPrefix(field, "https://app.example.com/verify-email?") {
+			link, err := url.Parse(field)
+			assert.NoError(t, err)
+			return link.Query().Get("token")
+		}
+	}
+	t.Fatalf("no verification link in %q", msg.Body)
+	return ""
+}
+
+func TestVerification_SendAndVerify(t *testing.T) {
+	service, mockRepo, notifier, user := newVerificationTestService(t)
+
+	assert.NoError(t, service.SendVerification(context.Background(), user))
+	if !assert.Len(t, notifier.messages, 1) {
+		return
+	}
+	token := verificationLinkToken(t, notifier.messages[0])
+
+	verified, err := service.Verify(context.Background(), token)
+	assert.NoError(t, err)
+	assert.True(t, verified.IsEmailVerified())
+	mockRepo.AssertCalled(t, "MarkEmailVerified", int64(1), mock.Anything)
+
+	// Following the link again is harmless
+	_, err = service.Verify(context.Background(), token)
+	assert.NoError(t, err)
+	mockRepo.AssertNumberOfCalls(t, "MarkEmailVerified", 1)
+
+	assert.ErrorIs(t, service.SendVerification(context.Background(), user), ErrEmailAlreadyVerified)
+}
+
+func TestVerification_RejectsBadTokens(t *testing.T) {
+	service, _, notifier, user := newVerificationTestService(t)
+	assert.NoError(t, service.SendVerification(context.Background(), user))
+	token := verificationLinkToken(t, notifier.messages[0])
+
+	for _, bad := range []string{"", "garbage", "1.2", "2." + strings.SplitN(token, ".", 2)[1], token + "x"} {
+		_, err := service.Verify(context.Background(), bad)
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken, bad)
+	}
+
+	// A link for the old address stops working once the email changes
+	user.Email = "changed@example.com"
+	_, err := service.Verify(context.Background(), token)
+	assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+	user.Email = "test@example.com"
+
+	later := time.Now().Add(2 * time.Hour)
+	service.now = func() time.Time { return later }
+	_, err = service.Verify(context.Background(), token)
+	assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+	assert.False(t, user.IsEmailVerified())
+}
+
+func TestVerification_ResendIsThrottledSilently(t *testing.T) {
+	service, mockRepo, notifier, _ := newVerificationTestService(t)
+	mockRepo.ExpectedCalls = nil
+	mockRepo.On("GetByEmail", "test@example.com").Return(&models.User{ID: 1, Email: "test@example.com", IsActive: true}, nil)
+	mockRepo.On("MarkVerificationSent", int64(1), mock.Anything, mock.Anything).Return(false, nil)
+
+	assert.ErrorIs(t, service.SendVerification(context.Background(), &models.User{ID: 1}), ErrVerificationThrottled)
+	assert.NoError(t, service.ResendVerification(context.Background(), "test@example.com"))
+	assert.Empty(t, notifier.messages)
+}
+
+func TestVerification_LoginModeBlocksUnverifiedUsers(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	hash, err := models.HashPassword("password123")
+	assert.NoError(t, err)
+	user := &models.User{ID: 1, Email: "test@example.com", Password: hash, Role: models.RoleUser, IsActive: true}
+	mockRepo.On("GetByEmail", "test@example.com").Return(user, nil)
+	mockRepo.On("UpdateLastLogin", int64(1)).Return(nil)
+
+	verification := config.VerificationConfig{Mode: config.VerificationLogin}
+	service := newTestService(t, mockRepo, withJWT(config.JWTConfig{Secret: "test-secret", Expiration: time.Hour}), withVerification(verification))
+
+	_, err = service.Login(context.Background(), &LoginRequest{Email: "test@example.com", Password: "password123"})
+	assert.ErrorIs(t, err, ErrEmailNotVerified)
+
+	// A wrong password still reads as wrong credentials
+	mockRepo.On("RecordFailedLogin", int64(1)).Return(1, nil)
+	_, err = service.Login(context.Background(), &LoginRequest{Email: "test@example.com", Password: "wrong"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	_, err = service.Login(context.Background(), &LoginRequest{Email: "test@example.com", Password: "password123"})
+	assert.NoError(t, err)
+}
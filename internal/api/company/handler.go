@@ -1,13 +1,17 @@
 package company
 
 import (
+	stderrors "errors"
 	"net/url"
 	"strconv"
 	"strings"
 
+	"github.com/alxand/nalo-workspace/internal/api/audit"
 	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
 	"github.com/alxand/nalo-workspace/internal/domain/models"
 	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/alxand/nalo-workspace/internal/pkg/tenant"
 	"github.com/alxand/nalo-workspace/internal/pkg/validation"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
@@ -15,12 +19,14 @@ import (
 
 type CompanyHandler struct {
 	Repo   interfaces.CompanyInterface
+	Audit  *audit.Service
 	Logger *zap.Logger
 }
 
-func NewCompanyHandler(repo interfaces.CompanyInterface, logger *zap.Logger) *CompanyHandler {
+func NewCompanyHandler(repo interfaces.CompanyInterface, auditService *audit.Service, logger *zap.Logger) *CompanyHandler {
 	return &CompanyHandler{
 		Repo:   repo,
+		Audit:  auditService,
 		Logger: logger,
 	}
 }
@@ -35,33 +41,37 @@ func NewCompanyHandler(repo interfaces.CompanyInterface, logger *zap.Logger) *Co
 // @Success 201 {object} models.Company
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /companies [post]
 func (h *CompanyHandler) CreateCompany(c *fiber.Ctx) error {
 	var company models.Company
 
 	if err := c.BodyParser(&company); err != nil {
-		h.Logger.Error("Failed to parse request body", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Invalid request body",
-			"details": err.Error(),
-		})
+		logger.FromCtx(c, h.Logger).Error("Failed to parse request body", zap.Error(err))
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
 	}
 
 	if err := validation.ValidateCompany(&company); err != nil {
-		h.Logger.Error("Validation failed", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Validation failed",
-			"details": err.Error(),
-		})
+		logger.FromCtx(c, h.Logger).Error("Validation failed", zap.Error(err))
+		return errors.ValidationError("Validation failed", err)
 	}
 
-	if err := h.Repo.Create(&company); err != nil {
-		h.Logger.Error("Failed to create company", zap.Error(err))
+	if err := h.Repo.Create(c.UserContext(), &company); err != nil {
+		if stderrors.Is(err, tenant.ErrOutsideTenant) {
+			return errors.Forbidden("Only users with access to every company can create companies", nil).WithCode(errors.CodeOutsideTenant)
+		}
+		logger.FromCtx(c, h.Logger).Error("Failed to create company", zap.Error(err))
 		return errors.DatabaseError("Failed to create company", err)
 	}
-
-	h.Logger.Info("Company created successfully", zap.Int64("company_id", company.ID))
+	h.Audit.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditCreate,
+		EntityType: models.AuditEntityCompany,
+		EntityID:   audit.ID(company.ID),
+		After:      &company,
+	})
+
+	logger.FromCtx(c, h.Logger).Info("Company created successfully", zap.Int64("company_id", company.ID))
 	return c.Status(fiber.StatusCreated).JSON(company)
 }
 
@@ -81,13 +91,13 @@ func (h *CompanyHandler) GetCompany(c *fiber.Ctx) error {
 	idParam := c.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid company ID"})
+		return errors.BadRequest("Invalid company ID", err).WithCode(errors.CodeInvalidID)
 	}
 
-	company, err := h.Repo.GetByID(id)
+	company, err := h.Repo.GetByID(c.UserContext(), id)
 	if err != nil {
-		h.Logger.Error("Failed to get company", zap.Int64("company_id", id), zap.Error(err))
-		return errors.NotFound("Company not found", err)
+		logger.FromCtx(c, h.Logger).Error("Failed to get company", zap.Int64("company_id", id), zap.Error(err))
+		return errors.NotFound("Company not found", err).WithCode(errors.CodeCompanyNotFound)
 	}
 
 	return c.JSON(company)
@@ -114,13 +124,13 @@ func (h *CompanyHandler) GetCompanyByCode(c *fiber.Ctx) error {
 	}
 	code := strings.TrimSpace(decodedCode)
 	if code == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Company code is required"})
+		return errors.BadRequest("Company code is required", nil).WithCode(errors.CodeMissingParameter)
 	}
 
-	company, err := h.Repo.GetByCode(code)
+	company, err := h.Repo.GetByCode(c.UserContext(), code)
 	if err != nil {
-		h.Logger.Error("Failed to get company by code", zap.String("code", code), zap.Error(err))
-		return errors.NotFound("Company not found", err)
+		logger.FromCtx(c, h.Logger).Error("Failed to get company by code", zap.String("code", code), zap.Error(err))
+		return errors.NotFound("Company not found", err).WithCode(errors.CodeCompanyNotFound)
 	}
 
 	return c.JSON(company)
@@ -141,16 +151,16 @@ func (h *CompanyHandler) GetCompaniesByCountry(c *fiber.Ctx) error {
 	countryIDParam := c.Params("countryId")
 	countryID, err := strconv.ParseInt(countryIDParam, 10, 64)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid country ID"})
+		return errors.BadRequest("Invalid country ID", err).WithCode(errors.CodeInvalidID)
 	}
 
-	companies, err := h.Repo.GetByCountry(countryID)
+	companies, err := h.Repo.GetByCountry(c.UserContext(), countryID)
 	if err != nil {
-		h.Logger.Error("Failed to get companies by country", zap.Int64("country_id", countryID), zap.Error(err))
+		logger.FromCtx(c, h.Logger).Error("Failed to get companies by country", zap.Int64("country_id", countryID), zap.Error(err))
 		return errors.DatabaseError("Failed to get companies", err)
 	}
 
-	h.Logger.Info("Companies retrieved by country", zap.Int64("country_id", countryID), zap.Int("count", len(companies)))
+	logger.FromCtx(c, h.Logger).Info("Companies retrieved by country", zap.Int64("country_id", countryID), zap.Int("count", len(companies)))
 	return c.JSON(companies)
 }
 
@@ -174,16 +184,16 @@ func (h *CompanyHandler) GetCompaniesByIndustry(c *fiber.Ctx) error {
 	}
 	industry := strings.TrimSpace(decodedIndustry)
 	if industry == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Industry is required"})
+		return errors.BadRequest("Industry is required", nil).WithCode(errors.CodeMissingParameter)
 	}
 
-	companies, err := h.Repo.GetByIndustry(industry)
+	companies, err := h.Repo.GetByIndustry(c.UserContext(), industry)
 	if err != nil {
-		h.Logger.Error("Failed to get companies by industry", zap.String("industry", industry), zap.Error(err))
+		logger.FromCtx(c, h.Logger).Error("Failed to get companies by industry", zap.String("industry", industry), zap.Error(err))
 		return errors.DatabaseError("Failed to get companies", err)
 	}
 
-	h.Logger.Info("Companies retrieved by industry", zap.String("industry", industry), zap.Int("count", len(companies)))
+	logger.FromCtx(c, h.Logger).Info("Companies retrieved by industry", zap.String("industry", industry), zap.Int("count", len(companies)))
 	return c.JSON(companies)
 }
 
@@ -197,13 +207,13 @@ func (h *CompanyHandler) GetCompaniesByIndustry(c *fiber.Ctx) error {
 // @Failure 500 {object} map[string]string
 // @Router /companies [get]
 func (h *CompanyHandler) GetAllCompanies(c *fiber.Ctx) error {
-	companies, err := h.Repo.GetAll()
+	companies, err := h.Repo.GetAll(c.UserContext())
 	if err != nil {
-		h.Logger.Error("Failed to get companies", zap.Error(err))
+		logger.FromCtx(c, h.Logger).Error("Failed to get companies", zap.Error(err))
 		return errors.DatabaseError("Failed to get companies", err)
 	}
 
-	h.Logger.Info("Companies retrieved successfully", zap.Int("count", len(companies)))
+	logger.FromCtx(c, h.Logger).Info("Companies retrieved successfully", zap.Int("count", len(companies)))
 	return c.JSON(companies)
 }
 
@@ -225,29 +235,43 @@ func (h *CompanyHandler) UpdateCompany(c *fiber.Ctx) error {
 	idParam := c.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid company ID"})
+		return errors.BadRequest("Invalid company ID", err).WithCode(errors.CodeInvalidID)
 	}
 
 	var company models.Company
 	if err := c.BodyParser(&company); err != nil {
-		h.Logger.Error("Failed to parse request body", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		logger.FromCtx(c, h.Logger).Error("Failed to parse request body", zap.Error(err))
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
 	}
 
 	company.ID = id
 
 	if err := validation.ValidateCompany(&company); err != nil {
-		h.Logger.Error("Validation failed", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Validation failed", "details": err.Error()})
+		logger.FromCtx(c, h.Logger).Error("Validation failed", zap.Error(err))
+		return errors.ValidationError("Validation failed", err)
 	}
 
-	updatedCompany, err := h.Repo.Update(&company)
+	// Check if company exists
+	existing, err := h.Repo.GetByID(c.UserContext(), id)
 	if err != nil {
-		h.Logger.Error("Failed to update company", zap.Int64("company_id", id), zap.Error(err))
-		return errors.DatabaseError("Failed to update company", err)
+		logger.FromCtx(c, h.Logger).Error("Failed to get company", zap.Int64("company_id", id), zap.Error(err))
+		return errors.NotFound("Company not found", err).WithCode(errors.CodeCompanyNotFound)
 	}
 
-	h.Logger.Info("Company updated successfully", zap.Int64("company_id", id))
+	updatedCompany, err := h.Repo.Update(c.UserContext(), &company)
+	if err != nil {
+		logger.FromCtx(c, h.Logger).Error("Failed to update company", zap.Int64("company_id", id), zap.Error(err))
+		return errors.DatabaseError("Failed to update company", err)
+	}
+	h.Audit.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditUpdate,
+		EntityType: models.AuditEntityCompany,
+		EntityID:   audit.ID(id),
+		Before:     existing,
+		After:      updatedCompany,
+	})
+
+	logger.FromCtx(c, h.Logger).Info("Company updated successfully", zap.Int64("company_id", id))
 	return c.JSON(updatedCompany)
 }
 
@@ -266,14 +290,26 @@ func (h *CompanyHandler) DeleteCompany(c *fiber.Ctx) error {
 	idParam := c.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid company ID"})
+		return errors.BadRequest("Invalid company ID", err).WithCode(errors.CodeInvalidID)
 	}
 
-	if err := h.Repo.Delete(id); err != nil {
-		h.Logger.Error("Failed to delete company", zap.Int64("company_id", id), zap.Error(err))
-		return errors.DatabaseError("Failed to delete company", err)
+	existing, err := h.Repo.GetByID(c.UserContext(), id)
+	if err != nil {
+		logger.FromCtx(c, h.Logger).Error("Failed to get company", zap.Int64("company_id", id), zap.Error(err))
+		return errors.NotFound("Company not found", err).WithCode(errors.CodeCompanyNotFound)
 	}
 
-	h.Logger.Info("Company deleted successfully", zap.Int64("company_id", id))
+	if err := h.Repo.Delete(c.UserContext(), id); err != nil {
+		logger.FromCtx(c, h.Logger).Error("Failed to delete company", zap.Int64("company_id", id), zap.Error(err))
+		return errors.DatabaseError("Failed to delete company", err)
+	}
+	h.Audit.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditDelete,
+		EntityType: models.AuditEntityCompany,
+		EntityID:   audit.ID(id),
+		Before:     existing,
+	})
+
+	logger.FromCtx(c, h.Logger).Info("Company deleted successfully", zap.Int64("company_id", id))
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -2,12 +2,16 @@ package company
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
 	"github.com/alxand/nalo-workspace/internal/domain/models"
 	"github.com/alxand/nalo-workspace/internal/pkg/logger"
 	"github.com/alxand/nalo-workspace/internal/pkg/middleware"
@@ -24,12 +28,12 @@ type MockCompanyRepository struct {
 	mock.Mock
 }
 
-func (m *MockCompanyRepository) Create(company *models.Company) error {
+func (m *MockCompanyRepository) Create(ctx context.Context, company *models.Company) error {
 	args := m.Called(company)
 	return args.Error(0)
 }
 
-func (m *MockCompanyRepository) GetByID(id int64) (*models.Company, error) {
+func (m *MockCompanyRepository) GetByID(ctx context.Context, id int64) (*models.Company, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -37,7 +41,7 @@ func (m *MockCompanyRepository) GetByID(id int64) (*models.Company, error) {
 	return args.Get(0).(*models.Company), args.Error(1)
 }
 
-func (m *MockCompanyRepository) GetByCode(code string) (*models.Company, error) {
+func (m *MockCompanyRepository) GetByCode(ctx context.Context, code string) (*models.Company, error) {
 	args := m.Called(code)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -45,22 +49,22 @@ func (m *MockCompanyRepository) GetByCode(code string) (*models.Company, error)
 	return args.Get(0).(*models.Company), args.Error(1)
 }
 
-func (m *MockCompanyRepository) GetByCountry(countryID int64) ([]models.Company, error) {
+func (m *MockCompanyRepository) GetByCountry(ctx context.Context, countryID int64) ([]models.Company, error) {
 	args := m.Called(countryID)
 	return args.Get(0).([]models.Company), args.Error(1)
 }
 
-func (m *MockCompanyRepository) GetByIndustry(industry string) ([]models.Company, error) {
+func (m *MockCompanyRepository) GetByIndustry(ctx context.Context, industry string) ([]models.Company, error) {
 	args := m.Called(industry)
 	return args.Get(0).([]models.Company), args.Error(1)
 }
 
-func (m *MockCompanyRepository) GetAll() ([]models.Company, error) {
+func (m *MockCompanyRepository) GetAll(ctx context.Context) ([]models.Company, error) {
 	args := m.Called()
 	return args.Get(0).([]models.Company), args.Error(1)
 }
 
-func (m *MockCompanyRepository) Update(company *models.Company) (*models.Company, error) {
+func (m *MockCompanyRepository) Update(ctx context.Context, company *models.Company) (*models.Company, error) {
 	args := m.Called(company)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -68,7 +72,7 @@ func (m *MockCompanyRepository) Update(company *models.Company) (*models.Company
 	return args.Get(0).(*models.Company), args.Error(1)
 }
 
-func (m *MockCompanyRepository) Delete(id int64) error {
+func (m *MockCompanyRepository) Delete(ctx context.Context, id int64) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
@@ -76,18 +80,29 @@ func (m *MockCompanyRepository) Delete(id int64) error {
 func setupTestApp() *fiber.App {
 	logger := zap.NewNop()
 	app := fiber.New(fiber.Config{
-		ErrorHandler: middleware.ErrorHandler(logger),
+		ErrorHandler: middleware.ErrorHandler(logger, false),
 	})
 	return app
 }
 
+// stubAuditLogs keeps audit log entries in memory
+type stubAuditLogs struct {
+	interfaces.AuditLogInterface
+	entries []models.AuditLog
+}
+
+func (s *stubAuditLogs) Create(ctx context.Context, entry *models.AuditLog) error {
+	s.entries = append(s.entries, *entry)
+	return nil
+}
+
 func setupTestHandler() (*CompanyHandler, *MockCompanyRepository) {
 	// Initialize validation for all tests
 	validation.Init()
 
 	mockRepo := new(MockCompanyRepository)
 	logger := zap.NewNop()
-	handler := NewCompanyHandler(mockRepo, logger)
+	handler := NewCompanyHandler(mockRepo, audit.NewService(config.AuditConfig{}, &stubAuditLogs{}, logger), logger)
 	return handler, mockRepo
 }
 
@@ -112,7 +127,7 @@ func TestCreateCompany_Success(t *testing.T) {
 
 	// Setup app and handler
 	app := test_helpers.SetupTestApp()
-	handler := NewCompanyHandler(testDB.CompanyRepo, logger.Get())
+	handler := NewCompanyHandler(testDB.CompanyRepo, audit.NewService(config.AuditConfig{}, testDB.AuditLogRepo, logger.Get()), logger.Get())
 
 	company := models.Company{
 		Name:        "TechCorp",
@@ -141,6 +156,15 @@ func TestCreateCompany_Success(t *testing.T) {
 	assert.Equal(t, company.Code, responseCompany.Code)
 	assert.Equal(t, company.CountryID, responseCompany.CountryID)
 	assert.NotZero(t, responseCompany.ID) // Should have been assigned by database
+
+	entries, err := testDB.AuditLogRepo.List(context.Background(), models.AuditLogFilter{EntityType: models.AuditEntityCompany, Limit: 10})
+	if !assert.NoError(t, err) || !assert.Len(t, entries, 1) {
+		t.FailNow()
+	}
+	assert.Equal(t, models.AuditCreate, entries[0].Action)
+	assert.Equal(t, audit.ID(responseCompany.ID), entries[0].EntityID)
+	assert.Empty(t, entries[0].Before)
+	assert.Contains(t, string(entries[0].After), `"code":"TECH001"`)
 }
 
 func TestCreateCompany_InvalidJSON(t *testing.T) {
@@ -158,7 +182,7 @@ func TestCreateCompany_InvalidJSON(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid request body")
+	assert.Contains(t, response["detail"], "Invalid request body")
 
 	mockRepo.AssertNotCalled(t, "Create")
 }
@@ -186,7 +210,8 @@ func TestCreateCompany_ValidationError(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Validation failed")
+	assert.Contains(t, response["detail"], "Validation failed")
+	assert.Equal(t, "request.validation_failed", response["code"])
 
 	mockRepo.AssertNotCalled(t, "Create")
 }
@@ -215,7 +240,7 @@ func TestCreateCompany_InvalidSize(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Validation failed")
+	assert.Contains(t, response["detail"], "Validation failed")
 
 	mockRepo.AssertNotCalled(t, "Create")
 }
@@ -299,7 +324,7 @@ func TestGetCompany_InvalidID(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid company ID")
+	assert.Contains(t, response["detail"], "Invalid company ID")
 
 	mockRepo.AssertNotCalled(t, "GetByID")
 }
@@ -382,7 +407,7 @@ func TestGetCompanyByCode_EmptyString(t *testing.T) {
 
 	// Setup app and handler
 	app := test_helpers.SetupTestApp()
-	handler := NewCompanyHandler(testDB.CompanyRepo, logger.Get())
+	handler := NewCompanyHandler(testDB.CompanyRepo, audit.NewService(config.AuditConfig{}, testDB.AuditLogRepo, logger.Get()), logger.Get())
 
 	app.Get("/companies/code/:code", handler.GetCompanyByCode)
 
@@ -394,7 +419,7 @@ func TestGetCompanyByCode_EmptyString(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Company code is required")
+	assert.Contains(t, response["detail"], "Company code is required")
 }
 
 func TestGetCompanyByCode_NotFound(t *testing.T) {
@@ -477,7 +502,7 @@ func TestGetCompaniesByCountry_InvalidCountryID(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid country ID")
+	assert.Contains(t, response["detail"], "Invalid country ID")
 
 	mockRepo.AssertNotCalled(t, "GetByCountry")
 }
@@ -577,7 +602,7 @@ func TestGetCompaniesByIndustry_EmptyString(t *testing.T) {
 
 	// Setup app and handler
 	app := test_helpers.SetupTestApp()
-	handler := NewCompanyHandler(testDB.CompanyRepo, logger.Get())
+	handler := NewCompanyHandler(testDB.CompanyRepo, audit.NewService(config.AuditConfig{}, testDB.AuditLogRepo, logger.Get()), logger.Get())
 
 	app.Get("/companies/industry/:industry", handler.GetCompaniesByIndustry)
 
@@ -589,7 +614,7 @@ func TestGetCompaniesByIndustry_EmptyString(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Industry is required")
+	assert.Contains(t, response["detail"], "Industry is required")
 }
 
 func TestGetCompaniesByIndustry_DatabaseError(t *testing.T) {
@@ -695,6 +720,7 @@ func TestUpdateCompany_Success(t *testing.T) {
 	expectedCompany.CreatedAt = time.Now()
 	expectedCompany.UpdatedAt = time.Now()
 
+	mockRepo.On("GetByID", int64(1)).Return(&models.Company{ID: 1, Name: "TechCorp"}, nil).Once()
 	mockRepo.On("Update", mock.AnythingOfType("*models.Company")).Return(&expectedCompany, nil).Once()
 
 	app.Put("/companies/:id", handler.UpdateCompany)
@@ -740,7 +766,7 @@ func TestUpdateCompany_InvalidID(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid company ID")
+	assert.Contains(t, response["detail"], "Invalid company ID")
 
 	mockRepo.AssertNotCalled(t, "Update")
 }
@@ -760,7 +786,7 @@ func TestUpdateCompany_InvalidJSON(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid request body")
+	assert.Contains(t, response["detail"], "Invalid request body")
 
 	mockRepo.AssertNotCalled(t, "Update")
 }
@@ -788,7 +814,7 @@ func TestUpdateCompany_ValidationError(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Validation failed")
+	assert.Contains(t, response["detail"], "Validation failed")
 
 	mockRepo.AssertNotCalled(t, "Update")
 }
@@ -808,6 +834,7 @@ func TestUpdateCompany_DatabaseError(t *testing.T) {
 		Founded:     2010,
 	}
 
+	mockRepo.On("GetByID", int64(1)).Return(&models.Company{ID: 1, Name: "TechCorp"}, nil).Once()
 	mockRepo.On("Update", mock.AnythingOfType("*models.Company")).Return(nil, errors.New("database error")).Once()
 
 	app.Put("/companies/:id", handler.UpdateCompany)
@@ -827,6 +854,7 @@ func TestDeleteCompany_Success(t *testing.T) {
 	app := setupTestApp()
 	handler, mockRepo := setupTestHandler()
 
+	mockRepo.On("GetByID", int64(1)).Return(&models.Company{ID: 1, Name: "TechCorp"}, nil).Once()
 	mockRepo.On("Delete", int64(1)).Return(nil).Once()
 
 	app.Delete("/companies/:id", handler.DeleteCompany)
@@ -852,7 +880,7 @@ func TestDeleteCompany_InvalidID(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid company ID")
+	assert.Contains(t, response["detail"], "Invalid company ID")
 
 	mockRepo.AssertNotCalled(t, "Delete")
 }
@@ -861,6 +889,7 @@ func TestDeleteCompany_DatabaseError(t *testing.T) {
 	app := setupTestApp()
 	handler, mockRepo := setupTestHandler()
 
+	mockRepo.On("GetByID", int64(1)).Return(&models.Company{ID: 1, Name: "TechCorp"}, nil).Once()
 	mockRepo.On("Delete", int64(1)).Return(errors.New("database error")).Once()
 
 	app.Delete("/companies/:id", handler.DeleteCompany)
@@ -872,3 +901,20 @@ func TestDeleteCompany_DatabaseError(t *testing.T) {
 
 	mockRepo.AssertExpectations(t)
 }
+
+func TestDeleteCompany_NotFound(t *testing.T) {
+	app := setupTestApp()
+	handler, mockRepo := setupTestHandler()
+
+	mockRepo.On("GetByID", int64(999)).Return(nil, errors.New("not found")).Once()
+
+	app.Delete("/companies/:id", handler.DeleteCompany)
+
+	req := httptest.NewRequest("DELETE", "/companies/999", nil)
+	resp, _ := app.Test(req)
+
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Delete")
+}
@@ -5,9 +5,11 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/alxand/nalo-workspace/internal/api/audit"
 	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
 	"github.com/alxand/nalo-workspace/internal/domain/models"
 	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
 	"github.com/alxand/nalo-workspace/internal/pkg/validation"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
@@ -15,12 +17,14 @@ import (
 
 type ContinentHandler struct {
 	Repo   interfaces.ContinentInterface
+	Audit  *audit.Service
 	Logger *zap.Logger
 }
 
-func NewContinentHandler(repo interfaces.ContinentInterface, logger *zap.Logger) *ContinentHandler {
+func NewContinentHandler(repo interfaces.ContinentInterface, auditService *audit.Service, logger *zap.Logger) *ContinentHandler {
 	return &ContinentHandler{
 		Repo:   repo,
+		Audit:  auditService,
 		Logger: logger,
 	}
 }
@@ -41,27 +45,27 @@ func (h *ContinentHandler) CreateContinent(c *fiber.Ctx) error {
 	var continent models.Continent
 
 	if err := c.BodyParser(&continent); err != nil {
-		h.Logger.Error("Failed to parse request body", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Invalid request body",
-			"details": err.Error(),
-		})
+		logger.FromCtx(c, h.Logger).Error("Failed to parse request body", zap.Error(err))
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
 	}
 
 	if err := validation.ValidateContinent(&continent); err != nil {
-		h.Logger.Error("Validation failed", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Validation failed",
-			"details": err.Error(),
-		})
+		logger.FromCtx(c, h.Logger).Error("Validation failed", zap.Error(err))
+		return errors.ValidationError("Validation failed", err)
 	}
 
-	if err := h.Repo.Create(&continent); err != nil {
-		h.Logger.Error("Failed to create continent", zap.Error(err))
+	if err := h.Repo.Create(c.UserContext(), &continent); err != nil {
+		logger.FromCtx(c, h.Logger).Error("Failed to create continent", zap.Error(err))
 		return errors.DatabaseError("Failed to create continent", err)
 	}
+	h.Audit.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditCreate,
+		EntityType: models.AuditEntityContinent,
+		EntityID:   audit.ID(continent.ID),
+		After:      &continent,
+	})
 
-	h.Logger.Info("Continent created successfully", zap.Int64("continent_id", continent.ID))
+	logger.FromCtx(c, h.Logger).Info("Continent created successfully", zap.Int64("continent_id", continent.ID))
 	return c.Status(fiber.StatusCreated).JSON(continent)
 }
 
@@ -81,13 +85,13 @@ func (h *ContinentHandler) GetContinent(c *fiber.Ctx) error {
 	idParam := c.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid continent ID"})
+		return errors.BadRequest("Invalid continent ID", err).WithCode(errors.CodeInvalidID)
 	}
 
-	continent, err := h.Repo.GetByID(id)
+	continent, err := h.Repo.GetByID(c.UserContext(), id)
 	if err != nil {
-		h.Logger.Error("Failed to get continent", zap.Int64("continent_id", id), zap.Error(err))
-		return errors.NotFound("Continent not found", err)
+		logger.FromCtx(c, h.Logger).Error("Failed to get continent", zap.Int64("continent_id", id), zap.Error(err))
+		return errors.NotFound("Continent not found", err).WithCode(errors.CodeContinentNotFound)
 	}
 
 	return c.JSON(continent)
@@ -114,13 +118,13 @@ func (h *ContinentHandler) GetContinentByCode(c *fiber.Ctx) error {
 	}
 	code := strings.TrimSpace(decodedCode)
 	if code == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Continent code is required"})
+		return errors.BadRequest("Continent code is required", nil).WithCode(errors.CodeMissingParameter)
 	}
 
-	continent, err := h.Repo.GetByCode(code)
+	continent, err := h.Repo.GetByCode(c.UserContext(), code)
 	if err != nil {
-		h.Logger.Error("Failed to get continent by code", zap.String("code", code), zap.Error(err))
-		return errors.NotFound("Continent not found", err)
+		logger.FromCtx(c, h.Logger).Error("Failed to get continent by code", zap.String("code", code), zap.Error(err))
+		return errors.NotFound("Continent not found", err).WithCode(errors.CodeContinentNotFound)
 	}
 
 	return c.JSON(continent)
@@ -136,13 +140,13 @@ func (h *ContinentHandler) GetContinentByCode(c *fiber.Ctx) error {
 // @Failure 500 {object} map[string]string
 // @Router /continents [get]
 func (h *ContinentHandler) GetAllContinents(c *fiber.Ctx) error {
-	continents, err := h.Repo.GetAll()
+	continents, err := h.Repo.GetAll(c.UserContext())
 	if err != nil {
-		h.Logger.Error("Failed to get continents", zap.Error(err))
+		logger.FromCtx(c, h.Logger).Error("Failed to get continents", zap.Error(err))
 		return errors.DatabaseError("Failed to get continents", err)
 	}
 
-	h.Logger.Info("Continents retrieved successfully", zap.Int("count", len(continents)))
+	logger.FromCtx(c, h.Logger).Info("Continents retrieved successfully", zap.Int("count", len(continents)))
 	return c.JSON(continents)
 }
 
@@ -164,29 +168,42 @@ func (h *ContinentHandler) UpdateContinent(c *fiber.Ctx) error {
 	idParam := c.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid continent ID"})
+		return errors.BadRequest("Invalid continent ID", err).WithCode(errors.CodeInvalidID)
 	}
 
 	var continent models.Continent
 	if err := c.BodyParser(&continent); err != nil {
-		h.Logger.Error("Failed to parse request body", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		logger.FromCtx(c, h.Logger).Error("Failed to parse request body", zap.Error(err))
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
 	}
 
 	continent.ID = id
 
 	if err := validation.ValidateContinent(&continent); err != nil {
-		h.Logger.Error("Validation failed", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Validation failed", "details": err.Error()})
+		logger.FromCtx(c, h.Logger).Error("Validation failed", zap.Error(err))
+		return errors.ValidationError("Validation failed", err)
 	}
 
-	updatedContinent, err := h.Repo.Update(&continent)
+	existing, err := h.Repo.GetByID(c.UserContext(), id)
 	if err != nil {
-		h.Logger.Error("Failed to update continent", zap.Int64("continent_id", id), zap.Error(err))
+		logger.FromCtx(c, h.Logger).Error("Failed to get continent", zap.Int64("continent_id", id), zap.Error(err))
+		return errors.NotFound("Continent not found", err).WithCode(errors.CodeContinentNotFound)
+	}
+
+	updatedContinent, err := h.Repo.Update(c.UserContext(), &continent)
+	if err != nil {
+		logger.FromCtx(c, h.Logger).Error("Failed to update continent", zap.Int64("continent_id", id), zap.Error(err))
 		return errors.DatabaseError("Failed to update continent", err)
 	}
+	h.Audit.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditUpdate,
+		EntityType: models.AuditEntityContinent,
+		EntityID:   audit.ID(id),
+		Before:     existing,
+		After:      updatedContinent,
+	})
 
-	h.Logger.Info("Continent updated successfully", zap.Int64("continent_id", id))
+	logger.FromCtx(c, h.Logger).Info("Continent updated successfully", zap.Int64("continent_id", id))
 	return c.JSON(updatedContinent)
 }
 
@@ -205,14 +222,26 @@ func (h *ContinentHandler) DeleteContinent(c *fiber.Ctx) error {
 	idParam := c.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid continent ID"})
+		return errors.BadRequest("Invalid continent ID", err).WithCode(errors.CodeInvalidID)
+	}
+
+	existing, err := h.Repo.GetByID(c.UserContext(), id)
+	if err != nil {
+		logger.FromCtx(c, h.Logger).Error("Failed to get continent", zap.Int64("continent_id", id), zap.Error(err))
+		return errors.NotFound("Continent not found", err).WithCode(errors.CodeContinentNotFound)
 	}
 
-	if err := h.Repo.Delete(id); err != nil {
-		h.Logger.Error("Failed to delete continent", zap.Int64("continent_id", id), zap.Error(err))
+	if err := h.Repo.Delete(c.UserContext(), id); err != nil {
+		logger.FromCtx(c, h.Logger).Error("Failed to delete continent", zap.Int64("continent_id", id), zap.Error(err))
 		return errors.DatabaseError("Failed to delete continent", err)
 	}
+	h.Audit.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditDelete,
+		EntityType: models.AuditEntityContinent,
+		EntityID:   audit.ID(id),
+		Before:     existing,
+	})
 
-	h.Logger.Info("Continent deleted successfully", zap.Int64("continent_id", id))
+	logger.FromCtx(c, h.Logger).Info("Continent deleted successfully", zap.Int64("continent_id", id))
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -2,12 +2,16 @@ package continent
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
 	"github.com/alxand/nalo-workspace/internal/domain/models"
 	"github.com/alxand/nalo-workspace/internal/pkg/middleware"
 	"github.com/alxand/nalo-workspace/internal/pkg/validation"
@@ -22,12 +26,12 @@ type MockContinentRepository struct {
 	mock.Mock
 }
 
-func (m *MockContinentRepository) Create(continent *models.Continent) error {
+func (m *MockContinentRepository) Create(ctx context.Context, continent *models.Continent) error {
 	args := m.Called(continent)
 	return args.Error(0)
 }
 
-func (m *MockContinentRepository) GetByID(id int64) (*models.Continent, error) {
+func (m *MockContinentRepository) GetByID(ctx context.Context, id int64) (*models.Continent, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -35,7 +39,7 @@ func (m *MockContinentRepository) GetByID(id int64) (*models.Continent, error) {
 	return args.Get(0).(*models.Continent), args.Error(1)
 }
 
-func (m *MockContinentRepository) GetByCode(code string) (*models.Continent, error) {
+func (m *MockContinentRepository) GetByCode(ctx context.Context, code string) (*models.Continent, error) {
 	args := m.Called(code)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -43,12 +47,12 @@ func (m *MockContinentRepository) GetByCode(code string) (*models.Continent, err
 	return args.Get(0).(*models.Continent), args.Error(1)
 }
 
-func (m *MockContinentRepository) GetAll() ([]models.Continent, error) {
+func (m *MockContinentRepository) GetAll(ctx context.Context) ([]models.Continent, error) {
 	args := m.Called()
 	return args.Get(0).([]models.Continent), args.Error(1)
 }
 
-func (m *MockContinentRepository) Update(continent *models.Continent) (*models.Continent, error) {
+func (m *MockContinentRepository) Update(ctx context.Context, continent *models.Continent) (*models.Continent, error) {
 	args := m.Called(continent)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -56,15 +60,26 @@ func (m *MockContinentRepository) Update(continent *models.Continent) (*models.C
 	return args.Get(0).(*models.Continent), args.Error(1)
 }
 
-func (m *MockContinentRepository) Delete(id int64) error {
+func (m *MockContinentRepository) Delete(ctx context.Context, id int64) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
+// stubAuditLogs keeps audit log entries in memory
+type stubAuditLogs struct {
+	interfaces.AuditLogInterface
+	entries []models.AuditLog
+}
+
+func (s *stubAuditLogs) Create(ctx context.Context, entry *models.AuditLog) error {
+	s.entries = append(s.entries, *entry)
+	return nil
+}
+
 func setupTestApp() *fiber.App {
 	logger := zap.NewNop()
 	app := fiber.New(fiber.Config{
-		ErrorHandler: middleware.ErrorHandler(logger),
+		ErrorHandler: middleware.ErrorHandler(logger, false),
 	})
 	return app
 }
@@ -75,7 +90,7 @@ func setupTestHandler() (*ContinentHandler, *MockContinentRepository) {
 
 	mockRepo := new(MockContinentRepository)
 	logger := zap.NewNop()
-	handler := NewContinentHandler(mockRepo, logger)
+	handler := NewContinentHandler(mockRepo, audit.NewService(config.AuditConfig{}, &stubAuditLogs{}, logger), logger)
 	return handler, mockRepo
 }
 
@@ -133,7 +148,7 @@ func TestCreateContinent_InvalidJSON(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid request body")
+	assert.Contains(t, response["detail"], "Invalid request body")
 
 	mockRepo.AssertNotCalled(t, "Create")
 }
@@ -162,7 +177,7 @@ func TestCreateContinent_ValidationError(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Validation failed")
+	assert.Contains(t, response["detail"], "Validation failed")
 
 	mockRepo.AssertNotCalled(t, "Create")
 }
@@ -239,7 +254,7 @@ func TestGetContinent_InvalidID(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid continent ID")
+	assert.Contains(t, response["detail"], "Invalid continent ID")
 
 	mockRepo.AssertNotCalled(t, "GetByID")
 }
@@ -380,6 +395,7 @@ func TestUpdateContinent_Success(t *testing.T) {
 	expectedContinent.CreatedAt = time.Now()
 	expectedContinent.UpdatedAt = time.Now()
 
+	mockRepo.On("GetByID", int64(1)).Return(&models.Continent{ID: 1}, nil).Once()
 	mockRepo.On("Update", mock.AnythingOfType("*models.Continent")).Return(&expectedContinent, nil).Once()
 
 	app.Put("/continents/:id", handler.UpdateContinent)
@@ -422,7 +438,7 @@ func TestUpdateContinent_InvalidID(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid continent ID")
+	assert.Contains(t, response["detail"], "Invalid continent ID")
 
 	mockRepo.AssertNotCalled(t, "Update")
 }
@@ -442,7 +458,7 @@ func TestUpdateContinent_InvalidJSON(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid request body")
+	assert.Contains(t, response["detail"], "Invalid request body")
 
 	mockRepo.AssertNotCalled(t, "Update")
 }
@@ -471,7 +487,7 @@ func TestUpdateContinent_ValidationError(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Validation failed")
+	assert.Contains(t, response["detail"], "Validation failed")
 
 	mockRepo.AssertNotCalled(t, "Update")
 }
@@ -489,6 +505,7 @@ func TestUpdateContinent_DatabaseError(t *testing.T) {
 		Description: "Updated European continent",
 	}
 
+	mockRepo.On("GetByID", int64(1)).Return(&models.Continent{ID: 1}, nil).Once()
 	mockRepo.On("Update", mock.AnythingOfType("*models.Continent")).Return(nil, errors.New("database error")).Once()
 
 	app.Put("/continents/:id", handler.UpdateContinent)
@@ -508,6 +525,7 @@ func TestDeleteContinent_Success(t *testing.T) {
 	app := setupTestApp()
 	handler, mockRepo := setupTestHandler()
 
+	mockRepo.On("GetByID", int64(1)).Return(&models.Continent{ID: 1}, nil).Once()
 	mockRepo.On("Delete", int64(1)).Return(nil).Once()
 
 	app.Delete("/continents/:id", handler.DeleteContinent)
@@ -533,7 +551,7 @@ func TestDeleteContinent_InvalidID(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid continent ID")
+	assert.Contains(t, response["detail"], "Invalid continent ID")
 
 	mockRepo.AssertNotCalled(t, "Delete")
 }
@@ -542,6 +560,7 @@ func TestDeleteContinent_DatabaseError(t *testing.T) {
 	app := setupTestApp()
 	handler, mockRepo := setupTestHandler()
 
+	mockRepo.On("GetByID", int64(1)).Return(&models.Continent{ID: 1}, nil).Once()
 	mockRepo.On("Delete", int64(1)).Return(errors.New("database error")).Once()
 
 	app.Delete("/continents/:id", handler.DeleteContinent)
@@ -553,3 +572,20 @@ func TestDeleteContinent_DatabaseError(t *testing.T) {
 
 	mockRepo.AssertExpectations(t)
 }
+
+func TestDeleteContinent_NotFound(t *testing.T) {
+	app := setupTestApp()
+	handler, mockRepo := setupTestHandler()
+
+	mockRepo.On("GetByID", int64(999)).Return(nil, errors.New("not found")).Once()
+
+	app.Delete("/continents/:id", handler.DeleteContinent)
+
+	req := httptest.NewRequest("DELETE", "/continents/999", nil)
+	resp, _ := app.Test(req)
+
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Delete")
+}
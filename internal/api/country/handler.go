@@ -5,9 +5,11 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/alxand/nalo-workspace/internal/api/audit"
 	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
 	"github.com/alxand/nalo-workspace/internal/domain/models"
 	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
 	"github.com/alxand/nalo-workspace/internal/pkg/validation"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
@@ -15,12 +17,14 @@ import (
 
 type CountryHandler struct {
 	Repo   interfaces.CountryInterface
+	Audit  *audit.Service
 	Logger *zap.Logger
 }
 
-func NewCountryHandler(repo interfaces.CountryInterface, logger *zap.Logger) *CountryHandler {
+func NewCountryHandler(repo interfaces.CountryInterface, auditService *audit.Service, logger *zap.Logger) *CountryHandler {
 	return &CountryHandler{
 		Repo:   repo,
+		Audit:  auditService,
 		Logger: logger,
 	}
 }
@@ -41,27 +45,27 @@ func (h *CountryHandler) CreateCountry(c *fiber.Ctx) error {
 	var country models.Country
 
 	if err := c.BodyParser(&country); err != nil {
-		h.Logger.Error("Failed to parse request body", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Invalid request body",
-			"details": err.Error(),
-		})
+		logger.FromCtx(c, h.Logger).Error("Failed to parse request body", zap.Error(err))
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
 	}
 
 	if err := validation.ValidateCountry(&country); err != nil {
-		h.Logger.Error("Validation failed", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Validation failed",
-			"details": err.Error(),
-		})
+		logger.FromCtx(c, h.Logger).Error("Validation failed", zap.Error(err))
+		return errors.ValidationError("Validation failed", err)
 	}
 
-	if err := h.Repo.Create(&country); err != nil {
-		h.Logger.Error("Failed to create country", zap.Error(err))
+	if err := h.Repo.Create(c.UserContext(), &country); err != nil {
+		logger.FromCtx(c, h.Logger).Error("Failed to create country", zap.Error(err))
 		return errors.DatabaseError("Failed to create country", err)
 	}
+	h.Audit.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditCreate,
+		EntityType: models.AuditEntityCountry,
+		EntityID:   audit.ID(country.ID),
+		After:      &country,
+	})
 
-	h.Logger.Info("Country created successfully", zap.Int64("country_id", country.ID))
+	logger.FromCtx(c, h.Logger).Info("Country created successfully", zap.Int64("country_id", country.ID))
 	return c.Status(fiber.StatusCreated).JSON(country)
 }
 
@@ -81,13 +85,13 @@ func (h *CountryHandler) GetCountry(c *fiber.Ctx) error {
 	idParam := c.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid country ID"})
+		return errors.BadRequest("Invalid country ID", err).WithCode(errors.CodeInvalidID)
 	}
 
-	country, err := h.Repo.GetByID(id)
+	country, err := h.Repo.GetByID(c.UserContext(), id)
 	if err != nil {
-		h.Logger.Error("Failed to get country", zap.Int64("country_id", id), zap.Error(err))
-		return errors.NotFound("Country not found", err)
+		logger.FromCtx(c, h.Logger).Error("Failed to get country", zap.Int64("country_id", id), zap.Error(err))
+		return errors.NotFound("Country not found", err).WithCode(errors.CodeCountryNotFound)
 	}
 
 	return c.JSON(country)
@@ -114,13 +118,13 @@ func (h *CountryHandler) GetCountryByCode(c *fiber.Ctx) error {
 	}
 	code := strings.TrimSpace(decodedCode)
 	if code == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Country code is required"})
+		return errors.BadRequest("Country code is required", nil).WithCode(errors.CodeMissingParameter)
 	}
 
-	country, err := h.Repo.GetByCode(code)
+	country, err := h.Repo.GetByCode(c.UserContext(), code)
 	if err != nil {
-		h.Logger.Error("Failed to get country by code", zap.String("code", code), zap.Error(err))
-		return errors.NotFound("Country not found", err)
+		logger.FromCtx(c, h.Logger).Error("Failed to get country by code", zap.String("code", code), zap.Error(err))
+		return errors.NotFound("Country not found", err).WithCode(errors.CodeCountryNotFound)
 	}
 
 	return c.JSON(country)
@@ -141,16 +145,16 @@ func (h *CountryHandler) GetCountriesByContinent(c *fiber.Ctx) error {
 	continentIDParam := c.Params("continentId")
 	continentID, err := strconv.ParseInt(continentIDParam, 10, 64)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid continent ID"})
+		return errors.BadRequest("Invalid continent ID", err).WithCode(errors.CodeInvalidID)
 	}
 
-	countries, err := h.Repo.GetByContinent(continentID)
+	countries, err := h.Repo.GetByContinent(c.UserContext(), continentID)
 	if err != nil {
-		h.Logger.Error("Failed to get countries by continent", zap.Int64("continent_id", continentID), zap.Error(err))
+		logger.FromCtx(c, h.Logger).Error("Failed to get countries by continent", zap.Int64("continent_id", continentID), zap.Error(err))
 		return errors.DatabaseError("Failed to get countries", err)
 	}
 
-	h.Logger.Info("Countries retrieved by continent", zap.Int64("continent_id", continentID), zap.Int("count", len(countries)))
+	logger.FromCtx(c, h.Logger).Info("Countries retrieved by continent", zap.Int64("continent_id", continentID), zap.Int("count", len(countries)))
 	return c.JSON(countries)
 }
 
@@ -164,13 +168,13 @@ func (h *CountryHandler) GetCountriesByContinent(c *fiber.Ctx) error {
 // @Failure 500 {object} map[string]string
 // @Router /countries [get]
 func (h *CountryHandler) GetAllCountries(c *fiber.Ctx) error {
-	countries, err := h.Repo.GetAll()
+	countries, err := h.Repo.GetAll(c.UserContext())
 	if err != nil {
-		h.Logger.Error("Failed to get countries", zap.Error(err))
+		logger.FromCtx(c, h.Logger).Error("Failed to get countries", zap.Error(err))
 		return errors.DatabaseError("Failed to get countries", err)
 	}
 
-	h.Logger.Info("Countries retrieved successfully", zap.Int("count", len(countries)))
+	logger.FromCtx(c, h.Logger).Info("Countries retrieved successfully", zap.Int("count", len(countries)))
 	return c.JSON(countries)
 }
 
@@ -192,29 +196,42 @@ func (h *CountryHandler) UpdateCountry(c *fiber.Ctx) error {
 	idParam := c.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid country ID"})
+		return errors.BadRequest("Invalid country ID", err).WithCode(errors.CodeInvalidID)
 	}
 
 	var country models.Country
 	if err := c.BodyParser(&country); err != nil {
-		h.Logger.Error("Failed to parse request body", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		logger.FromCtx(c, h.Logger).Error("Failed to parse request body", zap.Error(err))
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
 	}
 
 	country.ID = id
 
 	if err := validation.ValidateCountry(&country); err != nil {
-		h.Logger.Error("Validation failed", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Validation failed", "details": err.Error()})
+		logger.FromCtx(c, h.Logger).Error("Validation failed", zap.Error(err))
+		return errors.ValidationError("Validation failed", err)
 	}
 
-	updatedCountry, err := h.Repo.Update(&country)
+	existing, err := h.Repo.GetByID(c.UserContext(), id)
 	if err != nil {
-		h.Logger.Error("Failed to update country", zap.Int64("country_id", id), zap.Error(err))
+		logger.FromCtx(c, h.Logger).Error("Failed to get country", zap.Int64("country_id", id), zap.Error(err))
+		return errors.NotFound("Country not found", err).WithCode(errors.CodeCountryNotFound)
+	}
+
+	updatedCountry, err := h.Repo.Update(c.UserContext(), &country)
+	if err != nil {
+		logger.FromCtx(c, h.Logger).Error("Failed to update country", zap.Int64("country_id", id), zap.Error(err))
 		return errors.DatabaseError("Failed to update country", err)
 	}
+	h.Audit.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditUpdate,
+		EntityType: models.AuditEntityCountry,
+		EntityID:   audit.ID(id),
+		Before:     existing,
+		After:      updatedCountry,
+	})
 
-	h.Logger.Info("Country updated successfully", zap.Int64("country_id", id))
+	logger.FromCtx(c, h.Logger).Info("Country updated successfully", zap.Int64("country_id", id))
 	return c.JSON(updatedCountry)
 }
 
@@ -233,14 +250,26 @@ func (h *CountryHandler) DeleteCountry(c *fiber.Ctx) error {
 	idParam := c.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid country ID"})
+		return errors.BadRequest("Invalid country ID", err).WithCode(errors.CodeInvalidID)
+	}
+
+	existing, err := h.Repo.GetByID(c.UserContext(), id)
+	if err != nil {
+		logger.FromCtx(c, h.Logger).Error("Failed to get country", zap.Int64("country_id", id), zap.Error(err))
+		return errors.NotFound("Country not found", err).WithCode(errors.CodeCountryNotFound)
 	}
 
-	if err := h.Repo.Delete(id); err != nil {
-		h.Logger.Error("Failed to delete country", zap.Int64("country_id", id), zap.Error(err))
+	if err := h.Repo.Delete(c.UserContext(), id); err != nil {
+		logger.FromCtx(c, h.Logger).Error("Failed to delete country", zap.Int64("country_id", id), zap.Error(err))
 		return errors.DatabaseError("Failed to delete country", err)
 	}
+	h.Audit.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditDelete,
+		EntityType: models.AuditEntityCountry,
+		EntityID:   audit.ID(id),
+		Before:     existing,
+	})
 
-	h.Logger.Info("Country deleted successfully", zap.Int64("country_id", id))
+	logger.FromCtx(c, h.Logger).Info("Country deleted successfully", zap.Int64("country_id", id))
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -2,12 +2,16 @@ package country
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
 	"github.com/alxand/nalo-workspace/internal/domain/models"
 	"github.com/alxand/nalo-workspace/internal/pkg/middleware"
 	"github.com/alxand/nalo-workspace/internal/pkg/validation"
@@ -22,12 +26,12 @@ type MockCountryRepository struct {
 	mock.Mock
 }
 
-func (m *MockCountryRepository) Create(country *models.Country) error {
+func (m *MockCountryRepository) Create(ctx context.Context, country *models.Country) error {
 	args := m.Called(country)
 	return args.Error(0)
 }
 
-func (m *MockCountryRepository) GetByID(id int64) (*models.Country, error) {
+func (m *MockCountryRepository) GetByID(ctx context.Context, id int64) (*models.Country, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -35,7 +39,7 @@ func (m *MockCountryRepository) GetByID(id int64) (*models.Country, error) {
 	return args.Get(0).(*models.Country), args.Error(1)
 }
 
-func (m *MockCountryRepository) GetByCode(code string) (*models.Country, error) {
+func (m *MockCountryRepository) GetByCode(ctx context.Context, code string) (*models.Country, error) {
 	args := m.Called(code)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -43,17 +47,17 @@ func (m *MockCountryRepository) GetByCode(code string) (*models.Country, error)
 	return args.Get(0).(*models.Country), args.Error(1)
 }
 
-func (m *MockCountryRepository) GetByContinent(continentID int64) ([]models.Country, error) {
+func (m *MockCountryRepository) GetByContinent(ctx context.Context, continentID int64) ([]models.Country, error) {
 	args := m.Called(continentID)
 	return args.Get(0).([]models.Country), args.Error(1)
 }
 
-func (m *MockCountryRepository) GetAll() ([]models.Country, error) {
+func (m *MockCountryRepository) GetAll(ctx context.Context) ([]models.Country, error) {
 	args := m.Called()
 	return args.Get(0).([]models.Country), args.Error(1)
 }
 
-func (m *MockCountryRepository) Update(country *models.Country) (*models.Country, error) {
+func (m *MockCountryRepository) Update(ctx context.Context, country *models.Country) (*models.Country, error) {
 	args := m.Called(country)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -61,15 +65,26 @@ func (m *MockCountryRepository) Update(country *models.Country) (*models.Country
 	return args.Get(0).(*models.Country), args.Error(1)
 }
 
-func (m *MockCountryRepository) Delete(id int64) error {
+func (m *MockCountryRepository) Delete(ctx context.Context, id int64) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
+// stubAuditLogs keeps audit log entries in memory
+type stubAuditLogs struct {
+	interfaces.AuditLogInterface
+	entries []models.AuditLog
+}
+
+func (s *stubAuditLogs) Create(ctx context.Context, entry *models.AuditLog) error {
+	s.entries = append(s.entries, *entry)
+	return nil
+}
+
 func setupTestApp() *fiber.App {
 	logger := zap.NewNop()
 	app := fiber.New(fiber.Config{
-		ErrorHandler: middleware.ErrorHandler(logger),
+		ErrorHandler: middleware.ErrorHandler(logger, false),
 	})
 	return app
 }
@@ -80,7 +95,7 @@ func setupTestHandler() (*CountryHandler, *MockCountryRepository) {
 
 	mockRepo := new(MockCountryRepository)
 	logger := zap.NewNop()
-	handler := NewCountryHandler(mockRepo, logger)
+	handler := NewCountryHandler(mockRepo, audit.NewService(config.AuditConfig{}, &stubAuditLogs{}, logger), logger)
 	return handler, mockRepo
 }
 
@@ -139,7 +154,7 @@ func TestCreateCountry_InvalidJSON(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid request body")
+	assert.Contains(t, response["detail"], "Invalid request body")
 
 	mockRepo.AssertNotCalled(t, "Create")
 }
@@ -169,7 +184,7 @@ func TestCreateCountry_ValidationError(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Validation failed")
+	assert.Contains(t, response["detail"], "Validation failed")
 
 	mockRepo.AssertNotCalled(t, "Create")
 }
@@ -248,7 +263,7 @@ func TestGetCountry_InvalidID(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid country ID")
+	assert.Contains(t, response["detail"], "Invalid country ID")
 
 	mockRepo.AssertNotCalled(t, "GetByID")
 }
@@ -371,7 +386,7 @@ func TestGetCountriesByContinent_InvalidContinentID(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid continent ID")
+	assert.Contains(t, response["detail"], "Invalid continent ID")
 
 	mockRepo.AssertNotCalled(t, "GetByContinent")
 }
@@ -470,6 +485,7 @@ func TestUpdateCountry_Success(t *testing.T) {
 	expectedCountry.CreatedAt = time.Now()
 	expectedCountry.UpdatedAt = time.Now()
 
+	mockRepo.On("GetByID", int64(1)).Return(&models.Country{ID: 1}, nil).Once()
 	mockRepo.On("Update", mock.AnythingOfType("*models.Country")).Return(&expectedCountry, nil).Once()
 
 	app.Put("/countries/:id", handler.UpdateCountry)
@@ -513,7 +529,7 @@ func TestUpdateCountry_InvalidID(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid country ID")
+	assert.Contains(t, response["detail"], "Invalid country ID")
 
 	mockRepo.AssertNotCalled(t, "Update")
 }
@@ -533,7 +549,7 @@ func TestUpdateCountry_InvalidJSON(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid request body")
+	assert.Contains(t, response["detail"], "Invalid request body")
 
 	mockRepo.AssertNotCalled(t, "Update")
 }
@@ -563,7 +579,7 @@ func TestUpdateCountry_ValidationError(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Validation failed")
+	assert.Contains(t, response["detail"], "Validation failed")
 
 	mockRepo.AssertNotCalled(t, "Update")
 }
@@ -582,6 +598,7 @@ func TestUpdateCountry_DatabaseError(t *testing.T) {
 		Description: "Updated Federal Republic of Germany",
 	}
 
+	mockRepo.On("GetByID", int64(1)).Return(&models.Country{ID: 1}, nil).Once()
 	mockRepo.On("Update", mock.AnythingOfType("*models.Country")).Return(nil, errors.New("database error")).Once()
 
 	app.Put("/countries/:id", handler.UpdateCountry)
@@ -601,6 +618,7 @@ func TestDeleteCountry_Success(t *testing.T) {
 	app := setupTestApp()
 	handler, mockRepo := setupTestHandler()
 
+	mockRepo.On("GetByID", int64(1)).Return(&models.Country{ID: 1}, nil).Once()
 	mockRepo.On("Delete", int64(1)).Return(nil).Once()
 
 	app.Delete("/countries/:id", handler.DeleteCountry)
@@ -626,7 +644,7 @@ func TestDeleteCountry_InvalidID(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid country ID")
+	assert.Contains(t, response["detail"], "Invalid country ID")
 
 	mockRepo.AssertNotCalled(t, "Delete")
 }
@@ -635,6 +653,7 @@ func TestDeleteCountry_DatabaseError(t *testing.T) {
 	app := setupTestApp()
 	handler, mockRepo := setupTestHandler()
 
+	mockRepo.On("GetByID", int64(1)).Return(&models.Country{ID: 1}, nil).Once()
 	mockRepo.On("Delete", int64(1)).Return(errors.New("database error")).Once()
 
 	app.Delete("/countries/:id", handler.DeleteCountry)
@@ -646,3 +665,20 @@ func TestDeleteCountry_DatabaseError(t *testing.T) {
 
 	mockRepo.AssertExpectations(t)
 }
+
+func TestDeleteCountry_NotFound(t *testing.T) {
+	app := setupTestApp()
+	handler, mockRepo := setupTestHandler()
+
+	mockRepo.On("GetByID", int64(999)).Return(nil, errors.New("not found")).Once()
+
+	app.Delete("/countries/:id", handler.DeleteCountry)
+
+	req := httptest.NewRequest("DELETE", "/countries/999", nil)
+	resp, _ := app.Test(req)
+
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Delete")
+}
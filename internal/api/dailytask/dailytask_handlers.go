@@ -1,28 +1,65 @@
 package dailytask
 
 import (
+	"context"
+	stderrors "errors"
 	"strconv"
 
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/api/auth"
 	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
 	"github.com/alxand/nalo-workspace/internal/domain/models"
 	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/alxand/nalo-workspace/internal/pkg/metrics"
 	"github.com/alxand/nalo-workspace/internal/pkg/validation"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type TaskHandler struct {
-	Repo   interfaces.DailyTaskInterface
-	Logger *zap.Logger
+	Repo        interfaces.DailyTaskInterface
+	Users       interfaces.UserInterface
+	Permissions *auth.PermissionService
+	Audit       *audit.Service
+	Logger      *zap.Logger
 }
 
-func NewTDailyTaskHandler(repo interfaces.DailyTaskInterface, logger *zap.Logger) *TaskHandler {
+func NewTDailyTaskHandler(repo interfaces.DailyTaskInterface, users interfaces.UserInterface, permissions *auth.PermissionService, auditService *audit.Service, logger *zap.Logger) *TaskHandler {
 	return &TaskHandler{
-		Repo:   repo,
-		Logger: logger,
+		Repo:        repo,
+		Users:       users,
+		Permissions: permissions,
+		Audit:       auditService,
+		Logger:      logger,
 	}
 }
 
+// canAccess reports whether user may act on the tasks of ownerID, given the
+// own, team and all reach of a task permission. Team reach covers users of
+// the same company; users outside the tenant of ctx are never reached.
+func (h *TaskHandler) canAccess(ctx context.Context, user *models.User, ownerID int64, own, team, all string) (bool, error) {
+	if ownerID == user.ID {
+		return h.Permissions.Has(user, own), nil
+	}
+	if h.Permissions.Has(user, all) {
+		return true, nil
+	}
+	if user.CompanyID == nil || !h.Permissions.Has(user, team) {
+		return false, nil
+	}
+
+	owner, err := h.Users.GetByID(ctx, ownerID)
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return owner.CompanyID != nil && *owner.CompanyID == *user.CompanyID, nil
+}
+
 // CreateDailyTask godoc
 // @Summary Create a new daily task
 // @Tags tasks
@@ -44,41 +81,45 @@ func (h *TaskHandler) CreateDailyTask(c *fiber.Ctx) error {
 	var task models.DailyTask
 
 	if err := c.BodyParser(&task); err != nil {
-		h.Logger.Error("Failed to parse request body", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Invalid request body",
-			"details": err.Error(),
-		})
+		logger.FromCtx(c, h.Logger).Error("Failed to parse request body", zap.Error(err))
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
 	}
 
 	task.UserID = user.ID
 
 	if err := validation.ValidateDailyTask(&task); err != nil {
-		h.Logger.Error("Validation failed", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Validation failed",
-			"details": err.Error(),
-		})
+		logger.FromCtx(c, h.Logger).Error("Validation failed", zap.Error(err))
+		return errors.ValidationError("Validation failed", err)
 	}
 
-	if err := h.Repo.Create(&task); err != nil {
-		h.Logger.Error("Failed to create task", zap.Error(err))
+	if err := h.Repo.Create(c.UserContext(), &task); err != nil {
+		logger.FromCtx(c, h.Logger).Error("Failed to create task", zap.Error(err))
 		return errors.DatabaseError("Failed to create task", err)
 	}
+	h.Audit.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditCreate,
+		EntityType: models.AuditEntityDailyTask,
+		EntityID:   audit.ID(task.ID),
+		After:      &task,
+	})
 
-	h.Logger.Info("Task created successfully", zap.Int64("task_id", task.ID), zap.Int64("user_id", user.ID))
+	metrics.TaskCreated()
+	logger.FromCtx(c, h.Logger).Info("Task created successfully", zap.Int64("task_id", task.ID), zap.Int64("user_id", user.ID))
 	return c.Status(fiber.StatusCreated).JSON(task)
 }
 
 // GetTasksByDate godoc
 // @Summary Get tasks by date for the authenticated user
+// @Description With user_id, gets the tasks of another user; needs task:read:team for users of the same company or task:read:all
 // @Tags tasks
 // @Produce json
 // @Security BearerAuth
 // @Param date path string true "Date in YYYY-MM-DD format"
+// @Param user_id query int false "Owner of the tasks, defaults to the authenticated user"
 // @Success 200 {array} models.DailyTask
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /dailytask/{date} [get]
 func (h *TaskHandler) GetTasksByDate(c *fiber.Ctx) error {
@@ -89,21 +130,40 @@ func (h *TaskHandler) GetTasksByDate(c *fiber.Ctx) error {
 
 	date := c.Params("date")
 	if date == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Date parameter is required"})
+		return errors.BadRequest("Date parameter is required", nil).WithCode(errors.CodeMissingParameter)
+	}
+
+	ownerID := user.ID
+	if param := c.Query("user_id"); param != "" {
+		id, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return errors.BadRequest("Invalid user ID", err).WithCode(errors.CodeInvalidID)
+		}
+		ownerID = id
 	}
 
-	tasks, err := h.Repo.GetByDateAndUser(date, user.ID)
+	allowed, err := h.canAccess(c.UserContext(), user, ownerID, models.PermissionTaskRead, models.PermissionTaskReadTeam, models.PermissionTaskReadAll)
 	if err != nil {
-		h.Logger.Error("Failed to get tasks by date", zap.String("date", date), zap.Int64("user_id", user.ID), zap.Error(err))
+		logger.FromCtx(c, h.Logger).Error("Failed to check task access", zap.Int64("owner_id", ownerID), zap.Error(err))
+		return errors.DatabaseError("Failed to get tasks", err)
+	}
+	if !allowed {
+		return errors.Forbidden("You cannot read the tasks of this user", nil).WithCode(errors.CodePermissionDenied)
+	}
+
+	tasks, err := h.Repo.GetByDateAndUser(c.UserContext(), date, ownerID)
+	if err != nil {
+		logger.FromCtx(c, h.Logger).Error("Failed to get tasks by date", zap.String("date", date), zap.Int64("user_id", ownerID), zap.Error(err))
 		return errors.DatabaseError("Failed to get tasks", err)
 	}
 
-	h.Logger.Info("Tasks retrieved successfully", zap.String("date", date), zap.Int64("user_id", user.ID), zap.Int("count", len(tasks)))
+	logger.FromCtx(c, h.Logger).Info("Tasks retrieved successfully", zap.String("date", date), zap.Int64("user_id", ownerID), zap.Int("count", len(tasks)))
 	return c.JSON(tasks)
 }
 
 // UpdateTask godoc
-// @Summary Update a task (only if owned by the authenticated user)
+// @Summary Update a task
+// @Description Own tasks need task:write; tasks of others task:write:team for users of the same company or task:write:all
 // @Tags tasks
 // @Accept json
 // @Produce json
@@ -126,46 +186,59 @@ func (h *TaskHandler) UpdateTask(c *fiber.Ctx) error {
 	idParam := c.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid task ID"})
+		return errors.BadRequest("Invalid task ID", err).WithCode(errors.CodeInvalidID)
 	}
 
-	existingTask, err := h.Repo.GetByID(id)
+	existingTask, err := h.Repo.GetByID(c.UserContext(), id)
 	if err != nil {
-		h.Logger.Error("Failed to get task", zap.Int64("task_id", id), zap.Error(err))
-		return errors.NotFound("Task not found", err)
+		logger.FromCtx(c, h.Logger).Error("Failed to get task", zap.Int64("task_id", id), zap.Error(err))
+		return errors.NotFound("Task not found", err).WithCode(errors.CodeTaskNotFound)
 	}
 
-	if existingTask.UserID != user.ID {
-		h.Logger.Error("User trying to update task they don't own", zap.Int64("task_id", id), zap.Int64("user_id", user.ID), zap.Int64("task_user_id", existingTask.UserID))
-		return errors.Forbidden("You can only update your own tasks", nil)
+	allowed, err := h.canAccess(c.UserContext(), user, existingTask.UserID, models.PermissionTaskWrite, models.PermissionTaskWriteTeam, models.PermissionTaskWriteAll)
+	if err != nil {
+		logger.FromCtx(c, h.Logger).Error("Failed to check task access", zap.Int64("task_id", id), zap.Error(err))
+		return errors.DatabaseError("Failed to update task", err)
+	}
+	if !allowed {
+		logger.FromCtx(c, h.Logger).Error("User trying to update task they may not change", zap.Int64("task_id", id), zap.Int64("user_id", user.ID), zap.Int64("task_user_id", existingTask.UserID))
+		return errors.Forbidden("You cannot update this task", nil).WithCode(errors.CodeTaskNotOwner)
 	}
 
 	var task models.DailyTask
 	if err := c.BodyParser(&task); err != nil {
-		h.Logger.Error("Failed to parse request body", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		logger.FromCtx(c, h.Logger).Error("Failed to parse request body", zap.Error(err))
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
 	}
 
 	task.ID = id
-	task.UserID = user.ID
+	task.UserID = existingTask.UserID
 
 	if err := validation.ValidateDailyTask(&task); err != nil {
-		h.Logger.Error("Validation failed", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Validation failed", "details": err.Error()})
+		logger.FromCtx(c, h.Logger).Error("Validation failed", zap.Error(err))
+		return errors.ValidationError("Validation failed", err)
 	}
 
-	updatedTask, err := h.Repo.Update(&task)
+	updatedTask, err := h.Repo.Update(c.UserContext(), &task)
 	if err != nil {
-		h.Logger.Error("Failed to update task", zap.Int64("task_id", id), zap.Int64("user_id", user.ID), zap.Error(err))
+		logger.FromCtx(c, h.Logger).Error("Failed to update task", zap.Int64("task_id", id), zap.Int64("user_id", user.ID), zap.Error(err))
 		return errors.DatabaseError("Failed to update task", err)
 	}
+	h.Audit.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditUpdate,
+		EntityType: models.AuditEntityDailyTask,
+		EntityID:   audit.ID(id),
+		Before:     existingTask,
+		After:      updatedTask,
+	})
 
-	h.Logger.Info("Task updated successfully", zap.Int64("task_id", id), zap.Int64("user_id", user.ID))
+	logger.FromCtx(c, h.Logger).Info("Task updated successfully", zap.Int64("task_id", id), zap.Int64("user_id", user.ID))
 	return c.JSON(updatedTask)
 }
 
 // DeleteTask godoc
-// @Summary Delete a task (only if owned by the authenticated user)
+// @Summary Delete a task
+// @Description Own tasks need task:write; tasks of others task:write:team for users of the same company or task:write:all
 // @Tags tasks
 // @Security BearerAuth
 // @Param id path int true "Task ID"
@@ -185,25 +258,36 @@ func (h *TaskHandler) DeleteTask(c *fiber.Ctx) error {
 	idParam := c.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid task ID"})
+		return errors.BadRequest("Invalid task ID", err).WithCode(errors.CodeInvalidID)
 	}
 
-	existingTask, err := h.Repo.GetByID(id)
+	existingTask, err := h.Repo.GetByID(c.UserContext(), id)
 	if err != nil {
-		h.Logger.Error("Failed to get task", zap.Int64("task_id", id), zap.Error(err))
-		return errors.NotFound("Task not found", err)
+		logger.FromCtx(c, h.Logger).Error("Failed to get task", zap.Int64("task_id", id), zap.Error(err))
+		return errors.NotFound("Task not found", err).WithCode(errors.CodeTaskNotFound)
 	}
 
-	if existingTask.UserID != user.ID {
-		h.Logger.Error("User trying to delete task they don't own", zap.Int64("task_id", id), zap.Int64("user_id", user.ID), zap.Int64("task_user_id", existingTask.UserID))
-		return errors.Forbidden("You can only delete your own tasks", nil)
+	allowed, err := h.canAccess(c.UserContext(), user, existingTask.UserID, models.PermissionTaskWrite, models.PermissionTaskWriteTeam, models.PermissionTaskWriteAll)
+	if err != nil {
+		logger.FromCtx(c, h.Logger).Error("Failed to check task access", zap.Int64("task_id", id), zap.Error(err))
+		return errors.DatabaseError("Failed to delete task", err)
+	}
+	if !allowed {
+		logger.FromCtx(c, h.Logger).Error("User trying to delete task they may not change", zap.Int64("task_id", id), zap.Int64("user_id", user.ID), zap.Int64("task_user_id", existingTask.UserID))
+		return errors.Forbidden("You cannot delete this task", nil).WithCode(errors.CodeTaskNotOwner)
 	}
 
-	if err := h.Repo.Delete(id); err != nil {
-		h.Logger.Error("Failed to delete task", zap.Int64("task_id", id), zap.Int64("user_id", user.ID), zap.Error(err))
+	if err := h.Repo.Delete(c.UserContext(), id); err != nil {
+		logger.FromCtx(c, h.Logger).Error("Failed to delete task", zap.Int64("task_id", id), zap.Int64("user_id", user.ID), zap.Error(err))
 		return errors.DatabaseError("Failed to delete task", err)
 	}
+	h.Audit.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditDelete,
+		EntityType: models.AuditEntityDailyTask,
+		EntityID:   audit.ID(id),
+		Before:     existingTask,
+	})
 
-	h.Logger.Info("Task deleted successfully", zap.Int64("task_id", id), zap.Int64("user_id", user.ID))
+	logger.FromCtx(c, h.Logger).Info("Task deleted successfully", zap.Int64("task_id", id), zap.Int64("user_id", user.ID))
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -2,19 +2,26 @@ package dailytask
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/alxand/nalo-workspace/internal/api/audit"
+	"github.com/alxand/nalo-workspace/internal/api/auth"
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
 	"github.com/alxand/nalo-workspace/internal/domain/models"
 	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/middleware"
 	"github.com/alxand/nalo-workspace/internal/pkg/validation"
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // MockRepository is a mock implementation of the DailyTaskInterface
@@ -22,119 +29,132 @@ type MockRepository struct {
 	mock.Mock
 }
 
-func (m *MockRepository) Create(task *models.DailyTask) error {
+func (m *MockRepository) Create(ctx context.Context, task *models.DailyTask) error {
 	args := m.Called(task)
 	return args.Error(0)
 }
 
-func (m *MockRepository) GetByID(id int64) (*models.DailyTask, error) {
+func (m *MockRepository) GetByID(ctx context.Context, id int64) (*models.DailyTask, error) {
 	args := m.Called(id)
 	return args.Get(0).(*models.DailyTask), args.Error(1)
 }
 
-func (m *MockRepository) GetByDate(date string) ([]models.DailyTask, error) {
+func (m *MockRepository) GetByDate(ctx context.Context, date string) ([]models.DailyTask, error) {
 	args := m.Called(date)
 	return args.Get(0).([]models.DailyTask), args.Error(1)
 }
 
-func (m *MockRepository) GetByDateAndUser(date string, userID int64) ([]models.DailyTask, error) {
+func (m *MockRepository) GetByDateAndUser(ctx context.Context, date string, userID int64) ([]models.DailyTask, error) {
 	args := m.Called(date, userID)
 	return args.Get(0).([]models.DailyTask), args.Error(1)
 }
 
-func (m *MockRepository) Update(task *models.DailyTask) (*models.DailyTask, error) {
+func (m *MockRepository) Update(ctx context.Context, task *models.DailyTask) (*models.DailyTask, error) {
 	args := m.Called(task)
 	return args.Get(0).(*models.DailyTask), args.Error(1)
 }
 
-func (m *MockRepository) Delete(id int64) error {
+func (m *MockRepository) Delete(ctx context.Context, id int64) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockRepository) List(limit, offset int) ([]models.DailyTask, error) {
+func (m *MockRepository) List(ctx context.Context, limit, offset int) ([]models.DailyTask, error) {
 	args := m.Called(limit, offset)
 	return args.Get(0).([]models.DailyTask), args.Error(1)
 }
 
+// stubUsers knows a fixed set of users
+type stubUsers struct {
+	interfaces.UserInterface
+	users map[int64]*models.User
+}
+
+func (s *stubUsers) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	if user, ok := s.users[id]; ok {
+		return user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// stubRolePermissions holds the default role permissions
+type stubRolePermissions struct{}
+
+func (s *stubRolePermissions) List(ctx context.Context) ([]models.RolePermission, error) {
+	var grants []models.RolePermission
+	for role, permissions := range models.DefaultRolePermissions {
+		for _, permission := range permissions {
+			grants = append(grants, models.RolePermission{Role: role, Permission: permission})
+		}
+	}
+	return grants, nil
+}
+
+func (s *stubRolePermissions) ReplaceForRole(ctx context.Context, role models.UserRole, permissions []string) error {
+	return nil
+}
+
+// stubAuditLogs keeps audit log entries in memory
+type stubAuditLogs struct {
+	interfaces.AuditLogInterface
+	entries []models.AuditLog
+}
+
+func (s *stubAuditLogs) Create(ctx context.Context, entry *models.AuditLog) error {
+	s.entries = append(s.entries, *entry)
+	return nil
+}
+
 // TestHelper provides common test utilities
 type TestHelper struct {
 	app    *fiber.App
 	repo   *MockRepository
+	users  *stubUsers
+	audit  *stubAuditLogs
 	logger *zap.Logger
+	// user is the authenticated user of every request
+	user *models.User
 }
 
 func setupTest() *TestHelper {
 	validation.Init() // Register custom validation functions
+	logger, _ := zap.NewDevelopment()
 	app := fiber.New(fiber.Config{
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			// Return error as JSON with status code
-			code := fiber.StatusInternalServerError
-			if e, ok := err.(*fiber.Error); ok {
-				code = e.Code
-			}
-			return c.Status(code).JSON(fiber.Map{"error": err.Error()})
-		},
+		ErrorHandler: middleware.ErrorHandler(logger, false),
 	})
 	repo := new(MockRepository)
-	logger, _ := zap.NewDevelopment()
-
-	handler := NewTDailyTaskHandler(repo, logger)
-
-	// Setup routes with mock user middleware
-	app.Post("/tasks", func(c *fiber.Ctx) error {
-		// Add mock user to context
-		mockUser := &models.User{
-			ID:       1,
-			Email:    "test@example.com",
-			Username: "testuser",
-			Role:     models.RoleUser,
-		}
-		c.Locals("user", mockUser)
-		return handler.CreateDailyTask(c)
-	})
-
-	app.Get("/tasks/:date", func(c *fiber.Ctx) error {
-		// Add mock user to context
-		mockUser := &models.User{
-			ID:       1,
-			Email:    "test@example.com",
-			Username: "testuser",
-			Role:     models.RoleUser,
-		}
-		c.Locals("user", mockUser)
-		return handler.GetTasksByDate(c)
-	})
-
-	app.Put("/tasks/:id", func(c *fiber.Ctx) error {
-		// Add mock user to context
-		mockUser := &models.User{
-			ID:       1,
-			Email:    "test@example.com",
-			Username: "testuser",
-			Role:     models.RoleUser,
-		}
-		c.Locals("user", mockUser)
-		return handler.UpdateTask(c)
-	})
-
-	app.Delete("/tasks/:id", func(c *fiber.Ctx) error {
-		// Add mock user to context
-		mockUser := &models.User{
-			ID:       1,
-			Email:    "test@example.com",
-			Username: "testuser",
-			Role:     models.RoleUser,
-		}
-		c.Locals("user", mockUser)
-		return handler.DeleteTask(c)
-	})
-
-	return &TestHelper{
+	companyID, otherCompanyID := int64(1), int64(2)
+	users := &stubUsers{users: map[int64]*models.User{
+		1: {ID: 1, Email: "test@example.com", Username: "testuser", Role: models.RoleUser, CompanyID: &companyID},
+		2: {ID: 2, Email: "colleague@example.com", Username: "colleague", Role: models.RoleUser, CompanyID: &companyID},
+		3: {ID: 3, Email: "outsider@example.com", Username: "outsider", Role: models.RoleUser, CompanyID: &otherCompanyID},
+		4: {ID: 4, Email: "manager@example.com", Username: "manager", Role: models.RoleManager, CompanyID: &companyID},
+	}}
+	permissions := auth.NewPermissionService(&stubRolePermissions{}, logger)
+	_ = permissions.Sync(context.Background())
+
+	auditLogs := &stubAuditLogs{}
+	handler := NewTDailyTaskHandler(repo, users, permissions, audit.NewService(config.AuditConfig{}, auditLogs, logger), logger)
+	helper := &TestHelper{
 		app:    app,
 		repo:   repo,
+		users:  users,
+		audit:  auditLogs,
 		logger: logger,
+		user:   users.users[1],
 	}
+
+	// Setup routes with mock user middleware
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", helper.user)
+		return c.Next()
+	})
+	app.Post("/tasks", handler.CreateDailyTask)
+	app.Get("/tasks/:date", handler.GetTasksByDate)
+	app.Put("/tasks/:id", handler.UpdateTask)
+	app.Delete("/tasks/:id", handler.DeleteTask)
+
+	return helper
 }
 
 func TestCreateDailyTask_Success(t *testing.T) {
@@ -307,6 +327,15 @@ func TestUpdateTask_Success(t *testing.T) {
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 
 	helper.repo.AssertExpectations(t)
+	if !assert.Len(t, helper.audit.entries, 1) {
+		t.FailNow()
+	}
+	entry := helper.audit.entries[0]
+	assert.Equal(t, models.AuditUpdate, entry.Action)
+	assert.Equal(t, models.AuditEntityDailyTask, entry.EntityType)
+	assert.Equal(t, "1", entry.EntityID)
+	assert.Contains(t, string(entry.Before), `"status":"pending"`)
+	assert.Contains(t, string(entry.After), `"status":"completed"`)
 }
 
 func TestUpdateTask_InvalidID(t *testing.T) {
@@ -370,3 +399,60 @@ func TestDeleteTask_DatabaseError(t *testing.T) {
 
 	helper.repo.AssertExpectations(t)
 }
+
+func TestGetTasksByDate_OtherUser(t *testing.T) {
+	helper := setupTest()
+
+	// Users only read their own tasks
+	req := httptest.NewRequest("GET", "/tasks/2024-01-15?user_id=2", nil)
+	resp, err := helper.app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Managers read the tasks of their company
+	helper.user = helper.users.users[4]
+	helper.repo.On("GetByDateAndUser", "2024-01-15", int64(2)).Return([]models.DailyTask{{ID: 1, UserID: 2}}, nil)
+	req = httptest.NewRequest("GET", "/tasks/2024-01-15?user_id=2", nil)
+	resp, err = helper.app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// but not of other companies, or of users they cannot see
+	for _, id := range []string{"3", "99"} {
+		req = httptest.NewRequest("GET", "/tasks/2024-01-15?user_id="+id, nil)
+		resp, err = helper.app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, id)
+	}
+
+	helper.repo.AssertExpectations(t)
+}
+
+func TestUpdateTask_NotOwner(t *testing.T) {
+	helper := setupTest()
+
+	task := models.DailyTask{
+		Day:               "Monday",
+		Date:              time.Now(),
+		StartTime:         time.Now(),
+		EndTime:           time.Now().Add(time.Hour),
+		Status:            "completed",
+		Score:             9,
+		ProductivityScore: 8,
+	}
+	existingTask := models.DailyTask{ID: 1, UserID: 2, Day: "Monday", Date: time.Now(), Status: "pending"}
+	helper.repo.On("GetByID", int64(1)).Return(&existingTask, nil)
+
+	// Managers read the tasks of their company but do not change them
+	for _, id := range []int64{1, 4} {
+		helper.user = helper.users.users[id]
+		body, _ := json.Marshal(task)
+		req := httptest.NewRequest("PUT", "/tasks/1", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := helper.app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	}
+
+	helper.repo.AssertNotCalled(t, "Update", mock.Anything)
+}
@@ -1,27 +1,149 @@
 package user
 
 import (
+	"context"
+	stderrors "errors"
 	"strconv"
+	"time"
 
+	"github.com/alxand/nalo-workspace/internal/api/audit"
 	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
 	"github.com/alxand/nalo-workspace/internal/domain/models"
 	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/alxand/nalo-workspace/internal/pkg/tenant"
+	"github.com/alxand/nalo-workspace/internal/pkg/validation"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 )
 
+// TokenRevoker ends every session of a user; implemented by auth.Service
+type TokenRevoker interface {
+	RevokeAllTokens(ctx context.Context, userID int64) error
+}
+
+// VerificationSender mails email verification links; implemented by
+// auth.VerificationService
+type VerificationSender interface {
+	SendVerification(ctx context.Context, user *models.User) error
+}
+
+// AuditRecorder records changes to users; implemented by audit.Service
+type AuditRecorder interface {
+	Record(ctx context.Context, event audit.Event)
+}
+
+// CreateUserRequest represents a user created by an admin
+type CreateUserRequest struct {
+	Email     string          `json:"email" validate:"required,email"`
+	Username  string          `json:"username" validate:"required,min=3,max=50"`
+	Password  string          `json:"password" validate:"required,min=8"`
+	FirstName string          `json:"first_name" validate:"required,min=2,max=50"`
+	LastName  string          `json:"last_name" validate:"required,min=2,max=50"`
+	Role      models.UserRole `json:"role" validate:"required,oneof=admin user manager"`
+	CountryID *int64          `json:"country_id"`
+	CompanyID *int64          `json:"company_id"`
+	// SkipVerification marks the email as verified instead of mailing a link
+	SkipVerification bool `json:"skip_verification"`
+}
+
 type UserHandler struct {
 	userRepo interfaces.UserInterface
+	revoker  TokenRevoker
+	verifier VerificationSender
+	auditor  AuditRecorder
 	logger   *zap.Logger
 }
 
-func NewUserHandler(userRepo interfaces.UserInterface, logger *zap.Logger) *UserHandler {
+func NewUserHandler(userRepo interfaces.UserInterface, revoker TokenRevoker, verifier VerificationSender, auditor AuditRecorder, logger *zap.Logger) *UserHandler {
 	return &UserHandler{
 		userRepo: userRepo,
+		revoker:  revoker,
+		verifier: verifier,
+		auditor:  auditor,
 		logger:   logger,
 	}
 }
 
+// CreateUser godoc
+// @Summary Create a user (admin only)
+// @Description Creates an active user. Unless skip_verification is set, the user is mailed a verification link.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user body CreateUserRequest true "User data"
+// @Success 201 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users [post]
+func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
+	var req CreateUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return errors.ValidationError("Validation failed", err)
+	}
+
+	exists, err := h.userRepo.ExistsByEmail(c.UserContext(), req.Email)
+	if err != nil {
+		return errors.DatabaseError("Failed to create user", err)
+	}
+	if exists {
+		return errors.Conflict("email already exists", nil).WithCode(errors.CodeEmailTaken)
+	}
+	exists, err = h.userRepo.ExistsByUsername(c.UserContext(), req.Username)
+	if err != nil {
+		return errors.DatabaseError("Failed to create user", err)
+	}
+	if exists {
+		return errors.Conflict("username already exists", nil).WithCode(errors.CodeUsernameTaken)
+	}
+
+	user := &models.User{
+		Email:     req.Email,
+		Username:  req.Username,
+		Password:  req.Password, // Will be hashed by GORM hook
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Role:      req.Role,
+		IsActive:  true,
+		CountryID: req.CountryID,
+		CompanyID: req.CompanyID,
+	}
+	if req.SkipVerification {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	if err := h.userRepo.Create(c.UserContext(), user); err != nil {
+		if stderrors.Is(err, tenant.ErrOutsideTenant) {
+			return errors.Forbidden("Users can only be created in your company", nil).WithCode(errors.CodeOutsideTenant)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to create user", zap.String("email", req.Email), zap.Error(err))
+		return errors.DatabaseError("Failed to create user", err)
+	}
+	h.auditor.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditCreate,
+		EntityType: models.AuditEntityUser,
+		EntityID:   audit.ID(user.ID),
+		After:      user,
+	})
+
+	if !req.SkipVerification {
+		if err := h.verifier.SendVerification(c.UserContext(), user); err != nil {
+			logger.FromCtx(c, h.logger).Error("Failed to send verification email", zap.Int64("user_id", user.ID), zap.Error(err))
+		}
+	}
+
+	logger.FromCtx(c, h.logger).Info("User created by admin", zap.Int64("user_id", user.ID), zap.Bool("skip_verification", req.SkipVerification))
+	return c.Status(fiber.StatusCreated).JSON(user)
+}
+
 // ListUsers godoc
 // @Summary List all users (admin only)
 // @Tags users
@@ -50,9 +172,9 @@ func (h *UserHandler) ListUsers(c *fiber.Ctx) error {
 		}
 	}
 
-	users, err := h.userRepo.List(limit, offset)
+	users, err := h.userRepo.List(c.UserContext(), limit, offset)
 	if err != nil {
-		h.logger.Error("Failed to list users", zap.Error(err))
+		logger.FromCtx(c, h.logger).Error("Failed to list users", zap.Error(err))
 		return errors.DatabaseError("Failed to list users", err)
 	}
 
@@ -75,13 +197,13 @@ func (h *UserHandler) GetUser(c *fiber.Ctx) error {
 	idParam := c.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		return errors.BadRequest("Invalid user ID", err)
+		return errors.BadRequest("Invalid user ID", err).WithCode(errors.CodeInvalidID)
 	}
 
-	user, err := h.userRepo.GetByID(id)
+	user, err := h.userRepo.GetByID(c.UserContext(), id)
 	if err != nil {
-		h.logger.Error("Failed to get user", zap.Int64("user_id", id), zap.Error(err))
-		return errors.NotFound("User not found", err)
+		logger.FromCtx(c, h.logger).Error("Failed to get user", zap.Int64("user_id", id), zap.Error(err))
+		return errors.NotFound("User not found", err).WithCode(errors.CodeUserNotFound)
 	}
 
 	return c.JSON(user)
@@ -106,30 +228,62 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 	idParam := c.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		return errors.BadRequest("Invalid user ID", err)
+		return errors.BadRequest("Invalid user ID", err).WithCode(errors.CodeInvalidID)
 	}
 
 	// Check if user exists
-	_, err = h.userRepo.GetByID(id)
+	existing, err := h.userRepo.GetByID(c.UserContext(), id)
 	if err != nil {
-		h.logger.Error("Failed to get user", zap.Int64("user_id", id), zap.Error(err))
-		return errors.NotFound("User not found", err)
+		logger.FromCtx(c, h.logger).Error("Failed to get user", zap.Int64("user_id", id), zap.Error(err))
+		return errors.NotFound("User not found", err).WithCode(errors.CodeUserNotFound)
 	}
 
 	var user models.User
 	if err := c.BodyParser(&user); err != nil {
-		h.logger.Error("Failed to parse request body", zap.Error(err))
-		return errors.BadRequest("Invalid request body", err)
+		logger.FromCtx(c, h.logger).Error("Failed to parse request body", zap.Error(err))
+		return errors.BadRequest("Invalid request body", err).WithCode(errors.CodeInvalidBody)
 	}
 
 	user.ID = id
+	// Verification state is managed by the verification flow only
+	user.EmailVerifiedAt = existing.EmailVerifiedAt
+	user.VerificationSentAt = existing.VerificationSentAt
+	user.PendingEmail = existing.PendingEmail
+	// and two-factor state by the MFA endpoints of the user
+	user.MFAEnabled = existing.MFAEnabled
+	user.MFASecret = existing.MFASecret
+	user.MFALastStep = existing.MFALastStep
+	// and preferences by the profile endpoints of the user
+	user.Timezone = existing.Timezone
+	user.Locale = existing.Locale
+	user.AvatarURL = existing.AvatarURL
+	user.Notifications = existing.Notifications
 
-	if err := h.userRepo.Update(&user); err != nil {
-		h.logger.Error("Failed to update user", zap.Int64("user_id", id), zap.Error(err))
+	if err := h.userRepo.Update(c.UserContext(), &user); err != nil {
+		if stderrors.Is(err, tenant.ErrOutsideTenant) {
+			return errors.Forbidden("Users cannot be moved out of your company", nil).WithCode(errors.CodeOutsideTenant)
+		}
+		logger.FromCtx(c, h.logger).Error("Failed to update user", zap.Int64("user_id", id), zap.Error(err))
 		return errors.DatabaseError("Failed to update user", err)
 	}
+	h.auditor.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditUpdate,
+		EntityType: models.AuditEntityUser,
+		EntityID:   audit.ID(id),
+		Before:     existing,
+		After:      &user,
+	})
+
+	// A deactivated user must not keep working on tokens issued earlier
+	if existing.IsActive && !user.IsActive {
+		if err := h.revoker.RevokeAllTokens(c.UserContext(), id); err != nil {
+			logger.FromCtx(c, h.logger).Error("Failed to revoke tokens of deactivated user", zap.Int64("user_id", id), zap.Error(err))
+			return errors.DatabaseError("Failed to revoke user tokens", err)
+		}
+		logger.FromCtx(c, h.logger).Info("Revoked tokens of deactivated user", zap.Int64("user_id", id))
+	}
 
-	h.logger.Info("User updated successfully", zap.Int64("user_id", id))
+	logger.FromCtx(c, h.logger).Info("User updated successfully", zap.Int64("user_id", id))
 	return c.JSON(user)
 }
 
@@ -149,21 +303,68 @@ func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
 	idParam := c.Params("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		return errors.BadRequest("Invalid user ID", err)
+		return errors.BadRequest("Invalid user ID", err).WithCode(errors.CodeInvalidID)
 	}
 
 	// Check if user exists
-	_, err = h.userRepo.GetByID(id)
+	existing, err := h.userRepo.GetByID(c.UserContext(), id)
 	if err != nil {
-		h.logger.Error("Failed to get user", zap.Int64("user_id", id), zap.Error(err))
-		return errors.NotFound("User not found", err)
+		logger.FromCtx(c, h.logger).Error("Failed to get user", zap.Int64("user_id", id), zap.Error(err))
+		return errors.NotFound("User not found", err).WithCode(errors.CodeUserNotFound)
 	}
 
-	if err := h.userRepo.Delete(id); err != nil {
-		h.logger.Error("Failed to delete user", zap.Int64("user_id", id), zap.Error(err))
+	if err := h.userRepo.Delete(c.UserContext(), id); err != nil {
+		logger.FromCtx(c, h.logger).Error("Failed to delete user", zap.Int64("user_id", id), zap.Error(err))
 		return errors.DatabaseError("Failed to delete user", err)
 	}
+	h.auditor.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditDelete,
+		EntityType: models.AuditEntityUser,
+		EntityID:   audit.ID(id),
+		Before:     existing,
+	})
+
+	logger.FromCtx(c, h.logger).Info("User deleted successfully", zap.Int64("user_id", id))
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RevokeUserTokens godoc
+// @Summary Revoke all tokens of a user (admin only)
+// @Description Ends every session of the user: issued access tokens stop working and refresh and personal access tokens are revoked
+// @Tags users
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/revoke-tokens [post]
+func (h *UserHandler) RevokeUserTokens(c *fiber.Ctx) error {
+	idParam := c.Params("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		return errors.BadRequest("Invalid user ID", err).WithCode(errors.CodeInvalidID)
+	}
+
+	// Check if user exists
+	_, err = h.userRepo.GetByID(c.UserContext(), id)
+	if err != nil {
+		logger.FromCtx(c, h.logger).Error("Failed to get user", zap.Int64("user_id", id), zap.Error(err))
+		return errors.NotFound("User not found", err).WithCode(errors.CodeUserNotFound)
+	}
+
+	if err := h.revoker.RevokeAllTokens(c.UserContext(), id); err != nil {
+		logger.FromCtx(c, h.logger).Error("Failed to revoke user tokens", zap.Int64("user_id", id), zap.Error(err))
+		return errors.DatabaseError("Failed to revoke user tokens", err)
+	}
+	h.auditor.Record(c.UserContext(), audit.Event{
+		Action:     models.AuditTokensRevoked,
+		EntityType: models.AuditEntityUser,
+		EntityID:   audit.ID(id),
+	})
 
-	h.logger.Info("User deleted successfully", zap.Int64("user_id", id))
+	logger.FromCtx(c, h.logger).Info("User tokens revoked", zap.Int64("user_id", id))
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -2,11 +2,14 @@ package user
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/alxand/nalo-workspace/internal/api/audit"
 	"github.com/alxand/nalo-workspace/internal/domain/models"
 	"github.com/alxand/nalo-workspace/internal/pkg/middleware"
 	"github.com/gofiber/fiber/v2"
@@ -20,12 +23,12 @@ type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) Create(user *models.User) error {
+func (m *MockUserRepository) Create(ctx context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) GetByID(id int64) (*models.User, error) {
+func (m *MockUserRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -33,7 +36,7 @@ func (m *MockUserRepository) GetByID(id int64) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetByEmail(email string) (*models.User, error) {
+func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	args := m.Called(email)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -41,7 +44,7 @@ func (m *MockUserRepository) GetByEmail(email string) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetByUsername(username string) (*models.User, error) {
+func (m *MockUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	args := m.Called(username)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -49,59 +52,160 @@ func (m *MockUserRepository) GetByUsername(username string) (*models.User, error
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetByCountry(countryID int64) ([]models.User, error) {
+func (m *MockUserRepository) GetByCountry(ctx context.Context, countryID int64) ([]models.User, error) {
 	args := m.Called(countryID)
 	return args.Get(0).([]models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetByCompany(companyID int64) ([]models.User, error) {
+func (m *MockUserRepository) GetByCompany(ctx context.Context, companyID int64) ([]models.User, error) {
 	args := m.Called(companyID)
 	return args.Get(0).([]models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) Update(user *models.User) error {
+func (m *MockUserRepository) Update(ctx context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) Delete(id int64) error {
+func (m *MockUserRepository) Delete(ctx context.Context, id int64) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) List(limit, offset int) ([]models.User, error) {
+func (m *MockUserRepository) List(ctx context.Context, limit, offset int) ([]models.User, error) {
 	args := m.Called(limit, offset)
 	return args.Get(0).([]models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) UpdateLastLogin(id int64) error {
+func (m *MockUserRepository) UpdateLastLogin(ctx context.Context, id int64) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) ExistsByEmail(email string) (bool, error) {
+func (m *MockUserRepository) RecordFailedLogin(ctx context.Context, id int64, now time.Time) (int, error) {
+	args := m.Called(id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) LockUntil(ctx context.Context, id int64, until time.Time) error {
+	args := m.Called(id, until)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ResetFailedLogins(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, id int64, hash string) error {
+	args := m.Called(id, hash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, id int64, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) MarkVerificationSent(ctx context.Context, id int64, at, notBefore time.Time) (bool, error) {
+	args := m.Called(id, at, notBefore)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) UpdateMFA(ctx context.Context, id int64, enabled bool, secret string) error {
+	args := m.Called(id, enabled, secret)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) RecordMFAStep(ctx context.Context, id int64, step int64) (bool, error) {
+	args := m.Called(id, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) UpdateRole(ctx context.Context, id int64, role models.UserRole) error {
+	args := m.Called(id, role)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateProfile(ctx context.Context, user *models.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) RequestEmailChange(ctx context.Context, id int64, email string) error {
+	args := m.Called(id, email)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ConfirmEmailChange(ctx context.Context, id int64, email string, at time.Time) (bool, error) {
+	args := m.Called(id, email, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	args := m.Called(email)
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockUserRepository) ExistsByUsername(username string) (bool, error) {
+func (m *MockUserRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
 	args := m.Called(username)
 	return args.Bool(0), args.Error(1)
 }
 
+// stubTokenRevoker records the users whose tokens were revoked
+type stubTokenRevoker struct {
+	revoked []int64
+}
+
+func (s *stubTokenRevoker) RevokeAllTokens(ctx context.Context, userID int64) error {
+	s.revoked = append(s.revoked, userID)
+	return nil
+}
+
+// stubVerificationSender records the users sent a verification link
+type stubVerificationSender struct {
+	sent []int64
+}
+
+func (s *stubVerificationSender) SendVerification(ctx context.Context, user *models.User) error {
+	s.sent = append(s.sent, user.ID)
+	return nil
+}
+
+// stubAuditRecorder keeps the recorded audit events
+type stubAuditRecorder struct {
+	events []audit.Event
+}
+
+func (s *stubAuditRecorder) Record(ctx context.Context, event audit.Event) {
+	s.events = append(s.events, event)
+}
+
 func setupTestApp() *fiber.App {
 	logger := zap.NewNop()
 	app := fiber.New(fiber.Config{
-		ErrorHandler: middleware.ErrorHandler(logger),
+		ErrorHandler: middleware.ErrorHandler(logger, false),
 	})
 	return app
 }
 
 func setupTestHandler() (*UserHandler, *MockUserRepository) {
+	handler, mockRepo, _ := setupTestHandlerWithRevoker()
+	return handler, mockRepo
+}
+
+func setupTestHandlerWithRevoker() (*UserHandler, *MockUserRepository, *stubTokenRevoker) {
+	handler, mockRepo, revoker, _ := setupTestHandlerWithStubs()
+	return handler, mockRepo, revoker
+}
+
+func setupTestHandlerWithStubs() (*UserHandler, *MockUserRepository, *stubTokenRevoker, *stubVerificationSender) {
 	mockRepo := new(MockUserRepository)
+	revoker := &stubTokenRevoker{}
+	verifier := &stubVerificationSender{}
 	logger := zap.NewNop()
-	handler := NewUserHandler(mockRepo, logger)
-	return handler, mockRepo
+	handler := NewUserHandler(mockRepo, revoker, verifier, &stubAuditRecorder{}, logger)
+	return handler, mockRepo, revoker, verifier
 }
 
 func TestListUsers_Success(t *testing.T) {
@@ -190,7 +294,7 @@ func TestListUsers_DatabaseError(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Failed to list users")
+	assert.Contains(t, response["detail"], "Failed to list users")
 
 	mockRepo.AssertExpectations(t)
 }
@@ -238,7 +342,7 @@ func TestGetUser_InvalidID(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid user ID")
+	assert.Contains(t, response["detail"], "Invalid user ID")
 
 	mockRepo.AssertNotCalled(t, "GetByID")
 }
@@ -258,7 +362,7 @@ func TestGetUser_NotFound(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "User not found")
+	assert.Contains(t, response["detail"], "User not found")
 
 	mockRepo.AssertExpectations(t)
 }
@@ -303,6 +407,39 @@ func TestUpdateUser_Success(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestUpdateUser_KeepsProfilePreferences(t *testing.T) {
+	app := setupTestApp()
+	handler, mockRepo := setupTestHandler()
+
+	existingUser := &models.User{
+		ID:            1,
+		Email:         "user@example.com",
+		Username:      "testuser",
+		Timezone:      "Europe/Berlin",
+		Locale:        "de-DE",
+		AvatarURL:     "https://example.com/avatar.png",
+		Notifications: models.NotificationPreferences{TaskReminders: true, WeeklySummary: true},
+	}
+	mockRepo.On("GetByID", int64(1)).Return(existingUser, nil).Once()
+	mockRepo.On("Update", mock.MatchedBy(func(user *models.User) bool {
+		return user.Timezone == "Europe/Berlin" && user.Locale == "de-DE" &&
+			user.AvatarURL == "https://example.com/avatar.png" &&
+			user.Notifications == existingUser.Notifications
+	})).Return(nil).Once()
+
+	app.Put("/admin/users/:id", handler.UpdateUser)
+
+	// An admin edit without the preferences leaves them as they were
+	body, _ := json.Marshal(fiber.Map{"email": "user@example.com", "username": "testuser", "first_name": "Jane", "role": "manager"})
+	req := httptest.NewRequest("PUT", "/admin/users/1", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, _ := app.Test(req)
+
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestUpdateUser_InvalidID(t *testing.T) {
 	app := setupTestApp()
 	handler, mockRepo := setupTestHandler()
@@ -319,7 +456,7 @@ func TestUpdateUser_InvalidID(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid user ID")
+	assert.Contains(t, response["detail"], "Invalid user ID")
 
 	mockRepo.AssertNotCalled(t, "GetByID")
 }
@@ -342,7 +479,7 @@ func TestUpdateUser_InvalidJSON(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid request body")
+	assert.Contains(t, response["detail"], "Invalid request body")
 
 	mockRepo.AssertExpectations(t)
 }
@@ -365,7 +502,7 @@ func TestUpdateUser_UserNotFound(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "User not found")
+	assert.Contains(t, response["detail"], "User not found")
 
 	mockRepo.AssertExpectations(t)
 }
@@ -400,7 +537,7 @@ func TestUpdateUser_DatabaseError(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Failed to update user")
+	assert.Contains(t, response["detail"], "Failed to update user")
 
 	mockRepo.AssertExpectations(t)
 }
@@ -441,7 +578,7 @@ func TestDeleteUser_InvalidID(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Invalid user ID")
+	assert.Contains(t, response["detail"], "Invalid user ID")
 
 	mockRepo.AssertNotCalled(t, "Delete")
 }
@@ -461,7 +598,7 @@ func TestDeleteUser_UserNotFound(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "User not found")
+	assert.Contains(t, response["detail"], "User not found")
 
 	mockRepo.AssertExpectations(t)
 }
@@ -488,7 +625,158 @@ func TestDeleteUser_DatabaseError(t *testing.T) {
 
 	var response map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&response)
-	assert.Contains(t, response["error"], "Failed to delete user")
+	assert.Contains(t, response["detail"], "Failed to delete user")
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateUser_DeactivationRevokesTokens(t *testing.T) {
+	app := setupTestApp()
+	handler, mockRepo, revoker := setupTestHandlerWithRevoker()
+
+	existingUser := &models.User{ID: 1, Email: "user@example.com", Username: "testuser", IsActive: true}
+	mockRepo.On("GetByID", int64(1)).Return(existingUser, nil).Once()
+	mockRepo.On("Update", mock.AnythingOfType("*models.User")).Return(nil).Once()
+
+	app.Put("/admin/users/:id", handler.UpdateUser)
+
+	body, _ := json.Marshal(fiber.Map{"email": "user@example.com", "username": "testuser", "is_active": false})
+	req := httptest.NewRequest("PUT", "/admin/users/1", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, _ := app.Test(req)
+
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, []int64{1}, revoker.revoked)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRevokeUserTokens_Success(t *testing.T) {
+	app := setupTestApp()
+	handler, mockRepo, revoker := setupTestHandlerWithRevoker()
+
+	mockRepo.On("GetByID", int64(1)).Return(&models.User{ID: 1}, nil).Once()
+
+	app.Post("/admin/users/:id/revoke-tokens", handler.RevokeUserTokens)
+
+	resp, _ := app.Test(httptest.NewRequest("POST", "/admin/users/1/revoke-tokens", nil))
+
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, []int64{1}, revoker.revoked)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRevokeUserTokens_UserNotFound(t *testing.T) {
+	app := setupTestApp()
+	handler, mockRepo, revoker := setupTestHandlerWithRevoker()
+
+	mockRepo.On("GetByID", int64(999)).Return(nil, errors.New("not found")).Once()
+
+	app.Post("/admin/users/:id/revoke-tokens", handler.RevokeUserTokens)
+
+	resp, _ := app.Test(httptest.NewRequest("POST", "/admin/users/999/revoke-tokens", nil))
+
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	assert.Empty(t, revoker.revoked)
+}
+
+func TestDeleteUser_RecordsAuditEvent(t *testing.T) {
+	app := setupTestApp()
+	mockRepo := new(MockUserRepository)
+	auditor := &stubAuditRecorder{}
+	handler := NewUserHandler(mockRepo, &stubTokenRevoker{}, &stubVerificationSender{}, auditor, zap.NewNop())
+
+	existingUser := &models.User{ID: 1, Email: "user@example.com", Username: "testuser"}
+	mockRepo.On("GetByID", int64(1)).Return(existingUser, nil).Once()
+	mockRepo.On("Delete", int64(1)).Return(nil).Once()
+
+	app.Delete("/admin/users/:id", handler.DeleteUser)
+
+	resp, _ := app.Test(httptest.NewRequest("DELETE", "/admin/users/1", nil))
+
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	if !assert.Len(t, auditor.events, 1) {
+		t.FailNow()
+	}
+	event := auditor.events[0]
+	assert.Equal(t, models.AuditDelete, event.Action)
+	assert.Equal(t, models.AuditEntityUser, event.EntityType)
+	assert.Equal(t, "1", event.EntityID)
+	assert.Equal(t, existingUser, event.Before)
+	assert.Nil(t, event.After)
+}
+
+func TestCreateUser_SendsVerification(t *testing.T) {
+	app := setupTestApp()
+	handler, mockRepo, _, verifier := setupTestHandlerWithStubs()
+
+	mockRepo.On("ExistsByEmail", "new@example.com").Return(false, nil).Once()
+	mockRepo.On("ExistsByUsername", "newuser").Return(false, nil).Once()
+	mockRepo.On("Create", mock.MatchedBy(func(u *models.User) bool {
+		return u.EmailVerifiedAt == nil && u.IsActive
+	})).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).ID = 7
+	}).Return(nil).Once()
+
+	app.Post("/admin/users", handler.CreateUser)
+
+	body, _ := json.Marshal(fiber.Map{
+		"email": "new@example.com", "username": "newuser", "password": "password123",
+		"first_name": "New", "last_name": "User", "role": "user",
+	})
+	req := httptest.NewRequest("POST", "/admin/users", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, _ := app.Test(req)
+
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	assert.Equal(t, []int64{7}, verifier.sent)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateUser_SkipVerification(t *testing.T) {
+	app := setupTestApp()
+	handler, mockRepo, _, verifier := setupTestHandlerWithStubs()
+
+	mockRepo.On("ExistsByEmail", "new@example.com").Return(false, nil).Once()
+	mockRepo.On("ExistsByUsername", "newuser").Return(false, nil).Once()
+	mockRepo.On("Create", mock.MatchedBy(func(u *models.User) bool {
+		return u.EmailVerifiedAt != nil
+	})).Return(nil).Once()
+
+	app.Post("/admin/users", handler.CreateUser)
+
+	body, _ := json.Marshal(fiber.Map{
+		"email": "new@example.com", "username": "newuser", "password": "password123",
+		"first_name": "New", "last_name": "User", "role": "manager", "skip_verification": true,
+	})
+	req := httptest.NewRequest("POST", "/admin/users", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, _ := app.Test(req)
+
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	assert.Empty(t, verifier.sent)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateUser_EmailTaken(t *testing.T) {
+	app := setupTestApp()
+	handler, mockRepo := setupTestHandler()
+
+	mockRepo.On("ExistsByEmail", "taken@example.com").Return(true, nil).Once()
+
+	app.Post("/admin/users", handler.CreateUser)
+
+	body, _ := json.Marshal(fiber.Map{
+		"email": "taken@example.com", "username": "newuser", "password": "password123",
+		"first_name": "New", "last_name": "User", "role": "user",
+	})
+	req := httptest.NewRequest("POST", "/admin/users", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, _ := app.Test(req)
 
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
 	mockRepo.AssertExpectations(t)
 }
@@ -2,8 +2,10 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -11,33 +13,102 @@ import (
 
 // Config holds all application configuration
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Log      LogConfig
+	Server       ServerConfig
+	Database     DatabaseConfig
+	JWT          JWTConfig
+	Log          LogConfig
+	Metrics      MetricsConfig
+	Tracing      TracingConfig
+	RateLimit    RateLimitConfig
+	Login        LoginConfig
+	Notifier     NotifierConfig
+	Password     PasswordConfig
+	Verification VerificationConfig
+	MFA          MFAConfig
+	AccessTokens AccessTokenConfig
+	OIDC         OIDCConfig
+	Registration RegistrationConfig
+	Permissions  PermissionConfig
+	Audit        AuditConfig
 }
 
 // ServerConfig holds server-related configuration
 type ServerConfig struct {
-	Port         int
-	Host         string
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
+	Port           int
+	Host           string
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+	IdleTimeout    time.Duration
+	RequestTimeout time.Duration
+	// HealthCheckTimeout bounds each readiness check
+	HealthCheckTimeout time.Duration
+	// ShutdownDelay is how long readiness fails before the server drains
+	ShutdownDelay time.Duration
+	// ProxyHeader names the header carrying the client IP behind a trusted
+	// reverse proxy, e.g. X-Forwarded-For
+	ProxyHeader string
+	// TrustedProxies lists the proxy IPs and CIDR ranges ProxyHeader is
+	// read from; requests from any other peer are keyed by their own IP
+	TrustedProxies []string
+	// Debug exposes internal error details in API responses. Never enable
+	// it in production.
+	Debug bool
+	// PublicURL is the base URL of links sent to users, e.g. password resets
+	PublicURL string
 }
 
 // DatabaseConfig holds database-related configuration
 type DatabaseConfig struct {
-	Driver   string
-	DSN      string
-	TestDSN  string
-	MaxConns int
+	Driver       string
+	DSN          string
+	TestDSN      string
+	MaxConns     int
+	AutoMigrate  bool
+	QueryTimeout time.Duration
 }
 
 // JWTConfig holds JWT-related configuration
 type JWTConfig struct {
-	Secret     string
-	Expiration time.Duration
+	Secret string
+	// Expiration is the lifetime of access tokens; keep it short since
+	// refresh tokens keep sessions alive
+	Expiration        time.Duration
+	RefreshExpiration time.Duration
+	// ImpersonationExpiration is the lifetime of tokens admins get to act
+	// as another user, capped at Expiration
+	ImpersonationExpiration time.Duration
+	// RevocationSyncInterval is how often revoked tokens are reloaded from
+	// the database and expired revocations deleted
+	RevocationSyncInterval time.Duration
+
+	// Algorithm signs tokens: HS256 with Secret, or RS256 or EdDSA with key
+	// pairs whose public keys are published as a JWKS
+	Algorithm string
+	// KeysDir loads the key pairs from PEM files instead of generating them
+	KeysDir string
+	// SigningKeyID picks the file key that signs; the last by name otherwise
+	SigningKeyID string
+	// KeyEncryptionKey encrypts generated private keys at rest; derived
+	// from Secret when empty
+	KeyEncryptionKey string
+	// KeyRotationInterval is how often a new key pair is generated
+	KeyRotationInterval time.Duration
+	// KeyRetention is how long a replaced key still verifies tokens
+	KeyRetention time.Duration
+	// KeySyncInterval is how often keys are reloaded and rotation is due
+	KeySyncInterval time.Duration
+}
+
+// Supported JWT signing algorithms
+const (
+	JWTAlgorithmHS256 = "HS256"
+	JWTAlgorithmRS256 = "RS256"
+	JWTAlgorithmEdDSA = "EdDSA"
+)
+
+// Asymmetric reports whether tokens are signed with key pairs
+func (c JWTConfig) Asymmetric() bool {
+	return c.Algorithm != JWTAlgorithmHS256
 }
 
 // LogConfig holds logging-related configuration
@@ -46,6 +117,179 @@ type LogConfig struct {
 	Format string
 }
 
+// MetricsConfig holds Prometheus endpoint configuration
+type MetricsConfig struct {
+	Enabled bool
+	// AllowedNetworks lists the networks allowed to scrape /metrics
+	AllowedNetworks []*net.IPNet
+	// Token, when set, is required as a bearer token on /metrics
+	Token string
+}
+
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	// Exporter is one of "none", "stdout" or "otlp"
+	Exporter    string
+	ServiceName string
+	// OTLPEndpoint overrides OTEL_EXPORTER_OTLP_ENDPOINT when set
+	OTLPEndpoint string
+	SampleRatio  float64
+}
+
+// RateLimit allows Requests per Window, refilled continuously
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RateLimitConfig holds request rate limiting configuration
+type RateLimitConfig struct {
+	Enabled bool
+	// Public limits unauthenticated auth endpoints per client IP
+	Public RateLimit
+	// User limits authenticated endpoints per user ID
+	User RateLimit
+}
+
+// LoginConfig holds brute-force protection for password logins
+type LoginConfig struct {
+	// MaxAttempts is the number of consecutive failures that locks an account
+	MaxAttempts     int
+	LockoutDuration time.Duration
+	// DelayBase is the delay after the first failure, doubled on each
+	// further failure up to DelayMax
+	DelayBase time.Duration
+	DelayMax  time.Duration
+}
+
+// NotifierConfig selects how emails to users are delivered
+type NotifierConfig struct {
+	// Driver is log, file or smtp
+	Driver   string
+	FilePath string
+	From     string
+	SMTP     SMTPConfig
+}
+
+// SMTPConfig holds the outgoing mail server settings
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// PasswordConfig holds password recovery settings
+type PasswordConfig struct {
+	ResetTokenTTL time.Duration
+}
+
+// Email verification modes
+const (
+	// VerificationOptional sends verification links but does not enforce them
+	VerificationOptional = "optional"
+	// VerificationLogin refuses logins until the email is verified
+	VerificationLogin = "login"
+	// VerificationRestrict allows logins but only the auth routes until the
+	// email is verified
+	VerificationRestrict = "restrict"
+)
+
+// VerificationConfig holds email verification settings
+type VerificationConfig struct {
+	// Mode is optional, login or restrict
+	Mode     string
+	TokenTTL time.Duration
+	// ResendInterval is the minimum time between two verification emails
+	// to the same account
+	ResendInterval time.Duration
+}
+
+// Enforced reports whether unverified users are kept out of the API
+func (c VerificationConfig) Enforced() bool {
+	return c.Mode == VerificationLogin || c.Mode == VerificationRestrict
+}
+
+// MFAConfig holds two-factor authentication settings
+type MFAConfig struct {
+	// Issuer is the account label shown in authenticator apps
+	Issuer string
+	// EncryptionKey encrypts stored TOTP secrets; derived from the JWT
+	// secret when empty
+	EncryptionKey string
+	// RequiredRoles must enroll a second factor before using the API
+	RequiredRoles []string
+}
+
+// Requires reports whether users with role must use two-factor
+// authentication
+func (c MFAConfig) Requires(role string) bool {
+	for _, required := range c.RequiredRoles {
+		if required == role {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessTokenConfig holds personal access token settings
+type AccessTokenConfig struct {
+	// DefaultLifetime applies when a token is created without an expiry
+	DefaultLifetime time.Duration
+	// MaxLifetime caps the expiry users can choose
+	MaxLifetime time.Duration
+}
+
+// OIDCConfig holds OpenID Connect single sign-on settings. SSO is off
+// unless IssuerURL is set.
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback URL registered with the provider
+	RedirectURL string
+	Scopes      []string
+	// RoleClaim names the ID token claim, a string or a list of strings,
+	// that RoleMapping maps onto user roles
+	RoleClaim   string
+	RoleMapping map[string]string
+	// DefaultRole is given to provisioned users no mapping matches
+	DefaultRole string
+	// AllowSignup provisions unknown users on their first login
+	AllowSignup bool
+	// LinkByEmail links an existing account with the same verified email
+	LinkByEmail bool
+}
+
+// Enabled reports whether single sign-on is configured
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != ""
+}
+
+// RegistrationConfig holds account creation settings
+type RegistrationConfig struct {
+	// Enabled allows public self-registration; invitations work either way
+	Enabled bool
+	// InvitationTTL is how long an invitation link stays valid
+	InvitationTTL time.Duration
+}
+
+// PermissionConfig holds authorization settings
+type PermissionConfig struct {
+	// SyncInterval is how often role permissions are reloaded, so changes
+	// made through another instance take effect
+	SyncInterval time.Duration
+}
+
+// AuditConfig holds audit log settings
+type AuditConfig struct {
+	// Retention is how long audit log entries are kept; zero keeps them
+	// forever
+	Retention time.Duration
+	// PruneInterval is how often entries past the retention are deleted
+	PruneInterval time.Duration
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	// Load .env file if it exists
@@ -62,25 +306,67 @@ func Load() (*Config, error) {
 	}
 
 	config.Server = ServerConfig{
-		Port:         port,
-		Host:         getEnv("HOST", "0.0.0.0"),
-		ReadTimeout:  getDurationEnv("READ_TIMEOUT", 30*time.Second),
-		WriteTimeout: getDurationEnv("WRITE_TIMEOUT", 30*time.Second),
-		IdleTimeout:  getDurationEnv("IDLE_TIMEOUT", 60*time.Second),
+		Port:               port,
+		Host:               getEnv("HOST", "0.0.0.0"),
+		ReadTimeout:        getDurationEnv("READ_TIMEOUT", 30*time.Second),
+		WriteTimeout:       getDurationEnv("WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:        getDurationEnv("IDLE_TIMEOUT", 60*time.Second),
+		RequestTimeout:     getDurationEnv("REQUEST_TIMEOUT", 20*time.Second),
+		HealthCheckTimeout: getDurationEnv("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		ShutdownDelay:      getDurationEnv("SHUTDOWN_DELAY", 5*time.Second),
+		ProxyHeader:        getEnv("PROXY_HEADER", ""),
+		TrustedProxies:     getListEnv("TRUSTED_PROXIES", nil),
+		Debug:              getBoolEnv("DEBUG", false),
+		PublicURL:          strings.TrimSuffix(getEnv("PUBLIC_URL", "http://localhost:3000"), "/"),
+	}
+
+	if config.Server.ProxyHeader != "" && len(config.Server.TrustedProxies) == 0 {
+		return nil, fmt.Errorf("TRUSTED_PROXIES is required when PROXY_HEADER is set")
+	}
+	for _, proxy := range config.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: use an IP or CIDR range", proxy)
+		}
 	}
 
 	// Database config
 	config.Database = DatabaseConfig{
-		Driver:   getEnv("DB_DRIVER", "postgres"),
-		DSN:      getRequiredEnv("DSN"),
-		TestDSN:  getEnv("TEST_DSN", ":memory:"),
-		MaxConns: getIntEnv("DB_MAX_CONNS", 10),
+		Driver:       getEnv("DB_DRIVER", "postgres"),
+		DSN:          getRequiredEnv("DSN"),
+		TestDSN:      getEnv("TEST_DSN", ":memory:"),
+		MaxConns:     getIntEnv("DB_MAX_CONNS", 10),
+		AutoMigrate:  getBoolEnv("DB_AUTO_MIGRATE", false),
+		QueryTimeout: getDurationEnv("DB_QUERY_TIMEOUT", 5*time.Second),
 	}
 
 	// JWT config
 	config.JWT = JWTConfig{
-		Secret:     getRequiredEnv("JWT_SECRET"),
-		Expiration: getDurationEnv("JWT_EXPIRATION", 24*time.Hour),
+		Secret:                  getRequiredEnv("JWT_SECRET"),
+		Expiration:              getDurationEnv("JWT_EXPIRATION", 15*time.Minute),
+		RefreshExpiration:       getDurationEnv("JWT_REFRESH_EXPIRATION", 30*24*time.Hour),
+		ImpersonationExpiration: getDurationEnv("JWT_IMPERSONATION_EXPIRATION", 10*time.Minute),
+		RevocationSyncInterval:  getDurationEnv("JWT_REVOCATION_SYNC_INTERVAL", 30*time.Second),
+		Algorithm:               getEnv("JWT_ALGORITHM", JWTAlgorithmHS256),
+		KeysDir:                 getEnv("JWT_KEYS_DIR", ""),
+		SigningKeyID:            getEnv("JWT_SIGNING_KEY_ID", ""),
+		KeyEncryptionKey:        getEnv("JWT_KEY_ENCRYPTION_KEY", ""),
+		KeyRotationInterval:     getDurationEnv("JWT_KEY_ROTATION_INTERVAL", 30*24*time.Hour),
+		KeyRetention:            getDurationEnv("JWT_KEY_RETENTION", 24*time.Hour),
+		KeySyncInterval:         getDurationEnv("JWT_KEY_SYNC_INTERVAL", time.Minute),
+	}
+	switch config.JWT.Algorithm {
+	case JWTAlgorithmHS256, JWTAlgorithmRS256, JWTAlgorithmEdDSA:
+	default:
+		return nil, fmt.Errorf("invalid JWT_ALGORITHM %q: use HS256, RS256 or EdDSA", config.JWT.Algorithm)
+	}
+	if config.JWT.Asymmetric() {
+		// Tokens signed by a replaced key must stay valid until they expire
+		if config.JWT.KeyRetention < config.JWT.Expiration {
+			return nil, fmt.Errorf("JWT_KEY_RETENTION must be at least JWT_EXPIRATION")
+		}
+		if config.JWT.KeySyncInterval <= 0 || config.JWT.KeyRotationInterval <= 2*config.JWT.KeySyncInterval {
+			return nil, fmt.Errorf("JWT_KEY_ROTATION_INTERVAL must be more than twice JWT_KEY_SYNC_INTERVAL")
+		}
 	}
 
 	// Log config
@@ -89,6 +375,155 @@ func Load() (*Config, error) {
 		Format: getEnv("LOG_FORMAT", "json"),
 	}
 
+	// Metrics config
+	var networks []*net.IPNet
+	for _, cidr := range getListEnv("METRICS_ALLOWED_NETWORKS", []string{"127.0.0.1/32", "::1/128"}) {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid METRICS_ALLOWED_NETWORKS: %w", err)
+		}
+		networks = append(networks, network)
+	}
+
+	config.Metrics = MetricsConfig{
+		Enabled:         getBoolEnv("METRICS_ENABLED", true),
+		AllowedNetworks: networks,
+		Token:           getEnv("METRICS_TOKEN", ""),
+	}
+
+	// Tracing config
+	config.Tracing = TracingConfig{
+		Exporter:     getEnv("TRACING_EXPORTER", "none"),
+		ServiceName:  getEnv("TRACING_SERVICE_NAME", "nalo-workspace-api"),
+		OTLPEndpoint: getEnv("TRACING_OTLP_ENDPOINT", ""),
+		SampleRatio:  getFloatEnv("TRACING_SAMPLE_RATIO", 1.0),
+	}
+
+	// Rate limit config
+	config.RateLimit = RateLimitConfig{
+		Enabled: getBoolEnv("RATE_LIMIT_ENABLED", true),
+		Public: RateLimit{
+			Requests: getIntEnv("RATE_LIMIT_PUBLIC_REQUESTS", 10),
+			Window:   getDurationEnv("RATE_LIMIT_PUBLIC_WINDOW", time.Minute),
+		},
+		User: RateLimit{
+			Requests: getIntEnv("RATE_LIMIT_USER_REQUESTS", 300),
+			Window:   getDurationEnv("RATE_LIMIT_USER_WINDOW", time.Minute),
+		},
+	}
+	if config.RateLimit.Enabled {
+		if public := config.RateLimit.Public; public.Requests <= 0 || public.Window <= 0 {
+			return nil, fmt.Errorf("RATE_LIMIT_PUBLIC_REQUESTS and RATE_LIMIT_PUBLIC_WINDOW must be positive")
+		}
+		if user := config.RateLimit.User; user.Requests <= 0 || user.Window <= 0 {
+			return nil, fmt.Errorf("RATE_LIMIT_USER_REQUESTS and RATE_LIMIT_USER_WINDOW must be positive")
+		}
+	}
+
+	// Login protection config
+	config.Login = LoginConfig{
+		MaxAttempts:     getIntEnv("LOGIN_MAX_ATTEMPTS", 5),
+		LockoutDuration: getDurationEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		DelayBase:       getDurationEnv("LOGIN_DELAY_BASE", 250*time.Millisecond),
+		DelayMax:        getDurationEnv("LOGIN_DELAY_MAX", 4*time.Second),
+	}
+
+	// Notifier config
+	config.Notifier = NotifierConfig{
+		Driver:   getEnv("NOTIFIER_DRIVER", "log"),
+		FilePath: getEnv("NOTIFIER_FILE_PATH", "notifications.log"),
+		From:     getEnv("MAIL_FROM", "no-reply@localhost"),
+		SMTP: SMTPConfig{
+			Host:     getEnv("SMTP_HOST", "localhost"),
+			Port:     getIntEnv("SMTP_PORT", 587),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+		},
+	}
+
+	// Password config
+	config.Password = PasswordConfig{
+		ResetTokenTTL: getDurationEnv("PASSWORD_RESET_TOKEN_TTL", time.Hour),
+	}
+
+	// Email verification config
+	config.Verification = VerificationConfig{
+		Mode:           getEnv("EMAIL_VERIFICATION_MODE", VerificationOptional),
+		TokenTTL:       getDurationEnv("EMAIL_VERIFICATION_TOKEN_TTL", 48*time.Hour),
+		ResendInterval: getDurationEnv("EMAIL_VERIFICATION_RESEND_INTERVAL", 2*time.Minute),
+	}
+	switch config.Verification.Mode {
+	case VerificationOptional, VerificationLogin, VerificationRestrict:
+	default:
+		return nil, fmt.Errorf("invalid EMAIL_VERIFICATION_MODE %q", config.Verification.Mode)
+	}
+
+	// Two-factor authentication config
+	config.MFA = MFAConfig{
+		Issuer:        getEnv("MFA_ISSUER", "Nalo Workspace"),
+		EncryptionKey: getEnv("MFA_ENCRYPTION_KEY", ""),
+		RequiredRoles: getListEnv("MFA_REQUIRED_ROLES", nil),
+	}
+
+	// Personal access token config
+	config.AccessTokens = AccessTokenConfig{
+		DefaultLifetime: getDurationEnv("ACCESS_TOKEN_DEFAULT_LIFETIME", 30*24*time.Hour),
+		MaxLifetime:     getDurationEnv("ACCESS_TOKEN_MAX_LIFETIME", 365*24*time.Hour),
+	}
+
+	// OpenID Connect config
+	config.OIDC = OIDCConfig{
+		IssuerURL:    getEnv("OIDC_ISSUER_URL", ""),
+		ClientID:     getEnv("OIDC_CLIENT_ID", ""),
+		ClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:  getEnv("OIDC_REDIRECT_URL", ""),
+		Scopes:       getListEnv("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+		RoleClaim:    getEnv("OIDC_ROLE_CLAIM", "groups"),
+		RoleMapping:  getMapEnv("OIDC_ROLE_MAPPING"),
+		DefaultRole:  getEnv("OIDC_DEFAULT_ROLE", "user"),
+		AllowSignup:  getBoolEnv("OIDC_ALLOW_SIGNUP", false),
+		LinkByEmail:  getBoolEnv("OIDC_LINK_BY_EMAIL", false),
+	}
+	if config.OIDC.Enabled() {
+		if config.OIDC.ClientID == "" || config.OIDC.RedirectURL == "" {
+			return nil, fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required when OIDC_ISSUER_URL is set")
+		}
+		roles := []string{config.OIDC.DefaultRole}
+		for _, role := range config.OIDC.RoleMapping {
+			roles = append(roles, role)
+		}
+		for _, role := range roles {
+			switch role {
+			case "admin", "manager", "user":
+			default:
+				return nil, fmt.Errorf("invalid OIDC role %q", role)
+			}
+		}
+	}
+
+	// Registration config
+	config.Registration = RegistrationConfig{
+		Enabled:       getBoolEnv("REGISTRATION_ENABLED", true),
+		InvitationTTL: getDurationEnv("INVITATION_TTL", 7*24*time.Hour),
+	}
+
+	// Permission config
+	config.Permissions = PermissionConfig{
+		SyncInterval: getDurationEnv("PERMISSION_SYNC_INTERVAL", 30*time.Second),
+	}
+
+	// Audit config
+	config.Audit = AuditConfig{
+		Retention:     getDurationEnv("AUDIT_RETENTION", 365*24*time.Hour),
+		PruneInterval: getDurationEnv("AUDIT_PRUNE_INTERVAL", time.Hour),
+	}
+	if config.Audit.Retention < 0 {
+		return nil, fmt.Errorf("AUDIT_RETENTION must not be negative")
+	}
+	if config.Audit.PruneInterval <= 0 {
+		return nil, fmt.Errorf("AUDIT_PRUNE_INTERVAL must be positive")
+	}
+
 	return config, nil
 }
 
@@ -117,6 +552,52 @@ func getIntEnv(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getBoolEnv(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+func getListEnv(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getMapEnv parses "key=value" pairs separated by commas
+func getMapEnv(key string) map[string]string {
+	items := make(map[string]string)
+	for _, item := range getListEnv(key, nil) {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		items[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return items
+}
+
 func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
@@ -22,6 +22,18 @@ type DBConnector interface {
 	Connect() (*gorm.DB, error)
 }
 
+// NewConnector returns the connector matching the configured driver
+func NewConnector(dbConfig DatabaseConfig) (DBConnector, error) {
+	switch dbConfig.Driver {
+	case "postgres":
+		return NewPostgresConfig(dbConfig.DSN), nil
+	case "sqlite":
+		return NewSQLiteConfig(dbConfig.DSN), nil
+	default:
+		return nil, ErrUnsupportedDatabase
+	}
+}
+
 // PostgresConfig holds configuration for a PostgreSQL database connection.
 type PostgresConfig struct {
 	DSN string
@@ -41,10 +53,6 @@ func (p *PostgresConfig) Connect() (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to connect to postgres database: %w", err)
 	}
 
-	if err := RunMigrations(db); err != nil {
-		return nil, fmt.Errorf("gorm postgres failed to run migration: %w", err)
-	}
-
 	return db, nil
 }
 
@@ -67,9 +75,13 @@ func (s *SQLiteConfig) Connect() (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to connect to sqlite database: %w", err)
 	}
 
-	if err := RunMigrations(db); err != nil {
-		return nil, fmt.Errorf("gorm sqlite failed to run migration: %w", err)
+	// SQLite allows a single writer, and every new connection to ":memory:"
+	// opens a separate empty database, so keep the pool to one connection.
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sqlite connection pool: %w", err)
 	}
+	sqlDB.SetMaxOpenConns(1)
 
 	return db, nil
 }
@@ -1,6 +1,10 @@
 package container
 
 import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/api/audit"
 	"github.com/alxand/nalo-workspace/internal/api/auth"
 	"github.com/alxand/nalo-workspace/internal/api/company"
 	"github.com/alxand/nalo-workspace/internal/api/continent"
@@ -9,19 +13,33 @@ import (
 	"github.com/alxand/nalo-workspace/internal/api/user"
 	"github.com/alxand/nalo-workspace/internal/config"
 	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/migrations"
+	"github.com/alxand/nalo-workspace/internal/pkg/health"
 	"github.com/alxand/nalo-workspace/internal/pkg/logger"
-	postgresRepo "github.com/alxand/nalo-workspace/internal/repository/postgres"
+	"github.com/alxand/nalo-workspace/internal/pkg/metrics"
+	"github.com/alxand/nalo-workspace/internal/pkg/notify"
+	"github.com/alxand/nalo-workspace/internal/pkg/ratelimit"
+	"github.com/alxand/nalo-workspace/internal/pkg/tracing"
+	"github.com/alxand/nalo-workspace/internal/pkg/validation"
+	"github.com/alxand/nalo-workspace/internal/repository"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 // Container holds all application dependencies
 type Container struct {
-	Config *config.Config
-	Logger *zap.Logger
-	DB     *gorm.DB
+	Config   *config.Config
+	Logger   *zap.Logger
+	DB       *gorm.DB
+	Migrator *migrations.Migrator
+	Health   *health.Registry
+
+	// RateLimitStore holds rate limit buckets; swap in a shared store to
+	// enforce limits across instances
+	RateLimitStore ratelimit.Store
 
 	// Repositories
+	TxManager     interfaces.TransactionManager
 	DailyTaskRepo interfaces.DailyTaskInterface
 	UserRepo      interfaces.UserInterface
 	ContinentRepo interfaces.ContinentInterface
@@ -29,15 +47,44 @@ type Container struct {
 	CompanyRepo   interfaces.CompanyInterface
 
 	// Services
-	AuthService *auth.Service
+	AuthService          *auth.Service
+	PasswordService      *auth.PasswordService
+	VerificationService  *auth.VerificationService
+	MFAService           *auth.MFAService
+	AccessTokenService   *auth.AccessTokenService
+	OIDCService          *auth.OIDCService
+	InvitationService    *auth.InvitationService
+	PermissionService    *auth.PermissionService
+	ImpersonationService *auth.ImpersonationService
+	ProfileService       *auth.ProfileService
+	SessionService       *auth.SessionService
+	AuditService         *audit.Service
 
 	// Handlers
-	DailyTaskHandler *dailytask.TaskHandler
-	AuthHandler      *auth.AuthHandler
-	UserHandler      *user.UserHandler
-	ContinentHandler *continent.ContinentHandler
-	CountryHandler   *country.CountryHandler
-	CompanyHandler   *company.CompanyHandler
+	DailyTaskHandler     *dailytask.TaskHandler
+	AuthHandler          *auth.AuthHandler
+	PasswordHandler      *auth.PasswordHandler
+	VerificationHandler  *auth.VerificationHandler
+	MFAHandler           *auth.MFAHandler
+	AccessTokenHandler   *auth.AccessTokenHandler
+	OIDCHandler          *auth.OIDCHandler
+	JWKSHandler          *auth.JWKSHandler
+	InvitationHandler    *auth.InvitationHandler
+	PermissionHandler    *auth.PermissionHandler
+	ImpersonationHandler *auth.ImpersonationHandler
+	ProfileHandler       *auth.ProfileHandler
+	SessionHandler       *auth.SessionHandler
+	UserHandler          *user.UserHandler
+	ContinentHandler     *continent.ContinentHandler
+	CountryHandler       *country.CountryHandler
+	CompanyHandler       *company.CompanyHandler
+	AuditHandler         *audit.AuditHandler
+
+	shutdownTracing tracing.ShutdownFunc
+	stopRevocations func()
+	stopKeys        func()
+	stopPermissions func()
+	stopAudit       func()
 }
 
 // NewContainer creates a new container with all dependencies initialized
@@ -54,56 +101,207 @@ func NewContainer() (*Container, error) {
 	}
 	log := logger.Get()
 
+	// Register custom validation rules before any handler validates input
+	validation.Init()
+
+	// Initialize tracing before anything that opens spans
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize database
 	db, err := initDatabase(cfg.Database)
 	if err != nil {
 		return nil, err
 	}
 
-	// Auto-migrate models
-	if err := config.RunMigrations(db); err != nil {
+	// Make sure the schema is current before serving traffic
+	migrator, err := migrations.New(db, cfg.Database.Driver)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureSchema(migrator, cfg.Database.AutoMigrate, log); err != nil {
 		return nil, err
 	}
 
-	// Initialize repositories
-	dailyTaskRepo := postgresRepo.NewDailyTaskRepository(db)
-	userRepo := postgresRepo.NewUserRepository(db)
-	continentRepo := postgresRepo.NewContinentRepository(db)
-	countryRepo := postgresRepo.NewCountryRepository(db)
-	companyRepo := postgresRepo.NewCompanyRepository(db)
+	// Initialize repositories for the configured driver
+	repoFactory, err := repository.NewFactory(cfg.Database.Driver)
+	if err != nil {
+		return nil, err
+	}
+	repos := repoFactory.New(db)
+	dailyTaskRepo := repos.DailyTasks
+	userRepo := repos.Users
+	continentRepo := repos.Continents
+	countryRepo := repos.Countries
+	companyRepo := repos.Companies
+	txManager := repository.NewTransactionManager(db, repoFactory)
+
+	// Register readiness checks
+	healthRegistry := health.NewRegistry(cfg.Server.HealthCheckTimeout)
+	healthRegistry.Register("database", func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+	healthRegistry.Register("migrations", func(ctx context.Context) error {
+		return migrator.CheckCurrent(ctx)
+	})
+
+	// Load token revocations before serving so revoked tokens are refused
+	// from the first request
+	revocations := auth.NewRevocationList(repos.RevokedTokens, log)
+	if err := revocations.Sync(context.Background()); err != nil {
+		return nil, err
+	}
+	stopRevocations := revocations.Start(cfg.JWT.RevocationSyncInterval)
+
+	// Load, and when due rotate, the signing keys before serving
+	keys, err := auth.NewKeyRing(cfg.JWT, repos.SigningKeys, log)
+	if err != nil {
+		return nil, err
+	}
+	if err := keys.Sync(context.Background()); err != nil {
+		return nil, err
+	}
+	stopKeys := keys.Start(cfg.JWT.KeySyncInterval)
+	// Whoever can verify HS256 tokens can also forge them
+	if !cfg.JWT.Asymmetric() {
+		log.Warn("Access tokens are signed with the shared JWT_SECRET; set JWT_ALGORITHM to RS256 or EdDSA", zap.String("algorithm", cfg.JWT.Algorithm))
+	}
+
+	// Load role permissions before serving so every request is authorized
+	// against them
+	permissionService := auth.NewPermissionService(repos.RolePermissions, log)
+	if err := permissionService.Sync(context.Background()); err != nil {
+		return nil, err
+	}
+	stopPermissions := permissionService.Start(cfg.Permissions.SyncInterval)
+
+	// Drop audit log entries past their retention in the background
+	auditService := audit.NewService(cfg.Audit, repos.AuditLogs, log)
+	stopAudit := auditService.Start(cfg.Audit.PruneInterval)
 
 	// Initialize services
-	authService := auth.NewService(cfg.JWT, userRepo, log)
+	authService := auth.NewService(cfg.JWT, cfg.Login, cfg.Verification, userRepo, repos.RefreshTokens, repos.Sessions, repos.AccessTokens, revocations, keys, txManager, log)
+	notifier, err := notify.New(cfg.Notifier, log)
+	if err != nil {
+		return nil, err
+	}
+	passwordService := auth.NewPasswordService(cfg.Password, cfg.Server.PublicURL, authService, repos.PasswordResetTokens, notifier, log)
+	verificationService := auth.NewVerificationService(cfg.Verification, cfg.JWT.Secret, cfg.Server.PublicURL, userRepo, notifier, log)
+	mfaService, err := auth.NewMFAService(cfg.MFA, cfg.JWT.Secret, authService, repos.MFARecoveryCodes, log)
+	if err != nil {
+		return nil, err
+	}
+	accessTokenService := auth.NewAccessTokenService(cfg.AccessTokens, userRepo, repos.AccessTokens, log)
+	oidcService, err := auth.NewOIDCService(cfg.OIDC, cfg.JWT.Secret, authService, repos.ExternalIdentities, log)
+	if err != nil {
+		return nil, err
+	}
+	invitationService := auth.NewInvitationService(cfg.Registration, cfg.Server.PublicURL, repos.Invitations, companyRepo, countryRepo, txManager, permissionService, notifier, log)
+	impersonationService := auth.NewImpersonationService(authService, userRepo, permissionService, log)
+	profileService := auth.NewProfileService(userRepo, countryRepo, verificationService, log)
+	sessionService := auth.NewSessionService(authService, repos.Sessions, userRepo, log)
 
 	// Initialize handlers
-	dailyTaskHandler := dailytask.NewTDailyTaskHandler(dailyTaskRepo, log)
-	authHandler := auth.NewAuthHandler(authService, log)
-	userHandler := user.NewUserHandler(userRepo, log)
-	continentHandler := continent.NewContinentHandler(continentRepo, log)
-	countryHandler := country.NewCountryHandler(countryRepo, log)
-	companyHandler := company.NewCompanyHandler(companyRepo, log)
+	dailyTaskHandler := dailytask.NewTDailyTaskHandler(dailyTaskRepo, userRepo, permissionService, auditService, log)
+	authHandler := auth.NewAuthHandler(authService, verificationService, auditService, log)
+	passwordHandler := auth.NewPasswordHandler(passwordService, auditService, log)
+	verificationHandler := auth.NewVerificationHandler(verificationService, auditService, log)
+	mfaHandler := auth.NewMFAHandler(mfaService, auditService, log)
+	accessTokenHandler := auth.NewAccessTokenHandler(accessTokenService, auditService, log)
+	oidcHandler := auth.NewOIDCHandler(oidcService, auditService, log)
+	jwksHandler := auth.NewJWKSHandler(keys, log)
+	invitationHandler := auth.NewInvitationHandler(invitationService, auditService, log)
+	permissionHandler := auth.NewPermissionHandler(permissionService, auditService, log)
+	impersonationHandler := auth.NewImpersonationHandler(impersonationService, auditService, log)
+	profileHandler := auth.NewProfileHandler(profileService, auditService, log)
+	sessionHandler := auth.NewSessionHandler(sessionService, auditService, log)
+	userHandler := user.NewUserHandler(userRepo, authService, verificationService, auditService, log)
+	continentHandler := continent.NewContinentHandler(continentRepo, auditService, log)
+	countryHandler := country.NewCountryHandler(countryRepo, auditService, log)
+	companyHandler := company.NewCompanyHandler(companyRepo, auditService, log)
+	auditHandler := audit.NewAuditHandler(auditService, log)
 
 	return &Container{
-		Config:           cfg,
-		Logger:           log,
-		DB:               db,
-		DailyTaskRepo:    dailyTaskRepo,
-		UserRepo:         userRepo,
-		ContinentRepo:    continentRepo,
-		CountryRepo:      countryRepo,
-		CompanyRepo:      companyRepo,
-		AuthService:      authService,
-		DailyTaskHandler: dailyTaskHandler,
-		AuthHandler:      authHandler,
-		UserHandler:      userHandler,
-		ContinentHandler: continentHandler,
-		CountryHandler:   countryHandler,
-		CompanyHandler:   companyHandler,
+		Config:               cfg,
+		Logger:               log,
+		DB:                   db,
+		Migrator:             migrator,
+		Health:               healthRegistry,
+		RateLimitStore:       ratelimit.NewMemoryStore(),
+		TxManager:            txManager,
+		DailyTaskRepo:        dailyTaskRepo,
+		UserRepo:             userRepo,
+		ContinentRepo:        continentRepo,
+		CountryRepo:          countryRepo,
+		CompanyRepo:          companyRepo,
+		AuthService:          authService,
+		PasswordService:      passwordService,
+		VerificationService:  verificationService,
+		MFAService:           mfaService,
+		AccessTokenService:   accessTokenService,
+		OIDCService:          oidcService,
+		InvitationService:    invitationService,
+		PermissionService:    permissionService,
+		ImpersonationService: impersonationService,
+		ProfileService:       profileService,
+		SessionService:       sessionService,
+		AuditService:         auditService,
+		DailyTaskHandler:     dailyTaskHandler,
+		AuthHandler:          authHandler,
+		PasswordHandler:      passwordHandler,
+		VerificationHandler:  verificationHandler,
+		MFAHandler:           mfaHandler,
+		AccessTokenHandler:   accessTokenHandler,
+		OIDCHandler:          oidcHandler,
+		JWKSHandler:          jwksHandler,
+		InvitationHandler:    invitationHandler,
+		PermissionHandler:    permissionHandler,
+		ImpersonationHandler: impersonationHandler,
+		ProfileHandler:       profileHandler,
+		SessionHandler:       sessionHandler,
+		UserHandler:          userHandler,
+		ContinentHandler:     continentHandler,
+		CountryHandler:       countryHandler,
+		CompanyHandler:       companyHandler,
+		AuditHandler:         auditHandler,
+		shutdownTracing:      shutdownTracing,
+		stopRevocations:      stopRevocations,
+		stopKeys:             stopKeys,
+		stopPermissions:      stopPermissions,
+		stopAudit:            stopAudit,
 	}, nil
 }
 
 // Close closes all resources in the container
 func (c *Container) Close() error {
+	if c.stopRevocations != nil {
+		c.stopRevocations()
+	}
+	if c.stopKeys != nil {
+		c.stopKeys()
+	}
+	if c.stopPermissions != nil {
+		c.stopPermissions()
+	}
+	if c.stopAudit != nil {
+		c.stopAudit()
+	}
+
+	// Flush spans still buffered by the exporter
+	if c.shutdownTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := c.shutdownTracing(ctx); err != nil {
+			c.Logger.Error("Failed to flush traces", zap.Error(err))
+		}
+	}
+
 	// Close database connection
 	if c.DB != nil {
 		sqlDB, err := c.DB.DB()
@@ -117,16 +315,51 @@ func (c *Container) Close() error {
 
 // initDatabase initializes the database connection
 func initDatabase(dbConfig config.DatabaseConfig) (*gorm.DB, error) {
-	var connector config.DBConnector
+	connector, err := config.NewConnector(dbConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	// Bound every query that doesn't already carry a shorter deadline
+	if err := db.Use(repository.NewQueryTimeout(dbConfig.QueryTimeout)); err != nil {
+		return nil, err
+	}
+
+	// Trace every statement under the calling request's span
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		return nil, err
+	}
+
+	// Record statement latency and connection pool usage
+	if err := db.Use(metrics.NewGormPlugin()); err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if err := metrics.RegisterDB(sqlDB, dbConfig.Driver); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
 
-	switch dbConfig.Driver {
-	case "postgres":
-		connector = config.NewPostgresConfig(dbConfig.DSN)
-	case "sqlite":
-		connector = config.NewSQLiteConfig(dbConfig.DSN)
-	default:
-		return nil, config.ErrUnsupportedDatabase
+// ensureSchema applies pending migrations when autoMigrate is set and
+// otherwise refuses to start against an outdated schema
+func ensureSchema(migrator *migrations.Migrator, autoMigrate bool, log *zap.Logger) error {
+	if !autoMigrate {
+		return migrator.CheckCurrent(context.Background())
 	}
 
-	return connector.Connect()
+	applied, err := migrator.Up()
+	for _, migration := range applied {
+		log.Info("Applied migration", zap.String("migration", migration.String()))
+	}
+	return err
 }
@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+)
+
+// AuditLogInterface stores audit logs. There is no way to change an entry;
+// only those older than the retention period can be deleted.
+type AuditLogInterface interface {
+	Create(ctx context.Context, entry *models.AuditLog) error
+	// List returns the entries matching filter, newest first
+	List(ctx context.Context, filter models.AuditLogFilter) ([]models.AuditLog, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
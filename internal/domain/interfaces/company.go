@@ -1,14 +1,18 @@
 package interfaces
 
-import "github.com/alxand/nalo-workspace/internal/domain/models"
+import (
+	"context"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+)
 
 type CompanyInterface interface {
-	Create(company *models.Company) error
-	GetByID(id int64) (*models.Company, error)
-	GetByCode(code string) (*models.Company, error)
-	GetByCountry(countryID int64) ([]models.Company, error)
-	GetByIndustry(industry string) ([]models.Company, error)
-	GetAll() ([]models.Company, error)
-	Update(company *models.Company) (*models.Company, error)
-	Delete(id int64) error
+	Create(ctx context.Context, company *models.Company) error
+	GetByID(ctx context.Context, id int64) (*models.Company, error)
+	GetByCode(ctx context.Context, code string) (*models.Company, error)
+	GetByCountry(ctx context.Context, countryID int64) ([]models.Company, error)
+	GetByIndustry(ctx context.Context, industry string) ([]models.Company, error)
+	GetAll(ctx context.Context) ([]models.Company, error)
+	Update(ctx context.Context, company *models.Company) (*models.Company, error)
+	Delete(ctx context.Context, id int64) error
 }
@@ -1,12 +1,16 @@
 package interfaces
 
-import "github.com/alxand/nalo-workspace/internal/domain/models"
+import (
+	"context"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+)
 
 type ContinentInterface interface {
-	Create(continent *models.Continent) error
-	GetByID(id int64) (*models.Continent, error)
-	GetByCode(code string) (*models.Continent, error)
-	GetAll() ([]models.Continent, error)
-	Update(continent *models.Continent) (*models.Continent, error)
-	Delete(id int64) error
+	Create(ctx context.Context, continent *models.Continent) error
+	GetByID(ctx context.Context, id int64) (*models.Continent, error)
+	GetByCode(ctx context.Context, code string) (*models.Continent, error)
+	GetAll(ctx context.Context) ([]models.Continent, error)
+	Update(ctx context.Context, continent *models.Continent) (*models.Continent, error)
+	Delete(ctx context.Context, id int64) error
 }
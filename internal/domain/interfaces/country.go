@@ -1,13 +1,17 @@
 package interfaces
 
-import "github.com/alxand/nalo-workspace/internal/domain/models"
+import (
+	"context"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+)
 
 type CountryInterface interface {
-	Create(country *models.Country) error
-	GetByID(id int64) (*models.Country, error)
-	GetByCode(code string) (*models.Country, error)
-	GetByContinent(continentID int64) ([]models.Country, error)
-	GetAll() ([]models.Country, error)
-	Update(country *models.Country) (*models.Country, error)
-	Delete(id int64) error
+	Create(ctx context.Context, country *models.Country) error
+	GetByID(ctx context.Context, id int64) (*models.Country, error)
+	GetByCode(ctx context.Context, code string) (*models.Country, error)
+	GetByContinent(ctx context.Context, continentID int64) ([]models.Country, error)
+	GetAll(ctx context.Context) ([]models.Country, error)
+	Update(ctx context.Context, country *models.Country) (*models.Country, error)
+	Delete(ctx context.Context, id int64) error
 }
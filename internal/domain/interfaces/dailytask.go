@@ -1,13 +1,17 @@
 package interfaces
 
-import "github.com/alxand/nalo-workspace/internal/domain/models"
+import (
+	"context"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+)
 
 type DailyTaskInterface interface {
-	Create(task *models.DailyTask) error
-	GetByID(id int64) (*models.DailyTask, error)
-	GetByDate(date string) ([]models.DailyTask, error)
-	GetByDateAndUser(date string, userID int64) ([]models.DailyTask, error)
-	Update(task *models.DailyTask) (*models.DailyTask, error)
-	Delete(id int64) error
-	List(limit, offset int) ([]models.DailyTask, error)
+	Create(ctx context.Context, task *models.DailyTask) error
+	GetByID(ctx context.Context, id int64) (*models.DailyTask, error)
+	GetByDate(ctx context.Context, date string) ([]models.DailyTask, error)
+	GetByDateAndUser(ctx context.Context, date string, userID int64) ([]models.DailyTask, error)
+	Update(ctx context.Context, task *models.DailyTask) (*models.DailyTask, error)
+	Delete(ctx context.Context, id int64) error
+	List(ctx context.Context, limit, offset int) ([]models.DailyTask, error)
 }
@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+)
+
+type ExternalIdentityInterface interface {
+	Create(ctx context.Context, identity *models.ExternalIdentity) error
+	GetBySubject(ctx context.Context, issuer, subject string) (*models.ExternalIdentity, error)
+	TouchLogin(ctx context.Context, id int64, at time.Time) error
+}
@@ -0,0 +1,24 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+)
+
+type InvitationInterface interface {
+	Create(ctx context.Context, invitation *models.Invitation) error
+	GetByHash(ctx context.Context, hash string) (*models.Invitation, error)
+	// List returns invitations newest first, only those sent by invitedByID
+	// unless it is zero
+	List(ctx context.Context, invitedByID int64) ([]models.Invitation, error)
+	// Accept consumes a pending invitation for the account created from it
+	// and reports whether this call did
+	Accept(ctx context.Context, id, userID int64, at time.Time) (bool, error)
+	// Revoke revokes a pending invitation, only one sent by invitedByID
+	// unless it is zero, and reports whether it found one
+	Revoke(ctx context.Context, id, invitedByID int64, at time.Time) (bool, error)
+	// RevokeForEmail revokes every pending invitation to email
+	RevokeForEmail(ctx context.Context, email string, at time.Time) error
+}
@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+)
+
+type MFARecoveryCodeInterface interface {
+	// ReplaceForUser deletes the user's codes and stores the given hashes
+	ReplaceForUser(ctx context.Context, userID int64, hashes []string) error
+	// Consume uses an unused code of the user and reports whether it matched
+	Consume(ctx context.Context, userID int64, hash string, at time.Time) (bool, error)
+	CountUnused(ctx context.Context, userID int64) (int64, error)
+	DeleteForUser(ctx context.Context, userID int64) error
+}
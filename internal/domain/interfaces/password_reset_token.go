@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+)
+
+type PasswordResetTokenInterface interface {
+	Create(ctx context.Context, token *models.PasswordResetToken) error
+	GetByHash(ctx context.Context, hash string) (*models.PasswordResetToken, error)
+	// MarkUsed consumes an unused token and reports whether this call did
+	MarkUsed(ctx context.Context, id int64, at time.Time) (bool, error)
+	// InvalidateForUser consumes every outstanding token of the user
+	InvalidateForUser(ctx context.Context, userID int64, at time.Time) error
+}
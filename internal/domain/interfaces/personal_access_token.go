@@ -0,0 +1,23 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+)
+
+type PersonalAccessTokenInterface interface {
+	Create(ctx context.Context, token *models.PersonalAccessToken) error
+	GetByHash(ctx context.Context, hash string) (*models.PersonalAccessToken, error)
+	// ListForUser returns the tokens of the user that were not revoked,
+	// newest first
+	ListForUser(ctx context.Context, userID int64) ([]models.PersonalAccessToken, error)
+	// Revoke revokes a token of the user and reports whether it found one
+	// that was not revoked yet
+	Revoke(ctx context.Context, id, userID int64, at time.Time) (bool, error)
+	// RevokeAllForUser revokes every token of the user
+	RevokeAllForUser(ctx context.Context, userID int64, at time.Time) error
+	// TouchLastUsed records a use unless one was recorded after notBefore
+	TouchLastUsed(ctx context.Context, id int64, at, notBefore time.Time) error
+}
@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+)
+
+type RefreshTokenInterface interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	// MarkRotated flags an active token as used and reports whether this
+	// call was the one that did it
+	MarkRotated(ctx context.Context, id int64, at time.Time) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string, at time.Time) error
+	RevokeAllForUser(ctx context.Context, userID int64, at time.Time) error
+}
@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+)
+
+type RevokedTokenInterface interface {
+	Create(ctx context.Context, token *models.RevokedToken) error
+	ListActive(ctx context.Context, now time.Time) ([]models.RevokedToken, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
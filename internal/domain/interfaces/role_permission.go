@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+)
+
+type RolePermissionInterface interface {
+	List(ctx context.Context) ([]models.RolePermission, error)
+	// ReplaceForRole sets the permissions of role to exactly permissions
+	ReplaceForRole(ctx context.Context, role models.UserRole, permissions []string) error
+}
@@ -0,0 +1,22 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+)
+
+type SessionInterface interface {
+	Create(ctx context.Context, session *models.Session) error
+	GetByID(ctx context.Context, id string) (*models.Session, error)
+	// ListActive returns the sessions of the user that are neither revoked
+	// nor expired at now, most recently seen first
+	ListActive(ctx context.Context, userID int64, now time.Time) ([]models.Session, error)
+	// Touch records that the session was used at from ip
+	Touch(ctx context.Context, id string, at time.Time, ip string) error
+	// Extend moves the expiry of the session along with its refresh token
+	Extend(ctx context.Context, id string, expiresAt time.Time) error
+	Revoke(ctx context.Context, id string, at time.Time) error
+	RevokeAllForUser(ctx context.Context, userID int64, at time.Time) error
+}
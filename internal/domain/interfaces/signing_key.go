@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+)
+
+type SigningKeyInterface interface {
+	Create(ctx context.Context, key *models.SigningKey) error
+	// List returns every key, oldest activation first
+	List(ctx context.Context) ([]models.SigningKey, error)
+	Delete(ctx context.Context, id int64) error
+}
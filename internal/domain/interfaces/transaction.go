@@ -0,0 +1,32 @@
+package interfaces
+
+import "context"
+
+// Repositories groups every repository bound to the same database handle
+type Repositories struct {
+	DailyTasks DailyTaskInterface
+	Users      UserInterface
+	Continents ContinentInterface
+	Countries  CountryInterface
+	Companies  CompanyInterface
+
+	RefreshTokens RefreshTokenInterface
+	RevokedTokens RevokedTokenInterface
+	Sessions      SessionInterface
+
+	PasswordResetTokens PasswordResetTokenInterface
+	MFARecoveryCodes    MFARecoveryCodeInterface
+	AccessTokens        PersonalAccessTokenInterface
+	ExternalIdentities  ExternalIdentityInterface
+	SigningKeys         SigningKeyInterface
+	Invitations         InvitationInterface
+	RolePermissions     RolePermissionInterface
+	AuditLogs           AuditLogInterface
+}
+
+// TransactionManager runs a unit of work against repositories sharing one
+// transaction. The callback's ctx carries the transaction, so calling
+// WithinTransaction again with it opens a nested savepoint instead.
+type TransactionManager interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context, repos *Repositories) error) error
+}
@@ -1,18 +1,47 @@
 package interfaces
 
-import "github.com/alxand/nalo-workspace/internal/domain/models"
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+)
 
 type UserInterface interface {
-	Create(user *models.User) error
-	GetByID(id int64) (*models.User, error)
-	GetByEmail(email string) (*models.User, error)
-	GetByUsername(username string) (*models.User, error)
-	GetByCountry(countryID int64) ([]models.User, error)
-	GetByCompany(companyID int64) ([]models.User, error)
-	Update(user *models.User) error
-	Delete(id int64) error
-	List(limit, offset int) ([]models.User, error)
-	UpdateLastLogin(id int64) error
-	ExistsByEmail(email string) (bool, error)
-	ExistsByUsername(username string) (bool, error)
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id int64) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	GetByCountry(ctx context.Context, countryID int64) ([]models.User, error)
+	GetByCompany(ctx context.Context, companyID int64) ([]models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, id int64) error
+	List(ctx context.Context, limit, offset int) ([]models.User, error)
+	UpdateLastLogin(ctx context.Context, id int64) error
+	RecordFailedLogin(ctx context.Context, id int64, now time.Time) (int, error)
+	LockUntil(ctx context.Context, id int64, until time.Time) error
+	ResetFailedLogins(ctx context.Context, id int64) error
+	// UpdatePassword stores an already hashed password
+	UpdatePassword(ctx context.Context, id int64, hash string) error
+	MarkEmailVerified(ctx context.Context, id int64, at time.Time) error
+	// MarkVerificationSent records a verification email sent at at, unless
+	// one was already sent after notBefore; it reports whether it did
+	MarkVerificationSent(ctx context.Context, id int64, at, notBefore time.Time) (bool, error)
+	// UpdateMFA stores the encrypted TOTP secret and whether it is enabled,
+	// and resets the replay guard
+	UpdateMFA(ctx context.Context, id int64, enabled bool, secret string) error
+	// RecordMFAStep accepts a TOTP time step only if it is later than the
+	// last accepted one, and reports whether it did
+	RecordMFAStep(ctx context.Context, id int64, step int64) (bool, error)
+	UpdateRole(ctx context.Context, id int64, role models.UserRole) error
+	// UpdateProfile stores the fields users edit themselves: username,
+	// names, country and preferences
+	UpdateProfile(ctx context.Context, user *models.User) error
+	// RequestEmailChange stores the address the user wants to change to
+	RequestEmailChange(ctx context.Context, id int64, email string) error
+	// ConfirmEmailChange makes the pending address the verified email if it
+	// still is email, and reports whether it did
+	ConfirmEmailChange(ctx context.Context, id int64, email string, at time.Time) (bool, error)
+	ExistsByEmail(ctx context.Context, email string) (bool, error)
+	ExistsByUsername(ctx context.Context, username string) (bool, error)
 }
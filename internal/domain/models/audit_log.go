@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// Audit actions. Changes are recorded as create, update and delete of an
+// entity; the rest are authentication events on the user they concern.
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+
+	AuditLogin              = "login"
+	AuditLoginFailed        = "login_failed"
+	AuditTokenRefresh       = "token_refresh"
+	AuditLogout             = "logout"
+	AuditLogoutAll          = "logout_all"
+	AuditTokensRevoked      = "tokens_revoked"
+	AuditSessionRevoked     = "session_revoked"
+	AuditPasswordChange     = "password_change"
+	AuditPasswordReset      = "password_reset"
+	AuditEmailVerified      = "email_verified"
+	AuditMFAEnabled         = "mfa_enabled"
+	AuditMFADisabled        = "mfa_disabled"
+	AuditRecoveryCodes      = "recovery_codes_replaced"
+	AuditImpersonationStart = "impersonation_start"
+	AuditImpersonationEnd   = "impersonation_end"
+)
+
+// Audited entity types
+const (
+	AuditEntityUser        = "user"
+	AuditEntityDailyTask   = "daily_task"
+	AuditEntityContinent   = "continent"
+	AuditEntityCountry     = "country"
+	AuditEntityCompany     = "company"
+	AuditEntityInvitation  = "invitation"
+	AuditEntityAccessToken = "access_token"
+	AuditEntityRole        = "role"
+)
+
+// AuditData is a JSON document stored as text and rendered as JSON
+type AuditData string
+
+// MarshalJSON embeds the document instead of quoting it
+func (d AuditData) MarshalJSON() ([]byte, error) {
+	if d == "" {
+		return []byte("null"), nil
+	}
+	return []byte(d), nil
+}
+
+// AuditLog records who changed what, or which authentication event
+// happened, and from where. Entries are only appended, and removed once
+// older than the retention period.
+type AuditLog struct {
+	ID int64 `gorm:"primaryKey" json:"id"`
+	// ActorID is the user the request acted as, nil for failed logins
+	ActorID *int64 `gorm:"index" json:"actor_id"`
+	// ImpersonatorID is the admin acting as ActorID, if any
+	ImpersonatorID *int64    `json:"impersonator_id,omitempty"`
+	Action         string    `gorm:"not null;index" json:"action"`
+	EntityType     string    `gorm:"not null" json:"entity_type"`
+	EntityID       string    `gorm:"not null" json:"entity_id"`
+	Before         AuditData `gorm:"type:text" json:"before" swaggertype:"object"`
+	After          AuditData `gorm:"type:text" json:"after" swaggertype:"object"`
+	IP             string    `gorm:"not null" json:"ip"`
+	UserAgent      string    `gorm:"not null" json:"user_agent"`
+	RequestID      string    `gorm:"not null" json:"request_id"`
+	CreatedAt      time.Time `gorm:"not null;index" json:"created_at"`
+}
+
+// AuditLogFilter narrows a listing of audit logs; zero fields match all
+type AuditLogFilter struct {
+	ActorID        int64
+	ImpersonatorID int64
+	Action         string
+	EntityType     string
+	EntityID       string
+	From           time.Time
+	To             time.Time
+	Limit          int
+	Offset         int
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// ExternalIdentity links a user to an account at an OpenID Connect
+// provider, identified by the issuer and the subject claim
+type ExternalIdentity struct {
+	ID          int64      `gorm:"primaryKey" json:"id"`
+	UserID      int64      `gorm:"not null;index" json:"user_id"`
+	Issuer      string     `gorm:"not null;uniqueIndex:idx_external_identities_subject" json:"issuer"`
+	Subject     string     `gorm:"not null;uniqueIndex:idx_external_identities_subject" json:"subject"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Invitation status values, derived from the timestamps
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// Invitation lets someone create an account with the role, company and
+// country chosen by the admin or manager who invited them. Only the SHA-256
+// hash of the mailed token is stored.
+type Invitation struct {
+	ID          int64     `gorm:"primaryKey" json:"id"`
+	Email       string    `gorm:"not null;index" json:"email"`
+	Role        UserRole  `gorm:"not null" json:"role"`
+	CompanyID   *int64    `json:"company_id"`
+	CountryID   *int64    `json:"country_id"`
+	InvitedByID int64     `gorm:"not null;index" json:"invited_by_id"`
+	TokenHash   string    `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt   time.Time `gorm:"not null" json:"expires_at"`
+	// UserID is the account created when the invitation was accepted
+	UserID     *int64     `json:"user_id,omitempty"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Status reports whether the invitation is pending, accepted, revoked or
+// expired at now
+func (i *Invitation) Status(now time.Time) string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationAccepted
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case !now.Before(i.ExpiresAt):
+		return InvitationExpired
+	}
+	return InvitationPending
+}
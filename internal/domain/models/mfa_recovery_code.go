@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// MFARecoveryCode is a one-time code that replaces a TOTP code when the
+// authenticator is lost. Only the SHA-256 hash of the code is stored.
+type MFARecoveryCode struct {
+	ID        int64      `gorm:"primaryKey" json:"id"`
+	UserID    int64      `gorm:"not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
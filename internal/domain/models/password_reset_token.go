@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// PasswordResetToken is a single-use token mailed to a user who forgot
+// their password. Only the SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	ID        int64      `gorm:"primaryKey" json:"id"`
+	UserID    int64      `gorm:"not null;index" json:"user_id"`
+	TokenHash string     `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// IsUsable reports whether the token can still reset a password at now
+func (t *PasswordResetToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,107 @@
+package models
+
+// Permissions are granted to roles, not users. Task permissions come in
+// three reaches: own tasks, tasks of users in the same company (team) and
+// every task (all).
+const (
+	PermissionTaskRead      = "task:read"
+	PermissionTaskWrite     = "task:write"
+	PermissionTaskReadTeam  = "task:read:team"
+	PermissionTaskWriteTeam = "task:write:team"
+	PermissionTaskReadAll   = "task:read:all"
+	PermissionTaskWriteAll  = "task:write:all"
+
+	PermissionContinentRead  = "continent:read"
+	PermissionContinentWrite = "continent:write"
+	PermissionCountryRead    = "country:read"
+	PermissionCountryWrite   = "country:write"
+	PermissionCompanyRead    = "company:read"
+	PermissionCompanyWrite   = "company:write"
+
+	// PermissionInvitationWrite allows inviting users below the admin role
+	// into the inviter's company
+	PermissionInvitationWrite = "invitation:write"
+	// PermissionUserAdmin allows managing every user and inviting with any
+	// role into any company
+	PermissionUserAdmin = "user:admin"
+	// PermissionRoleAdmin allows changing which permissions roles have
+	PermissionRoleAdmin = "role:admin"
+	// PermissionTenantAll lifts the limit to the data of the user's own
+	// company
+	PermissionTenantAll = "tenant:all"
+	// PermissionUserImpersonate allows acting as another user for support
+	PermissionUserImpersonate = "user:impersonate"
+	// PermissionAuditRead allows reading the audit log
+	PermissionAuditRead = "audit:read"
+)
+
+// PermissionInfo describes a permission for the admin API
+type PermissionInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// PermissionCatalog lists every permission that can be granted
+var PermissionCatalog = []PermissionInfo{
+	{PermissionTaskRead, "Read own daily tasks"},
+	{PermissionTaskWrite, "Create, change and delete own daily tasks"},
+	{PermissionTaskReadTeam, "Read daily tasks of users in the same company"},
+	{PermissionTaskWriteTeam, "Change and delete daily tasks of users in the same company"},
+	{PermissionTaskReadAll, "Read every daily task"},
+	{PermissionTaskWriteAll, "Change and delete every daily task"},
+	{PermissionContinentRead, "Read continents"},
+	{PermissionContinentWrite, "Create, change and delete continents"},
+	{PermissionCountryRead, "Read countries"},
+	{PermissionCountryWrite, "Create, change and delete countries"},
+	{PermissionCompanyRead, "Read companies"},
+	{PermissionCompanyWrite, "Create, change and delete companies"},
+	{PermissionInvitationWrite, "Invite users into the own company, and list and revoke sent invitations"},
+	{PermissionUserAdmin, "Manage users and invite with any role into any company"},
+	{PermissionRoleAdmin, "Change the permissions of roles"},
+	{PermissionTenantAll, "Access the data of every company, not only the own"},
+	{PermissionUserImpersonate, "Act as another user to see what they see"},
+	{PermissionAuditRead, "Read the audit log of changes and sign-ins"},
+}
+
+// IsPermission reports whether name is in the catalog
+func IsPermission(name string) bool {
+	for _, permission := range PermissionCatalog {
+		if permission.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Roles lists every user role, most privileged first
+var Roles = []UserRole{RoleAdmin, RoleManager, RoleUser}
+
+// DefaultRolePermissions is the mapping the role_permissions migrations
+// install
+var DefaultRolePermissions = map[UserRole][]string{
+	RoleAdmin: {
+		PermissionTaskRead, PermissionTaskWrite,
+		PermissionTaskReadTeam, PermissionTaskWriteTeam,
+		PermissionTaskReadAll, PermissionTaskWriteAll,
+		PermissionContinentRead, PermissionContinentWrite,
+		PermissionCountryRead, PermissionCountryWrite,
+		PermissionCompanyRead, PermissionCompanyWrite,
+		PermissionInvitationWrite, PermissionUserAdmin, PermissionRoleAdmin,
+		PermissionTenantAll, PermissionUserImpersonate, PermissionAuditRead,
+	},
+	RoleManager: {
+		PermissionTaskRead, PermissionTaskWrite, PermissionTaskReadTeam,
+		PermissionContinentRead, PermissionCountryRead, PermissionCompanyRead,
+		PermissionInvitationWrite,
+	},
+	RoleUser: {
+		PermissionTaskRead, PermissionTaskWrite,
+		PermissionContinentRead, PermissionCountryRead, PermissionCompanyRead,
+	},
+}
+
+// RolePermission grants a permission to every user with the role
+type RolePermission struct {
+	Role       UserRole `gorm:"primaryKey" json:"role"`
+	Permission string   `gorm:"primaryKey" json:"permission"`
+}
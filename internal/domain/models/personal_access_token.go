@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Personal access token scopes
+const (
+	// ScopeTasksRead allows reading daily tasks
+	ScopeTasksRead = "tasks:read"
+	// ScopeTasksWrite allows creating, changing and deleting daily tasks
+	ScopeTasksWrite = "tasks:write"
+	// ScopeAdmin allows everything the owner's role allows, except managing
+	// the account itself
+	ScopeAdmin = "admin"
+)
+
+// PersonalAccessToken is a named, scoped, expiring token for scripts and CI
+// jobs, used in place of a login. Only the SHA-256 hash of the token is
+// stored; TokenPrefix lets users tell their tokens apart.
+type PersonalAccessToken struct {
+	ID          int64      `gorm:"primaryKey" json:"id"`
+	UserID      int64      `gorm:"not null;index" json:"user_id"`
+	Name        string     `gorm:"not null" json:"name"`
+	TokenPrefix string     `gorm:"not null" json:"token_prefix"`
+	TokenHash   string     `gorm:"uniqueIndex;not null" json:"-"`
+	Scopes      []string   `gorm:"serializer:json;not null" json:"scopes"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// IsActive reports whether the token can still be used at now
+func (t *PersonalAccessToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// HasScope reports whether the token grants scope. ScopeAdmin grants every
+// scope and ScopeTasksWrite grants ScopeTasksRead.
+func (t *PersonalAccessToken) HasScope(scope string) bool {
+	for _, granted := range t.Scopes {
+		if granted == scope || granted == ScopeAdmin || (granted == ScopeTasksWrite && scope == ScopeTasksRead) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// RefreshToken is a long-lived opaque token exchanged for new access tokens.
+// Only the SHA-256 hash of the token is stored.
+type RefreshToken struct {
+	ID        int64      `gorm:"primaryKey" json:"id"`
+	UserID    int64      `gorm:"not null;index" json:"user_id"`
+	FamilyID  string     `gorm:"not null;index" json:"family_id"`
+	TokenHash string     `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// IsActive reports whether the token can still be exchanged at now
+func (t *RefreshToken) IsActive(now time.Time) bool {
+	return t.RotatedAt == nil && t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
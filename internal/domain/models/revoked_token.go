@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RevokedToken revokes a single access token by its JWT ID, every access
+// token of a session by SessionID, or every access token of a user issued
+// up to RevokedBefore when both are nil
+type RevokedToken struct {
+	ID            int64      `gorm:"primaryKey" json:"id"`
+	JTI           *string    `gorm:"column:jti;uniqueIndex" json:"jti,omitempty"`
+	SessionID     *string    `json:"session_id,omitempty"`
+	UserID        int64      `gorm:"not null" json:"user_id"`
+	RevokedBefore *time.Time `json:"revoked_before,omitempty"`
+	ExpiresAt     time.Time  `gorm:"not null;index" json:"expires_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
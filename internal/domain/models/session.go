@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Session is one login of a user on a device. Its ID is the family of the
+// refresh tokens issued to the login, and access tokens carry it in their
+// sid claim, so revoking a session ends both.
+type Session struct {
+	ID         string     `gorm:"primaryKey" json:"id"`
+	UserID     int64      `gorm:"not null;index" json:"user_id"`
+	UserAgent  string     `gorm:"not null" json:"user_agent"`
+	IP         string     `gorm:"not null" json:"ip"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	LastSeenAt time.Time  `gorm:"not null" json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// Current marks the session of the request listing sessions
+	Current bool `gorm:"-" json:"current"`
+}
+
+// IsActive reports whether the session can still be used at now
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SigningKey is a generated key pair that signs JWTs. The newest key whose
+// ActivatesAt has passed signs; the others only verify until they are
+// retired. PrivateKey is a PKCS #8 PEM block encrypted with AES-GCM.
+type SigningKey struct {
+	ID          int64     `gorm:"primaryKey" json:"id"`
+	KID         string    `gorm:"column:kid;not null;uniqueIndex" json:"kid"`
+	Algorithm   string    `gorm:"not null" json:"algorithm"`
+	PublicKey   string    `gorm:"not null" json:"public_key"`
+	PrivateKey  string    `gorm:"not null" json:"-"`
+	ActivatesAt time.Time `gorm:"not null;index" json:"activates_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
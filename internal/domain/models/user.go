@@ -32,20 +32,73 @@ type User struct {
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 
+	// Brute-force protection; never exposed through the API
+	FailedLoginAttempts int        `gorm:"not null;default:0" json:"-"`
+	LockedUntil         *time.Time `json:"-"`
+
+	// Email verification; EmailVerifiedAt is nil until the user follows
+	// the verification link
+	EmailVerifiedAt    *time.Time `json:"email_verified_at"`
+	VerificationSentAt *time.Time `json:"-"`
+
+	// Two-factor authentication. MFASecret is the encrypted TOTP secret, set
+	// on enrollment and kept while MFAEnabled is false until confirmed.
+	// MFALastStep is the last accepted TOTP time step, so codes cannot be
+	// replayed.
+	MFAEnabled  bool   `gorm:"not null;default:false" json:"mfa_enabled"`
+	MFASecret   string `json:"-"`
+	MFALastStep int64  `gorm:"not null;default:0" json:"-"`
+
+	// Preferences the user edits through their profile
+	Timezone      string                  `json:"timezone"`
+	Locale        string                  `json:"locale"`
+	AvatarURL     string                  `json:"avatar_url"`
+	Notifications NotificationPreferences `gorm:"embedded;embeddedPrefix:notify_" json:"notifications"`
+
+	// PendingEmail is the address the user asked to change to. It replaces
+	// Email once verified through the link mailed to it.
+	PendingEmail *string `json:"pending_email,omitempty"`
+
 	// Relationships
 	DailyTasks []DailyTask `gorm:"foreignKey:UserID" json:"daily_tasks,omitempty"`
 	Country    *Country    `gorm:"foreignKey:CountryID" json:"country,omitempty"`
 	Company    *Company    `gorm:"foreignKey:CompanyID" json:"company,omitempty"`
 }
 
+// NotificationPreferences are the emails a user opted in to, besides
+// account emails such as verification links, which are always sent
+type NotificationPreferences struct {
+	TaskReminders bool `gorm:"not null;default:false" json:"task_reminders"`
+	WeeklySummary bool `gorm:"not null;default:false" json:"weekly_summary"`
+}
+
+// IsLocked reports whether the account is temporarily locked at now
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// IsEmailVerified reports whether the user has verified their email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// HashPassword returns the bcrypt hash stored for a plaintext password
+func HashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
+
 // BeforeCreate is a GORM hook that runs before creating a user
 func (u *User) BeforeCreate(tx *gorm.DB) error {
 	// Hash password before saving
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	hashedPassword, err := HashPassword(u.Password)
 	if err != nil {
 		return err
 	}
-	u.Password = string(hashedPassword)
+	u.Password = hashedPassword
 	return nil
 }
 
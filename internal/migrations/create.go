@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var nameSanitizer = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Create writes empty up and down files for a new migration into every
+// driver directory under dir, using the next free version number.
+func Create(dir, name string) ([]string, error) {
+	name = strings.Trim(nameSanitizer.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("migration name is required")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var drivers []string
+	var latest int64
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		drivers = append(drivers, entry.Name())
+
+		files, err := os.ReadDir(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			match := fileNamePattern.FindStringSubmatch(file.Name())
+			if match == nil {
+				continue
+			}
+			if version, err := strconv.ParseInt(match[1], 10, 64); err == nil && version > latest {
+				latest = version
+			}
+		}
+	}
+	if len(drivers) == 0 {
+		return nil, fmt.Errorf("no driver directories found in %s", dir)
+	}
+
+	prefix := fmt.Sprintf("%04d_%s", latest+1, name)
+	var created []string
+	for _, driver := range drivers {
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(dir, driver, fmt.Sprintf("%s.%s.sql", prefix, direction))
+			header := fmt.Sprintf("-- %s (%s, %s)\n", prefix, driver, direction)
+			if err := os.WriteFile(path, []byte(header), 0o644); err != nil {
+				return created, err
+			}
+			created = append(created, path)
+		}
+	}
+	return created, nil
+}
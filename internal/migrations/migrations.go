@@ -0,0 +1,355 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql
+var embedded embed.FS
+
+// Error definitions
+var (
+	ErrPendingMigrations = errors.New("database schema has pending migrations")
+	ErrNothingToRollback = errors.New("no applied migrations to roll back")
+)
+
+// fileNamePattern matches files such as 0002_add_user_timezone.up.sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned schema change with its rollback
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// String returns the migration file prefix, e.g. 0001_init
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Status describes whether a migration has been applied
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// schemaMigration is a row of the schema_migrations tracking table
+type schemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrator applies and rolls back versioned SQL migrations
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New creates a migrator using the migrations embedded for the given driver
+func New(db *gorm.DB, driver string) (*Migrator, error) {
+	source, err := fs.Sub(embedded, "sql/"+driver)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromFS(db, source)
+}
+
+// NewFromFS creates a migrator reading *.up.sql and *.down.sql files from fsys
+func NewFromFS(db *gorm.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if len(migrations) == 0 {
+		return nil, errors.New("no migrations found")
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Migrations returns every known migration ordered by version
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Up applies all pending migrations in order and returns the ones applied
+func (m *Migrator) Up() ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]Migration, 0, len(pending))
+	for _, migration := range pending {
+		if err := m.apply(migration); err != nil {
+			return applied, err
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// Down rolls back the given number of most recently applied migrations
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	records, err := m.appliedRecords()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, ErrNothingToRollback
+	}
+
+	rolledBack := make([]Migration, 0, steps)
+	for i := len(records) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+		migration, ok := m.find(records[i].Version)
+		if !ok {
+			return rolledBack, fmt.Errorf("applied migration %04d_%s has no source file", records[i].Version, records[i].Name)
+		}
+		if err := m.rollback(migration); err != nil {
+			return rolledBack, err
+		}
+		rolledBack = append(rolledBack, migration)
+	}
+	return rolledBack, nil
+}
+
+// Redo rolls back the most recently applied migration and applies it again
+func (m *Migrator) Redo() (*Migration, error) {
+	rolledBack, err := m.Down(1)
+	if err != nil {
+		return nil, err
+	}
+	migration := rolledBack[0]
+	if err := m.apply(migration); err != nil {
+		return nil, err
+	}
+	return &migration, nil
+}
+
+// Status reports the applied state of every known migration
+func (m *Migrator) Status() ([]Status, error) {
+	records, err := m.appliedRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	appliedAt := make(map[int64]time.Time, len(records))
+	for _, record := range records {
+		appliedAt[record.Version] = record.AppliedAt
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied yet
+func (m *Migrator) Pending() ([]Migration, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// CheckCurrent returns ErrPendingMigrations when the schema is behind. It
+// only reads, so readiness probes can call it; without a schema_migrations
+// table every migration is pending.
+func (m *Migrator) CheckCurrent(ctx context.Context) error {
+	db := m.db.WithContext(ctx)
+	var versions []int64
+	if db.Migrator().HasTable(&schemaMigration{}) {
+		if err := db.Model(&schemaMigration{}).Pluck("version", &versions).Error; err != nil {
+			return err
+		}
+	}
+
+	applied := make(map[int64]bool, len(versions))
+	for _, version := range versions {
+		applied[version] = true
+	}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d not applied, next is %s", ErrPendingMigrations, len(pending), pending[0])
+	}
+	return nil
+}
+
+func (m *Migrator) ensureTable() error {
+	return m.db.AutoMigrate(&schemaMigration{})
+}
+
+func (m *Migrator) appliedRecords() ([]schemaMigration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var records []schemaMigration
+	err := m.db.Order("version").Find(&records).Error
+	return records, err
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+func (m *Migrator) apply(migration Migration) error {
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := execScript(tx, migration.Up); err != nil {
+			return err
+		}
+		return tx.Create(&schemaMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now().UTC(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply migration %s: %w", migration, err)
+	}
+	return nil
+}
+
+func (m *Migrator) rollback(migration Migration) error {
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := execScript(tx, migration.Down); err != nil {
+			return err
+		}
+		return tx.Delete(&schemaMigration{}, migration.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to roll back migration %s: %w", migration, err)
+	}
+	return nil
+}
+
+func execScript(tx *gorm.DB, script string) error {
+	for _, statement := range splitStatements(script) {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a script on semicolons that end a line and drops
+// comment-only lines. Not every driver accepts several statements per Exec.
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" {
+			return nil, fmt.Errorf("migration %s has no up script", migration)
+		}
+		if strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("migration %s has no down script", migration)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
@@ -0,0 +1,171 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// schemaModels lists every model the SQL migrations must provide tables for
+var schemaModels = []interface{}{
+	&models.Continent{},
+	&models.Country{},
+	&models.Company{},
+	&models.User{},
+	&models.DailyTask{},
+	&models.Deliverable{},
+	&models.Activity{},
+	&models.ProductFocus{},
+	&models.NextStep{},
+	&models.Challenge{},
+	&models.Note{},
+	&models.Comment{},
+}
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := config.NewSQLiteConfig(":memory:").Connect()
+	if err != nil {
+		t.Fatalf("Failed to connect to sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestMigrator_UpMatchesModels(t *testing.T) {
+	db := setupTestDB(t)
+	migrator, err := New(db, "sqlite")
+	assert.NoError(t, err)
+
+	// Checking does not create the tracking table
+	assert.ErrorIs(t, migrator.CheckCurrent(context.Background()), ErrPendingMigrations)
+	assert.False(t, db.Migrator().HasTable("schema_migrations"))
+
+	applied, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(migrator.Migrations()))
+	assert.NoError(t, migrator.CheckCurrent(context.Background()))
+
+	// Every column GORM expects must exist, otherwise models and SQL have drifted
+	for _, model := range schemaModels {
+		stmt := &gorm.Statement{DB: db}
+		assert.NoError(t, stmt.Parse(model))
+		assert.True(t, db.Migrator().HasTable(model), "missing table %s", stmt.Schema.Table)
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" || field.IgnoreMigration {
+				continue
+			}
+			assert.True(t, db.Migrator().HasColumn(model, field.DBName), "missing column %s.%s", stmt.Schema.Table, field.DBName)
+		}
+	}
+
+	// Running again is a no-op
+	applied, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+func TestMigrator_DownAndRedo(t *testing.T) {
+	db := setupTestDB(t)
+	source := fstest.MapFS{
+		"0001_create_widgets.up.sql":   {Data: []byte("CREATE TABLE widgets (id INTEGER PRIMARY KEY);\n")},
+		"0001_create_widgets.down.sql": {Data: []byte("DROP TABLE widgets;\n")},
+		"0002_add_widget_name.up.sql": {Data: []byte(
+			"-- add a name column\nALTER TABLE widgets ADD COLUMN name TEXT;\nCREATE INDEX idx_widgets_name ON widgets (name);\n")},
+		"0002_add_widget_name.down.sql": {Data: []byte("DROP INDEX idx_widgets_name;\nALTER TABLE widgets DROP COLUMN name;\n")},
+	}
+	migrator, err := NewFromFS(db, source)
+	assert.NoError(t, err)
+
+	_, err = migrator.Down(1)
+	assert.True(t, errors.Is(err, ErrNothingToRollback))
+
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.True(t, db.Migrator().HasColumn("widgets", "name"))
+
+	redone, err := migrator.Redo()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), redone.Version)
+	assert.True(t, db.Migrator().HasColumn("widgets", "name"))
+
+	rolledBack, err := migrator.Down(1)
+	assert.NoError(t, err)
+	assert.Len(t, rolledBack, 1)
+	assert.False(t, db.Migrator().HasColumn("widgets", "name"))
+
+	statuses, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[1].Applied)
+
+	_, err = migrator.Down(5)
+	assert.NoError(t, err)
+	assert.False(t, db.Migrator().HasTable("widgets"))
+}
+
+func TestMigrator_FailedMigrationIsNotRecorded(t *testing.T) {
+	db := setupTestDB(t)
+	source := fstest.MapFS{
+		"0001_broken.up.sql":   {Data: []byte("CREATE TABLE ok_table (id INTEGER);\nNOT VALID SQL;\n")},
+		"0001_broken.down.sql": {Data: []byte("DROP TABLE ok_table;\n")},
+	}
+	migrator, err := NewFromFS(db, source)
+	assert.NoError(t, err)
+
+	_, err = migrator.Up()
+	assert.Error(t, err)
+
+	pending, err := migrator.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+	assert.False(t, db.Migrator().HasTable("ok_table"))
+}
+
+func TestLoad_RequiresDownScript(t *testing.T) {
+	_, err := load(fstest.MapFS{
+		"0001_only_up.up.sql": {Data: []byte("SELECT 1;")},
+	})
+	assert.Error(t, err)
+}
+
+func TestCreate_UsesNextVersionForEveryDriver(t *testing.T) {
+	dir := t.TempDir()
+	for _, driver := range []string{"postgres", "sqlite"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, driver), 0o755))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sqlite", "0003_existing.up.sql"), []byte("SELECT 1;"), 0o644))
+
+	files, err := Create(dir, "Add User Timezone")
+	assert.NoError(t, err)
+	assert.Len(t, files, 4)
+	assert.FileExists(t, filepath.Join(dir, "postgres", "0004_add_user_timezone.up.sql"))
+	assert.FileExists(t, filepath.Join(dir, "sqlite", "0004_add_user_timezone.down.sql"))
+}
+
+func TestEmbedded_DriversShareVersions(t *testing.T) {
+	versions := func(driver string) []string {
+		migrator, err := New(nil, driver)
+		if err != nil {
+			t.Fatalf("Failed to load %s migrations: %v", driver, err)
+		}
+		var names []string
+		for _, migration := range migrator.Migrations() {
+			names = append(names, migration.String())
+		}
+		return names
+	}
+
+	assert.Equal(t, versions("postgres"), versions("sqlite"))
+}
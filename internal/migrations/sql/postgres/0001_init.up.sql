@@ -0,0 +1,129 @@
+-- Baseline schema. IF NOT EXISTS lets databases created by the former
+-- AutoMigrate startup step adopt versioned migrations without changes.
+
+CREATE TABLE IF NOT EXISTS continents (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    code VARCHAR(2) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT uni_continents_name UNIQUE (name),
+    CONSTRAINT uni_continents_code UNIQUE (code)
+);
+
+CREATE TABLE IF NOT EXISTS countries (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    code VARCHAR(3) NOT NULL,
+    continent_id BIGINT NOT NULL,
+    description TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT fk_continents_countries FOREIGN KEY (continent_id) REFERENCES continents (id),
+    CONSTRAINT uni_countries_code UNIQUE (code)
+);
+
+CREATE TABLE IF NOT EXISTS companies (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    code TEXT,
+    country_id BIGINT NOT NULL,
+    description TEXT,
+    website TEXT,
+    industry TEXT,
+    size TEXT,
+    founded BIGINT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT fk_countries_companies FOREIGN KEY (country_id) REFERENCES countries (id),
+    CONSTRAINT uni_companies_code UNIQUE (code)
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    email TEXT NOT NULL,
+    username TEXT NOT NULL,
+    password TEXT NOT NULL,
+    first_name TEXT,
+    last_name TEXT,
+    role TEXT DEFAULT 'user',
+    is_active BOOLEAN DEFAULT true,
+    country_id BIGINT,
+    company_id BIGINT,
+    last_login TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT fk_countries_users FOREIGN KEY (country_id) REFERENCES countries (id),
+    CONSTRAINT fk_companies_users FOREIGN KEY (company_id) REFERENCES companies (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+
+CREATE TABLE IF NOT EXISTS daily_tasks (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    day TEXT,
+    date TIMESTAMPTZ,
+    start_time TIMESTAMPTZ,
+    end_time TIMESTAMPTZ,
+    status TEXT,
+    score BIGINT,
+    productivity_score BIGINT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT fk_users_daily_tasks FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_daily_tasks_user_id ON daily_tasks (user_id);
+
+CREATE TABLE IF NOT EXISTS deliverables (
+    id BIGSERIAL PRIMARY KEY,
+    task_id BIGINT,
+    item TEXT,
+    CONSTRAINT fk_daily_tasks_deliverables FOREIGN KEY (task_id) REFERENCES daily_tasks (id)
+);
+
+CREATE TABLE IF NOT EXISTS activities (
+    id BIGSERIAL PRIMARY KEY,
+    task_id BIGINT,
+    name TEXT,
+    CONSTRAINT fk_daily_tasks_activities FOREIGN KEY (task_id) REFERENCES daily_tasks (id)
+);
+
+CREATE TABLE IF NOT EXISTS product_focus (
+    id BIGSERIAL PRIMARY KEY,
+    task_id BIGINT,
+    area TEXT,
+    CONSTRAINT fk_daily_tasks_product_focus FOREIGN KEY (task_id) REFERENCES daily_tasks (id)
+);
+
+CREATE TABLE IF NOT EXISTS next_steps (
+    id BIGSERIAL PRIMARY KEY,
+    task_id BIGINT,
+    step TEXT,
+    CONSTRAINT fk_daily_tasks_next_steps FOREIGN KEY (task_id) REFERENCES daily_tasks (id)
+);
+
+CREATE TABLE IF NOT EXISTS challenges (
+    id BIGSERIAL PRIMARY KEY,
+    task_id BIGINT,
+    issue TEXT,
+    CONSTRAINT fk_daily_tasks_challenges FOREIGN KEY (task_id) REFERENCES daily_tasks (id)
+);
+
+CREATE TABLE IF NOT EXISTS notes (
+    id BIGSERIAL PRIMARY KEY,
+    task_id BIGINT,
+    text TEXT,
+    CONSTRAINT fk_daily_tasks_notes FOREIGN KEY (task_id) REFERENCES daily_tasks (id)
+);
+
+CREATE TABLE IF NOT EXISTS comments (
+    id BIGSERIAL PRIMARY KEY,
+    task_id BIGINT,
+    author TEXT,
+    content TEXT,
+    CONSTRAINT fk_daily_tasks_comments FOREIGN KEY (task_id) REFERENCES daily_tasks (id)
+);
@@ -0,0 +1,6 @@
+-- Email verification; accounts created before it are treated as verified
+
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN verification_sent_at TIMESTAMPTZ;
+
+UPDATE users SET email_verified_at = COALESCE(created_at, CURRENT_TIMESTAMP);
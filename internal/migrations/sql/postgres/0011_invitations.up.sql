@@ -0,0 +1,25 @@
+-- Invitations to create an account with a pre-assigned role, company and
+-- country; tokens are stored as SHA-256 hashes
+
+CREATE TABLE IF NOT EXISTS invitations (
+    id BIGSERIAL PRIMARY KEY,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL,
+    company_id BIGINT,
+    country_id BIGINT,
+    invited_by_id BIGINT NOT NULL,
+    token_hash TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    user_id BIGINT,
+    accepted_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    CONSTRAINT fk_companies_invitations FOREIGN KEY (company_id) REFERENCES companies (id) ON DELETE CASCADE,
+    CONSTRAINT fk_countries_invitations FOREIGN KEY (country_id) REFERENCES countries (id) ON DELETE CASCADE,
+    CONSTRAINT fk_users_invitations_sent FOREIGN KEY (invited_by_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_users_invitations_accepted FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invitations_token_hash ON invitations (token_hash);
+CREATE INDEX IF NOT EXISTS idx_invitations_email ON invitations (email);
+CREATE INDEX IF NOT EXISTS idx_invitations_invited_by_id ON invitations (invited_by_id);
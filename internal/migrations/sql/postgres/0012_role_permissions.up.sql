@@ -0,0 +1,38 @@
+-- Permissions granted to each role, editable by admins. By default only
+-- admins write reference data and manage users, and managers can read the
+-- tasks of their company and send invitations.
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role TEXT NOT NULL,
+    permission TEXT NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'task:read'),
+    ('admin', 'task:write'),
+    ('admin', 'task:read:team'),
+    ('admin', 'task:write:team'),
+    ('admin', 'task:read:all'),
+    ('admin', 'task:write:all'),
+    ('admin', 'continent:read'),
+    ('admin', 'continent:write'),
+    ('admin', 'country:read'),
+    ('admin', 'country:write'),
+    ('admin', 'company:read'),
+    ('admin', 'company:write'),
+    ('admin', 'invitation:write'),
+    ('admin', 'user:admin'),
+    ('admin', 'role:admin'),
+    ('manager', 'task:read'),
+    ('manager', 'task:write'),
+    ('manager', 'task:read:team'),
+    ('manager', 'continent:read'),
+    ('manager', 'country:read'),
+    ('manager', 'company:read'),
+    ('manager', 'invitation:write'),
+    ('user', 'task:read'),
+    ('user', 'task:write'),
+    ('user', 'continent:read'),
+    ('user', 'country:read'),
+    ('user', 'company:read');
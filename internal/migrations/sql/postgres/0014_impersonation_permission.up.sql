@@ -0,0 +1,3 @@
+-- Admins can act as another user for support.
+
+INSERT INTO role_permissions (role, permission) VALUES ('admin', 'user:impersonate');
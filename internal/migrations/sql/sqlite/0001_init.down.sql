@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS notes;
+DROP TABLE IF EXISTS challenges;
+DROP TABLE IF EXISTS next_steps;
+DROP TABLE IF EXISTS product_focus;
+DROP TABLE IF EXISTS activities;
+DROP TABLE IF EXISTS deliverables;
+DROP TABLE IF EXISTS daily_tasks;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS companies;
+DROP TABLE IF EXISTS countries;
+DROP TABLE IF EXISTS continents;
@@ -0,0 +1,129 @@
+-- Baseline schema. IF NOT EXISTS lets databases created by the former
+-- AutoMigrate startup step adopt versioned migrations without changes.
+
+CREATE TABLE IF NOT EXISTS continents (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    code TEXT NOT NULL,
+    description TEXT,
+    created_at DATETIME,
+    updated_at DATETIME,
+    CONSTRAINT uni_continents_name UNIQUE (name),
+    CONSTRAINT uni_continents_code UNIQUE (code)
+);
+
+CREATE TABLE IF NOT EXISTS countries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    code TEXT NOT NULL,
+    continent_id INTEGER NOT NULL,
+    description TEXT,
+    created_at DATETIME,
+    updated_at DATETIME,
+    CONSTRAINT fk_continents_countries FOREIGN KEY (continent_id) REFERENCES continents (id),
+    CONSTRAINT uni_countries_code UNIQUE (code)
+);
+
+CREATE TABLE IF NOT EXISTS companies (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    code TEXT,
+    country_id INTEGER NOT NULL,
+    description TEXT,
+    website TEXT,
+    industry TEXT,
+    size TEXT,
+    founded INTEGER,
+    created_at DATETIME,
+    updated_at DATETIME,
+    CONSTRAINT fk_countries_companies FOREIGN KEY (country_id) REFERENCES countries (id),
+    CONSTRAINT uni_companies_code UNIQUE (code)
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    email TEXT NOT NULL,
+    username TEXT NOT NULL,
+    password TEXT NOT NULL,
+    first_name TEXT,
+    last_name TEXT,
+    role TEXT DEFAULT 'user',
+    is_active NUMERIC DEFAULT true,
+    country_id INTEGER,
+    company_id INTEGER,
+    last_login DATETIME,
+    created_at DATETIME,
+    updated_at DATETIME,
+    CONSTRAINT fk_countries_users FOREIGN KEY (country_id) REFERENCES countries (id),
+    CONSTRAINT fk_companies_users FOREIGN KEY (company_id) REFERENCES companies (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+
+CREATE TABLE IF NOT EXISTS daily_tasks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    day TEXT,
+    date DATETIME,
+    start_time DATETIME,
+    end_time DATETIME,
+    status TEXT,
+    score INTEGER,
+    productivity_score INTEGER,
+    created_at DATETIME,
+    updated_at DATETIME,
+    CONSTRAINT fk_users_daily_tasks FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_daily_tasks_user_id ON daily_tasks (user_id);
+
+CREATE TABLE IF NOT EXISTS deliverables (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER,
+    item TEXT,
+    CONSTRAINT fk_daily_tasks_deliverables FOREIGN KEY (task_id) REFERENCES daily_tasks (id)
+);
+
+CREATE TABLE IF NOT EXISTS activities (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER,
+    name TEXT,
+    CONSTRAINT fk_daily_tasks_activities FOREIGN KEY (task_id) REFERENCES daily_tasks (id)
+);
+
+CREATE TABLE IF NOT EXISTS product_focus (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER,
+    area TEXT,
+    CONSTRAINT fk_daily_tasks_product_focus FOREIGN KEY (task_id) REFERENCES daily_tasks (id)
+);
+
+CREATE TABLE IF NOT EXISTS next_steps (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER,
+    step TEXT,
+    CONSTRAINT fk_daily_tasks_next_steps FOREIGN KEY (task_id) REFERENCES daily_tasks (id)
+);
+
+CREATE TABLE IF NOT EXISTS challenges (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER,
+    issue TEXT,
+    CONSTRAINT fk_daily_tasks_challenges FOREIGN KEY (task_id) REFERENCES daily_tasks (id)
+);
+
+CREATE TABLE IF NOT EXISTS notes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER,
+    text TEXT,
+    CONSTRAINT fk_daily_tasks_notes FOREIGN KEY (task_id) REFERENCES daily_tasks (id)
+);
+
+CREATE TABLE IF NOT EXISTS comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER,
+    author TEXT,
+    content TEXT,
+    CONSTRAINT fk_daily_tasks_comments FOREIGN KEY (task_id) REFERENCES daily_tasks (id)
+);
@@ -0,0 +1,4 @@
+-- Failed login tracking for temporary account lockout
+
+ALTER TABLE users ADD COLUMN failed_login_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN locked_until DATETIME;
@@ -0,0 +1,16 @@
+-- Access token revocations. A row either revokes one token by its jti or,
+-- with jti NULL, every token of the user issued up to revoked_before. Rows
+-- are deleted once expires_at passes since the tokens they cover have
+-- expired by then.
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    jti TEXT,
+    user_id INTEGER NOT NULL,
+    revoked_before DATETIME,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_revoked_tokens_jti ON revoked_tokens (jti);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS mfa_recovery_codes;
+ALTER TABLE users DROP COLUMN mfa_last_step;
+ALTER TABLE users DROP COLUMN mfa_secret;
+ALTER TABLE users DROP COLUMN mfa_enabled;
@@ -0,0 +1,15 @@
+-- Accounts at OpenID Connect providers linked to users, for single sign-on
+
+CREATE TABLE IF NOT EXISTS external_identities (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT,
+    last_login_at DATETIME,
+    created_at DATETIME,
+    CONSTRAINT fk_users_external_identities FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_external_identities_subject ON external_identities (issuer, subject);
+CREATE INDEX IF NOT EXISTS idx_external_identities_user_id ON external_identities (user_id);
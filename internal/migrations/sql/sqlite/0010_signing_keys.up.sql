@@ -0,0 +1,14 @@
+-- Generated key pairs that sign JWTs, rotated on a schedule
+
+CREATE TABLE IF NOT EXISTS signing_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kid TEXT NOT NULL,
+    algorithm TEXT NOT NULL,
+    public_key TEXT NOT NULL,
+    private_key TEXT NOT NULL,
+    activates_at DATETIME NOT NULL,
+    created_at DATETIME
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_signing_keys_kid ON signing_keys (kid);
+CREATE INDEX IF NOT EXISTS idx_signing_keys_activates_at ON signing_keys (activates_at);
@@ -0,0 +1,25 @@
+-- Invitations to create an account with a pre-assigned role, company and
+-- country; tokens are stored as SHA-256 hashes
+
+CREATE TABLE IF NOT EXISTS invitations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL,
+    company_id INTEGER,
+    country_id INTEGER,
+    invited_by_id INTEGER NOT NULL,
+    token_hash TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    user_id INTEGER,
+    accepted_at DATETIME,
+    revoked_at DATETIME,
+    created_at DATETIME,
+    CONSTRAINT fk_companies_invitations FOREIGN KEY (company_id) REFERENCES companies (id) ON DELETE CASCADE,
+    CONSTRAINT fk_countries_invitations FOREIGN KEY (country_id) REFERENCES countries (id) ON DELETE CASCADE,
+    CONSTRAINT fk_users_invitations_sent FOREIGN KEY (invited_by_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_users_invitations_accepted FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invitations_token_hash ON invitations (token_hash);
+CREATE INDEX IF NOT EXISTS idx_invitations_email ON invitations (email);
+CREATE INDEX IF NOT EXISTS idx_invitations_invited_by_id ON invitations (invited_by_id);
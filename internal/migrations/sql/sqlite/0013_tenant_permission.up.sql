@@ -0,0 +1,4 @@
+-- Queries are limited to the company of the user; only admins see across
+-- companies by default.
+
+INSERT INTO role_permissions (role, permission) VALUES ('admin', 'tenant:all');
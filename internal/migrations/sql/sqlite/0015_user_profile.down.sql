@@ -0,0 +1,6 @@
+ALTER TABLE users DROP COLUMN pending_email;
+ALTER TABLE users DROP COLUMN notify_weekly_summary;
+ALTER TABLE users DROP COLUMN notify_task_reminders;
+ALTER TABLE users DROP COLUMN avatar_url;
+ALTER TABLE users DROP COLUMN locale;
+ALTER TABLE users DROP COLUMN timezone;
@@ -0,0 +1,9 @@
+-- Preferences users edit themselves, and the email address they are
+-- changing to until it is verified
+
+ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN avatar_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN notify_task_reminders NUMERIC NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN notify_weekly_summary NUMERIC NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN pending_email TEXT;
@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission = 'audit:read';
+DROP TRIGGER IF EXISTS audit_logs_append_only;
+DROP TABLE IF EXISTS audit_logs;
@@ -0,0 +1,26 @@
+-- Logins of users per device. A session is identified by the family of its
+-- refresh tokens; revoked_tokens rows with a session_id revoke the access
+-- tokens issued to one session.
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    expires_at DATETIME NOT NULL,
+    last_seen_at DATETIME NOT NULL,
+    revoked_at DATETIME,
+    created_at DATETIME,
+    CONSTRAINT fk_users_sessions FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+-- Logins made before sessions were tracked keep working and are listed
+INSERT INTO sessions (id, user_id, expires_at, last_seen_at, created_at)
+SELECT family_id, user_id, MAX(expires_at), MAX(created_at), MIN(created_at)
+FROM refresh_tokens
+WHERE rotated_at IS NULL AND revoked_at IS NULL
+GROUP BY family_id, user_id;
+
+ALTER TABLE revoked_tokens ADD COLUMN session_id TEXT;
@@ -0,0 +1,101 @@
+package errors
+
+import "net/http"
+
+// Code is a stable, machine-readable error identifier. Clients may switch on
+// it; messages are for humans and can change at any time.
+type Code string
+
+// Error code catalog. Codes are never renamed or reused once published.
+const (
+	// Request errors
+	CodeInvalidRequest   Code = "request.invalid"
+	CodeInvalidBody      Code = "request.invalid_body"
+	CodeInvalidID        Code = "request.invalid_id"
+	CodeMissingParameter Code = "request.missing_parameter"
+	CodeValidationFailed Code = "request.validation_failed"
+	CodeRateLimited      Code = "request.rate_limited"
+	CodeTimeout          Code = "request.timeout"
+	CodeRouteNotFound    Code = "route.not_found"
+	CodeMethodNotAllowed Code = "route.method_not_allowed"
+
+	// Authentication and authorization errors
+	CodeUnauthenticated      Code = "auth.unauthenticated"
+	CodeTokenMissing         Code = "auth.token_missing"
+	CodeTokenMalformed       Code = "auth.token_malformed"
+	CodeTokenInvalid         Code = "auth.token_invalid"
+	CodeTokenExpired         Code = "auth.token_expired"
+	CodeTokenRevoked         Code = "auth.token_revoked"
+	CodeInvalidCredentials   Code = "auth.invalid_credentials"
+	CodeAccountDeactivated   Code = "auth.account_deactivated"
+	CodeAccountLocked        Code = "auth.account_locked"
+	CodeRefreshTokenInvalid  Code = "auth.refresh_token_invalid"
+	CodeRefreshTokenReused   Code = "auth.refresh_token_reused"
+	CodeResetTokenInvalid    Code = "auth.reset_token_invalid"
+	CodeInvalidPassword      Code = "auth.invalid_current_password"
+	CodeEmailNotVerified     Code = "auth.email_not_verified"
+	CodeVerificationInvalid  Code = "auth.verification_token_invalid"
+	CodeMFACodeInvalid       Code = "auth.mfa_code_invalid"
+	CodeMFAChallenge         Code = "auth.mfa_challenge_invalid"
+	CodeMFAAlreadyEnabled    Code = "auth.mfa_already_enabled"
+	CodeMFANotEnrolled       Code = "auth.mfa_not_enrolled"
+	CodeMFARequired          Code = "auth.mfa_required"
+	CodeInsufficientScope    Code = "auth.insufficient_scope"
+	CodePermissionDenied     Code = "auth.permission_denied"
+	CodeSSOStateInvalid      Code = "auth.sso_state_invalid"
+	CodeSSOFailed            Code = "auth.sso_failed"
+	CodeSSONotLinked         Code = "auth.sso_not_linked"
+	CodeRegistrationDisabled Code = "auth.registration_disabled"
+	CodeInvitationInvalid    Code = "auth.invitation_invalid"
+	CodeForbidden            Code = "auth.forbidden"
+	CodeOutsideTenant        Code = "auth.outside_tenant"
+	CodeImpersonating        Code = "auth.impersonating"
+	CodeNotImpersonating     Code = "auth.not_impersonating"
+
+	// Resource errors
+	CodeNotFound           Code = "resource.not_found"
+	CodeConflict           Code = "resource.conflict"
+	CodeUserNotFound       Code = "user.not_found"
+	CodeEmailTaken         Code = "user.email_taken"
+	CodeUsernameTaken      Code = "user.username_taken"
+	CodeTokenNotFound      Code = "token.not_found"
+	CodeInvitationNotFound Code = "invitation.not_found"
+	CodeSessionNotFound    Code = "session.not_found"
+	CodeTaskNotFound       Code = "task.not_found"
+	CodeTaskNotOwner       Code = "task.not_owner"
+	CodeContinentNotFound  Code = "continent.not_found"
+	CodeCountryNotFound    Code = "country.not_found"
+	CodeCompanyNotFound    Code = "company.not_found"
+
+	// Server errors
+	CodeInternal           Code = "internal.error"
+	CodeDatabase           Code = "internal.database"
+	CodeServiceUnavailable Code = "service.unavailable"
+)
+
+// CodeForStatus returns the generic code used when an error has no more
+// specific one
+func CodeForStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeServiceUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeInvalidRequest
+}
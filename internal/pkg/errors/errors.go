@@ -1,15 +1,27 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
 	"net/http"
+
+	"github.com/go-playground/validator/v10"
 )
 
 // AppError represents an application error
 type AppError struct {
-	Code    int    `json:"code"`
+	Code      int          `json:"code"`
+	Message   string       `json:"message"`
+	ErrorCode Code         `json:"error_code"`
+	Fields    []FieldError `json:"errors,omitempty"`
+	Err       error        `json:"-"`
+}
+
+// FieldError describes why a single request field failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
 	Message string `json:"message"`
-	Err     error  `json:"-"`
 }
 
 // Error implements the error interface
@@ -25,12 +37,19 @@ func (e *AppError) Unwrap() error {
 	return e.Err
 }
 
+// WithCode replaces the generic error code with a more specific one
+func (e *AppError) WithCode(code Code) *AppError {
+	e.ErrorCode = code
+	return e
+}
+
 // New creates a new AppError
 func New(code int, message string, err error) *AppError {
 	return &AppError{
-		Code:    code,
-		Message: message,
-		Err:     err,
+		Code:      code,
+		Message:   message,
+		ErrorCode: CodeForStatus(code),
+		Err:       err,
 	}
 }
 
@@ -51,17 +70,43 @@ func NotFound(message string, err error) *AppError {
 	return New(http.StatusNotFound, message, err)
 }
 
+func Conflict(message string, err error) *AppError {
+	return New(http.StatusConflict, message, err)
+}
+
+func TooManyRequests(message string, err error) *AppError {
+	return New(http.StatusTooManyRequests, message, err)
+}
+
 func InternalServerError(message string, err error) *AppError {
 	return New(http.StatusInternalServerError, message, err)
 }
 
+func ServiceUnavailable(message string, err error) *AppError {
+	return New(http.StatusServiceUnavailable, message, err)
+}
+
+// ValidationError reports a failed request validation. Validator errors are
+// broken down into one FieldError per offending field.
 func ValidationError(message string, err error) *AppError {
-	return New(http.StatusBadRequest, message, err)
+	appErr := New(http.StatusBadRequest, message, err).WithCode(CodeValidationFailed)
+
+	var validationErrs validator.ValidationErrors
+	if stderrors.As(err, &validationErrs) {
+		for _, fe := range validationErrs {
+			appErr.Fields = append(appErr.Fields, FieldError{
+				Field:   fieldPath(fe),
+				Rule:    fe.Tag(),
+				Message: fieldMessage(fe),
+			})
+		}
+	}
+	return appErr
 }
 
 // Database errors
 func DatabaseError(message string, err error) *AppError {
-	return InternalServerError(fmt.Sprintf("Database error: %s", message), err)
+	return InternalServerError(fmt.Sprintf("Database error: %s", message), err).WithCode(CodeDatabase)
 }
 
 // Authentication errors
@@ -73,3 +118,34 @@ func AuthenticationError(message string, err error) *AppError {
 func AuthorizationError(message string, err error) *AppError {
 	return Forbidden(fmt.Sprintf("Authorization error: %s", message), err)
 }
+
+// fieldPath drops the root struct name from the validator namespace so
+// "LoginRequest.email" becomes "email"
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	for i := 0; i < len(namespace); i++ {
+		if namespace[i] == '.' {
+			return namespace[i+1:]
+		}
+	}
+	return fe.Field()
+}
+
+// fieldMessage renders a short human-readable explanation of a failed rule
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "len":
+		return fmt.Sprintf("must be exactly %s characters", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	}
+	return fmt.Sprintf("failed the %q rule", fe.Tag())
+}
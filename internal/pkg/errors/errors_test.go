@@ -0,0 +1,60 @@
+package errors
+
+import (
+	stderrors "errors"
+	"net/http"
+	"testing"
+
+	"github.com/alxand/nalo-workspace/internal/pkg/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstructors_DefaultCodes(t *testing.T) {
+	assert.Equal(t, CodeInvalidRequest, BadRequest("bad", nil).ErrorCode)
+	assert.Equal(t, CodeUnauthenticated, Unauthorized("who", nil).ErrorCode)
+	assert.Equal(t, CodeForbidden, Forbidden("no", nil).ErrorCode)
+	assert.Equal(t, CodeNotFound, NotFound("gone", nil).ErrorCode)
+	assert.Equal(t, CodeConflict, Conflict("taken", nil).ErrorCode)
+	assert.Equal(t, CodeRateLimited, TooManyRequests("slow down", nil).ErrorCode)
+	assert.Equal(t, CodeInternal, InternalServerError("oops", nil).ErrorCode)
+	assert.Equal(t, CodeDatabase, DatabaseError("oops", nil).ErrorCode)
+	assert.Equal(t, CodeServiceUnavailable, ServiceUnavailable("down", nil).ErrorCode)
+}
+
+func TestWithCode(t *testing.T) {
+	err := NotFound("Task not found", nil).WithCode(CodeTaskNotFound)
+	assert.Equal(t, CodeTaskNotFound, err.ErrorCode)
+	assert.Equal(t, http.StatusNotFound, err.Code)
+}
+
+func TestValidationError_FieldErrors(t *testing.T) {
+	validation.Init()
+	type request struct {
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"required,min=8"`
+	}
+
+	err := ValidationError("Validation failed", validation.ValidateStruct(request{Email: "nope", Password: "short"}))
+
+	assert.Equal(t, CodeValidationFailed, err.ErrorCode)
+	assert.Equal(t, []FieldError{
+		{Field: "email", Rule: "email", Message: "must be a valid email address"},
+		{Field: "password", Rule: "min", Message: "must be at least 8"},
+	}, err.Fields)
+}
+
+func TestValidationError_NonValidatorError(t *testing.T) {
+	err := ValidationError("Validation failed", stderrors.New("custom check failed"))
+	assert.Empty(t, err.Fields)
+	assert.Equal(t, CodeValidationFailed, err.ErrorCode)
+}
+
+func TestProblem(t *testing.T) {
+	problem := ValidationError("Validation failed", nil).Problem()
+	assert.Equal(t, "about:blank", problem.Type)
+	assert.Equal(t, "Bad Request", problem.Title)
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, "Validation failed", problem.Detail)
+	assert.Equal(t, CodeValidationFailed, problem.Code)
+	assert.Empty(t, problem.Debug)
+}
@@ -0,0 +1,43 @@
+package errors
+
+import "net/http"
+
+// ProblemContentType is the media type of RFC 7807 error responses
+const ProblemContentType = "application/problem+json"
+
+// Problem is the RFC 7807 problem details body returned for every error.
+// Code, RequestID, Errors and Debug are extension members.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	Debug     string       `json:"debug,omitempty"`
+}
+
+// NewProblem builds a problem for the given status. The type is left as
+// about:blank since codes, not URIs, identify the error kind.
+func NewProblem(status int, code Code, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Problem converts the error into a problem body. Err is deliberately left
+// out; the caller decides whether internal details may be exposed.
+func (e *AppError) Problem() *Problem {
+	problem := NewProblem(e.Code, e.ErrorCode, e.Message)
+	if problem.Code == "" {
+		problem.Code = CodeForStatus(e.Code)
+	}
+	problem.Errors = e.Fields
+	return problem
+}
@@ -0,0 +1,158 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Check statuses
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc reports an error when a dependency is unhealthy
+type CheckFunc func(ctx context.Context) error
+
+// Result is the outcome of a single readiness check
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report aggregates every check result
+type Report struct {
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+	Checks    []Result  `json:"checks"`
+}
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Registry holds the readiness checks and the shutdown flag
+type Registry struct {
+	timeout      time.Duration
+	mu           sync.RWMutex
+	checks       []namedCheck
+	shuttingDown atomic.Bool
+}
+
+// NewRegistry creates a registry whose checks each get the given timeout
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout}
+}
+
+// Register adds a named readiness check
+func (r *Registry) Register(name string, check CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, namedCheck{name: name, check: check})
+}
+
+// SetShuttingDown makes readiness fail so load balancers stop routing
+// traffic while in-flight requests drain
+func (r *Registry) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Run executes all checks concurrently and returns the combined report
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]namedCheck(nil), r.checks...)
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c namedCheck) {
+			defer wg.Done()
+			results[i] = r.runCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Timestamp: time.Now().UTC(), Checks: results}
+	if r.shuttingDown.Load() {
+		report.Status = StatusFail
+		report.Checks = append(report.Checks, Result{Name: "shutdown", Status: StatusFail, Error: "server is shutting down"})
+	}
+	for _, result := range results {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// runCheck bounds a check by the registry timeout even if it ignores ctx
+func (r *Registry) runCheck(ctx context.Context, c namedCheck) Result {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- c.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{
+		Name:      c.name,
+		Status:    StatusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler reports that the process is up and serving requests.
+// It deliberately checks no dependencies so a database outage does not
+// get the container restarted.
+func LivenessHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"status":    StatusOK,
+			"timestamp": time.Now().UTC(),
+		})
+	}
+}
+
+// ReadinessHandler runs the registered checks and answers 503 when any fails
+func (r *Registry) ReadinessHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		report := r.Run(c.UserContext())
+		status := fiber.StatusOK
+		if report.Status != StatusOK {
+			status = fiber.StatusServiceUnavailable
+		}
+		return c.Status(status).JSON(report)
+	}
+}
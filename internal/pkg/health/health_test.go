@@ -0,0 +1,87 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTestApp(registry *Registry) *fiber.App {
+	app := fiber.New()
+	app.Get("/livez", LivenessHandler())
+	app.Get("/readyz", registry.ReadinessHandler())
+	return app
+}
+
+func getReport(t *testing.T, app *fiber.App) (int, Report) {
+	resp, err := app.Test(httptest.NewRequest("GET", "/readyz", nil))
+	assert.NoError(t, err)
+
+	var report Report
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	return resp.StatusCode, report
+}
+
+func TestReadiness_AllChecksPass(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register("database", func(ctx context.Context) error { return nil })
+	registry.Register("migrations", func(ctx context.Context) error { return nil })
+
+	status, report := getReport(t, setupTestApp(registry))
+
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, "database", report.Checks[0].Name)
+}
+
+func TestReadiness_FailingCheck(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register("database", func(ctx context.Context) error { return errors.New("connection refused") })
+	registry.Register("migrations", func(ctx context.Context) error { return nil })
+
+	status, report := getReport(t, setupTestApp(registry))
+
+	assert.Equal(t, fiber.StatusServiceUnavailable, status)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, StatusFail, report.Checks[0].Status)
+	assert.Equal(t, "connection refused", report.Checks[0].Error)
+	assert.Equal(t, StatusOK, report.Checks[1].Status)
+}
+
+func TestReadiness_SlowCheckTimesOut(t *testing.T) {
+	registry := NewRegistry(50 * time.Millisecond)
+	registry.Register("database", func(ctx context.Context) error {
+		time.Sleep(time.Second) // ignores ctx on purpose
+		return nil
+	})
+
+	start := time.Now()
+	status, report := getReport(t, setupTestApp(registry))
+
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, fiber.StatusServiceUnavailable, status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+}
+
+func TestReadiness_FailsWhileShuttingDown(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register("database", func(ctx context.Context) error { return nil })
+	app := setupTestApp(registry)
+
+	registry.SetShuttingDown()
+	status, report := getReport(t, app)
+	assert.Equal(t, fiber.StatusServiceUnavailable, status)
+	assert.Equal(t, StatusFail, report.Status)
+
+	// Liveness is unaffected
+	resp, err := app.Test(httptest.NewRequest("GET", "/livez", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,34 @@
+package logger
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+const localsKey = "logger"
+
+// SetCtx stores the request-scoped logger in the Fiber context
+func SetCtx(c *fiber.Ctx, l *zap.Logger) {
+	c.Locals(localsKey, l)
+}
+
+// FromCtx returns the request-scoped logger annotated with the matched
+// route, or fallback when the request has none (for example in tests that
+// skip the request ID middleware)
+func FromCtx(c *fiber.Ctx, fallback *zap.Logger) *zap.Logger {
+	l, ok := c.Locals(localsKey).(*zap.Logger)
+	if !ok {
+		if fallback == nil {
+			return Get()
+		}
+		return fallback
+	}
+	return l.With(zap.String("route", c.Route().Path))
+}
+
+// AddCtxFields adds fields to the request-scoped logger, if there is one
+func AddCtxFields(c *fiber.Ctx, fields ...zap.Field) {
+	if l, ok := c.Locals(localsKey).(*zap.Logger); ok {
+		c.Locals(localsKey, l.With(fields...))
+	}
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const startKey = "metrics:start"
+
+// GormPlugin is a GORM plugin that records how long each statement takes,
+// labelled by operation.
+type GormPlugin struct{}
+
+// NewGormPlugin creates the GORM metrics plugin
+func NewGormPlugin() *GormPlugin {
+	return &GormPlugin{}
+}
+
+// Name implements gorm.Plugin
+func (p *GormPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize implements gorm.Plugin
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	// Only the statement itself is timed, not hooks or preloads around it
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("metrics:before_create", p.before); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("gorm:create").Register("metrics:after_create", p.after("create")); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("metrics:before_query", p.before); err != nil {
+		return err
+	}
+	if err := callbacks.Query().After("gorm:query").Register("metrics:after_query", p.after("query")); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("metrics:before_update", p.before); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("metrics:after_update", p.after("update")); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("metrics:before_delete", p.before); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:delete").Register("metrics:after_delete", p.after("delete")); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("metrics:before_row", p.before); err != nil {
+		return err
+	}
+	if err := callbacks.Row().After("gorm:row").Register("metrics:after_row", p.after("row")); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().Before("gorm:raw").Register("metrics:before_raw", p.before); err != nil {
+		return err
+	}
+	return callbacks.Raw().After("gorm:raw").Register("metrics:after_raw", p.after("raw"))
+}
+
+func (p *GormPlugin) before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func (p *GormPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if start, ok := db.InstanceGet(startKey); ok {
+			ObserveQuery(operation, time.Since(start.(time.Time)))
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute labels requests the router could not match, so arbitrary
+// 404 paths cannot blow up label cardinality
+const unmatchedRoute = "unmatched"
+
+var errInvalidToken = stderrors.New("bearer token does not match METRICS_TOKEN")
+
+// Middleware records request count and latency labelled by route template.
+// Errors are rendered through the app's error handler here so the recorded
+// status matches what the client receives. Panics are recorded as the 500
+// the recover middleware answers with.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		var err error
+		finished := false
+
+		defer func() {
+			status := c.Response().StatusCode()
+			if !finished {
+				status = fiber.StatusInternalServerError
+			}
+			// Fiber strings alias reusable request buffers, and label values outlive the request
+			ObserveRequest(strings.Clone(c.Method()), routeLabel(c, err), status, time.Since(start))
+		}()
+
+		err = c.Next()
+		finished = true
+		if err != nil {
+			if handlerErr := c.App().Config().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+		return nil
+	}
+}
+
+// routeLabel returns the template of the route that handled the request.
+// When only middleware ran and the router reported no match, the request
+// is labelled unmatched.
+func routeLabel(c *fiber.Ctx, err error) string {
+	route := c.Route()
+	if !isMiddleware(route) {
+		return route.Path
+	}
+
+	var fiberErr *fiber.Error
+	if stderrors.As(err, &fiberErr) &&
+		(fiberErr.Code == fiber.StatusNotFound || fiberErr.Code == fiber.StatusMethodNotAllowed) {
+		return unmatchedRoute
+	}
+	return route.Path
+}
+
+// isMiddleware reports whether the route was registered with Use. Fiber
+// keeps this unexported; without the field every route counts as matched.
+func isMiddleware(route *fiber.Route) bool {
+	use := reflect.ValueOf(route).Elem().FieldByName("use")
+	return use.IsValid() && use.Kind() == reflect.Bool && use.Bool()
+}
+
+// Handler serves the registry in the Prometheus exposition format. Scrapers
+// must connect from one of networks and, when token is set, present it as a
+// bearer token.
+func Handler(networks []*net.IPNet, token string) fiber.Handler {
+	serve := adaptor.HTTPHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+
+	return func(c *fiber.Ctx) error {
+		if !allowed(networks, net.ParseIP(c.IP())) {
+			return errors.Forbidden("Metrics are not available from this address", fmt.Errorf("address %s is not allowed", c.IP()))
+		}
+
+		if token != "" {
+			provided := strings.TrimPrefix(c.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				return errors.Unauthorized("Invalid metrics token", errInvalidToken).WithCode(errors.CodeTokenInvalid)
+			}
+		}
+
+		return serve(c)
+	}
+}
+
+func allowed(networks []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
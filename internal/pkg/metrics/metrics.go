@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "nalo"
+
+// Registry holds every collector exposed on /metrics. A dedicated registry
+// keeps tests and multiple app instances from clashing with the global one.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests processed, by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "GORM statement latency, by operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+
+	tasksCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_created_total",
+		Help:      "Daily tasks created.",
+	})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_logins_total",
+		Help:      "Login attempts, by result.",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		dbQueryDuration,
+		tasksCreated,
+		logins,
+	)
+
+	// Expose both results from the start so rate() works before the first failure
+	logins.WithLabelValues("success")
+	logins.WithLabelValues("failure")
+}
+
+// RegisterDB exposes connection pool statistics from sql.DB.Stats()
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveRequest records a finished HTTP request
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+// ObserveQuery records the duration of a database statement
+func ObserveQuery(operation string, duration time.Duration) {
+	dbQueryDuration.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+// TaskCreated counts a created daily task
+func TaskCreated() {
+	tasksCreated.Inc()
+}
+
+// LoginSucceeded counts a successful login
+func LoginSucceeded() {
+	logins.WithLabelValues("success").Inc()
+}
+
+// LoginFailed counts a rejected login
+func LoginFailed() {
+	logins.WithLabelValues("failure").Inc()
+}
@@ -0,0 +1,196 @@
+package metrics
+
+import (
+	"io"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestApp() *fiber.App {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			if appErr, ok := err.(*errors.AppError); ok {
+				return c.Status(appErr.Code).SendString(appErr.Message)
+			}
+			if fiberErr, ok := err.(*fiber.Error); ok {
+				return c.Status(fiberErr.Code).SendString(fiberErr.Message)
+			}
+			return c.SendStatus(fiber.StatusInternalServerError)
+		},
+	})
+	app.Use(recover.New())
+	app.Use(Middleware())
+	app.Get("/tasks/:id", func(c *fiber.Ctx) error {
+		switch c.Params("id") {
+		case "missing":
+			return errors.NotFound("Task not found", nil)
+		case "gone":
+			return fiber.ErrNotFound
+		case "broken":
+			panic("task is broken")
+		}
+		return c.SendString("ok")
+	})
+	return app
+}
+
+func mustParseNetwork(t *testing.T, cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", cidr, err)
+	}
+	return network
+}
+
+func TestMiddleware_LabelsByRouteTemplate(t *testing.T) {
+	app := setupTestApp()
+	before := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/tasks/:id", "200"))
+
+	for _, id := range []string{"1", "2", "3"} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/tasks/"+id, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	}
+
+	assert.Equal(t, before+3, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/tasks/:id", "200")))
+}
+
+func TestMiddleware_RecordsErrorStatus(t *testing.T) {
+	app := setupTestApp()
+	before := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/tasks/:id", "404"))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/tasks/missing", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	assert.Equal(t, before+1, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/tasks/:id", "404")))
+}
+
+func TestMiddleware_NotFoundFromMatchedRouteKeepsRoute(t *testing.T) {
+	app := setupTestApp()
+	before := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/tasks/:id", "404"))
+	unmatched := testutil.ToFloat64(httpRequests.WithLabelValues("GET", unmatchedRoute, "404"))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/tasks/gone", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	assert.Equal(t, before+1, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/tasks/:id", "404")))
+	assert.Equal(t, unmatched, testutil.ToFloat64(httpRequests.WithLabelValues("GET", unmatchedRoute, "404")))
+}
+
+func TestMiddleware_RecordsPanicsAsServerErrors(t *testing.T) {
+	app := setupTestApp()
+	before := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/tasks/:id", "500"))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/tasks/broken", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+
+	assert.Equal(t, before+1, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/tasks/:id", "500")))
+}
+
+func TestMiddleware_UnmatchedRoutesShareALabel(t *testing.T) {
+	app := setupTestApp()
+	before := testutil.ToFloat64(httpRequests.WithLabelValues("GET", unmatchedRoute, "404"))
+
+	for _, path := range []string{"/nope", "/also/nope"} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	}
+
+	assert.Equal(t, before+2, testutil.ToFloat64(httpRequests.WithLabelValues("GET", unmatchedRoute, "404")))
+}
+
+func TestHandler_AccessControl(t *testing.T) {
+	anywhere := []*net.IPNet{mustParseNetwork(t, "0.0.0.0/0")}
+	loopback := []*net.IPNet{mustParseNetwork(t, "127.0.0.1/32")}
+
+	tests := []struct {
+		name     string
+		networks []*net.IPNet
+		token    string
+		header   string
+		status   int
+	}{
+		{"allowed network", anywhere, "", "", fiber.StatusOK},
+		{"disallowed network", loopback, "", "", fiber.StatusForbidden},
+		{"missing token", anywhere, "secret", "", fiber.StatusUnauthorized},
+		{"wrong token", anywhere, "secret", "Bearer wrong", fiber.StatusUnauthorized},
+		{"valid token", anywhere, "secret", "Bearer secret", fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := setupTestApp()
+			app.Get("/metrics", Handler(tt.networks, tt.token))
+
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+
+			if tt.status == fiber.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				assert.Contains(t, string(body), "nalo_http_requests_total")
+				assert.Contains(t, string(body), "nalo_auth_logins_total")
+			}
+		})
+	}
+}
+
+func TestGormPlugin_ObservesQueries(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	assert.NoError(t, db.Use(NewGormPlugin()))
+
+	type widget struct {
+		ID   int64
+		Name string
+	}
+	assert.NoError(t, db.Exec("CREATE TABLE widgets (id INTEGER PRIMARY KEY, name TEXT)").Error)
+
+	assert.NoError(t, db.Create(&widget{Name: "gear"}).Error)
+	var widgets []widget
+	assert.NoError(t, db.Find(&widgets).Error)
+
+	operations := collectOperations(t)
+	assert.Contains(t, operations, "create")
+	assert.Contains(t, operations, "query")
+	assert.Contains(t, operations, "raw")
+}
+
+func collectOperations(t *testing.T) []string {
+	families, err := Registry.Gather()
+	assert.NoError(t, err)
+
+	var operations []string
+	for _, family := range families {
+		if family.GetName() != "nalo_db_query_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "operation" && metric.GetHistogram().GetSampleCount() > 0 {
+					operations = append(operations, label.GetValue())
+				}
+			}
+		}
+	}
+	return operations
+}
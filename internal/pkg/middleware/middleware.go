@@ -1,73 +1,226 @@
 package middleware
 
 import (
+	"context"
+	stderrors "errors"
 	"strings"
+	"time"
 
+	"github.com/alxand/nalo-workspace/internal/api/audit"
 	"github.com/alxand/nalo-workspace/internal/api/auth"
+	"github.com/alxand/nalo-workspace/internal/config"
 	"github.com/alxand/nalo-workspace/internal/domain/models"
 	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	applogger "github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/alxand/nalo-workspace/internal/pkg/tenant"
+	"github.com/alxand/nalo-workspace/internal/pkg/tracing"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
-// ErrorHandler handles application errors
-func ErrorHandler(logger *zap.Logger) fiber.ErrorHandler {
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+const (
+	requestIDKey       = "request_id"
+	maxRequestIDLength = 128
+)
+
+// ErrorHandler renders every error as an RFC 7807 problem. Wrapped
+// internal errors are only included in the response when debug is true.
+func ErrorHandler(logger *zap.Logger, debug bool) fiber.ErrorHandler {
 	return func(c *fiber.Ctx, err error) error {
-		// Check if it's our custom AppError
-		if appErr, ok := err.(*errors.AppError); ok {
-			logger.Error("Application error",
+		log := requestLogger(c, logger)
+
+		var problem *errors.Problem
+		var appErr *errors.AppError
+		var fiberErr *fiber.Error
+		switch {
+		case stderrors.As(err, &appErr):
+			log.Error("Application error",
 				zap.String("path", c.Path()),
 				zap.String("method", c.Method()),
 				zap.Int("status", appErr.Code),
+				zap.String("error_code", string(appErr.ErrorCode)),
 				zap.String("message", appErr.Message),
 				zap.Error(appErr.Err),
 			)
-			return c.Status(appErr.Code).JSON(fiber.Map{
-				"error":   appErr.Message,
-				"code":    appErr.Code,
-				"details": appErr.Err.Error(),
-			})
+			problem = appErr.Problem()
+			if debug && appErr.Err != nil {
+				problem.Debug = appErr.Err.Error()
+			}
+
+		// Router errors such as 404 and 405 keep their status
+		case stderrors.As(err, &fiberErr):
+			problem = errors.NewProblem(fiberErr.Code, fiberErrorCode(fiberErr.Code), fiberErr.Message)
+
+		default:
+			log.Error("Unhandled error",
+				zap.String("path", c.Path()),
+				zap.String("method", c.Method()),
+				zap.Error(err),
+			)
+			problem = errors.NewProblem(fiber.StatusInternalServerError, errors.CodeInternal, "Internal server error")
+			if debug {
+				problem.Debug = err.Error()
+			}
 		}
 
-		// Handle other errors
-		logger.Error("Unhandled error",
-			zap.String("path", c.Path()),
-			zap.String("method", c.Method()),
-			zap.Error(err),
-		)
+		problem.Instance = c.Path()
+		problem.RequestID = GetRequestID(c)
+		return c.Status(problem.Status).JSON(problem, errors.ProblemContentType)
+	}
+}
 
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Internal server error",
-		})
+// fiberErrorCode maps router errors onto the error code catalog
+func fiberErrorCode(status int) errors.Code {
+	switch status {
+	case fiber.StatusNotFound:
+		return errors.CodeRouteNotFound
+	case fiber.StatusMethodNotAllowed:
+		return errors.CodeMethodNotAllowed
 	}
+	return errors.CodeForStatus(status)
 }
 
-// JWT middleware using the auth service
-func JWT(authService *auth.Service) fiber.Handler {
+// JWT authenticates requests with an access token from the auth service
+// or a personal access token. Requests made with a personal access token
+// carry it in the "access_token" local for RequireScope, and those made
+// with an impersonation token the acting admin in the "impersonator" local.
+func JWT(authService *auth.Service, accessTokens *auth.AccessTokenService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
-			return errors.Unauthorized("Missing authorization header", nil)
+			return errors.Unauthorized("Missing authorization header", nil).WithCode(errors.CodeTokenMissing)
 		}
 
 		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenStr == authHeader {
-			return errors.Unauthorized("Invalid authorization header format", nil)
+			return errors.Unauthorized("Invalid authorization header format", nil).WithCode(errors.CodeTokenMalformed)
 		}
 
-		// Get user from token
-		user, err := authService.GetUserFromToken(tokenStr)
+		if auth.IsAccessToken(tokenStr) {
+			user, token, err := accessTokens.Authenticate(c.UserContext(), tokenStr)
+			if err != nil {
+				if stderrors.Is(err, auth.ErrAccountDeactivated) {
+					return errors.Unauthorized("Account is deactivated", err).WithCode(errors.CodeAccountDeactivated)
+				}
+				return errors.Unauthorized("Invalid, expired or revoked personal access token", err).WithCode(errors.CodeTokenInvalid)
+			}
+			c.Locals("user", user)
+			c.Locals("access_token", token)
+			applogger.AddCtxFields(c, zap.Int64("user_id", user.ID), zap.Int64("access_token_id", token.ID))
+			c.SetUserContext(audit.WithActor(c.UserContext(), user.ID, 0))
+			return c.Next()
+		}
+
+		// Get user, and the admin acting as them when impersonating, from token
+		user, impersonator, err := authService.AuthenticateToken(c.UserContext(), tokenStr)
 		if err != nil {
-			return errors.Unauthorized("Invalid or expired token", err)
+			switch {
+			case stderrors.Is(err, auth.ErrTokenExpired):
+				return errors.Unauthorized("Token has expired", err).WithCode(errors.CodeTokenExpired)
+			case stderrors.Is(err, auth.ErrTokenRevoked):
+				return errors.Unauthorized("Token has been revoked", err).WithCode(errors.CodeTokenRevoked)
+			case stderrors.Is(err, auth.ErrAccountDeactivated):
+				return errors.Unauthorized("Account is deactivated", err).WithCode(errors.CodeAccountDeactivated)
+			}
+			return errors.Unauthorized("Invalid or expired token", err).WithCode(errors.CodeTokenInvalid)
 		}
 
-		// Add user to context
+		// Add user and the raw token, needed to revoke it on logout, to context
 		c.Locals("user", user)
+		c.Locals("token", tokenStr)
+		applogger.AddCtxFields(c, zap.Int64("user_id", user.ID))
+
+		// Every log line and audit log entry of an impersonated request
+		// names the admin
+		var impersonatorID int64
+		if impersonator != nil {
+			impersonatorID = impersonator.ID
+			c.Locals("impersonator", impersonator)
+			applogger.AddCtxFields(c, zap.Int64("impersonator_id", impersonator.ID), zap.Bool("impersonated", true))
+		}
+		c.SetUserContext(audit.WithActor(c.UserContext(), user.ID, impersonatorID))
+		return c.Next()
+	}
+}
+
+// RequireScope limits requests made with a personal access token to tokens
+// granting one of scopes. Requests made with a login session pass.
+func RequireScope(scopes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, ok := c.Locals("access_token").(*models.PersonalAccessToken)
+		if !ok {
+			return c.Next()
+		}
+		for _, scope := range scopes {
+			if token.HasScope(scope) {
+				return c.Next()
+			}
+		}
+		return errors.Forbidden("Personal access token lacks the required scope", nil).WithCode(errors.CodeInsufficientScope)
+	}
+}
+
+// RequireSession refuses personal access tokens, for routes that manage the
+// account and need a real login
+func RequireSession() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("access_token").(*models.PersonalAccessToken); ok {
+			return errors.Forbidden("Personal access tokens cannot be used here", nil).WithCode(errors.CodeInsufficientScope)
+		}
+		return c.Next()
+	}
+}
+
+// NotImpersonating refuses impersonation tokens, for routes that change
+// how the account is secured or remove it
+func NotImpersonating() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("impersonator").(*models.User); ok {
+			return errors.Forbidden("Not allowed while impersonating", nil).WithCode(errors.CodeImpersonating)
+		}
+		return c.Next()
+	}
+}
+
+// RequirePermission refuses users whose role grants none of permissions
+func RequirePermission(permissionService *auth.PermissionService, permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := c.Locals("user").(*models.User)
+		if !ok || user == nil {
+			return errors.Unauthorized("User not found in context", nil)
+		}
+		if !permissionService.Has(user, permissions...) {
+			return errors.Forbidden("Insufficient permissions", nil).WithCode(errors.CodePermissionDenied)
+		}
+		return c.Next()
+	}
+}
+
+// Tenant limits the repository queries of the request to the company of
+// the user, unless their role has tenant:all
+func Tenant(permissionService *auth.PermissionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := c.Locals("user").(*models.User)
+		if !ok || user == nil {
+			return errors.Unauthorized("User not found in context", nil)
+		}
+		t := tenant.Tenant{UserID: user.ID, All: permissionService.Has(user, models.PermissionTenantAll)}
+		if user.CompanyID != nil {
+			t.CompanyID = *user.CompanyID
+		}
+		c.SetUserContext(tenant.NewContext(c.UserContext(), t))
 		return c.Next()
 	}
 }
 
-// RoleMiddleware checks if user has required role
+// RoleMiddleware checks if user has required role.
+//
+// Deprecated: routes check permissions with RequirePermission, which admins
+// can change per role.
 func RoleMiddleware(requiredRoles ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		user := c.Locals("user")
@@ -98,21 +251,52 @@ func RoleMiddleware(requiredRoles ...string) fiber.Handler {
 	}
 }
 
-// AdminMiddleware checks if user is admin
+// AdminMiddleware checks if user is admin.
+//
+// Deprecated: use RequirePermission.
 func AdminMiddleware() fiber.Handler {
 	return RoleMiddleware("admin")
 }
 
+// VerifiedEmail refuses users whose email address is not verified yet
+func VerifiedEmail() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := c.Locals("user").(*models.User)
+		if !ok || user == nil {
+			return errors.Unauthorized("User not found in context", nil)
+		}
+		if !user.IsEmailVerified() {
+			return errors.Forbidden("Email address is not verified", nil).WithCode(errors.CodeEmailNotVerified)
+		}
+		return c.Next()
+	}
+}
+
+// MFAEnrolled refuses users whose role requires two-factor authentication
+// until they have turned it on
+func MFAEnrolled(cfg config.MFAConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := c.Locals("user").(*models.User)
+		if !ok || user == nil {
+			return errors.Unauthorized("User not found in context", nil)
+		}
+		if cfg.Requires(string(user.Role)) && !user.MFAEnabled {
+			return errors.Forbidden("Two-factor authentication must be enabled for this account", nil).WithCode(errors.CodeMFARequired)
+		}
+		return c.Next()
+	}
+}
+
 // RequestLogger logs incoming requests
 func RequestLogger(logger *zap.Logger) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		start := c.Context().Time()
+		start := time.Now()
 
 		err := c.Next()
 
-		duration := c.Context().Time().Sub(start)
+		duration := time.Since(start)
 
-		logger.Info("Request processed",
+		requestLogger(c, logger).Info("Request processed",
 			zap.String("method", c.Method()),
 			zap.String("path", c.Path()),
 			zap.Int("status", c.Response().StatusCode()),
@@ -125,6 +309,95 @@ func RequestLogger(logger *zap.Logger) fiber.Handler {
 	}
 }
 
+// RequestID accepts a well-formed X-Request-ID from the client or generates
+// one, echoes it on the response and stores a request-scoped logger carrying
+// it (and the trace ID) for handlers to use via logger.FromCtx
+func RequestID(logger *zap.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID := c.Get(RequestIDHeader)
+		if validRequestID(requestID) {
+			// Fiber strings alias the request buffer; the ID outlives it in logs
+			requestID = strings.Clone(requestID)
+		} else {
+			requestID = uuid.NewString()
+		}
+
+		c.Locals(requestIDKey, requestID)
+		c.Set(RequestIDHeader, requestID)
+		applogger.SetCtx(c, logger.With(append(
+			[]zap.Field{zap.String("request_id", requestID)},
+			tracing.LogFields(c.UserContext())...,
+		)...))
+
+		return c.Next()
+	}
+}
+
+// Audit records where the request came from, for the audit log entries
+// it writes. It runs after RequestID; JWT adds the user.
+func Audit() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Fiber strings alias the request buffer; entries may be written
+		// after it is reused
+		c.SetUserContext(audit.NewContext(c.UserContext(), audit.Source{
+			IP:        strings.Clone(c.IP()),
+			UserAgent: strings.Clone(c.Get(fiber.HeaderUserAgent)),
+			RequestID: GetRequestID(c),
+		}))
+		return c.Next()
+	}
+}
+
+// GetRequestID returns the ID assigned by RequestID, or "" when the
+// middleware is not installed
+func GetRequestID(c *fiber.Ctx) string {
+	requestID, _ := c.Locals(requestIDKey).(string)
+	return requestID
+}
+
+// validRequestID rejects IDs that are empty, overly long or could forge
+// log output
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// requestLogger prefers the request-scoped logger set up by RequestID and
+// otherwise annotates base with the current trace
+func requestLogger(c *fiber.Ctx, base *zap.Logger) *zap.Logger {
+	return applogger.FromCtx(c, base.With(tracing.LogFields(c.UserContext())...))
+}
+
+// RequestTimeout bounds the request's user context with a deadline so that
+// repository calls made through c.UserContext() are cancelled with it
+func RequestTimeout(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if timeout <= 0 {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+		if err != nil && stderrors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return errors.ServiceUnavailable("Request timed out", err).WithCode(errors.CodeTimeout)
+		}
+		return err
+	}
+}
+
 // CORS middleware
 func CORS() fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -0,0 +1,334 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/api/auth"
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func setupTestApp(log *zap.Logger) *fiber.App {
+	return setupTestAppWithDebug(log, false)
+}
+
+func setupTestAppWithDebug(log *zap.Logger, debug bool) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler(log, debug)})
+	app.Use(RequestID(log))
+	app.Get("/tasks/:id", func(c *fiber.Ctx) error {
+		// Stands in for JWT, which needs a full auth service
+		logger.AddCtxFields(c, zap.Int64("user_id", 7))
+		logger.FromCtx(c, nil).Info("Handling task")
+		return c.SendString("ok")
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return errors.NotFound("Task not found", nil).WithCode(errors.CodeTaskNotFound)
+	})
+	app.Get("/db", func(c *fiber.Ctx) error {
+		return errors.DatabaseError("Failed to get tasks", stderrors.New("pq: relation \"daily_tasks\" does not exist"))
+	})
+	app.Get("/panic-like", func(c *fiber.Ctx) error {
+		return stderrors.New("raw internal failure")
+	})
+	return app
+}
+
+func decodeBody(t *testing.T, r io.Reader) map[string]interface{} {
+	var body map[string]interface{}
+	assert.NoError(t, json.NewDecoder(r).Decode(&body))
+	return body
+}
+
+func TestRequestID_GeneratedWhenMissing(t *testing.T) {
+	app := setupTestApp(zap.NewNop())
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/tasks/1", nil))
+	assert.NoError(t, err)
+	assert.Len(t, resp.Header.Get(RequestIDHeader), 36)
+}
+
+func TestRequestID_AcceptsValidIncomingID(t *testing.T) {
+	app := setupTestApp(zap.NewNop())
+
+	req := httptest.NewRequest("GET", "/tasks/1", nil)
+	req.Header.Set(RequestIDHeader, "edge-1234.abc")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "edge-1234.abc", resp.Header.Get(RequestIDHeader))
+}
+
+func TestRequestID_ReplacesInvalidIncomingID(t *testing.T) {
+	app := setupTestApp(zap.NewNop())
+
+	for _, id := range []string{"bad id", "forged\"log", strings.Repeat("a", maxRequestIDLength+1)} {
+		req := httptest.NewRequest("GET", "/tasks/1", nil)
+		req.Header.Set(RequestIDHeader, id)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.NotEqual(t, id, resp.Header.Get(RequestIDHeader))
+		assert.Len(t, resp.Header.Get(RequestIDHeader), 36)
+	}
+}
+
+func TestRequestID_InErrorBody(t *testing.T) {
+	app := setupTestApp(zap.NewNop())
+
+	req := httptest.NewRequest("GET", "/fail", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	body := decodeBody(t, resp.Body)
+	assert.Equal(t, "req-42", body["request_id"])
+	assert.Equal(t, "Task not found", body["detail"])
+	assert.NotContains(t, body, "debug")
+
+	// Router errors carry it too
+	resp, err = app.Test(httptest.NewRequest("GET", "/nope", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, resp.Header.Get(RequestIDHeader), decodeBody(t, resp.Body)["request_id"])
+}
+
+func TestRequestID_ScopedLoggerFields(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	app := setupTestApp(zap.New(core))
+
+	req := httptest.NewRequest("GET", "/tasks/1", nil)
+	req.Header.Set(RequestIDHeader, "req-7")
+	_, err := app.Test(req)
+	assert.NoError(t, err)
+
+	entries := logs.FilterMessage("Handling task").All()
+	if assert.Len(t, entries, 1) {
+		fields := entries[0].ContextMap()
+		assert.Equal(t, "req-7", fields["request_id"])
+		assert.Equal(t, int64(7), fields["user_id"])
+		assert.Equal(t, "/tasks/:id", fields["route"])
+	}
+}
+
+func TestErrorHandler_ProblemDetails(t *testing.T) {
+	app := setupTestApp(zap.NewNop())
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/fail", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, errors.ProblemContentType, resp.Header.Get(fiber.HeaderContentType))
+
+	body := decodeBody(t, resp.Body)
+	assert.Equal(t, "about:blank", body["type"])
+	assert.Equal(t, "Not Found", body["title"])
+	assert.Equal(t, float64(fiber.StatusNotFound), body["status"])
+	assert.Equal(t, "/fail", body["instance"])
+	assert.Equal(t, string(errors.CodeTaskNotFound), body["code"])
+}
+
+func TestErrorHandler_RouterErrorCodes(t *testing.T) {
+	app := setupTestApp(zap.NewNop())
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/nope", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, string(errors.CodeRouteNotFound), decodeBody(t, resp.Body)["code"])
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/fail", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, string(errors.CodeMethodNotAllowed), decodeBody(t, resp.Body)["code"])
+}
+
+func TestErrorHandler_HidesInternalDetails(t *testing.T) {
+	app := setupTestApp(zap.NewNop())
+
+	for path, code := range map[string]errors.Code{"/db": errors.CodeDatabase, "/panic-like": errors.CodeInternal} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+
+		raw, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(raw), "daily_tasks")
+		assert.NotContains(t, string(raw), "raw internal failure")
+
+		var body map[string]interface{}
+		assert.NoError(t, json.Unmarshal(raw, &body))
+		assert.Equal(t, string(code), body["code"])
+	}
+}
+
+func TestErrorHandler_DebugExposesInternalDetails(t *testing.T) {
+	app := setupTestAppWithDebug(zap.NewNop(), true)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/db", nil))
+	assert.NoError(t, err)
+	assert.Contains(t, decodeBody(t, resp.Body)["debug"], "daily_tasks")
+}
+
+func TestVerifiedEmail(t *testing.T) {
+	verifiedAt := time.Now()
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler(zap.NewNop(), false)})
+	app.Get("/:user", func(c *fiber.Ctx) error {
+		// Stands in for JWT
+		user := &models.User{ID: 1}
+		if c.Params("user") == "verified" {
+			user.EmailVerifiedAt = &verifiedAt
+		}
+		c.Locals("user", user)
+		return c.Next()
+	}, VerifiedEmail(), func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/verified", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/unverified", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "auth.email_not_verified", decodeBody(t, resp.Body)["code"])
+}
+
+func TestMFAEnrolled(t *testing.T) {
+	cfg := config.MFAConfig{RequiredRoles: []string{"admin"}}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler(zap.NewNop(), false)})
+	app.Get("/:role/:mfa", func(c *fiber.Ctx) error {
+		// Stands in for JWT
+		c.Locals("user", &models.User{ID: 1, Role: models.UserRole(c.Params("role")), MFAEnabled: c.Params("mfa") == "on"})
+		return c.Next()
+	}, MFAEnrolled(cfg), func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	for path, status := range map[string]int{
+		"/admin/on":  fiber.StatusOK,
+		"/admin/off": fiber.StatusForbidden,
+		"/user/off":  fiber.StatusOK,
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, status, resp.StatusCode, path)
+		if status == fiber.StatusForbidden {
+			assert.Equal(t, "auth.mfa_required", decodeBody(t, resp.Body)["code"])
+		}
+	}
+}
+
+func TestRequireScopeAndSession(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler(zap.NewNop(), false)})
+	// Stands in for JWT: "session" is a login, anything else the scope of a
+	// personal access token
+	authenticate := func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: 1})
+		if scope := c.Params("scope"); scope != "session" {
+			c.Locals("access_token", &models.PersonalAccessToken{ID: 1, Scopes: []string{scope}})
+		}
+		return c.Next()
+	}
+	ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+	app.Get("/read/:scope", authenticate, RequireScope(models.ScopeTasksRead), ok)
+	app.Get("/write/:scope", authenticate, RequireScope(models.ScopeTasksWrite), ok)
+	app.Get("/account/:scope", authenticate, RequireSession(), ok)
+
+	for path, status := range map[string]int{
+		"/read/session":      fiber.StatusOK,
+		"/read/tasks:read":   fiber.StatusOK,
+		"/read/tasks:write":  fiber.StatusOK,
+		"/read/admin":        fiber.StatusOK,
+		"/write/tasks:read":  fiber.StatusForbidden,
+		"/write/tasks:write": fiber.StatusOK,
+		"/account/session":   fiber.StatusOK,
+		"/account/admin":     fiber.StatusForbidden,
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, status, resp.StatusCode, path)
+		if status == fiber.StatusForbidden {
+			assert.Equal(t, "auth.insufficient_scope", decodeBody(t, resp.Body)["code"], path)
+		}
+	}
+}
+
+// defaultRolePermissions holds the default role permissions
+type defaultRolePermissions struct{}
+
+func (defaultRolePermissions) List(ctx context.Context) ([]models.RolePermission, error) {
+	var grants []models.RolePermission
+	for role, permissions := range models.DefaultRolePermissions {
+		for _, permission := range permissions {
+			grants = append(grants, models.RolePermission{Role: role, Permission: permission})
+		}
+	}
+	return grants, nil
+}
+
+func (defaultRolePermissions) ReplaceForRole(ctx context.Context, role models.UserRole, permissions []string) error {
+	return nil
+}
+
+func TestRequirePermission(t *testing.T) {
+	permissions := auth.NewPermissionService(defaultRolePermissions{}, zap.NewNop())
+	assert.NoError(t, permissions.Sync(context.Background()))
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler(zap.NewNop(), false)})
+	// Stands in for JWT
+	authenticate := func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: 1, Role: models.UserRole(c.Params("role"))})
+		return c.Next()
+	}
+	ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+	app.Get("/companies/:role", authenticate, RequirePermission(permissions, models.PermissionCompanyWrite), ok)
+	app.Get("/invitations/:role", authenticate, RequirePermission(permissions, models.PermissionInvitationWrite, models.PermissionUserAdmin), ok)
+	app.Get("/anonymous", RequirePermission(permissions, models.PermissionTaskRead), ok)
+
+	for path, status := range map[string]int{
+		"/companies/admin":     fiber.StatusOK,
+		"/companies/manager":   fiber.StatusForbidden,
+		"/invitations/manager": fiber.StatusOK,
+		"/invitations/user":    fiber.StatusForbidden,
+		"/invitations/guest":   fiber.StatusForbidden,
+		"/anonymous":           fiber.StatusUnauthorized,
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, status, resp.StatusCode, path)
+		if status == fiber.StatusForbidden {
+			assert.Equal(t, "auth.permission_denied", decodeBody(t, resp.Body)["code"], path)
+		}
+	}
+}
+
+func TestNotImpersonating(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler(zap.NewNop(), false)})
+	// Stands in for JWT
+	authenticate := func(c *fiber.Ctx) error {
+		c.Locals("user", &models.User{ID: 2})
+		if c.Query("as") == "admin" {
+			c.Locals("impersonator", &models.User{ID: 1})
+		}
+		return c.Next()
+	}
+	app.Post("/password", authenticate, NotImpersonating(), func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/password", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/password?as=admin", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "auth.impersonating", decodeBody(t, resp.Body)["code"])
+}
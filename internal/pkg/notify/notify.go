@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"go.uber.org/zap"
+)
+
+// Notifier drivers
+const (
+	DriverLog  = "log"
+	DriverFile = "file"
+	DriverSMTP = "smtp"
+)
+
+// Message is an email to a single recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to users
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New builds the notifier selected by cfg.Driver
+func New(cfg config.NotifierConfig, logger *zap.Logger) (Notifier, error) {
+	switch cfg.Driver {
+	case DriverLog, "":
+		return NewLogNotifier(logger), nil
+	case DriverFile:
+		return NewFileNotifier(cfg.FilePath), nil
+	case DriverSMTP:
+		return NewSMTPNotifier(cfg.SMTP, cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unsupported notifier driver %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"context"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestNew_SelectsDriver(t *testing.T) {
+	logger := zap.NewNop()
+
+	n, err := New(config.NotifierConfig{Driver: DriverLog}, logger)
+	assert.NoError(t, err)
+	assert.IsType(t, &LogNotifier{}, n)
+
+	n, err = New(config.NotifierConfig{Driver: DriverFile, FilePath: "out.log"}, logger)
+	assert.NoError(t, err)
+	assert.IsType(t, &FileNotifier{}, n)
+
+	n, err = New(config.NotifierConfig{Driver: DriverSMTP}, logger)
+	assert.NoError(t, err)
+	assert.IsType(t, &SMTPNotifier{}, n)
+
+	_, err = New(config.NotifierConfig{Driver: "pigeon"}, logger)
+	assert.Error(t, err)
+}
+
+func TestFileNotifier_AppendsMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.log")
+	n := NewFileNotifier(path)
+
+	assert.NoError(t, n.Send(context.Background(), Message{To: "a@example.com", Subject: "First", Body: "one"}))
+	assert.NoError(t, n.Send(context.Background(), Message{To: "b@example.com", Subject: "Second", Body: "two"}))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "To: a@example.com\nSubject: First\n\none")
+	assert.Contains(t, string(content), "To: b@example.com\nSubject: Second\n\ntwo")
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestSMTPNotifier_Send(t *testing.T) {
+	n := NewSMTPNotifier(config.SMTPConfig{Host: "mail.example.com", Port: 587, Username: "user", Password: "secret"}, "no-reply@example.com")
+
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotAuth smtp.Auth
+	var gotMsg []byte
+	n.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotAuth, gotFrom, gotTo, gotMsg = addr, a, from, to, msg
+		return nil
+	}
+
+	err := n.Send(context.Background(), Message{
+		To:      "user@example.com\r\nBcc: victim@example.com",
+		Subject: "Reset your password",
+		Body:    "line one\nline two",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "mail.example.com:587", gotAddr)
+	assert.NotNil(t, gotAuth)
+	assert.Equal(t, "no-reply@example.com", gotFrom)
+	assert.Len(t, gotTo, 1)
+
+	msg := string(gotMsg)
+	assert.Contains(t, msg, "From: no-reply@example.com\r\n")
+	assert.Contains(t, msg, "Subject: Reset your password\r\n")
+	assert.Contains(t, msg, "\r\n\r\nline one\r\nline two")
+
+	// Line breaks cannot smuggle extra headers
+	assert.NotContains(t, msg, "\r\nBcc:")
+	assert.True(t, strings.HasPrefix(msg, "From: "))
+}
+
+func TestSMTPNotifier_NoAuthWithoutUsername(t *testing.T) {
+	n := NewSMTPNotifier(config.SMTPConfig{Host: "localhost", Port: 25}, "no-reply@example.com")
+
+	called := false
+	n.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		called = true
+		assert.Nil(t, a)
+		return nil
+	}
+
+	assert.NoError(t, n.Send(context.Background(), Message{To: "user@example.com", Subject: "Hi", Body: "Hello"}))
+	assert.True(t, called)
+}
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+)
+
+var headerSanitizer = strings.NewReplacer("\r", "", "\n", "")
+
+// SMTPNotifier sends messages as plain-text email. net/smtp upgrades to TLS
+// with STARTTLS when the server offers it.
+type SMTPNotifier struct {
+	cfg  config.SMTPConfig
+	from string
+
+	// sendMail is replaced in tests
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTPNotifier creates a notifier sending through the given server
+func NewSMTPNotifier(cfg config.SMTPConfig, from string) *SMTPNotifier {
+	return &SMTPNotifier{cfg: cfg, from: from, sendMail: smtp.SendMail}
+}
+
+func (n *SMTPNotifier) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+	}
+
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port))
+	return n.sendMail(addr, auth, n.from, []string{msg.To}, n.format(msg))
+}
+
+// format renders the RFC 5322 message
+func (n *SMTPNotifier) format(msg Message) []byte {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		// Line breaks in a value would let it inject further headers
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, headerSanitizer.Replace(value))
+	}
+	header("From", n.from)
+	header("To", msg.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().UTC().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return buf.Bytes()
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/alxand/nalo-workspace/internal/pkg/logger"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// KeyFunc identifies the client a request is counted against
+type KeyFunc func(c *fiber.Ctx) string
+
+// ByIP keys requests by client IP
+func ByIP(c *fiber.Ctx) string {
+	return "ip:" + strings.Clone(c.IP())
+}
+
+// ByUser keys requests by the authenticated user and falls back to the
+// client IP when the route is not behind JWT
+func ByUser(c *fiber.Ctx) string {
+	if user, ok := c.Locals("user").(*models.User); ok {
+		return "user:" + strconv.FormatInt(user.ID, 10)
+	}
+	return ByIP(c)
+}
+
+// Middleware enforces limit per key, namespaced by name so separate limits
+// on the same store don't share buckets. It sets the RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers on every response and
+// Retry-After when the request is rejected. Store failures let the request
+// through rather than taking the API down.
+func Middleware(store Store, name string, limit Limit, key KeyFunc) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		result, err := store.Take(c.UserContext(), name+":"+key(c), limit)
+		if err != nil {
+			logger.FromCtx(c, nil).Error("Rate limit store failed", zap.String("limiter", name), zap.Error(err))
+			return c.Next()
+		}
+
+		c.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+
+		if !result.Allowed {
+			c.Set(fiber.HeaderRetryAfter, ceilSeconds(result.RetryAfter))
+			return errors.TooManyRequests("Too many requests", nil)
+		}
+		return c.Next()
+	}
+}
+
+// ceilSeconds formats d as whole seconds, rounded up so clients never retry early
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time { return f.now }
+
+func newTestStore() (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.now = clock.Now
+	return store, clock
+}
+
+func TestMemoryStore_BurstThenRefill(t *testing.T) {
+	store, clock := newTestStore()
+	limit := Limit{Requests: 3, Window: 3 * time.Second}
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(ctx, "k", limit)
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result, err := store.Take(ctx, "k", limit)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+
+	// One token per second comes back
+	clock.now = clock.now.Add(time.Second)
+	result, err = store.Take(ctx, "k", limit)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
+
+func TestMemoryStore_KeysAreIndependent(t *testing.T) {
+	store, _ := newTestStore()
+	limit := Limit{Requests: 1, Window: time.Minute}
+
+	first, _ := store.Take(context.Background(), "a", limit)
+	second, _ := store.Take(context.Background(), "b", limit)
+	assert.True(t, first.Allowed)
+	assert.True(t, second.Allowed)
+}
+
+func TestMemoryStore_SweepsRefilledBuckets(t *testing.T) {
+	store, clock := newTestStore()
+	limit := Limit{Requests: 1, Window: time.Second}
+
+	_, _ = store.Take(context.Background(), "idle", limit)
+	clock.now = clock.now.Add(2 * sweepInterval)
+	_, _ = store.Take(context.Background(), "active", limit)
+
+	assert.NotContains(t, store.buckets, "idle")
+	assert.Contains(t, store.buckets, "active")
+}
+
+func setupTestApp(store Store, limit Limit) *fiber.App {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			if appErr, ok := err.(*errors.AppError); ok {
+				return c.Status(appErr.Code).SendString(appErr.Message)
+			}
+			return c.SendStatus(fiber.StatusInternalServerError)
+		},
+	})
+	app.Get("/public", Middleware(store, "public", limit, ByIP), func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+	app.Get("/private/:user", func(c *fiber.Ctx) error {
+		id, _ := c.ParamsInt("user")
+		c.Locals("user", &models.User{ID: int64(id)})
+		return c.Next()
+	}, Middleware(store, "user", limit, ByUser), func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+	return app
+}
+
+func TestMiddleware_HeadersAndRejection(t *testing.T) {
+	store, _ := newTestStore()
+	app := setupTestApp(store, Limit{Requests: 2, Window: time.Minute})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/public", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get("RateLimit-Limit"))
+	assert.Equal(t, "1", resp.Header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", resp.Header.Get("RateLimit-Reset"))
+	assert.Empty(t, resp.Header.Get("Retry-After"))
+
+	_, _ = app.Test(httptest.NewRequest("GET", "/public", nil))
+	resp, err = app.Test(httptest.NewRequest("GET", "/public", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", resp.Header.Get("Retry-After"))
+}
+
+func TestMiddleware_ByUserSeparatesUsers(t *testing.T) {
+	store, _ := newTestStore()
+	app := setupTestApp(store, Limit{Requests: 1, Window: time.Minute})
+
+	resp, _ := app.Test(httptest.NewRequest("GET", "/private/1", nil))
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp, _ = app.Test(httptest.NewRequest("GET", "/private/1", nil))
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+
+	// Same IP, different user
+	resp, _ = app.Test(httptest.NewRequest("GET", "/private/2", nil))
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit) (Result, error) {
+	return Result{}, context.DeadlineExceeded
+}
+
+func TestMiddleware_FailsOpen(t *testing.T) {
+	app := setupTestApp(failingStore{}, Limit{Requests: 1, Window: time.Minute})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/public", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
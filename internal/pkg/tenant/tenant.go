@@ -0,0 +1,56 @@
+// Package tenant carries the company a request is limited to, so
+// repositories can scope every query by it
+package tenant
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrOutsideTenant is returned when a write would put data into another
+// company than the tenant's
+var ErrOutsideTenant = errors.New("outside of your company")
+
+type ctxKey struct{}
+
+// Tenant limits data to one company. A user always sees their own records,
+// so users without a company see nothing else.
+type Tenant struct {
+	// CompanyID is the company of the user, 0 when they have none
+	CompanyID int64
+	UserID    int64
+	// All lifts the limit
+	All bool
+}
+
+// NewContext returns ctx limited to t
+func NewContext(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, ctxKey{}, t)
+}
+
+// FromContext returns the tenant of ctx. Contexts without one, such as
+// logins and background jobs, are not limited.
+func FromContext(ctx context.Context) (Tenant, bool) {
+	t, ok := ctx.Value(ctxKey{}).(Tenant)
+	return t, ok
+}
+
+// Limited returns the tenant of ctx when its queries must be scoped
+func Limited(ctx context.Context) (Tenant, bool) {
+	t, ok := FromContext(ctx)
+	return t, ok && !t.All
+}
+
+// Allows reports whether ctx may write data belonging to companyID, where
+// nil stands for no company
+func Allows(ctx context.Context, companyID *int64) bool {
+	t, limited := Limited(ctx)
+	if !limited {
+		return true
+	}
+	var id int64
+	if companyID != nil {
+		id = *companyID
+	}
+	return id == t.CompanyID
+}
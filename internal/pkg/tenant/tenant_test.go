@@ -0,0 +1,32 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllows(t *testing.T) {
+	acme, globex := int64(1), int64(2)
+	unscoped := context.Background()
+	admin := NewContext(unscoped, Tenant{CompanyID: acme, UserID: 1, All: true})
+	member := NewContext(unscoped, Tenant{CompanyID: acme, UserID: 2})
+	loner := NewContext(unscoped, Tenant{UserID: 3})
+
+	for _, ctx := range []context.Context{unscoped, admin} {
+		_, limited := Limited(ctx)
+		assert.False(t, limited)
+		assert.True(t, Allows(ctx, &globex))
+		assert.True(t, Allows(ctx, nil))
+	}
+
+	_, limited := Limited(member)
+	assert.True(t, limited)
+	assert.True(t, Allows(member, &acme))
+	assert.False(t, Allows(member, &globex))
+	assert.False(t, Allows(member, nil))
+
+	assert.True(t, Allows(loner, nil))
+	assert.False(t, Allows(loner, &acme))
+}
@@ -0,0 +1,156 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const incomingTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+func spanNamed(spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	for _, span := range spans {
+		if span.Name() == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/tasks/:id", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	req := httptest.NewRequest("GET", "/tasks/42", nil)
+	req.Header.Set("traceparent", incomingTraceparent)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	span := spanNamed(recorder.Ended(), "GET /tasks/:id")
+	if assert.NotNil(t, span) {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	}
+}
+
+func TestGormPlugin_NestsUnderRequestSpan(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	assert.NoError(t, db.Use(NewGormPlugin()))
+	assert.NoError(t, db.Exec("CREATE TABLE widgets (id INTEGER PRIMARY KEY, name TEXT)").Error)
+
+	type widget struct {
+		ID   int64
+		Name string
+	}
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/widgets", func(c *fiber.Ctx) error {
+		var widgets []widget
+		if err := db.WithContext(c.UserContext()).Where("name = ?", "secret-value").Find(&widgets).Error; err != nil {
+			return err
+		}
+		return c.JSON(widgets)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/widgets", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	spans := recorder.Ended()
+	server := spanNamed(spans, "GET /widgets")
+	query := spanNamed(spans, "gorm.query")
+	if assert.NotNil(t, server) && assert.NotNil(t, query) {
+		assert.Equal(t, server.SpanContext().SpanID(), query.Parent().SpanID())
+		for _, attr := range query.Attributes() {
+			assert.NotContains(t, attr.Value.Emit(), "secret-value")
+		}
+	}
+}
+
+func TestLogFields(t *testing.T) {
+	setupRecorder(t)
+
+	assert.Empty(t, LogFields(context.Background()))
+
+	ctx, span := Tracer().Start(context.Background(), "test")
+	defer span.End()
+
+	fields := LogFields(ctx)
+	if assert.Len(t, fields, 2) {
+		assert.Equal(t, "trace_id", fields[0].Key)
+		assert.Equal(t, span.SpanContext().TraceID().String(), fields[0].String)
+		assert.Equal(t, "span_id", fields[1].Key)
+	}
+}
+
+func TestTransport_InjectsTraceparent(t *testing.T) {
+	setupRecorder(t)
+
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	ctx, span := Tracer().Start(context.Background(), "caller")
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+	resp, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Contains(t, received, span.SpanContext().TraceID().String())
+}
+
+func TestInit_Exporters(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	for _, exporter := range []string{ExporterNone, ExporterStdout} {
+		shutdown, err := Init(context.Background(), config.TracingConfig{Exporter: exporter, ServiceName: "test", SampleRatio: 1})
+		assert.NoError(t, err, exporter)
+		assert.NoError(t, shutdown(context.Background()), exporter)
+	}
+
+	_, err := Init(context.Background(), config.TracingConfig{Exporter: "zipkin"})
+	assert.Error(t, err)
+}
@@ -1,6 +1,8 @@
 package validation
 
 import (
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/alxand/nalo-workspace/internal/domain/models"
@@ -11,6 +13,9 @@ var validate = validator.New()
 
 // Init initializes the validator with custom validation rules
 func Init() {
+	// Report fields by their JSON name so clients can map errors to inputs
+	validate.RegisterTagNameFunc(jsonFieldName)
+
 	// Register custom validation functions
 	validate.RegisterValidation("date_format", validateDateFormat)
 	validate.RegisterValidation("time_range", validateTimeRange)
@@ -44,6 +49,11 @@ func ValidateDailyTask(task *models.DailyTask) error {
 	return validate.Struct(validationStruct)
 }
 
+// ValidateStruct validates any request struct against its validate tags
+func ValidateStruct(s interface{}) error {
+	return validate.Struct(s)
+}
+
 // ValidateContinent validates a Continent model
 func ValidateContinent(continent *models.Continent) error {
 	return validate.Struct(continent)
@@ -59,6 +69,19 @@ func ValidateCompany(company *models.Company) error {
 	return validate.Struct(company)
 }
 
+// jsonFieldName returns the JSON name of a struct field, falling back to the
+// Go name when there is no json tag
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
 // validateDateFormat validates that the date is in the correct format
 func validateDateFormat(fl validator.FieldLevel) bool {
 	date, ok := fl.Field().Interface().(time.Time)
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// AuditLogRepository is not scoped to tenants: the audit log is read by
+// admins across companies
+type AuditLogRepository struct {
+	DB *gorm.DB
+}
+
+func NewAuditLogRepository(db *gorm.DB) interfaces.AuditLogInterface {
+	return &AuditLogRepository{DB: db}
+}
+
+func (r *AuditLogRepository) Create(ctx context.Context, entry *models.AuditLog) error {
+	ctx, span := startSpan(ctx, "AuditLogRepository.Create")
+	defer span.End()
+	return r.DB.WithContext(ctx).Create(entry).Error
+}
+
+func (r *AuditLogRepository) List(ctx context.Context, filter models.AuditLogFilter) ([]models.AuditLog, error) {
+	ctx, span := startSpan(ctx, "AuditLogRepository.List")
+	defer span.End()
+	query := r.DB.WithContext(ctx).Order("created_at DESC, id DESC").Limit(filter.Limit).Offset(filter.Offset)
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.ImpersonatorID != 0 {
+		query = query.Where("impersonator_id = ?", filter.ImpersonatorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	var entries []models.AuditLog
+	err := query.Find(&entries).Error
+	return entries, err
+}
+
+func (r *AuditLogRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "AuditLogRepository.DeleteBefore")
+	defer span.End()
+	result := r.DB.WithContext(ctx).Where("created_at < ?", before).Delete(&models.AuditLog{})
+	return result.RowsAffected, result.Error
+}
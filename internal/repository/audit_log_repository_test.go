@@ -0,0 +1,71 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLogRepository_SQLite(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	repo := testDB.AuditLogRepo
+	now := time.Now()
+	admin, bob := int64(1), int64(2)
+
+	entries := []*models.AuditLog{
+		{ActorID: &admin, Action: models.AuditDelete, EntityType: models.AuditEntityCompany, EntityID: "7", Before: `{"name":"Globex"}`, CreatedAt: now.Add(-48 * time.Hour)},
+		{ActorID: &admin, Action: models.AuditUpdate, EntityType: models.AuditEntityUser, EntityID: "2", Before: `{"role":"user"}`, After: `{"role":"admin"}`, CreatedAt: now.Add(-time.Hour)},
+		{ActorID: &bob, ImpersonatorID: &admin, Action: models.AuditCreate, EntityType: models.AuditEntityDailyTask, EntityID: "3", CreatedAt: now},
+		{Action: models.AuditLoginFailed, EntityType: models.AuditEntityUser, After: `{"email":"bob@example.com"}`, CreatedAt: now},
+	}
+	for _, entry := range entries {
+		assert.NoError(t, repo.Create(ctx, entry))
+	}
+
+	all, err := repo.List(ctx, models.AuditLogFilter{Limit: 10})
+	assert.NoError(t, err)
+	if assert.Len(t, all, 4) {
+		assert.Equal(t, entries[3].ID, all[0].ID, "newest first")
+		assert.Equal(t, entries[0].ID, all[3].ID)
+	}
+
+	byAdmin, err := repo.List(ctx, models.AuditLogFilter{ActorID: admin, Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, byAdmin, 2)
+
+	impersonated, err := repo.List(ctx, models.AuditLogFilter{ImpersonatorID: admin, Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, impersonated, 1)
+
+	promotions, err := repo.List(ctx, models.AuditLogFilter{EntityType: models.AuditEntityUser, EntityID: "2", Action: models.AuditUpdate, Limit: 10})
+	assert.NoError(t, err)
+	if assert.Len(t, promotions, 1) {
+		assert.Equal(t, models.AuditData(`{"role":"admin"}`), promotions[0].After)
+	}
+
+	recent, err := repo.List(ctx, models.AuditLogFilter{From: now.Add(-2 * time.Hour), To: now.Add(-time.Minute), Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, recent, 1)
+
+	page, err := repo.List(ctx, models.AuditLogFilter{Limit: 2, Offset: 3})
+	assert.NoError(t, err)
+	assert.Len(t, page, 1)
+
+	// Entries cannot be changed, only pruned
+	err = testDB.DB.Model(&models.AuditLog{}).Where("id = ?", entries[0].ID).Update("action", models.AuditCreate).Error
+	assert.Error(t, err)
+
+	deleted, err := repo.DeleteBefore(ctx, now.Add(-24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/tenant"
+	"gorm.io/gorm"
+)
+
+type CompanyRepository struct {
+	db *gorm.DB
+}
+
+func NewCompanyRepository(db *gorm.DB) interfaces.CompanyInterface {
+	return &CompanyRepository{db: db}
+}
+
+// Create refuses tenants limited to a company, since a new company is a new
+// tenant
+func (r *CompanyRepository) Create(ctx context.Context, company *models.Company) error {
+	ctx, span := startSpan(ctx, "CompanyRepository.Create")
+	defer span.End()
+	if _, limited := tenant.Limited(ctx); limited {
+		return tenant.ErrOutsideTenant
+	}
+	return r.db.WithContext(ctx).Create(company).Error
+}
+
+func (r *CompanyRepository) GetByID(ctx context.Context, id int64) (*models.Company, error) {
+	ctx, span := startSpan(ctx, "CompanyRepository.GetByID")
+	defer span.End()
+	var company models.Company
+	err := r.db.WithContext(ctx).Scopes(companiesOfTenant(ctx)).Preload("Country").Preload("Users").First(&company, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &company, nil
+}
+
+func (r *CompanyRepository) GetByCode(ctx context.Context, code string) (*models.Company, error) {
+	ctx, span := startSpan(ctx, "CompanyRepository.GetByCode")
+	defer span.End()
+	var company models.Company
+	err := r.db.WithContext(ctx).Scopes(companiesOfTenant(ctx)).Preload("Country").Preload("Users").Where("code = ?", code).First(&company).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &company, nil
+}
+
+func (r *CompanyRepository) GetByCountry(ctx context.Context, countryID int64) ([]models.Company, error) {
+	ctx, span := startSpan(ctx, "CompanyRepository.GetByCountry")
+	defer span.End()
+	var companies []models.Company
+	err := r.db.WithContext(ctx).Scopes(companiesOfTenant(ctx)).Preload("Country").Preload("Users").Where("country_id = ?", countryID).Find(&companies).Error
+	return companies, err
+}
+
+func (r *CompanyRepository) GetByIndustry(ctx context.Context, industry string) ([]models.Company, error) {
+	ctx, span := startSpan(ctx, "CompanyRepository.GetByIndustry")
+	defer span.End()
+	var companies []models.Company
+	err := r.db.WithContext(ctx).Scopes(companiesOfTenant(ctx)).Preload("Country").Preload("Users").Where("industry = ?", industry).Find(&companies).Error
+	return companies, err
+}
+
+func (r *CompanyRepository) GetAll(ctx context.Context) ([]models.Company, error) {
+	ctx, span := startSpan(ctx, "CompanyRepository.GetAll")
+	defer span.End()
+	var companies []models.Company
+	err := r.db.WithContext(ctx).Scopes(companiesOfTenant(ctx)).Preload("Country").Preload("Users").Find(&companies).Error
+	return companies, err
+}
+
+func (r *CompanyRepository) Update(ctx context.Context, company *models.Company) (*models.Company, error) {
+	ctx, span := startSpan(ctx, "CompanyRepository.Update")
+	defer span.End()
+	if err := visible(r.db.WithContext(ctx), &models.Company{}, company.ID, companiesOfTenant(ctx)); err != nil {
+		return nil, err
+	}
+	err := r.db.WithContext(ctx).Save(company).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, company.ID)
+}
+
+func (r *CompanyRepository) Delete(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, "CompanyRepository.Delete")
+	defer span.End()
+	return r.db.WithContext(ctx).Scopes(companiesOfTenant(ctx)).Delete(&models.Company{}, id).Error
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+type ContinentRepository struct {
+	db *gorm.DB
+}
+
+func NewContinentRepository(db *gorm.DB) interfaces.ContinentInterface {
+	return &ContinentRepository{db: db}
+}
+
+func (r *ContinentRepository) Create(ctx context.Context, continent *models.Continent) error {
+	ctx, span := startSpan(ctx, "ContinentRepository.Create")
+	defer span.End()
+	return r.db.WithContext(ctx).Create(continent).Error
+}
+
+func (r *ContinentRepository) GetByID(ctx context.Context, id int64) (*models.Continent, error) {
+	ctx, span := startSpan(ctx, "ContinentRepository.GetByID")
+	defer span.End()
+	var continent models.Continent
+	err := r.db.WithContext(ctx).Preload("Countries").First(&continent, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &continent, nil
+}
+
+func (r *ContinentRepository) GetByCode(ctx context.Context, code string) (*models.Continent, error) {
+	ctx, span := startSpan(ctx, "ContinentRepository.GetByCode")
+	defer span.End()
+	var continent models.Continent
+	err := r.db.WithContext(ctx).Preload("Countries").Where("code = ?", code).First(&continent).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &continent, nil
+}
+
+func (r *ContinentRepository) GetAll(ctx context.Context) ([]models.Continent, error) {
+	ctx, span := startSpan(ctx, "ContinentRepository.GetAll")
+	defer span.End()
+	var continents []models.Continent
+	err := r.db.WithContext(ctx).Preload("Countries").Find(&continents).Error
+	return continents, err
+}
+
+func (r *ContinentRepository) Update(ctx context.Context, continent *models.Continent) (*models.Continent, error) {
+	ctx, span := startSpan(ctx, "ContinentRepository.Update")
+	defer span.End()
+	err := r.db.WithContext(ctx).Save(continent).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, continent.ID)
+}
+
+func (r *ContinentRepository) Delete(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, "ContinentRepository.Delete")
+	defer span.End()
+	return r.db.WithContext(ctx).Delete(&models.Continent{}, id).Error
+}
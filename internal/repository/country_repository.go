@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// CountryRepository reads countries with the companies and users of the
+// tenant in ctx; countries themselves are shared by every tenant
+type CountryRepository struct {
+	db *gorm.DB
+}
+
+func NewCountryRepository(db *gorm.DB) interfaces.CountryInterface {
+	return &CountryRepository{db: db}
+}
+
+func (r *CountryRepository) Create(ctx context.Context, country *models.Country) error {
+	ctx, span := startSpan(ctx, "CountryRepository.Create")
+	defer span.End()
+	return r.db.WithContext(ctx).Create(country).Error
+}
+
+func (r *CountryRepository) GetByID(ctx context.Context, id int64) (*models.Country, error) {
+	ctx, span := startSpan(ctx, "CountryRepository.GetByID")
+	defer span.End()
+	var country models.Country
+	err := r.db.WithContext(ctx).Preload("Continent").Preload("Companies", companiesOfTenant(ctx)).Preload("Users", usersOfTenant(ctx)).First(&country, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &country, nil
+}
+
+func (r *CountryRepository) GetByCode(ctx context.Context, code string) (*models.Country, error) {
+	ctx, span := startSpan(ctx, "CountryRepository.GetByCode")
+	defer span.End()
+	var country models.Country
+	err := r.db.WithContext(ctx).Preload("Continent").Preload("Companies", companiesOfTenant(ctx)).Preload("Users", usersOfTenant(ctx)).Where("code = ?", code).First(&country).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &country, nil
+}
+
+func (r *CountryRepository) GetByContinent(ctx context.Context, continentID int64) ([]models.Country, error) {
+	ctx, span := startSpan(ctx, "CountryRepository.GetByContinent")
+	defer span.End()
+	var countries []models.Country
+	err := r.db.WithContext(ctx).Preload("Continent").Preload("Companies", companiesOfTenant(ctx)).Where("continent_id = ?", continentID).Find(&countries).Error
+	return countries, err
+}
+
+func (r *CountryRepository) GetAll(ctx context.Context) ([]models.Country, error) {
+	ctx, span := startSpan(ctx, "CountryRepository.GetAll")
+	defer span.End()
+	var countries []models.Country
+	err := r.db.WithContext(ctx).Preload("Continent").Preload("Companies", companiesOfTenant(ctx)).Find(&countries).Error
+	return countries, err
+}
+
+func (r *CountryRepository) Update(ctx context.Context, country *models.Country) (*models.Country, error) {
+	ctx, span := startSpan(ctx, "CountryRepository.Update")
+	defer span.End()
+	err := r.db.WithContext(ctx).Save(country).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, country.ID)
+}
+
+func (r *CountryRepository) Delete(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, "CountryRepository.Delete")
+	defer span.End()
+	return r.db.WithContext(ctx).Delete(&models.Country{}, id).Error
+}
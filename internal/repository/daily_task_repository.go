@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+type DailyTaskRepository struct {
+	DB      *gorm.DB
+	dialect Dialect
+}
+
+func NewDailyTaskRepository(db *gorm.DB, dialect Dialect) interfaces.DailyTaskInterface {
+	return &DailyTaskRepository{DB: db, dialect: dialect}
+}
+
+// withAssociations preloads every child collection of a daily task
+func withAssociations(db *gorm.DB) *gorm.DB {
+	return db.Preload("Deliverables").
+		Preload("Activities").
+		Preload("ProductFocus").
+		Preload("NextSteps").
+		Preload("Challenges").
+		Preload("Notes").
+		Preload("Comments")
+}
+
+// scoped starts a query limited to the tasks of the tenant in ctx
+func (r *DailyTaskRepository) scoped(ctx context.Context) *gorm.DB {
+	return r.DB.WithContext(ctx).Scopes(tasksOfTenant(ctx))
+}
+
+// onDate filters tasks to a single calendar day given as YYYY-MM-DD
+func (r *DailyTaskRepository) onDate(ctx context.Context, date string) (*gorm.DB, error) {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, err
+	}
+	return r.scoped(ctx).Where(r.dialect.DateOf("daily_tasks.date")+" = ?", date), nil
+}
+
+func (r *DailyTaskRepository) Create(ctx context.Context, log *models.DailyTask) error {
+	ctx, span := startSpan(ctx, "DailyTaskRepository.Create")
+	defer span.End()
+	return r.DB.WithContext(ctx).Create(log).Error
+}
+
+func (r *DailyTaskRepository) GetByDate(ctx context.Context, date string) ([]models.DailyTask, error) {
+	ctx, span := startSpan(ctx, "DailyTaskRepository.GetByDate")
+	defer span.End()
+	var logs []models.DailyTask
+	query, err := r.onDate(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+	err = withAssociations(query).Find(&logs).Error
+	return logs, err
+}
+
+func (r *DailyTaskRepository) Update(ctx context.Context, log *models.DailyTask) (*models.DailyTask, error) {
+	ctx, span := startSpan(ctx, "DailyTaskRepository.Update")
+	defer span.End()
+	if err := visible(r.DB.WithContext(ctx), &models.DailyTask{}, log.ID, tasksOfTenant(ctx)); err != nil {
+		return nil, err
+	}
+	err := r.DB.WithContext(ctx).Session(&gorm.Session{FullSaveAssociations: true}).Save(log).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Reload the updated task with all associations
+	var updated models.DailyTask
+	if err := withAssociations(r.scoped(ctx)).First(&updated, log.ID).Error; err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+func (r *DailyTaskRepository) Delete(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, "DailyTaskRepository.Delete")
+	defer span.End()
+	return r.scoped(ctx).Delete(&models.DailyTask{}, id).Error
+}
+
+func (r *DailyTaskRepository) GetByID(ctx context.Context, id int64) (*models.DailyTask, error) {
+	ctx, span := startSpan(ctx, "DailyTaskRepository.GetByID")
+	defer span.End()
+	var task models.DailyTask
+	err := r.scoped(ctx).First(&task, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (r *DailyTaskRepository) GetByDateAndUser(ctx context.Context, date string, userID int64) ([]models.DailyTask, error) {
+	ctx, span := startSpan(ctx, "DailyTaskRepository.GetByDateAndUser")
+	defer span.End()
+	var tasks []models.DailyTask
+	query, err := r.onDate(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+	err = query.Where("user_id = ?", userID).Find(&tasks).Error
+	return tasks, err
+}
+
+func (r *DailyTaskRepository) List(ctx context.Context, limit, offset int) ([]models.DailyTask, error) {
+	ctx, span := startSpan(ctx, "DailyTaskRepository.List")
+	defer span.End()
+	var tasks []models.DailyTask
+	err := r.scoped(ctx).Limit(limit).Offset(offset).Find(&tasks).Error
+	return tasks, err
+}
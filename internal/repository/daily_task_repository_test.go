@@ -0,0 +1,214 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/repository"
+	"github.com/alxand/nalo-workspace/internal/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestUser(t *testing.T, testDB *test_helpers.TestDB) *models.User {
+	ctx := context.Background()
+	user := &models.User{
+		Email:     "sqlite@example.com",
+		Username:  "sqliteuser",
+		Password:  "password123",
+		FirstName: "Sqlite",
+		LastName:  "User",
+		Role:      models.RoleUser,
+		IsActive:  true,
+	}
+	if err := testDB.UserRepo.Create(ctx, user); err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	return user
+}
+
+func TestNewFactory_UnsupportedDriver(t *testing.T) {
+	factory, err := repository.NewFactory("mysql")
+	assert.Error(t, err)
+	assert.Nil(t, factory)
+}
+
+func TestNewFactory_SelectsDialect(t *testing.T) {
+	for _, driver := range []string{repository.DriverPostgres, repository.DriverSQLite} {
+		factory, err := repository.NewFactory(driver)
+		assert.NoError(t, err)
+		assert.Equal(t, driver, factory.Dialect().Name())
+	}
+}
+
+func TestDailyTaskRepository_SQLiteGetByDateAndUser(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	user := createTestUser(t, testDB)
+
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	task := &models.DailyTask{
+		UserID:    user.ID,
+		Day:       "Monday",
+		Date:      start,
+		StartTime: start,
+		EndTime:   start.Add(8 * time.Hour),
+		Status:    "in_progress",
+		Notes:     []models.Note{{Text: "standup"}},
+	}
+	assert.NoError(t, testDB.DailyTaskRepo.Create(ctx, task))
+
+	tasks, err := testDB.DailyTaskRepo.GetByDateAndUser(ctx, "2024-01-15", user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+
+	tasks, err = testDB.DailyTaskRepo.GetByDateAndUser(ctx, "2024-01-16", user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+
+	tasks, err = testDB.DailyTaskRepo.GetByDate(ctx, "2024-01-15")
+	assert.NoError(t, err)
+	if assert.Len(t, tasks, 1) {
+		assert.Len(t, tasks[0].Notes, 1)
+	}
+
+	_, err = testDB.DailyTaskRepo.GetByDate(ctx, "15/01/2024")
+	assert.Error(t, err)
+}
+
+func TestDailyTaskRepository_SQLiteUpdateAndDelete(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	user := createTestUser(t, testDB)
+
+	now := time.Now().UTC()
+	task := &models.DailyTask{
+		UserID:    user.ID,
+		Day:       "Tuesday",
+		Date:      now,
+		StartTime: now,
+		EndTime:   now.Add(time.Hour),
+		Status:    "pending",
+	}
+	assert.NoError(t, testDB.DailyTaskRepo.Create(ctx, task))
+
+	task.Status = "completed"
+	task.Deliverables = []models.Deliverable{{Item: "release notes"}}
+	updated, err := testDB.DailyTaskRepo.Update(ctx, task)
+	assert.NoError(t, err)
+	assert.Equal(t, "completed", updated.Status)
+	assert.Len(t, updated.Deliverables, 1)
+
+	listed, err := testDB.DailyTaskRepo.List(ctx, 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, listed, 1)
+
+	assert.NoError(t, testDB.DailyTaskRepo.Delete(ctx, task.ID))
+	_, err = testDB.DailyTaskRepo.GetByID(ctx, task.ID)
+	assert.Error(t, err)
+}
+
+func TestUserRepository_SQLiteUpdateLastLogin(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	user := createTestUser(t, testDB)
+
+	assert.NoError(t, testDB.UserRepo.UpdateLastLogin(ctx, user.ID))
+
+	reloaded, err := testDB.UserRepo.GetByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, reloaded.LastLogin)
+}
+
+func TestUserRepository_SQLiteFailedLogins(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	user := createTestUser(t, testDB)
+
+	for want := 1; want <= 3; want++ {
+		attempts, err := testDB.UserRepo.RecordFailedLogin(ctx, user.ID, time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, want, attempts)
+	}
+
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	assert.NoError(t, testDB.UserRepo.LockUntil(ctx, user.ID, until))
+	reloaded, err := testDB.UserRepo.GetByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, reloaded.IsLocked(time.Now()))
+	assert.Equal(t, 3, reloaded.FailedLoginAttempts)
+
+	// While locked, failures keep counting
+	attempts, err := testDB.UserRepo.RecordFailedLogin(ctx, user.ID, until.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, attempts)
+
+	// Once the lock has expired it is lifted and the count starts over
+	attempts, err = testDB.UserRepo.RecordFailedLogin(ctx, user.ID, until.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+	reloaded, err = testDB.UserRepo.GetByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, reloaded.LockedUntil)
+
+	assert.NoError(t, testDB.UserRepo.LockUntil(ctx, user.ID, until))
+	assert.NoError(t, testDB.UserRepo.ResetFailedLogins(ctx, user.ID))
+	reloaded, err = testDB.UserRepo.GetByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.False(t, reloaded.IsLocked(time.Now()))
+	assert.Zero(t, reloaded.FailedLoginAttempts)
+}
+
+func TestUserRepository_SQLiteEmailVerification(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	user := createTestUser(t, testDB)
+	now := time.Now()
+
+	// The first send passes, a second one within the interval is throttled
+	sent, err := testDB.UserRepo.MarkVerificationSent(ctx, user.ID, now, now.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, sent)
+	sent, err = testDB.UserRepo.MarkVerificationSent(ctx, user.ID, now.Add(time.Second), now.Add(time.Second-time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, sent)
+	later := now.Add(2 * time.Minute)
+	sent, err = testDB.UserRepo.MarkVerificationSent(ctx, user.ID, later, later.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, sent)
+
+	reloaded, err := testDB.UserRepo.GetByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.False(t, reloaded.IsEmailVerified())
+
+	assert.NoError(t, testDB.UserRepo.MarkEmailVerified(ctx, user.ID, now))
+	reloaded, err = testDB.UserRepo.GetByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, reloaded.IsEmailVerified())
+}
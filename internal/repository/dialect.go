@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"fmt"
+)
+
+// Supported database drivers
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Dialect captures the SQL differences between the supported databases.
+// Repositories share one GORM implementation and ask the dialect only for
+// the fragments that cannot be written portably.
+type Dialect interface {
+	// Name returns the driver name the dialect was registered under
+	Name() string
+	// DateOf returns an expression truncating a timestamp column to a calendar date
+	DateOf(column string) string
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return DriverPostgres }
+
+func (postgresDialect) DateOf(column string) string {
+	return fmt.Sprintf("CAST(%s AS DATE)", column)
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return DriverSQLite }
+
+// SQLite stores timestamps as text, so comparing them against a bare
+// YYYY-MM-DD string only matches when the time part is exactly midnight.
+func (sqliteDialect) DateOf(column string) string {
+	return fmt.Sprintf("date(%s)", column)
+}
+
+// DialectFor returns the dialect registered for the given driver
+func DialectFor(driver string) (Dialect, error) {
+	switch driver {
+	case DriverPostgres:
+		return postgresDialect{}, nil
+	case DriverSQLite:
+		return sqliteDialect{}, nil
+	default:
+		return nil, fmt.Errorf("no repository dialect for driver %q", driver)
+	}
+}
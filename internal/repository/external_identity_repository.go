@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+type ExternalIdentityRepository struct {
+	DB *gorm.DB
+}
+
+func NewExternalIdentityRepository(db *gorm.DB) interfaces.ExternalIdentityInterface {
+	return &ExternalIdentityRepository{DB: db}
+}
+
+func (r *ExternalIdentityRepository) Create(ctx context.Context, identity *models.ExternalIdentity) error {
+	ctx, span := startSpan(ctx, "ExternalIdentityRepository.Create")
+	defer span.End()
+	return r.DB.WithContext(ctx).Create(identity).Error
+}
+
+func (r *ExternalIdentityRepository) GetBySubject(ctx context.Context, issuer, subject string) (*models.ExternalIdentity, error) {
+	ctx, span := startSpan(ctx, "ExternalIdentityRepository.GetBySubject")
+	defer span.End()
+	var identity models.ExternalIdentity
+	err := r.DB.WithContext(ctx).Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	return &identity, err
+}
+
+func (r *ExternalIdentityRepository) TouchLogin(ctx context.Context, id int64, at time.Time) error {
+	ctx, span := startSpan(ctx, "ExternalIdentityRepository.TouchLogin")
+	defer span.End()
+	return r.DB.WithContext(ctx).Model(&models.ExternalIdentity{}).Where("id = ?", id).UpdateColumn("last_login_at", at).Error
+}
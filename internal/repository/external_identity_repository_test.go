@@ -0,0 +1,44 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExternalIdentityRepository_SQLite(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	user := createTestUser(t, testDB)
+	repo := testDB.ExternalIdentityRepo
+
+	identity := &models.ExternalIdentity{UserID: user.ID, Issuer: "https://idp.example.com", Subject: "sub-1", Email: user.Email}
+	assert.NoError(t, repo.Create(ctx, identity))
+
+	// A subject is linked to one user per issuer
+	duplicate := &models.ExternalIdentity{UserID: user.ID, Issuer: "https://idp.example.com", Subject: "sub-1"}
+	assert.Error(t, repo.Create(ctx, duplicate))
+	other := &models.ExternalIdentity{UserID: user.ID, Issuer: "https://other.example.com", Subject: "sub-1"}
+	assert.NoError(t, repo.Create(ctx, other))
+
+	_, err = repo.GetBySubject(ctx, "https://idp.example.com", "sub-2")
+	assert.Error(t, err)
+
+	now := time.Now()
+	assert.NoError(t, repo.TouchLogin(ctx, identity.ID, now))
+	stored, err := repo.GetBySubject(ctx, "https://idp.example.com", "sub-1")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, stored.UserID)
+	if assert.NotNil(t, stored.LastLoginAt) {
+		assert.WithinDuration(t, now, *stored.LastLoginAt, time.Millisecond)
+	}
+}
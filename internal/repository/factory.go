@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"gorm.io/gorm"
+)
+
+// Factory builds repositories for the configured database driver
+type Factory struct {
+	dialect Dialect
+}
+
+// NewFactory creates a repository factory for the given driver
+func NewFactory(driver string) (*Factory, error) {
+	dialect, err := DialectFor(driver)
+	if err != nil {
+		return nil, err
+	}
+	return &Factory{dialect: dialect}, nil
+}
+
+// Dialect returns the dialect used by repositories built by this factory
+func (f *Factory) Dialect() Dialect {
+	return f.dialect
+}
+
+// New builds the full set of repositories on top of db
+func (f *Factory) New(db *gorm.DB) *interfaces.Repositories {
+	return &interfaces.Repositories{
+		DailyTasks: NewDailyTaskRepository(db, f.dialect),
+		Users:      NewUserRepository(db),
+		Continents: NewContinentRepository(db),
+		Countries:  NewCountryRepository(db),
+		Companies:  NewCompanyRepository(db),
+
+		RefreshTokens: NewRefreshTokenRepository(db),
+		RevokedTokens: NewRevokedTokenRepository(db),
+		Sessions:      NewSessionRepository(db),
+
+		PasswordResetTokens: NewPasswordResetTokenRepository(db),
+		MFARecoveryCodes:    NewMFARecoveryCodeRepository(db),
+		AccessTokens:        NewPersonalAccessTokenRepository(db),
+		ExternalIdentities:  NewExternalIdentityRepository(db),
+		SigningKeys:         NewSigningKeyRepository(db),
+		Invitations:         NewInvitationRepository(db),
+		RolePermissions:     NewRolePermissionRepository(db),
+		AuditLogs:           NewAuditLogRepository(db),
+	}
+}
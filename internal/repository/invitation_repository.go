@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/tenant"
+	"gorm.io/gorm"
+)
+
+type InvitationRepository struct {
+	DB *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) interfaces.InvitationInterface {
+	return &InvitationRepository{DB: db}
+}
+
+func (r *InvitationRepository) Create(ctx context.Context, invitation *models.Invitation) error {
+	ctx, span := startSpan(ctx, "InvitationRepository.Create")
+	defer span.End()
+	if !tenant.Allows(ctx, invitation.CompanyID) {
+		return tenant.ErrOutsideTenant
+	}
+	return r.DB.WithContext(ctx).Create(invitation).Error
+}
+
+// GetByHash and Accept are not scoped: invitations are accepted before the
+// invitee has an account
+func (r *InvitationRepository) GetByHash(ctx context.Context, hash string) (*models.Invitation, error) {
+	ctx, span := startSpan(ctx, "InvitationRepository.GetByHash")
+	defer span.End()
+	var invitation models.Invitation
+	err := r.DB.WithContext(ctx).Where("token_hash = ?", hash).First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *InvitationRepository) List(ctx context.Context, invitedByID int64) ([]models.Invitation, error) {
+	ctx, span := startSpan(ctx, "InvitationRepository.List")
+	defer span.End()
+	query := r.DB.WithContext(ctx).Scopes(invitationsOfTenant(ctx)).Order("created_at DESC, id DESC")
+	if invitedByID != 0 {
+		query = query.Where("invited_by_id = ?", invitedByID)
+	}
+	var invitations []models.Invitation
+	err := query.Find(&invitations).Error
+	return invitations, err
+}
+
+// Accept only matches a pending invitation, so an invitation creates one
+// account even under concurrent requests
+func (r *InvitationRepository) Accept(ctx context.Context, id, userID int64, at time.Time) (bool, error) {
+	ctx, span := startSpan(ctx, "InvitationRepository.Accept")
+	defer span.End()
+	result := r.DB.WithContext(ctx).Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", id, at).
+		UpdateColumns(map[string]interface{}{"accepted_at": at, "user_id": userID})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *InvitationRepository) Revoke(ctx context.Context, id, invitedByID int64, at time.Time) (bool, error) {
+	ctx, span := startSpan(ctx, "InvitationRepository.Revoke")
+	defer span.End()
+	query := r.DB.WithContext(ctx).Scopes(invitationsOfTenant(ctx)).Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id)
+	if invitedByID != 0 {
+		query = query.Where("invited_by_id = ?", invitedByID)
+	}
+	result := query.UpdateColumn("revoked_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *InvitationRepository) RevokeForEmail(ctx context.Context, email string, at time.Time) error {
+	ctx, span := startSpan(ctx, "InvitationRepository.RevokeForEmail")
+	defer span.End()
+	return r.DB.WithContext(ctx).Scopes(invitationsOfTenant(ctx)).Model(&models.Invitation{}).
+		Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL", email).
+		UpdateColumn("revoked_at", at).Error
+}
@@ -0,0 +1,74 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvitationRepository_SQLite(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	inviter := createTestUser(t, testDB)
+	repo := testDB.InvitationRepo
+	now := time.Now()
+
+	first := &models.Invitation{Email: "a@example.com", Role: models.RoleUser, InvitedByID: inviter.ID, TokenHash: "hash-1", ExpiresAt: now.Add(time.Hour)}
+	second := &models.Invitation{Email: "a@example.com", Role: models.RoleManager, InvitedByID: inviter.ID, TokenHash: "hash-2", ExpiresAt: now.Add(time.Hour)}
+	expired := &models.Invitation{Email: "b@example.com", Role: models.RoleUser, InvitedByID: inviter.ID, TokenHash: "hash-3", ExpiresAt: now.Add(-time.Minute)}
+	for _, invitation := range []*models.Invitation{first, second, expired} {
+		assert.NoError(t, repo.Create(ctx, invitation))
+	}
+
+	listed, err := repo.List(ctx, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, listed, 3) {
+		assert.Equal(t, expired.ID, listed[0].ID, "newest first")
+	}
+	listed, err = repo.List(ctx, inviter.ID+1)
+	assert.NoError(t, err)
+	assert.Empty(t, listed)
+
+	// Revoking by email leaves other addresses alone
+	assert.NoError(t, repo.RevokeForEmail(ctx, "a@example.com", now))
+	stored, err := repo.GetByHash(ctx, "hash-2")
+	assert.NoError(t, err)
+	assert.Equal(t, models.InvitationRevoked, stored.Status(now))
+	stored, err = repo.GetByHash(ctx, "hash-3")
+	assert.NoError(t, err)
+	assert.Equal(t, models.InvitationExpired, stored.Status(now))
+
+	// Revoked and expired invitations cannot be accepted
+	accepted, err := repo.Accept(ctx, second.ID, inviter.ID, now)
+	assert.NoError(t, err)
+	assert.False(t, accepted)
+	accepted, err = repo.Accept(ctx, expired.ID, inviter.ID, now)
+	assert.NoError(t, err)
+	assert.False(t, accepted)
+
+	pending := &models.Invitation{Email: "c@example.com", Role: models.RoleUser, InvitedByID: inviter.ID, TokenHash: "hash-4", ExpiresAt: now.Add(time.Hour)}
+	assert.NoError(t, repo.Create(ctx, pending))
+	revoked, err := repo.Revoke(ctx, pending.ID, inviter.ID+1, now)
+	assert.NoError(t, err)
+	assert.False(t, revoked, "only the sender's invitations match")
+
+	// Only the first acceptance wins, and accepted invitations stay
+	accepted, err = repo.Accept(ctx, pending.ID, inviter.ID, now)
+	assert.NoError(t, err)
+	assert.True(t, accepted)
+	accepted, err = repo.Accept(ctx, pending.ID, inviter.ID, now)
+	assert.NoError(t, err)
+	assert.False(t, accepted)
+	revoked, err = repo.Revoke(ctx, pending.ID, 0, now)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
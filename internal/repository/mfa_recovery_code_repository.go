@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+type MFARecoveryCodeRepository struct {
+	DB *gorm.DB
+}
+
+func NewMFARecoveryCodeRepository(db *gorm.DB) interfaces.MFARecoveryCodeInterface {
+	return &MFARecoveryCodeRepository{DB: db}
+}
+
+func (r *MFARecoveryCodeRepository) ReplaceForUser(ctx context.Context, userID int64, hashes []string) error {
+	ctx, span := startSpan(ctx, "MFARecoveryCodeRepository.ReplaceForUser")
+	defer span.End()
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]models.MFARecoveryCode, len(hashes))
+		for i, hash := range hashes {
+			codes[i] = models.MFARecoveryCode{UserID: userID, CodeHash: hash}
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// Consume only matches an unused code, so a code can be used once even by
+// concurrent requests
+func (r *MFARecoveryCodeRepository) Consume(ctx context.Context, userID int64, hash string, at time.Time) (bool, error) {
+	ctx, span := startSpan(ctx, "MFARecoveryCodeRepository.Consume")
+	defer span.End()
+	result := r.DB.WithContext(ctx).Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		UpdateColumn("used_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *MFARecoveryCodeRepository) CountUnused(ctx context.Context, userID int64) (int64, error) {
+	ctx, span := startSpan(ctx, "MFARecoveryCodeRepository.CountUnused")
+	defer span.End()
+	var count int64
+	err := r.DB.WithContext(ctx).Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *MFARecoveryCodeRepository) DeleteForUser(ctx context.Context, userID int64) error {
+	ctx, span := startSpan(ctx, "MFARecoveryCodeRepository.DeleteForUser")
+	defer span.End()
+	return r.DB.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error
+}
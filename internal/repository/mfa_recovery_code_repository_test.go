@@ -0,0 +1,74 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMFARecoveryCodeRepository_SQLiteSingleUse(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	user := createTestUser(t, testDB)
+	repo := testDB.MFARecoveryCodeRepo
+
+	assert.NoError(t, repo.ReplaceForUser(ctx, user.ID, []string{"hash-1", "hash-2"}))
+
+	// Only the first use wins
+	used, err := repo.Consume(ctx, user.ID, "hash-1", time.Now())
+	assert.NoError(t, err)
+	assert.True(t, used)
+	used, err = repo.Consume(ctx, user.ID, "hash-1", time.Now())
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	count, err := repo.CountUnused(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Replacing drops the old codes
+	assert.NoError(t, repo.ReplaceForUser(ctx, user.ID, []string{"hash-3"}))
+	used, err = repo.Consume(ctx, user.ID, "hash-2", time.Now())
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	assert.NoError(t, repo.DeleteForUser(ctx, user.ID))
+	count, err = repo.CountUnused(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestUserRepository_SQLiteMFA(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	user := createTestUser(t, testDB)
+
+	assert.NoError(t, testDB.UserRepo.UpdateMFA(ctx, user.ID, true, "encrypted"))
+	reloaded, err := testDB.UserRepo.GetByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, reloaded.MFAEnabled)
+	assert.Equal(t, "encrypted", reloaded.MFASecret)
+
+	// A step is accepted once and never after a later one
+	recorded, err := testDB.UserRepo.RecordMFAStep(ctx, user.ID, 100)
+	assert.NoError(t, err)
+	assert.True(t, recorded)
+	for _, step := range []int64{100, 99} {
+		recorded, err = testDB.UserRepo.RecordMFAStep(ctx, user.ID, step)
+		assert.NoError(t, err)
+		assert.False(t, recorded, step)
+	}
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+type PasswordResetTokenRepository struct {
+	DB *gorm.DB
+}
+
+func NewPasswordResetTokenRepository(db *gorm.DB) interfaces.PasswordResetTokenInterface {
+	return &PasswordResetTokenRepository{DB: db}
+}
+
+func (r *PasswordResetTokenRepository) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	ctx, span := startSpan(ctx, "PasswordResetTokenRepository.Create")
+	defer span.End()
+	return r.DB.WithContext(ctx).Create(token).Error
+}
+
+func (r *PasswordResetTokenRepository) GetByHash(ctx context.Context, hash string) (*models.PasswordResetToken, error) {
+	ctx, span := startSpan(ctx, "PasswordResetTokenRepository.GetByHash")
+	defer span.End()
+	var token models.PasswordResetToken
+	err := r.DB.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed only matches an unused token, so a token can be consumed once
+// even by concurrent requests
+func (r *PasswordResetTokenRepository) MarkUsed(ctx context.Context, id int64, at time.Time) (bool, error) {
+	ctx, span := startSpan(ctx, "PasswordResetTokenRepository.MarkUsed")
+	defer span.End()
+	result := r.DB.WithContext(ctx).Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		UpdateColumn("used_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *PasswordResetTokenRepository) InvalidateForUser(ctx context.Context, userID int64, at time.Time) error {
+	ctx, span := startSpan(ctx, "PasswordResetTokenRepository.InvalidateForUser")
+	defer span.End()
+	return r.DB.WithContext(ctx).Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		UpdateColumn("used_at", at).Error
+}
@@ -0,0 +1,50 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordResetTokenRepository_SQLiteSingleUse(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	user := createTestUser(t, testDB)
+	repo := testDB.PasswordResetTokenRepo
+	expiresAt := time.Now().Add(time.Hour)
+
+	first := &models.PasswordResetToken{UserID: user.ID, TokenHash: "hash-1", ExpiresAt: expiresAt}
+	second := &models.PasswordResetToken{UserID: user.ID, TokenHash: "hash-2", ExpiresAt: expiresAt}
+	for _, token := range []*models.PasswordResetToken{first, second} {
+		assert.NoError(t, repo.Create(ctx, token))
+	}
+
+	// Only the first use wins
+	used, err := repo.MarkUsed(ctx, first.ID, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, used)
+	used, err = repo.MarkUsed(ctx, first.ID, time.Now())
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	stored, err := repo.GetByHash(ctx, "hash-2")
+	assert.NoError(t, err)
+	assert.True(t, stored.IsUsable(time.Now()))
+
+	assert.NoError(t, repo.InvalidateForUser(ctx, user.ID, time.Now()))
+	stored, err = repo.GetByHash(ctx, "hash-2")
+	assert.NoError(t, err)
+	assert.False(t, stored.IsUsable(time.Now()))
+
+	_, err = repo.GetByHash(ctx, "missing")
+	assert.Error(t, err)
+}
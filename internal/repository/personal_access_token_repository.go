@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+type PersonalAccessTokenRepository struct {
+	DB *gorm.DB
+}
+
+func NewPersonalAccessTokenRepository(db *gorm.DB) interfaces.PersonalAccessTokenInterface {
+	return &PersonalAccessTokenRepository{DB: db}
+}
+
+func (r *PersonalAccessTokenRepository) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	ctx, span := startSpan(ctx, "PersonalAccessTokenRepository.Create")
+	defer span.End()
+	return r.DB.WithContext(ctx).Create(token).Error
+}
+
+func (r *PersonalAccessTokenRepository) GetByHash(ctx context.Context, hash string) (*models.PersonalAccessToken, error) {
+	ctx, span := startSpan(ctx, "PersonalAccessTokenRepository.GetByHash")
+	defer span.End()
+	var token models.PersonalAccessToken
+	err := r.DB.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error
+	return &token, err
+}
+
+func (r *PersonalAccessTokenRepository) ListForUser(ctx context.Context, userID int64) ([]models.PersonalAccessToken, error) {
+	ctx, span := startSpan(ctx, "PersonalAccessTokenRepository.ListForUser")
+	defer span.End()
+	var tokens []models.PersonalAccessToken
+	err := r.DB.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC, id DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *PersonalAccessTokenRepository) Revoke(ctx context.Context, id, userID int64, at time.Time) (bool, error) {
+	ctx, span := startSpan(ctx, "PersonalAccessTokenRepository.Revoke")
+	defer span.End()
+	result := r.DB.WithContext(ctx).Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		UpdateColumn("revoked_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *PersonalAccessTokenRepository) RevokeAllForUser(ctx context.Context, userID int64, at time.Time) error {
+	ctx, span := startSpan(ctx, "PersonalAccessTokenRepository.RevokeAllForUser")
+	defer span.End()
+	return r.DB.WithContext(ctx).Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		UpdateColumn("revoked_at", at).Error
+}
+
+// TouchLastUsed writes at most once per interval per token, so busy
+// automation does not turn every request into a write
+func (r *PersonalAccessTokenRepository) TouchLastUsed(ctx context.Context, id int64, at, notBefore time.Time) error {
+	ctx, span := startSpan(ctx, "PersonalAccessTokenRepository.TouchLastUsed")
+	defer span.End()
+	return r.DB.WithContext(ctx).Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, notBefore).
+		UpdateColumn("last_used_at", at).Error
+}
@@ -0,0 +1,76 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPersonalAccessTokenRepository_SQLite(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	user := createTestUser(t, testDB)
+	repo := testDB.AccessTokenRepo
+	now := time.Now()
+
+	token := &models.PersonalAccessToken{
+		UserID:      user.ID,
+		Name:        "ci",
+		TokenPrefix: "nalo_pat_abcd",
+		TokenHash:   "hash-1",
+		Scopes:      []string{models.ScopeTasksRead, models.ScopeTasksWrite},
+		ExpiresAt:   now.Add(time.Hour),
+	}
+	assert.NoError(t, repo.Create(ctx, token))
+
+	stored, err := repo.GetByHash(ctx, "hash-1")
+	assert.NoError(t, err)
+	assert.Equal(t, token.Scopes, stored.Scopes)
+	assert.Nil(t, stored.LastUsedAt)
+
+	// Uses within the interval are not written again
+	assert.NoError(t, repo.TouchLastUsed(ctx, token.ID, now, now.Add(-time.Minute)))
+	assert.NoError(t, repo.TouchLastUsed(ctx, token.ID, now.Add(time.Second), now.Add(time.Second-time.Minute)))
+	stored, err = repo.GetByHash(ctx, "hash-1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, stored.LastUsedAt) {
+		assert.WithinDuration(t, now, *stored.LastUsedAt, time.Millisecond)
+	}
+
+	// Only the owner revokes, once
+	revoked, err := repo.Revoke(ctx, token.ID, user.ID+1, now)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+	revoked, err = repo.Revoke(ctx, token.ID, user.ID, now)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = repo.Revoke(ctx, token.ID, user.ID, now)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	listed, err := repo.ListForUser(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, listed)
+
+	// Revoking every token of the user leaves revoked ones alone
+	for _, hash := range []string{"hash-2", "hash-3"} {
+		assert.NoError(t, repo.Create(ctx, &models.PersonalAccessToken{UserID: user.ID, Name: hash, TokenHash: hash, Scopes: []string{models.ScopeAdmin}, ExpiresAt: now.Add(time.Hour)}))
+	}
+	assert.NoError(t, repo.RevokeAllForUser(ctx, user.ID, now.Add(time.Minute)))
+	listed, err = repo.ListForUser(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, listed)
+	stored, err = repo.GetByHash(ctx, "hash-1")
+	if assert.NoError(t, err) && assert.NotNil(t, stored.RevokedAt) {
+		assert.WithinDuration(t, now, *stored.RevokedAt, time.Millisecond)
+	}
+}
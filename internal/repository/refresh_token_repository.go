@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository struct {
+	DB *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) interfaces.RefreshTokenInterface {
+	return &RefreshTokenRepository{DB: db}
+}
+
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	ctx, span := startSpan(ctx, "RefreshTokenRepository.Create")
+	defer span.End()
+	return r.DB.WithContext(ctx).Create(token).Error
+}
+
+func (r *RefreshTokenRepository) GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	ctx, span := startSpan(ctx, "RefreshTokenRepository.GetByHash")
+	defer span.End()
+	var token models.RefreshToken
+	err := r.DB.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkRotated only matches a token that is neither rotated nor revoked, so
+// of two concurrent refreshes with the same token exactly one succeeds
+func (r *RefreshTokenRepository) MarkRotated(ctx context.Context, id int64, at time.Time) (bool, error) {
+	ctx, span := startSpan(ctx, "RefreshTokenRepository.MarkRotated")
+	defer span.End()
+	result := r.DB.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", id).
+		UpdateColumn("rotated_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	ctx, span := startSpan(ctx, "RefreshTokenRepository.RevokeFamily")
+	defer span.End()
+	return r.DB.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		UpdateColumn("revoked_at", at).Error
+}
+
+func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID int64, at time.Time) error {
+	ctx, span := startSpan(ctx, "RefreshTokenRepository.RevokeAllForUser")
+	defer span.End()
+	return r.DB.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		UpdateColumn("revoked_at", at).Error
+}
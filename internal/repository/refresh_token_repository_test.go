@@ -0,0 +1,55 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshTokenRepository_SQLiteRotationAndRevocation(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	user := createTestUser(t, testDB)
+	repo := testDB.RefreshTokenRepo
+	expiresAt := time.Now().Add(time.Hour)
+
+	first := &models.RefreshToken{UserID: user.ID, FamilyID: "family-a", TokenHash: "hash-1", ExpiresAt: expiresAt}
+	second := &models.RefreshToken{UserID: user.ID, FamilyID: "family-a", TokenHash: "hash-2", ExpiresAt: expiresAt}
+	other := &models.RefreshToken{UserID: user.ID, FamilyID: "family-b", TokenHash: "hash-3", ExpiresAt: expiresAt}
+	for _, token := range []*models.RefreshToken{first, second, other} {
+		assert.NoError(t, repo.Create(ctx, token))
+	}
+
+	// Only the first rotation wins
+	rotated, err := repo.MarkRotated(ctx, first.ID, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, rotated)
+	rotated, err = repo.MarkRotated(ctx, first.ID, time.Now())
+	assert.NoError(t, err)
+	assert.False(t, rotated)
+
+	assert.NoError(t, repo.RevokeFamily(ctx, "family-a", time.Now()))
+	stored, err := repo.GetByHash(ctx, "hash-2")
+	assert.NoError(t, err)
+	assert.NotNil(t, stored.RevokedAt)
+	stored, err = repo.GetByHash(ctx, "hash-3")
+	assert.NoError(t, err)
+	assert.True(t, stored.IsActive(time.Now()))
+
+	assert.NoError(t, repo.RevokeAllForUser(ctx, user.ID, time.Now()))
+	stored, err = repo.GetByHash(ctx, "hash-3")
+	assert.NoError(t, err)
+	assert.False(t, stored.IsActive(time.Now()))
+
+	_, err = repo.GetByHash(ctx, "missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevokedTokenRepository struct {
+	DB *gorm.DB
+}
+
+func NewRevokedTokenRepository(db *gorm.DB) interfaces.RevokedTokenInterface {
+	return &RevokedTokenRepository{DB: db}
+}
+
+// Create ignores a jti that is already revoked so revoking is idempotent
+func (r *RevokedTokenRepository) Create(ctx context.Context, token *models.RevokedToken) error {
+	ctx, span := startSpan(ctx, "RevokedTokenRepository.Create")
+	defer span.End()
+	return r.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+func (r *RevokedTokenRepository) ListActive(ctx context.Context, now time.Time) ([]models.RevokedToken, error) {
+	ctx, span := startSpan(ctx, "RevokedTokenRepository.ListActive")
+	defer span.End()
+	var tokens []models.RevokedToken
+	err := r.DB.WithContext(ctx).Where("expires_at > ?", now).Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *RevokedTokenRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "RevokedTokenRepository.DeleteExpired")
+	defer span.End()
+	result := r.DB.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.RevokedToken{})
+	return result.RowsAffected, result.Error
+}
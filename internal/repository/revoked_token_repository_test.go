@@ -0,0 +1,40 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevokedTokenRepository_SQLite(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	repo := testDB.RevokedTokenRepo
+	now := time.Now()
+	jti := "jti-1"
+	before := now
+
+	assert.NoError(t, repo.Create(ctx, &models.RevokedToken{JTI: &jti, UserID: 1, ExpiresAt: now.Add(time.Minute)}))
+	// Revoking the same token twice is not an error
+	assert.NoError(t, repo.Create(ctx, &models.RevokedToken{JTI: &jti, UserID: 1, ExpiresAt: now.Add(time.Minute)}))
+	// User-wide revocations have no jti; several may coexist
+	assert.NoError(t, repo.Create(ctx, &models.RevokedToken{UserID: 2, RevokedBefore: &before, ExpiresAt: now.Add(time.Minute)}))
+	assert.NoError(t, repo.Create(ctx, &models.RevokedToken{UserID: 3, RevokedBefore: &before, ExpiresAt: now.Add(-time.Minute)}))
+
+	active, err := repo.ListActive(ctx, now)
+	assert.NoError(t, err)
+	assert.Len(t, active, 2)
+
+	deleted, err := repo.DeleteExpired(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+type RolePermissionRepository struct {
+	DB *gorm.DB
+}
+
+func NewRolePermissionRepository(db *gorm.DB) interfaces.RolePermissionInterface {
+	return &RolePermissionRepository{DB: db}
+}
+
+func (r *RolePermissionRepository) List(ctx context.Context) ([]models.RolePermission, error) {
+	ctx, span := startSpan(ctx, "RolePermissionRepository.List")
+	defer span.End()
+	var grants []models.RolePermission
+	err := r.DB.WithContext(ctx).Order("role, permission").Find(&grants).Error
+	return grants, err
+}
+
+// ReplaceForRole deletes and inserts in one transaction, so readers never
+// see a role without its permissions
+func (r *RolePermissionRepository) ReplaceForRole(ctx context.Context, role models.UserRole, permissions []string) error {
+	ctx, span := startSpan(ctx, "RolePermissionRepository.ReplaceForRole")
+	defer span.End()
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role = ?", role).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		if len(permissions) == 0 {
+			return nil
+		}
+		grants := make([]models.RolePermission, len(permissions))
+		for i, permission := range permissions {
+			grants[i] = models.RolePermission{Role: role, Permission: permission}
+		}
+		return tx.Create(&grants).Error
+	})
+}
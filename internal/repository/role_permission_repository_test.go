@@ -0,0 +1,46 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRolePermissionRepository_SQLite(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	repo := testDB.RolePermissionRepo
+
+	// The migration installs the default mapping
+	grants, err := repo.List(ctx)
+	assert.NoError(t, err)
+	seeded := make(map[models.UserRole][]string)
+	for _, grant := range grants {
+		seeded[grant.Role] = append(seeded[grant.Role], grant.Permission)
+	}
+	for _, role := range models.Roles {
+		assert.ElementsMatch(t, models.DefaultRolePermissions[role], seeded[role], role)
+	}
+	assert.Len(t, seeded, len(models.Roles))
+
+	// Replacing one role leaves the others alone
+	assert.NoError(t, repo.ReplaceForRole(ctx, models.RoleUser, []string{models.PermissionTaskRead}))
+	assert.NoError(t, repo.ReplaceForRole(ctx, models.RoleManager, nil))
+	grants, err = repo.List(ctx)
+	assert.NoError(t, err)
+	replaced := make(map[models.UserRole][]string)
+	for _, grant := range grants {
+		replaced[grant.Role] = append(replaced[grant.Role], grant.Permission)
+	}
+	assert.Equal(t, []string{models.PermissionTaskRead}, replaced[models.RoleUser])
+	assert.Empty(t, replaced[models.RoleManager])
+	assert.ElementsMatch(t, models.DefaultRolePermissions[models.RoleAdmin], replaced[models.RoleAdmin])
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	DB *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) interfaces.SessionInterface {
+	return &SessionRepository{DB: db}
+}
+
+func (r *SessionRepository) Create(ctx context.Context, session *models.Session) error {
+	ctx, span := startSpan(ctx, "SessionRepository.Create")
+	defer span.End()
+	return r.DB.WithContext(ctx).Create(session).Error
+}
+
+func (r *SessionRepository) GetByID(ctx context.Context, id string) (*models.Session, error) {
+	ctx, span := startSpan(ctx, "SessionRepository.GetByID")
+	defer span.End()
+	var session models.Session
+	err := r.DB.WithContext(ctx).Where("id = ?", id).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepository) ListActive(ctx context.Context, userID int64, now time.Time) ([]models.Session, error) {
+	ctx, span := startSpan(ctx, "SessionRepository.ListActive")
+	defer span.End()
+	var sessions []models.Session
+	err := r.DB.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *SessionRepository) Touch(ctx context.Context, id string, at time.Time, ip string) error {
+	ctx, span := startSpan(ctx, "SessionRepository.Touch")
+	defer span.End()
+	updates := map[string]interface{}{"last_seen_at": at}
+	if ip != "" {
+		updates["ip"] = ip
+	}
+	return r.DB.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		UpdateColumns(updates).Error
+}
+
+func (r *SessionRepository) Extend(ctx context.Context, id string, expiresAt time.Time) error {
+	ctx, span := startSpan(ctx, "SessionRepository.Extend")
+	defer span.End()
+	return r.DB.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		UpdateColumn("expires_at", expiresAt).Error
+}
+
+func (r *SessionRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	ctx, span := startSpan(ctx, "SessionRepository.Revoke")
+	defer span.End()
+	return r.DB.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		UpdateColumn("revoked_at", at).Error
+}
+
+func (r *SessionRepository) RevokeAllForUser(ctx context.Context, userID int64, at time.Time) error {
+	ctx, span := startSpan(ctx, "SessionRepository.RevokeAllForUser")
+	defer span.End()
+	return r.DB.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		UpdateColumn("revoked_at", at).Error
+}
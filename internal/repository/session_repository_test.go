@@ -0,0 +1,68 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionRepository_SQLiteListTouchAndRevoke(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	user := createTestUser(t, testDB)
+	repo := testDB.SessionRepo
+	now := time.Now()
+
+	laptop := &models.Session{ID: "laptop", UserID: user.ID, UserAgent: "Firefox", IP: "203.0.113.7", ExpiresAt: now.Add(time.Hour), LastSeenAt: now.Add(-time.Hour)}
+	phone := &models.Session{ID: "phone", UserID: user.ID, UserAgent: "Safari", IP: "203.0.113.8", ExpiresAt: now.Add(time.Hour), LastSeenAt: now.Add(-time.Minute)}
+	expired := &models.Session{ID: "expired", UserID: user.ID, ExpiresAt: now.Add(-time.Minute), LastSeenAt: now.Add(-2 * time.Hour)}
+	for _, session := range []*models.Session{laptop, phone, expired} {
+		assert.NoError(t, repo.Create(ctx, session))
+	}
+
+	// Most recently seen first, without expired sessions
+	sessions, err := repo.ListActive(ctx, user.ID, now)
+	if assert.NoError(t, err) && assert.Len(t, sessions, 2) {
+		assert.Equal(t, "phone", sessions[0].ID)
+		assert.Equal(t, "laptop", sessions[1].ID)
+	}
+
+	assert.NoError(t, repo.Touch(ctx, "laptop", now, "198.51.100.1"))
+	assert.NoError(t, repo.Extend(ctx, "laptop", now.Add(2*time.Hour)))
+	stored, err := repo.GetByID(ctx, "laptop")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "198.51.100.1", stored.IP)
+		assert.WithinDuration(t, now, stored.LastSeenAt, time.Second)
+		assert.WithinDuration(t, now.Add(2*time.Hour), stored.ExpiresAt, time.Second)
+	}
+
+	assert.NoError(t, repo.Revoke(ctx, "phone", now))
+	sessions, err = repo.ListActive(ctx, user.ID, now)
+	if assert.NoError(t, err) && assert.Len(t, sessions, 1) {
+		assert.Equal(t, "laptop", sessions[0].ID)
+	}
+
+	// A revoked session is no longer touched
+	assert.NoError(t, repo.Touch(ctx, "phone", now.Add(time.Minute), ""))
+	stored, err = repo.GetByID(ctx, "phone")
+	if assert.NoError(t, err) && assert.NotNil(t, stored.RevokedAt) {
+		assert.WithinDuration(t, now.Add(-time.Minute), stored.LastSeenAt, time.Second)
+	}
+
+	assert.NoError(t, repo.RevokeAllForUser(ctx, user.ID, now))
+	sessions, err = repo.ListActive(ctx, user.ID, now)
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	_, err = repo.GetByID(ctx, "missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+type SigningKeyRepository struct {
+	DB *gorm.DB
+}
+
+func NewSigningKeyRepository(db *gorm.DB) interfaces.SigningKeyInterface {
+	return &SigningKeyRepository{DB: db}
+}
+
+func (r *SigningKeyRepository) Create(ctx context.Context, key *models.SigningKey) error {
+	ctx, span := startSpan(ctx, "SigningKeyRepository.Create")
+	defer span.End()
+	return r.DB.WithContext(ctx).Create(key).Error
+}
+
+func (r *SigningKeyRepository) List(ctx context.Context) ([]models.SigningKey, error) {
+	ctx, span := startSpan(ctx, "SigningKeyRepository.List")
+	defer span.End()
+	var keys []models.SigningKey
+	err := r.DB.WithContext(ctx).Order("activates_at ASC, id ASC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *SigningKeyRepository) Delete(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, "SigningKeyRepository.Delete")
+	defer span.End()
+	return r.DB.WithContext(ctx).Delete(&models.SigningKey{}, id).Error
+}
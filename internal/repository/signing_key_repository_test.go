@@ -0,0 +1,42 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigningKeyRepository_SQLite(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx := context.Background()
+	repo := testDB.SigningKeyRepo
+	now := time.Now()
+
+	next := &models.SigningKey{KID: "next", Algorithm: "EdDSA", PublicKey: "public", PrivateKey: "sealed", ActivatesAt: now.Add(time.Minute)}
+	current := &models.SigningKey{KID: "current", Algorithm: "EdDSA", PublicKey: "public", PrivateKey: "sealed", ActivatesAt: now}
+	assert.NoError(t, repo.Create(ctx, next))
+	assert.NoError(t, repo.Create(ctx, current))
+	assert.Error(t, repo.Create(ctx, &models.SigningKey{KID: "current", Algorithm: "EdDSA", PublicKey: "p", PrivateKey: "s", ActivatesAt: now}))
+
+	// Listed by activation, not creation
+	keys, err := repo.List(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, keys, 2) {
+		assert.Equal(t, "current", keys[0].KID)
+		assert.Equal(t, "next", keys[1].KID)
+	}
+
+	assert.NoError(t, repo.Delete(ctx, current.ID))
+	keys, err = repo.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+}
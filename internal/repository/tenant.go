@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/alxand/nalo-workspace/internal/pkg/tenant"
+	"gorm.io/gorm"
+)
+
+// Tenant scopes limit queries to the company of the tenant in ctx, if it is
+// limited. Users always see their own user and tasks.
+
+func usersOfTenant(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		t, limited := tenant.Limited(ctx)
+		if !limited {
+			return db
+		}
+		return db.Where("(users.company_id = ? OR users.id = ?)", t.CompanyID, t.UserID)
+	}
+}
+
+func companiesOfTenant(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		t, limited := tenant.Limited(ctx)
+		if !limited {
+			return db
+		}
+		return db.Where("companies.id = ?", t.CompanyID)
+	}
+}
+
+func tasksOfTenant(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		t, limited := tenant.Limited(ctx)
+		if !limited {
+			return db
+		}
+		return db.Where("(daily_tasks.user_id = ? OR daily_tasks.user_id IN (SELECT id FROM users WHERE company_id = ?))", t.UserID, t.CompanyID)
+	}
+}
+
+func invitationsOfTenant(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		t, limited := tenant.Limited(ctx)
+		if !limited {
+			return db
+		}
+		return db.Where("invitations.company_id = ?", t.CompanyID)
+	}
+}
+
+// visible returns gorm.ErrRecordNotFound unless the row of model with id
+// passes scope. Save inserts when it updates nothing, so updates check
+// first rather than scoping the statement.
+func visible(db *gorm.DB, model interface{}, id int64, scope func(*gorm.DB) *gorm.DB) error {
+	var count int64
+	if err := db.Model(model).Scopes(scope).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const cancelKey = "query_timeout:cancel"
+
+// QueryTimeout is a GORM plugin that bounds every statement by a default
+// timeout. A shorter deadline already on the context (for example the
+// per-request deadline) is left untouched.
+type QueryTimeout struct {
+	Timeout time.Duration
+}
+
+// NewQueryTimeout creates the plugin; a zero timeout disables it
+func NewQueryTimeout(timeout time.Duration) *QueryTimeout {
+	return &QueryTimeout{Timeout: timeout}
+}
+
+// Name implements gorm.Plugin
+func (p *QueryTimeout) Name() string {
+	return "query_timeout"
+}
+
+// Initialize implements gorm.Plugin
+func (p *QueryTimeout) Initialize(db *gorm.DB) error {
+	if p.Timeout <= 0 {
+		return nil
+	}
+
+	// Row and Rows hand open cursors back to the caller, so only callbacks
+	// that finish reading inside GORM are wrapped.
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:begin_transaction").Register("query_timeout:before_create", p.before); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("gorm:commit_or_rollback_transaction").Register("query_timeout:after_create", p.after); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("query_timeout:before_query", p.before); err != nil {
+		return err
+	}
+	if err := callbacks.Query().After("gorm:after_query").Register("query_timeout:after_query", p.after); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:begin_transaction").Register("query_timeout:before_update", p.before); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:commit_or_rollback_transaction").Register("query_timeout:after_update", p.after); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:begin_transaction").Register("query_timeout:before_delete", p.before); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register("query_timeout:after_delete", p.after); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().Before("gorm:raw").Register("query_timeout:before_raw", p.before); err != nil {
+		return err
+	}
+	return callbacks.Raw().After("gorm:raw").Register("query_timeout:after_raw", p.after)
+}
+
+func (p *QueryTimeout) before(db *gorm.DB) {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= p.Timeout {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	db.Statement.Context = ctx
+	db.InstanceSet(cancelKey, cancel)
+}
+
+func (p *QueryTimeout) after(db *gorm.DB) {
+	if cancel, ok := db.InstanceGet(cancelKey); ok {
+		cancel.(context.CancelFunc)()
+	}
+}
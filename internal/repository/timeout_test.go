@@ -0,0 +1,54 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/repository"
+	"github.com/alxand/nalo-workspace/internal/test_helpers"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestQueryTimeout_AppliesDefaultDeadline(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	assert.NoError(t, testDB.DB.Use(repository.NewQueryTimeout(2*time.Second)))
+
+	var remaining time.Duration
+	err = testDB.DB.Callback().Query().After("query_timeout:before_query").Register("test:capture_deadline", func(db *gorm.DB) {
+		if deadline, ok := db.Statement.Context.Deadline(); ok {
+			remaining = time.Until(deadline)
+		}
+	})
+	assert.NoError(t, err)
+
+	// No deadline on the context: the default applies
+	_, _ = testDB.ContinentRepo.GetAll(context.Background())
+	assert.True(t, remaining > 0 && remaining <= 2*time.Second)
+
+	// A shorter request deadline is kept
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_, _ = testDB.ContinentRepo.GetAll(ctx)
+	assert.True(t, remaining > 0 && remaining <= 500*time.Millisecond)
+}
+
+func TestRepository_CancelledContext(t *testing.T) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer test_helpers.CleanupTestDB(testDB)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = testDB.ContinentRepo.GetAll(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
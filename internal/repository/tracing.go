@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/alxand/nalo-workspace/internal/pkg/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan opens a span for a repository call; the GORM statement spans
+// it issues nest underneath
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, name)
+}
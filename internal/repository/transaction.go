@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// TransactionManager implements interfaces.TransactionManager with GORM
+type TransactionManager struct {
+	db      *gorm.DB
+	factory *Factory
+}
+
+// NewTransactionManager creates a transaction manager building its
+// transaction-bound repositories with factory
+func NewTransactionManager(db *gorm.DB, factory *Factory) interfaces.TransactionManager {
+	return &TransactionManager{db: db, factory: factory}
+}
+
+// WithinTransaction runs fn in a transaction, or in a savepoint when ctx
+// already carries one. Returning an error rolls back; a panic rolls back and
+// is re-raised once the transaction is closed.
+func (m *TransactionManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context, repos *interfaces.Repositories) error) error {
+	ctx, span := startSpan(ctx, "TransactionManager.WithinTransaction")
+	defer span.End()
+
+	db := m.db
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		db = tx
+	}
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx), m.factory.New(tx))
+	})
+}
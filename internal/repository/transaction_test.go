@@ -0,0 +1,107 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/repository"
+	"github.com/alxand/nalo-workspace/internal/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTransactionManager(t *testing.T) (*test_helpers.TestDB, interfaces.TransactionManager) {
+	testDB, err := test_helpers.SetupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	t.Cleanup(func() { test_helpers.CleanupTestDB(testDB) })
+
+	factory, err := repository.NewFactory(repository.DriverSQLite)
+	if err != nil {
+		t.Fatalf("Failed to create repository factory: %v", err)
+	}
+	return testDB, repository.NewTransactionManager(testDB.DB, factory)
+}
+
+func continentCount(t *testing.T, testDB *test_helpers.TestDB) int {
+	continents, err := testDB.ContinentRepo.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to list continents: %v", err)
+	}
+	return len(continents)
+}
+
+func TestTransactionManager_CommitsAcrossRepositories(t *testing.T) {
+	testDB, txManager := setupTransactionManager(t)
+
+	err := txManager.WithinTransaction(context.Background(), func(ctx context.Context, repos *interfaces.Repositories) error {
+		continent := &models.Continent{Name: "Europe", Code: "EU"}
+		if err := repos.Continents.Create(ctx, continent); err != nil {
+			return err
+		}
+		return repos.Countries.Create(ctx, &models.Country{Name: "Germany", Code: "DEU", ContinentID: continent.ID})
+	})
+	assert.NoError(t, err)
+
+	country, err := testDB.CountryRepo.GetByCode(context.Background(), "DEU")
+	assert.NoError(t, err)
+	assert.Equal(t, "EU", country.Continent.Code)
+}
+
+func TestTransactionManager_RollsBackOnError(t *testing.T) {
+	testDB, txManager := setupTransactionManager(t)
+	failure := errors.New("second step failed")
+
+	err := txManager.WithinTransaction(context.Background(), func(ctx context.Context, repos *interfaces.Repositories) error {
+		if err := repos.Continents.Create(ctx, &models.Continent{Name: "Europe", Code: "EU"}); err != nil {
+			return err
+		}
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, 0, continentCount(t, testDB))
+}
+
+func TestTransactionManager_RollsBackOnPanic(t *testing.T) {
+	testDB, txManager := setupTransactionManager(t)
+
+	assert.Panics(t, func() {
+		_ = txManager.WithinTransaction(context.Background(), func(ctx context.Context, repos *interfaces.Repositories) error {
+			if err := repos.Continents.Create(ctx, &models.Continent{Name: "Europe", Code: "EU"}); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	})
+	assert.Equal(t, 0, continentCount(t, testDB))
+}
+
+func TestTransactionManager_NestedSavepoint(t *testing.T) {
+	testDB, txManager := setupTransactionManager(t)
+
+	err := txManager.WithinTransaction(context.Background(), func(ctx context.Context, repos *interfaces.Repositories) error {
+		if err := repos.Continents.Create(ctx, &models.Continent{Name: "Europe", Code: "EU"}); err != nil {
+			return err
+		}
+
+		// The inner failure only rolls back to its savepoint
+		nestedErr := txManager.WithinTransaction(ctx, func(ctx context.Context, repos *interfaces.Repositories) error {
+			if err := repos.Continents.Create(ctx, &models.Continent{Name: "Asia", Code: "AS"}); err != nil {
+				return err
+			}
+			return errors.New("discard asia")
+		})
+		assert.Error(t, nestedErr)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	continents, err := testDB.ContinentRepo.GetAll(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, continents, 1) {
+		assert.Equal(t, "EU", continents[0].Code)
+	}
+}
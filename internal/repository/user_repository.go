@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/tenant"
+	"gorm.io/gorm"
+)
+
+type UserRepository struct {
+	DB *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) interfaces.UserInterface {
+	return &UserRepository{DB: db}
+}
+
+// scoped starts a query limited to the users of the tenant in ctx
+func (r *UserRepository) scoped(ctx context.Context) *gorm.DB {
+	return r.DB.WithContext(ctx).Scopes(usersOfTenant(ctx))
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	ctx, span := startSpan(ctx, "UserRepository.Create")
+	defer span.End()
+	if !tenant.Allows(ctx, user.CompanyID) {
+		return tenant.ErrOutsideTenant
+	}
+	return r.DB.WithContext(ctx).Create(user).Error
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	ctx, span := startSpan(ctx, "UserRepository.GetByID")
+	defer span.End()
+	var user models.User
+	err := r.scoped(ctx).Preload("Country").Preload("Company").First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, span := startSpan(ctx, "UserRepository.GetByEmail")
+	defer span.End()
+	var user models.User
+	err := r.scoped(ctx).Preload("Country").Preload("Company").Where("email = ?", email).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	ctx, span := startSpan(ctx, "UserRepository.GetByUsername")
+	defer span.End()
+	var user models.User
+	err := r.scoped(ctx).Preload("Country").Preload("Company").Where("username = ?", username).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) GetByCountry(ctx context.Context, countryID int64) ([]models.User, error) {
+	ctx, span := startSpan(ctx, "UserRepository.GetByCountry")
+	defer span.End()
+	var users []models.User
+	err := r.scoped(ctx).Preload("Country").Preload("Company").Where("country_id = ?", countryID).Find(&users).Error
+	return users, err
+}
+
+func (r *UserRepository) GetByCompany(ctx context.Context, companyID int64) ([]models.User, error) {
+	ctx, span := startSpan(ctx, "UserRepository.GetByCompany")
+	defer span.End()
+	var users []models.User
+	err := r.scoped(ctx).Preload("Country").Preload("Company").Where("company_id = ?", companyID).Find(&users).Error
+	return users, err
+}
+
+func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
+	ctx, span := startSpan(ctx, "UserRepository.Update")
+	defer span.End()
+	if !tenant.Allows(ctx, user.CompanyID) {
+		return tenant.ErrOutsideTenant
+	}
+	if err := visible(r.DB.WithContext(ctx), &models.User{}, user.ID, usersOfTenant(ctx)); err != nil {
+		return err
+	}
+	return r.DB.WithContext(ctx).Save(user).Error
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, "UserRepository.Delete")
+	defer span.End()
+	return r.scoped(ctx).Delete(&models.User{}, id).Error
+}
+
+func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]models.User, error) {
+	ctx, span := startSpan(ctx, "UserRepository.List")
+	defer span.End()
+	var users []models.User
+	err := r.scoped(ctx).Preload("Country").Preload("Company").Limit(limit).Offset(offset).Find(&users).Error
+	return users, err
+}
+
+func (r *UserRepository) UpdateLastLogin(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, "UserRepository.UpdateLastLogin")
+	defer span.End()
+	now := time.Now()
+	return r.scoped(ctx).Model(&models.User{}).Where("id = ?", id).Update("last_login", now).Error
+}
+
+// RecordFailedLogin increments the failed attempt counter atomically and
+// returns the new count. A lock that expired by now is lifted and the count
+// starts over, so an account is not locked again by its next failure.
+func (r *UserRepository) RecordFailedLogin(ctx context.Context, id int64, now time.Time) (int, error) {
+	ctx, span := startSpan(ctx, "UserRepository.RecordFailedLogin")
+	defer span.End()
+	var attempts int
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+			"failed_login_attempts": gorm.Expr("CASE WHEN locked_until IS NOT NULL AND locked_until <= ? THEN 1 ELSE failed_login_attempts + 1 END", now),
+			"locked_until":          gorm.Expr("CASE WHEN locked_until <= ? THEN NULL ELSE locked_until END", now),
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Select("failed_login_attempts").Where("id = ?", id).Scan(&attempts).Error
+	})
+	return attempts, err
+}
+
+func (r *UserRepository) LockUntil(ctx context.Context, id int64, until time.Time) error {
+	ctx, span := startSpan(ctx, "UserRepository.LockUntil")
+	defer span.End()
+	return r.scoped(ctx).Model(&models.User{}).Where("id = ?", id).UpdateColumn("locked_until", until).Error
+}
+
+func (r *UserRepository) ResetFailedLogins(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, "UserRepository.ResetFailedLogins")
+	defer span.End()
+	return r.scoped(ctx).Model(&models.User{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"failed_login_attempts": 0, "locked_until": nil}).Error
+}
+
+func (r *UserRepository) UpdatePassword(ctx context.Context, id int64, hash string) error {
+	ctx, span := startSpan(ctx, "UserRepository.UpdatePassword")
+	defer span.End()
+	return r.scoped(ctx).Model(&models.User{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"password": hash, "updated_at": time.Now()}).Error
+}
+
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, id int64, at time.Time) error {
+	ctx, span := startSpan(ctx, "UserRepository.MarkEmailVerified")
+	defer span.End()
+	return r.scoped(ctx).Model(&models.User{}).Where("id = ? AND email_verified_at IS NULL", id).
+		UpdateColumn("email_verified_at", at).Error
+}
+
+// MarkVerificationSent checks and updates the throttle in one statement, so
+// concurrent resends cannot both pass
+func (r *UserRepository) MarkVerificationSent(ctx context.Context, id int64, at, notBefore time.Time) (bool, error) {
+	ctx, span := startSpan(ctx, "UserRepository.MarkVerificationSent")
+	defer span.End()
+	result := r.scoped(ctx).Model(&models.User{}).
+		Where("id = ? AND (verification_sent_at IS NULL OR verification_sent_at <= ?)", id, notBefore).
+		UpdateColumn("verification_sent_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *UserRepository) UpdateMFA(ctx context.Context, id int64, enabled bool, secret string) error {
+	ctx, span := startSpan(ctx, "UserRepository.UpdateMFA")
+	defer span.End()
+	return r.scoped(ctx).Model(&models.User{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"mfa_enabled": enabled, "mfa_secret": secret, "mfa_last_step": 0, "updated_at": time.Now()}).Error
+}
+
+func (r *UserRepository) RecordMFAStep(ctx context.Context, id int64, step int64) (bool, error) {
+	ctx, span := startSpan(ctx, "UserRepository.RecordMFAStep")
+	defer span.End()
+	result := r.scoped(ctx).Model(&models.User{}).
+		Where("id = ? AND mfa_last_step < ?", id, step).
+		UpdateColumn("mfa_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+// UpdateProfile writes only the fields users edit themselves, so role,
+// company and account state are never touched
+func (r *UserRepository) UpdateProfile(ctx context.Context, user *models.User) error {
+	ctx, span := startSpan(ctx, "UserRepository.UpdateProfile")
+	defer span.End()
+	return r.scoped(ctx).Model(&models.User{}).Where("id = ?", user.ID).
+		UpdateColumns(map[string]interface{}{
+			"username":              user.Username,
+			"first_name":            user.FirstName,
+			"last_name":             user.LastName,
+			"country_id":            user.CountryID,
+			"timezone":              user.Timezone,
+			"locale":                user.Locale,
+			"avatar_url":            user.AvatarURL,
+			"notify_task_reminders": user.Notifications.TaskReminders,
+			"notify_weekly_summary": user.Notifications.WeeklySummary,
+			"updated_at":            time.Now(),
+		}).Error
+}
+
+func (r *UserRepository) RequestEmailChange(ctx context.Context, id int64, email string) error {
+	ctx, span := startSpan(ctx, "UserRepository.RequestEmailChange")
+	defer span.End()
+	return r.scoped(ctx).Model(&models.User{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"pending_email": email, "updated_at": time.Now()}).Error
+}
+
+// ConfirmEmailChange only applies while email is still the pending address,
+// so a link to an address that was replaced since does nothing
+func (r *UserRepository) ConfirmEmailChange(ctx context.Context, id int64, email string, at time.Time) (bool, error) {
+	ctx, span := startSpan(ctx, "UserRepository.ConfirmEmailChange")
+	defer span.End()
+	result := r.scoped(ctx).Model(&models.User{}).
+		Where("id = ? AND pending_email = ?", id, email).
+		UpdateColumns(map[string]interface{}{"email": email, "pending_email": nil, "email_verified_at": at, "updated_at": at})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *UserRepository) UpdateRole(ctx context.Context, id int64, role models.UserRole) error {
+	ctx, span := startSpan(ctx, "UserRepository.UpdateRole")
+	defer span.End()
+	return r.scoped(ctx).Model(&models.User{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"role": role, "updated_at": time.Now()}).Error
+}
+
+// ExistsByEmail and ExistsByUsername are not scoped: emails and usernames
+// are unique across tenants
+func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
+	ctx, span := startSpan(ctx, "UserRepository.ExistsByEmail")
+	defer span.End()
+	var count int64
+	err := r.DB.WithContext(ctx).Model(&models.User{}).Where("email = ?", email).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *UserRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
+	ctx, span := startSpan(ctx, "UserRepository.ExistsByUsername")
+	defer span.End()
+	var count int64
+	err := r.DB.WithContext(ctx).Model(&models.User{}).Where("username = ?", username).Count(&count).Error
+	return count > 0, err
+}
@@ -1,9 +1,11 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	"github.com/alxand/nalo-workspace/internal/api/audit"
 	"github.com/alxand/nalo-workspace/internal/api/auth"
 	"github.com/alxand/nalo-workspace/internal/api/company"
 	"github.com/alxand/nalo-workspace/internal/api/continent"
@@ -11,7 +13,12 @@ import (
 	"github.com/alxand/nalo-workspace/internal/api/dailytask"
 	"github.com/alxand/nalo-workspace/internal/api/user"
 	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/pkg/health"
+	"github.com/alxand/nalo-workspace/internal/pkg/metrics"
 	"github.com/alxand/nalo-workspace/internal/pkg/middleware"
+	"github.com/alxand/nalo-workspace/internal/pkg/ratelimit"
+	"github.com/alxand/nalo-workspace/internal/pkg/tracing"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/helmet"
@@ -35,7 +42,13 @@ func NewApp(config *config.Config, logger *zap.Logger) *App {
 		ReadTimeout:  config.Server.ReadTimeout,
 		WriteTimeout: config.Server.WriteTimeout,
 		IdleTimeout:  config.Server.IdleTimeout,
-		ErrorHandler: middleware.ErrorHandler(logger),
+		ProxyHeader:  config.Server.ProxyHeader,
+		// The header is only read from trusted proxies, and only a valid
+		// client address in it is used
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          config.Server.TrustedProxies,
+		EnableIPValidation:      true,
+		ErrorHandler:            middleware.ErrorHandler(logger, config.Server.Debug),
 	})
 
 	return &App{
@@ -48,22 +61,44 @@ func NewApp(config *config.Config, logger *zap.Logger) *App {
 // SetupRoutes configures all application routes
 func (a *App) SetupRoutes(
 	authHandler *auth.AuthHandler,
+	passwordHandler *auth.PasswordHandler,
+	verificationHandler *auth.VerificationHandler,
+	mfaHandler *auth.MFAHandler,
+	accessTokenHandler *auth.AccessTokenHandler,
+	oidcHandler *auth.OIDCHandler,
+	jwksHandler *auth.JWKSHandler,
+	invitationHandler *auth.InvitationHandler,
+	permissionHandler *auth.PermissionHandler,
+	impersonationHandler *auth.ImpersonationHandler,
+	profileHandler *auth.ProfileHandler,
+	sessionHandler *auth.SessionHandler,
+	auditHandler *audit.AuditHandler,
 	taskHandler *dailytask.TaskHandler,
 	userHandler *user.UserHandler,
 	continentHandler *continent.ContinentHandler,
 	countryHandler *country.CountryHandler,
 	companyHandler *company.CompanyHandler,
 	authService *auth.Service,
+	accessTokenService *auth.AccessTokenService,
+	permissionService *auth.PermissionService,
+	healthRegistry *health.Registry,
+	rateLimitStore ratelimit.Store,
 ) {
 	// Middleware
 	a.app.Use(recover.New())
+	a.app.Use(tracing.Middleware())
+	a.app.Use(middleware.RequestID(a.logger))
+	a.app.Use(middleware.Audit())
 	a.app.Use(helmet.New())
 	a.app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders: "Origin,Content-Type,Accept,Authorization",
+		AllowOrigins:  "*",
+		AllowMethods:  "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:  "Origin,Content-Type,Accept,Authorization,X-Request-ID",
+		ExposeHeaders: "X-Request-ID,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Retry-After",
 	}))
 	a.app.Use(middleware.RequestLogger(a.logger))
+	a.app.Use(metrics.Middleware())
+	a.app.Use(middleware.RequestTimeout(a.config.Server.RequestTimeout))
 
 	// Health check
 	a.app.Get("/health", func(c *fiber.Ctx) error {
@@ -74,71 +109,183 @@ func (a *App) SetupRoutes(
 		})
 	})
 
+	// Liveness and readiness probes
+	a.app.Get("/livez", health.LivenessHandler())
+	a.app.Get("/readyz", healthRegistry.ReadinessHandler())
+
+	// Public keys that verify access tokens
+	a.app.Get("/.well-known/jwks.json", jwksHandler.JWKS)
+
+	// Prometheus metrics
+	if a.config.Metrics.Enabled {
+		a.app.Get("/metrics", metrics.Handler(a.config.Metrics.AllowedNetworks, a.config.Metrics.Token))
+	}
+
 	// Swagger documentation
 	a.app.Get("/swagger/*", swagger.HandlerDefault)
 
 	// API routes
 	api := a.app.Group("/api/v1")
 
+	// Rate limits: per client IP on public auth routes, per user once authenticated
+	publicLimit := noLimit
+	userLimit := noLimit
+	if cfg := a.config.RateLimit; cfg.Enabled {
+		publicLimit = ratelimit.Middleware(rateLimitStore, "public", ratelimit.Limit(cfg.Public), ratelimit.ByIP)
+		userLimit = ratelimit.Middleware(rateLimitStore, "user", ratelimit.Limit(cfg.User), ratelimit.ByUser)
+	}
+
 	// Auth routes (no authentication required)
 	authGroup := api.Group("/auth")
-	authGroup.Post("/register", authHandler.Register)
-	authGroup.Post("/login", authHandler.Login)
+	if a.config.Registration.Enabled {
+		authGroup.Post("/register", publicLimit, authHandler.Register)
+	} else {
+		authGroup.Post("/register", publicLimit, authHandler.RegistrationDisabled)
+	}
+	authGroup.Post("/invitations/accept", publicLimit, invitationHandler.AcceptInvitation)
+	authGroup.Post("/login", publicLimit, authHandler.Login)
+	authGroup.Post("/login/mfa", publicLimit, mfaHandler.LoginMFA)
+	authGroup.Post("/refresh", publicLimit, authHandler.RefreshToken)
+	authGroup.Post("/password/forgot", publicLimit, passwordHandler.ForgotPassword)
+	authGroup.Post("/password/reset", publicLimit, passwordHandler.ResetPassword)
+	authGroup.Post("/verify-email", publicLimit, verificationHandler.VerifyEmail)
+	authGroup.Post("/verify-email/resend", publicLimit, verificationHandler.ResendVerification)
+
+	// Single sign-on, when an identity provider is configured
+	if a.config.OIDC.Enabled() {
+		authGroup.Get("/oidc/login", publicLimit, oidcHandler.Login)
+		authGroup.Get("/oidc/callback", publicLimit, oidcHandler.Callback)
+	}
+
+	// Protected routes (authentication required), reachable with a login
+	// session or a personal access token. Repositories limit their queries
+	// to the company of the user.
+	protected := api.Group("/", middleware.JWT(authService, accessTokenService), middleware.Tenant(permissionService), userLimit)
 
-	// Protected routes (authentication required)
-	protected := api.Group("/", middleware.JWT(authService))
+	// Personal access tokens cannot manage the account they belong to, and
+	// admins acting as a user cannot take the account over
+	session := middleware.RequireSession()
+	notImpersonating := middleware.NotImpersonating()
+	adminScope := middleware.RequireScope(models.ScopeAdmin)
+
+	// can requires one of permissions from the role of the user
+	can := func(permissions ...string) fiber.Handler {
+		return middleware.RequirePermission(permissionService, permissions...)
+	}
 
 	// Auth protected routes
 	protected.Get("/auth/profile", authHandler.Profile)
-	protected.Post("/auth/refresh", authHandler.RefreshToken)
+	protected.Put("/auth/profile", session, profileHandler.UpdateProfile)
+	protected.Patch("/auth/profile", session, profileHandler.PatchProfile)
+	protected.Get("/auth/permissions", permissionHandler.EffectivePermissions)
+	protected.Post("/auth/logout", session, authHandler.Logout)
+	protected.Post("/auth/logout-all", session, notImpersonating, authHandler.LogoutAll)
+	protected.Post("/auth/password/change", session, notImpersonating, passwordHandler.ChangePassword)
+	protected.Get("/auth/mfa", session, mfaHandler.Status)
+	protected.Post("/auth/mfa/enroll", session, notImpersonating, mfaHandler.Enroll)
+	protected.Post("/auth/mfa/confirm", session, notImpersonating, mfaHandler.Confirm)
+	protected.Post("/auth/mfa/disable", session, notImpersonating, mfaHandler.Disable)
+	protected.Post("/auth/mfa/recovery-codes", session, notImpersonating, mfaHandler.RegenerateRecoveryCodes)
+	protected.Get("/auth/tokens", session, accessTokenHandler.ListTokens)
+	protected.Post("/auth/tokens", session, notImpersonating, accessTokenHandler.CreateToken)
+	protected.Delete("/auth/tokens/:id", session, notImpersonating, accessTokenHandler.RevokeToken)
+	protected.Post("/auth/impersonation/end", session, impersonationHandler.EndImpersonation)
+	protected.Get("/auth/sessions", session, sessionHandler.ListSessions)
+	protected.Post("/auth/sessions/revoke-others", session, notImpersonating, sessionHandler.RevokeOtherSessions)
+	protected.Delete("/auth/sessions/:id", session, notImpersonating, sessionHandler.RevokeSession)
+
+	// Routes registered below need a verified email when verification is
+	// enforced, and two-factor authentication for the roles that require
+	// it; the auth routes above stay reachable to finish either
+	if a.config.Verification.Enforced() {
+		protected.Use(middleware.VerifiedEmail())
+	}
+	if len(a.config.MFA.RequiredRoles) > 0 {
+		protected.Use(middleware.MFAEnrolled(a.config.MFA))
+	}
 
-	// Daily task routes (authentication required)
+	// Daily task routes (authentication required); handlers also check who
+	// owns the task
+	readTasks := middleware.RequireScope(models.ScopeTasksRead)
+	writeTasks := middleware.RequireScope(models.ScopeTasksWrite)
+	canReadTasks := can(models.PermissionTaskRead, models.PermissionTaskReadTeam, models.PermissionTaskReadAll)
+	canWriteTasks := can(models.PermissionTaskWrite, models.PermissionTaskWriteTeam, models.PermissionTaskWriteAll)
 	tasksGroup := protected.Group("/dailytask")
-	tasksGroup.Post("/", taskHandler.CreateDailyTask)
-	tasksGroup.Get("/:date", taskHandler.GetTasksByDate)
-	tasksGroup.Put("/:id", taskHandler.UpdateTask)
-	tasksGroup.Delete("/:id", taskHandler.DeleteTask)
+	tasksGroup.Post("/", writeTasks, can(models.PermissionTaskWrite), taskHandler.CreateDailyTask)
+	tasksGroup.Get("/:date", readTasks, canReadTasks, taskHandler.GetTasksByDate)
+	tasksGroup.Put("/:id", writeTasks, canWriteTasks, taskHandler.UpdateTask)
+	tasksGroup.Delete("/:id", writeTasks, canWriteTasks, taskHandler.DeleteTask)
 
 	// Continent routes (authentication required)
-	continentsGroup := protected.Group("/continents")
-	continentsGroup.Post("/", continentHandler.CreateContinent)
-	continentsGroup.Get("/", continentHandler.GetAllContinents)
-	continentsGroup.Get("/:id", continentHandler.GetContinent)
-	continentsGroup.Get("/code/:code", continentHandler.GetContinentByCode)
-	continentsGroup.Put("/:id", continentHandler.UpdateContinent)
-	continentsGroup.Delete("/:id", continentHandler.DeleteContinent)
+	readContinents := can(models.PermissionContinentRead)
+	writeContinents := can(models.PermissionContinentWrite)
+	continentsGroup := protected.Group("/continents", adminScope)
+	continentsGroup.Post("/", writeContinents, continentHandler.CreateContinent)
+	continentsGroup.Get("/", readContinents, continentHandler.GetAllContinents)
+	continentsGroup.Get("/:id", readContinents, continentHandler.GetContinent)
+	continentsGroup.Get("/code/:code", readContinents, continentHandler.GetContinentByCode)
+	continentsGroup.Put("/:id", writeContinents, continentHandler.UpdateContinent)
+	continentsGroup.Delete("/:id", writeContinents, continentHandler.DeleteContinent)
 
 	// Country routes (authentication required)
-	countriesGroup := protected.Group("/countries")
-	countriesGroup.Post("/", countryHandler.CreateCountry)
-	countriesGroup.Get("/", countryHandler.GetAllCountries)
-	countriesGroup.Get("/:id", countryHandler.GetCountry)
-	countriesGroup.Get("/code/:code", countryHandler.GetCountryByCode)
-	countriesGroup.Get("/continent/:continentId", countryHandler.GetCountriesByContinent)
-	countriesGroup.Put("/:id", countryHandler.UpdateCountry)
-	countriesGroup.Delete("/:id", countryHandler.DeleteCountry)
+	readCountries := can(models.PermissionCountryRead)
+	writeCountries := can(models.PermissionCountryWrite)
+	countriesGroup := protected.Group("/countries", adminScope)
+	countriesGroup.Post("/", writeCountries, countryHandler.CreateCountry)
+	countriesGroup.Get("/", readCountries, countryHandler.GetAllCountries)
+	countriesGroup.Get("/:id", readCountries, countryHandler.GetCountry)
+	countriesGroup.Get("/code/:code", readCountries, countryHandler.GetCountryByCode)
+	countriesGroup.Get("/continent/:continentId", readCountries, countryHandler.GetCountriesByContinent)
+	countriesGroup.Put("/:id", writeCountries, countryHandler.UpdateCountry)
+	countriesGroup.Delete("/:id", writeCountries, countryHandler.DeleteCountry)
 
 	// Company routes (authentication required)
-	companiesGroup := protected.Group("/companies")
-	companiesGroup.Post("/", companyHandler.CreateCompany)
-	companiesGroup.Get("/", companyHandler.GetAllCompanies)
-	companiesGroup.Get("/:id", companyHandler.GetCompany)
-	companiesGroup.Get("/code/:code", companyHandler.GetCompanyByCode)
-	companiesGroup.Get("/country/:countryId", companyHandler.GetCompaniesByCountry)
-	companiesGroup.Get("/industry/:industry", companyHandler.GetCompaniesByIndustry)
-	companiesGroup.Put("/:id", companyHandler.UpdateCompany)
-	companiesGroup.Delete("/:id", companyHandler.DeleteCompany)
-
-	// Admin routes (admin role required)
-	adminGroup := protected.Group("/admin", middleware.AdminMiddleware())
-	adminGroup.Get("/users", userHandler.ListUsers)
-	adminGroup.Get("/users/:id", userHandler.GetUser)
-	adminGroup.Put("/users/:id", userHandler.UpdateUser)
-	adminGroup.Delete("/users/:id", userHandler.DeleteUser)
+	readCompanies := can(models.PermissionCompanyRead)
+	writeCompanies := can(models.PermissionCompanyWrite)
+	companiesGroup := protected.Group("/companies", adminScope)
+	companiesGroup.Post("/", writeCompanies, companyHandler.CreateCompany)
+	companiesGroup.Get("/", readCompanies, companyHandler.GetAllCompanies)
+	companiesGroup.Get("/:id", readCompanies, companyHandler.GetCompany)
+	companiesGroup.Get("/code/:code", readCompanies, companyHandler.GetCompanyByCode)
+	companiesGroup.Get("/country/:countryId", readCompanies, companyHandler.GetCompaniesByCountry)
+	companiesGroup.Get("/industry/:industry", readCompanies, companyHandler.GetCompaniesByIndustry)
+	companiesGroup.Put("/:id", writeCompanies, companyHandler.UpdateCompany)
+	companiesGroup.Delete("/:id", writeCompanies, companyHandler.DeleteCompany)
+
+	// Invitation routes
+	invitationsGroup := protected.Group("/invitations", adminScope, can(models.PermissionInvitationWrite, models.PermissionUserAdmin))
+	invitationsGroup.Post("/", invitationHandler.CreateInvitation)
+	invitationsGroup.Get("/", invitationHandler.ListInvitations)
+	invitationsGroup.Delete("/:id", invitationHandler.RevokeInvitation)
+
+	// Admin routes
+	adminGroup := protected.Group("/admin", adminScope)
+	usersGroup := adminGroup.Group("/users", can(models.PermissionUserAdmin))
+	usersGroup.Get("/", userHandler.ListUsers)
+	usersGroup.Post("/", userHandler.CreateUser)
+	usersGroup.Get("/:id", userHandler.GetUser)
+	usersGroup.Put("/:id", userHandler.UpdateUser)
+	usersGroup.Delete("/:id", notImpersonating, userHandler.DeleteUser)
+	usersGroup.Post("/:id/revoke-tokens", userHandler.RevokeUserTokens)
+	usersGroup.Get("/:id/sessions", sessionHandler.ListUserSessions)
+	usersGroup.Delete("/:id/sessions/:session_id", sessionHandler.RevokeUserSession)
+	usersGroup.Get("/:id/tokens", accessTokenHandler.ListUserTokens)
+	usersGroup.Delete("/:id/tokens/:token_id", accessTokenHandler.RevokeUserToken)
+	usersGroup.Post("/:id/impersonate", session, notImpersonating, can(models.PermissionUserImpersonate), impersonationHandler.Impersonate)
+	manageRoles := can(models.PermissionRoleAdmin)
+	adminGroup.Get("/permissions", manageRoles, permissionHandler.ListPermissions)
+	adminGroup.Get("/roles", manageRoles, permissionHandler.ListRoles)
+	adminGroup.Put("/roles/:role/permissions", manageRoles, permissionHandler.UpdateRolePermissions)
+	adminGroup.Get("/audit-logs", can(models.PermissionAuditRead), auditHandler.ListAuditLogs)
 
 	a.logger.Info("Routes configured successfully")
 }
 
+// noLimit stands in for a rate limiter when limiting is disabled
+func noLimit(c *fiber.Ctx) error {
+	return c.Next()
+}
+
 // Start starts the server
 func (a *App) Start() error {
 	addr := fmt.Sprintf("%s:%d", a.config.Server.Host, a.config.Server.Port)
@@ -146,8 +293,9 @@ func (a *App) Start() error {
 	return a.app.Listen(addr)
 }
 
-// Shutdown gracefully shuts down the server
-func (a *App) Shutdown() error {
+// Shutdown gracefully shuts down the server, waiting for in-flight
+// requests until ctx is done
+func (a *App) Shutdown(ctx context.Context) error {
 	a.logger.Info("Shutting down server")
-	return a.app.Shutdown()
+	return a.app.ShutdownWithContext(ctx)
 }
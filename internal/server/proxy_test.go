@@ -0,0 +1,70 @@
+package server
+
+import (
+	"io"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alxand/nalo-workspace/internal/config"
+	"github.com/alxand/nalo-workspace/internal/pkg/metrics"
+	"github.com/alxand/nalo-workspace/internal/pkg/ratelimit"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// newProxyTestApp serves the rate limit key of the request and metrics to
+// 10.0.0.0/8, behind the given trusted proxies. Test requests come from
+// 0.0.0.0.
+func newProxyTestApp(t *testing.T, trustedProxies []string) *fiber.App {
+	_, internal, err := net.ParseCIDR("10.0.0.0/8")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	cfg := &config.Config{Server: config.ServerConfig{ProxyHeader: fiber.HeaderXForwardedFor, TrustedProxies: trustedProxies}}
+	app := NewApp(cfg, zap.NewNop()).app
+	app.Get("/key", func(c *fiber.Ctx) error {
+		return c.SendString(ratelimit.ByIP(c))
+	})
+	app.Get("/metrics", metrics.Handler([]*net.IPNet{internal}, ""))
+	return app
+}
+
+// proxyRequest sends a request with X-Forwarded-For set to forwardedFor and
+// returns the status and body
+func proxyRequest(t *testing.T, app *fiber.App, path, forwardedFor string) (int, string) {
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set(fiber.HeaderXForwardedFor, forwardedFor)
+	resp, err := app.Test(req)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestProxyHeader_IgnoredFromUntrustedPeer(t *testing.T) {
+	app := newProxyTestApp(t, []string{"192.0.2.1"})
+
+	// A spoofed header neither gets a fresh rate limit bucket nor passes
+	// the metrics allowlist
+	_, key := proxyRequest(t, app, "/key", "10.1.2.3")
+	assert.Equal(t, "ip:0.0.0.0", key)
+	status, _ := proxyRequest(t, app, "/metrics", "10.1.2.3")
+	assert.Equal(t, fiber.StatusForbidden, status)
+}
+
+func TestProxyHeader_ReadFromTrustedProxy(t *testing.T) {
+	app := newProxyTestApp(t, []string{"0.0.0.0/32"})
+
+	_, key := proxyRequest(t, app, "/key", "10.1.2.3")
+	assert.Equal(t, "ip:10.1.2.3", key)
+	status, _ := proxyRequest(t, app, "/metrics", "10.1.2.3")
+	assert.Equal(t, fiber.StatusOK, status)
+
+	// Only a client address is used, never the raw list
+	_, key = proxyRequest(t, app, "/key", "not-an-ip, 10.1.2.3, 192.0.2.1")
+	assert.Equal(t, "ip:10.1.2.3", key)
+}
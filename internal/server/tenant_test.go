@@ -0,0 +1,279 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alxand/nalo-workspace/internal/container"
+	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// tenantTestEnv runs the full application against a SQLite file with two
+// companies, Acme and Globex. Everything seeded for Globex carries "globex"
+// in a field that is serialized, so a response mentioning it leaked.
+type tenantTestEnv struct {
+	app       *fiber.App
+	container *container.Container
+	acme      *models.Company
+	globex    *models.Company
+	users     map[string]*models.User
+	tasks     map[string]*models.DailyTask
+}
+
+const tenantTestDate = "2024-03-01"
+
+func newTenantTestEnv(t *testing.T) *tenantTestEnv {
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("DSN", filepath.Join(t.TempDir(), "tenant.db"))
+	t.Setenv("DB_AUTO_MIGRATE", "true")
+	t.Setenv("JWT_SECRET", "tenant-test-secret")
+	t.Setenv("RATE_LIMIT_ENABLED", "false")
+	t.Setenv("LOG_LEVEL", "error")
+
+	c, err := container.NewContainer()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { c.Close() })
+
+	a := NewApp(c.Config, c.Logger)
+	a.SetupRoutes(
+		c.AuthHandler, c.PasswordHandler, c.VerificationHandler, c.MFAHandler, c.AccessTokenHandler,
+		c.OIDCHandler, c.JWKSHandler, c.InvitationHandler, c.PermissionHandler, c.ImpersonationHandler, c.ProfileHandler, c.SessionHandler, c.AuditHandler, c.DailyTaskHandler,
+		c.UserHandler, c.ContinentHandler, c.CountryHandler, c.CompanyHandler,
+		c.AuthService, c.AccessTokenService, c.PermissionService, c.Health, c.RateLimitStore,
+	)
+
+	// Seeded without a tenant, as the seed command does
+	ctx := context.Background()
+	env := &tenantTestEnv{app: a.app, container: c, users: make(map[string]*models.User), tasks: make(map[string]*models.DailyTask)}
+	continent := &models.Continent{Name: "Europe", Code: "EU"}
+	assert.NoError(t, c.ContinentRepo.Create(ctx, continent))
+	country := &models.Country{Name: "Germany", Code: "DEU", ContinentID: continent.ID}
+	assert.NoError(t, c.CountryRepo.Create(ctx, country))
+	env.acme = &models.Company{Name: "Acme", Code: "ACME", CountryID: country.ID, Industry: "Technology", Size: "small"}
+	env.globex = &models.Company{Name: "Globex", Code: "GLOBEX", CountryID: country.ID, Industry: "Technology", Size: "small"}
+	assert.NoError(t, c.CompanyRepo.Create(ctx, env.acme))
+	assert.NoError(t, c.CompanyRepo.Create(ctx, env.globex))
+
+	now := time.Now()
+	for _, u := range []struct {
+		name    string
+		role    models.UserRole
+		company *int64
+	}{
+		{"admin", models.RoleAdmin, nil},
+		{"acme-manager", models.RoleManager, &env.acme.ID},
+		{"acme-user", models.RoleUser, &env.acme.ID},
+		{"globex-user", models.RoleUser, &env.globex.ID},
+		{"loner", models.RoleUser, nil},
+	} {
+		user := &models.User{
+			Email: u.name + "@example.com", Username: u.name, Password: "password123",
+			FirstName: u.name, LastName: "Tester", Role: u.role, IsActive: true,
+			CompanyID: u.company, EmailVerifiedAt: &now,
+		}
+		assert.NoError(t, c.UserRepo.Create(ctx, user))
+		env.users[u.name] = user
+
+		date, _ := time.Parse("2006-01-02", tenantTestDate)
+		task := &models.DailyTask{
+			UserID: user.ID, Date: date, StartTime: date, EndTime: date.Add(time.Hour),
+			Status: "in_progress", Day: "day of " + u.name,
+		}
+		assert.NoError(t, c.DailyTaskRepo.Create(ctx, task))
+		env.tasks[u.name] = task
+	}
+	return env
+}
+
+// login returns an access token of the seeded user
+func (env *tenantTestEnv) login(t *testing.T, name string) string {
+	status, body := env.request(t, "", "POST", "/api/v1/auth/login", map[string]string{"email": name + "@example.com", "password": "password123"})
+	if !assert.Equal(t, fiber.StatusOK, status, body) {
+		t.FailNow()
+	}
+	var resp struct {
+		Token string `json:"token"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(body), &resp))
+	return resp.Token
+}
+
+func (env *tenantTestEnv) request(t *testing.T, token, method, path string, payload interface{}) (int, string) {
+	var body io.Reader
+	if payload != nil {
+		encoded, _ := json.Marshal(payload)
+		body = bytes.NewReader(encoded)
+	}
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := env.app.Test(req, -1)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(raw)
+}
+
+// leaked returns body without the echo of the request path in errors
+func leaked(body, path string) string {
+	return strings.ReplaceAll(strings.ToLower(body), strings.ToLower(path), "")
+}
+
+// The container registers process-wide metrics, so both parts share one
+// application
+func TestTenantIsolation(t *testing.T) {
+	env := newTenantTestEnv(t)
+	t.Run("ScopesWithinTheCompany", func(t *testing.T) { testTenantScopes(t, env) })
+	t.Run("NoEndpointLeaksAnotherCompany", func(t *testing.T) { testNoTenantLeaks(t, env) })
+}
+
+func testNoTenantLeaks(t *testing.T, env *tenantTestEnv) {
+	ctx := context.Background()
+
+	// Give managers every permission but tenant:all, so only the tenant
+	// stands between them and Globex
+	var permissions []string
+	for _, permission := range models.PermissionCatalog {
+		if permission.Name != models.PermissionTenantAll {
+			permissions = append(permissions, permission.Name)
+		}
+	}
+	assert.NoError(t, env.container.PermissionService.Update(ctx, models.RoleManager, permissions))
+	token := env.login(t, "acme-manager")
+
+	globexUser := env.users["globex-user"]
+	globexTask := env.tasks["globex-user"]
+	acmeUser := env.users["acme-user"]
+	moved := *acmeUser
+	moved.CompanyID = &env.globex.ID
+	country, err := env.container.CountryRepo.GetByID(ctx, env.globex.CountryID)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	reads := []string{
+		"/api/v1/companies",
+		fmt.Sprintf("/api/v1/companies/%d", env.acme.ID),
+		fmt.Sprintf("/api/v1/companies/%d", env.globex.ID),
+		"/api/v1/companies/code/GLOBEX",
+		fmt.Sprintf("/api/v1/companies/country/%d", env.globex.CountryID),
+		"/api/v1/companies/industry/Technology",
+		"/api/v1/admin/users?limit=100",
+		fmt.Sprintf("/api/v1/admin/users/%d", globexUser.ID),
+		fmt.Sprintf("/api/v1/admin/users/%d/tokens", globexUser.ID),
+		"/api/v1/dailytask/" + tenantTestDate,
+		fmt.Sprintf("/api/v1/dailytask/%s?user_id=%d", tenantTestDate, globexUser.ID),
+		"/api/v1/invitations",
+		"/api/v1/countries",
+		fmt.Sprintf("/api/v1/countries/%d", env.globex.CountryID),
+		"/api/v1/countries/code/DEU",
+		fmt.Sprintf("/api/v1/countries/continent/%d", country.ContinentID),
+		"/api/v1/continents",
+		fmt.Sprintf("/api/v1/continents/%d", country.ContinentID),
+		"/api/v1/continents/code/EU",
+	}
+	for _, path := range reads {
+		status, body := env.request(t, token, "GET", path, nil)
+		assert.Less(t, status, 500, path)
+		assert.NotContains(t, leaked(body, path), "globex", path)
+	}
+
+	writes := []struct {
+		method, path string
+		payload      interface{}
+		status       int
+	}{
+		{"PUT", fmt.Sprintf("/api/v1/companies/%d", env.globex.ID), env.globex, fiber.StatusNotFound},
+		{"DELETE", fmt.Sprintf("/api/v1/companies/%d", env.globex.ID), nil, fiber.StatusNotFound},
+		{"POST", "/api/v1/companies", models.Company{Name: "Initech", CountryID: env.acme.CountryID, Size: "small"}, fiber.StatusForbidden},
+		{"PUT", fmt.Sprintf("/api/v1/admin/users/%d", globexUser.ID), globexUser, fiber.StatusNotFound},
+		{"PUT", fmt.Sprintf("/api/v1/admin/users/%d", acmeUser.ID), moved, fiber.StatusForbidden},
+		{"DELETE", fmt.Sprintf("/api/v1/admin/users/%d", globexUser.ID), nil, fiber.StatusNotFound},
+		{"POST", fmt.Sprintf("/api/v1/admin/users/%d/revoke-tokens", globexUser.ID), nil, fiber.StatusNotFound},
+		{"DELETE", fmt.Sprintf("/api/v1/admin/users/%d/tokens/1", globexUser.ID), nil, fiber.StatusNotFound},
+		{"POST", "/api/v1/admin/users", map[string]interface{}{
+			"email": "spy@example.com", "username": "spy", "password": "password123",
+			"first_name": "Spy", "last_name": "Tester", "role": "user", "company_id": env.globex.ID,
+		}, fiber.StatusForbidden},
+		{"PUT", fmt.Sprintf("/api/v1/dailytask/%d", globexTask.ID), globexTask, fiber.StatusNotFound},
+		{"DELETE", fmt.Sprintf("/api/v1/dailytask/%d", globexTask.ID), nil, fiber.StatusNotFound},
+		{"POST", "/api/v1/invitations", map[string]interface{}{"email": "new@example.com", "role": "user", "company_id": env.globex.ID}, fiber.StatusNotFound},
+	}
+	for _, w := range writes {
+		status, body := env.request(t, token, w.method, w.path, w.payload)
+		assert.Equal(t, w.status, status, "%s %s: %s", w.method, w.path, body)
+		assert.NotContains(t, leaked(body, w.path), "globex", w.path)
+	}
+
+	// Globex is untouched
+	company, err := env.container.CompanyRepo.GetByID(ctx, env.globex.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Globex", company.Name)
+		assert.Len(t, company.Users, 1)
+	}
+	task, err := env.container.DailyTaskRepo.GetByID(ctx, globexTask.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, globexTask.Day, task.Day)
+	}
+	_, err = env.container.UserRepo.GetByEmail(ctx, "spy@example.com")
+	assert.Error(t, err)
+}
+
+func testTenantScopes(t *testing.T, env *tenantTestEnv) {
+	// Managers see their company and its users
+	manager := env.login(t, "acme-manager")
+	status, body := env.request(t, manager, "GET", "/api/v1/companies", nil)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Contains(t, body, `"name":"Acme"`)
+	assert.Contains(t, body, "acme-user@example.com")
+	status, body = env.request(t, manager, "GET", fmt.Sprintf("/api/v1/dailytask/%s?user_id=%d", tenantTestDate, env.users["acme-user"].ID), nil)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Contains(t, body, "day of acme-user")
+
+	// Users without a company see only themselves
+	loner := env.login(t, "loner")
+	status, body = env.request(t, loner, "GET", "/api/v1/companies", nil)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "[]", body)
+	status, body = env.request(t, loner, "GET", "/api/v1/dailytask/"+tenantTestDate, nil)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Contains(t, body, "day of loner")
+
+	// Admins see across companies
+	admin := env.login(t, "admin")
+	status, body = env.request(t, admin, "GET", "/api/v1/companies", nil)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Contains(t, body, `"name":"Acme"`)
+	assert.Contains(t, body, `"name":"Globex"`)
+	status, body = env.request(t, admin, "GET", fmt.Sprintf("/api/v1/dailytask/%s?user_id=%d", tenantTestDate, env.users["globex-user"].ID), nil)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Contains(t, body, "day of globex-user")
+
+	// unless their role loses tenant:all
+	grants := env.container.PermissionService.For(models.RoleAdmin)
+	var limited []string
+	for _, permission := range grants {
+		if permission != models.PermissionTenantAll {
+			limited = append(limited, permission)
+		}
+	}
+	assert.NoError(t, env.container.PermissionService.Update(context.Background(), models.RoleAdmin, limited))
+	status, body = env.request(t, admin, "GET", "/api/v1/companies", nil)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "[]", body)
+}
@@ -1,13 +1,16 @@
 package test_helpers
 
 import (
+	"context"
+
 	"github.com/alxand/nalo-workspace/internal/config"
 	"github.com/alxand/nalo-workspace/internal/domain/interfaces"
 	"github.com/alxand/nalo-workspace/internal/domain/models"
+	"github.com/alxand/nalo-workspace/internal/migrations"
 	"github.com/alxand/nalo-workspace/internal/pkg/logger"
 	"github.com/alxand/nalo-workspace/internal/pkg/middleware"
 	"github.com/alxand/nalo-workspace/internal/pkg/validation"
-	"github.com/alxand/nalo-workspace/internal/repository/sqlite"
+	"github.com/alxand/nalo-workspace/internal/repository"
 	"github.com/gofiber/fiber/v2"
 	fiberlogger "github.com/gofiber/fiber/v2/middleware/logger"
 	"gorm.io/gorm"
@@ -16,10 +19,24 @@ import (
 // TestDB holds the test database and repositories
 type TestDB struct {
 	DB            *gorm.DB
+	DailyTaskRepo interfaces.DailyTaskInterface
 	CompanyRepo   interfaces.CompanyInterface
 	ContinentRepo interfaces.ContinentInterface
 	CountryRepo   interfaces.CountryInterface
 	UserRepo      interfaces.UserInterface
+
+	RefreshTokenRepo interfaces.RefreshTokenInterface
+	RevokedTokenRepo interfaces.RevokedTokenInterface
+	SessionRepo      interfaces.SessionInterface
+
+	PasswordResetTokenRepo interfaces.PasswordResetTokenInterface
+	MFARecoveryCodeRepo    interfaces.MFARecoveryCodeInterface
+	AccessTokenRepo        interfaces.PersonalAccessTokenInterface
+	ExternalIdentityRepo   interfaces.ExternalIdentityInterface
+	SigningKeyRepo         interfaces.SigningKeyInterface
+	InvitationRepo         interfaces.InvitationInterface
+	RolePermissionRepo     interfaces.RolePermissionInterface
+	AuditLogRepo           interfaces.AuditLogInterface
 }
 
 // SetupTestDB creates a new SQLite in-memory database for testing
@@ -36,18 +53,42 @@ func SetupTestDB() (*TestDB, error) {
 		return nil, err
 	}
 
+	// Apply the schema
+	migrator, err := migrations.New(db, repository.DriverSQLite)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := migrator.Up(); err != nil {
+		return nil, err
+	}
+
 	// Create repositories
-	companyRepo := sqlite.NewCompanyRepository(db)
-	continentRepo := sqlite.NewContinentRepository(db)
-	countryRepo := sqlite.NewCountryRepository(db)
-	userRepo := sqlite.NewUserRepository(db)
+	factory, err := repository.NewFactory(repository.DriverSQLite)
+	if err != nil {
+		return nil, err
+	}
+	repos := factory.New(db)
 
 	return &TestDB{
 		DB:            db,
-		CompanyRepo:   companyRepo,
-		ContinentRepo: continentRepo,
-		CountryRepo:   countryRepo,
-		UserRepo:      userRepo,
+		DailyTaskRepo: repos.DailyTasks,
+		CompanyRepo:   repos.Companies,
+		ContinentRepo: repos.Continents,
+		CountryRepo:   repos.Countries,
+		UserRepo:      repos.Users,
+
+		RefreshTokenRepo: repos.RefreshTokens,
+		RevokedTokenRepo: repos.RevokedTokens,
+		SessionRepo:      repos.Sessions,
+
+		PasswordResetTokenRepo: repos.PasswordResetTokens,
+		MFARecoveryCodeRepo:    repos.MFARecoveryCodes,
+		AccessTokenRepo:        repos.AccessTokens,
+		ExternalIdentityRepo:   repos.ExternalIdentities,
+		SigningKeyRepo:         repos.SigningKeys,
+		InvitationRepo:         repos.Invitations,
+		RolePermissionRepo:     repos.RolePermissions,
+		AuditLogRepo:           repos.AuditLogs,
 	}, nil
 }
 
@@ -57,7 +98,7 @@ func SetupTestApp() *fiber.App {
 	log := logger.Get()
 
 	app := fiber.New(fiber.Config{
-		ErrorHandler: middleware.ErrorHandler(log),
+		ErrorHandler: middleware.ErrorHandler(log, false),
 	})
 
 	// Add request logger middleware
@@ -86,7 +127,7 @@ func CreateTestContinent(testDB *TestDB, name, code string) (*models.Continent,
 		Description: "Test continent",
 	}
 
-	err := testDB.ContinentRepo.Create(continent)
+	err := testDB.ContinentRepo.Create(context.Background(), continent)
 	if err != nil {
 		return nil, err
 	}
@@ -103,7 +144,7 @@ func CreateTestCountry(testDB *TestDB, name, code string, continentID int64) (*m
 		Description: "Test country",
 	}
 
-	err := testDB.CountryRepo.Create(country)
+	err := testDB.CountryRepo.Create(context.Background(), country)
 	if err != nil {
 		return nil, err
 	}
@@ -122,7 +163,7 @@ func CreateTestCompany(testDB *TestDB, name, code string, countryID int64) (*mod
 		Size:        "medium",
 	}
 
-	err := testDB.CompanyRepo.Create(company)
+	err := testDB.CompanyRepo.Create(context.Background(), company)
 	if err != nil {
 		return nil, err
 	}